@@ -1,12 +1,29 @@
 package cli
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"postapi/internal/application"
+	"time"
 )
 
 type Cli struct {
 	length int
 	args   []string
+	trash  *application.TrashUseCase
+	users  *application.UserUseCase
+	posts  *application.PostUseCase
+}
+
+func NewCli(args []string, trash *application.TrashUseCase, users *application.UserUseCase, posts *application.PostUseCase) *Cli {
+	return &Cli{
+		length: len(args),
+		args:   args,
+		trash:  trash,
+		users:  users,
+		posts:  posts,
+	}
 }
 
 func (c *Cli) StartCli() error {
@@ -15,7 +32,26 @@ func (c *Cli) StartCli() error {
 	}
 	switch c.args[0] {
 	case "-v":
-	case "erase":
+	case "purge":
+		users, posts, err := c.trash.Purge(context.Background(), time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Purged %d users and %d posts\n", users, posts)
+	case "recount":
+		fixed, err := c.users.Recount(context.Background())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Fixed the counts of %d users\n", fixed)
+	case "reindex":
+		indexed, err := c.posts.Reindex(context.Background())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Reindexed %d posts\n", indexed)
+	default:
+		return fmt.Errorf("unknown command %q", c.args[0])
 	}
 	return nil
 }
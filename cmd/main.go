@@ -2,23 +2,40 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"postapi/cmd/cli"
 	"postapi/internal/application"
+	"postapi/internal/domain"
+	"postapi/internal/infrastructure/activitypub"
 	"postapi/internal/infrastructure/handlers"
 	httpserver "postapi/internal/infrastructure/httpserver"
+	"postapi/internal/infrastructure/openapi"
 	"postapi/internal/infrastructure/persistence"
+	"postapi/internal/infrastructure/realtime"
+	"postapi/internal/infrastructure/storage"
+	"postapi/internal/infrastructure/webmention"
 	"postapi/internal/middleware"
+	"strings"
 	"syscall"
 	"time"
 )
 
 func main() {
 	// Configuración de la base de datos
-	database := &persistence.DB{}
+	queryTimeout, err := queryTimeout(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Invalid query timeout: %v", err)
+	}
+	database := &persistence.DB{
+		QueryTimeout: queryTimeout,
+		Driver:       os.Getenv("POSTAPI_DATABASE"),
+		DSN:          os.Getenv("POSTAPI_DATABASE_URL"),
+	}
 
-	err := database.Open()
+	err = database.Open()
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -28,29 +45,112 @@ func main() {
 	postRepo := database.PostRepository
 	profileRepo := database.ProfileRepository
 	followRepo := database.UserFollowRepository
+	tagRepo := database.TagRepository
+	mentionRepo := database.MentionRepository
+	notificationRepo := database.NotificationRepository
+	blockRepo := database.UserBlockRepository
+	conversationRepo := database.ConversationRepository
+	mediaRepo := database.MediaRepository
+
+	baseURL := os.Getenv("POSTAPI_BASE_URL")
+
+	blobStore, err := newBlobStore()
+	if err != nil {
+		log.Fatalf("Failed to open media storage: %v", err)
+	}
 
 	jwtService := application.NewJWTService("secret-key")
 
-	postUseCase := application.PostUseCase{PostRepo: postRepo}
-	userUseCase := application.UserUseCase{UserRepo: userRepo, FollowRepo: followRepo}
-	profileUseCase := application.ProfileUseCase{ProfileRepository: profileRepo}
+	mediaUseCase := application.MediaUseCase{MediaRepo: mediaRepo, BlobStore: blobStore}
+	trashUseCase := application.TrashUseCase{PostRepo: postRepo, UserRepo: userRepo, MediaUseCase: &mediaUseCase, Retention: application.TrashRetention}
+	postUseCase := application.PostUseCase{PostRepo: postRepo, TagRepo: tagRepo, MentionRepo: mentionRepo, UserRepo: userRepo, MediaRepo: mediaRepo, WebmentionRepo: database.WebmentionRepository, MediaUseCase: &mediaUseCase, TrashUseCase: &trashUseCase, UnitOfWork: database.UnitOfWork}
+	userUseCase := application.UserUseCase{UserRepo: userRepo, FollowRepo: followRepo, BlockRepo: blockRepo, TrashUseCase: &trashUseCase, UnitOfWork: database.UnitOfWork}
+	profileUseCase := application.ProfileUseCase{ProfileRepository: profileRepo, MediaRepo: mediaRepo, MediaUseCase: &mediaUseCase}
+	hub := realtime.NewHub(1024, 64)
+	eventUseCase := application.EventUseCase{Publisher: hub, FollowRepo: followRepo}
+	notificationUseCase := application.NotificationUseCase{NotificationRepo: notificationRepo, Events: &eventUseCase}
+	conversationUseCase := application.ConversationUseCase{
+		ConversationRepo: conversationRepo,
+		UserRepo:         userRepo,
+		FollowRepo:       followRepo,
+		BlockRepo:        blockRepo,
+		ProfileRepo:      profileRepo,
+	}
+	// Los IDs de ActivityPub y los enlaces de Webmention necesitan una URL
+	// pública fija, aunque no se haya configurado
+	siteURL := strings.TrimRight(baseURL, "/")
+	if siteURL == "" {
+		siteURL = "http://localhost:8080"
+	}
+	userAgent := "postapi (+" + siteURL + ")"
+	federationClient := activitypub.NewClient(userAgent)
+	federationUseCase := application.FederationUseCase{
+		BaseURL:        siteURL,
+		UserRepo:       userRepo,
+		PostRepo:       postRepo,
+		ProfileRepo:    profileRepo,
+		KeyRepo:        database.ActorKeyRepository,
+		FollowerRepo:   database.RemoteFollowerRepository,
+		DeliveryRepo:   database.DeliveryRepository,
+		WebmentionRepo: database.WebmentionRepository,
+		Client:         federationClient,
+	}
+	webmentionUseCase := application.WebmentionUseCase{
+		BaseURL:        siteURL,
+		PostRepo:       postRepo,
+		WebmentionRepo: database.WebmentionRepository,
+		Client:         webmention.NewClient(userAgent),
+	}
+	micropubUseCase := application.MicropubUseCase{BaseURL: siteURL, PostRepo: postRepo, MediaUseCase: &mediaUseCase}
 
-	postHandler := &handlers.PostHandler{PostUseCase: postUseCase}
-	followHandler := &handlers.FollowHandler{UserUseCase: userUseCase}
+	// Comandos de mantenimiento, p. ej. `purge` o `recount`
+	if len(os.Args) > 1 {
+		if err := cli.NewCli(os.Args[1:], &trashUseCase, &userUseCase, &postUseCase).StartCli(); err != nil {
+			log.Fatalf("Command failed: %v", err)
+		}
+		return
+	}
+
+	postHandler := &handlers.PostHandler{PostUseCase: postUseCase, NotificationUseCase: notificationUseCase, EventUseCase: eventUseCase, FederationUseCase: federationUseCase, WebmentionUseCase: webmentionUseCase}
+	followHandler := &handlers.FollowHandler{UserUseCase: userUseCase, NotificationUseCase: notificationUseCase, EventUseCase: eventUseCase}
 	userHandler := &handlers.UserHandler{UserUseCase: userUseCase, JWTService: jwtService}
 	profileHandler := &handlers.ProfileHandler{ProfileUseCase: profileUseCase}
-
-	authMiddleware := middleware.NewAuthMiddleware(jwtService)
+	tagHandler := &handlers.TagHandler{PostUseCase: postUseCase}
+	notificationHandler := &handlers.NotificationHandler{NotificationUseCase: notificationUseCase}
+	streamHandler := &handlers.StreamHandler{Hub: hub}
+	wsHandler := &handlers.WebSocketHandler{Hub: hub}
+	conversationHandler := &handlers.ConversationHandler{ConversationUseCase: conversationUseCase, EventUseCase: eventUseCase}
+	mediaHandler := &handlers.MediaHandler{MediaUseCase: mediaUseCase}
+	avatarHandler := &handlers.AvatarHandler{}
+	apHandler := &handlers.ActivityPubHandler{FederationUseCase: federationUseCase, Verifier: &activitypub.Verifier{Client: federationClient}}
+	wmHandler := &handlers.WebmentionHandler{WebmentionUseCase: webmentionUseCase}
+	mpHandler := &handlers.MicropubHandler{MicropubUseCase: micropubUseCase, Posts: postHandler, Media: mediaHandler}
+	feedHandler := &handlers.FeedHandler{PostUseCase: postUseCase, BaseURL: siteURL}
+	docsHandler := &handlers.OpenAPIHandler{Document: openapi.New(siteURL)}
+
+	authMiddleware := middleware.NewAuthMiddleware(jwtService, userRepo)
 
 	router := httpserver.NewRouter(
 		postHandler,
 		followHandler,
 		userHandler,
 		profileHandler,
+		tagHandler,
+		notificationHandler,
+		streamHandler,
+		wsHandler,
+		conversationHandler,
+		mediaHandler,
+		avatarHandler,
+		feedHandler,
+		apHandler,
+		wmHandler,
+		mpHandler,
+		docsHandler,
 		authMiddleware,
 	)
 
-	server := httpserver.NewServer("8080", router)
+	server := httpserver.NewServer("8080", router, hub)
 
 	// Canal para manejar señales de interrupción
 	done := make(chan os.Signal, 1)
@@ -65,9 +165,17 @@ func main() {
 
 	log.Println("Server started successfully")
 
+	// Tareas periódicas: purga de la papelera, envío de actividades federadas
+	// y verificación de webmentions
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	go trashUseCase.RunPurgeJob(jobsCtx, time.Hour)
+	go federationUseCase.RunDeliveryJob(jobsCtx, 10*time.Second)
+	go webmentionUseCase.RunVerificationJob(jobsCtx, 10*time.Second)
+
 	// Esperar señal de interrupción
 	<-done
 	log.Println("Server stopping...")
+	stopJobs()
 
 	// Shutdown gracefully con timeout de 30 segundos
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -79,3 +187,53 @@ func main() {
 
 	log.Println("Server stopped")
 }
+
+// queryTimeout lee POSTAPI_QUERY_TIMEOUT. Si no está definido, los comandos
+// de mantenimiento, que recorren tablas enteras, no tienen límite.
+func queryTimeout(args []string) (time.Duration, error) {
+	d, err := envDuration("POSTAPI_QUERY_TIMEOUT")
+	if err == nil && d == 0 && len(args) > 0 {
+		switch args[0] {
+		case "purge", "recount", "reindex":
+			d = -1
+		}
+	}
+	return d, err
+}
+
+// envDuration lee una duración como "5s" de la variable name; cero si no
+// está definida.
+func envDuration(name string) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	return d, nil
+}
+
+// newBlobStore elige dónde se guardan los archivos subidos según
+// POSTAPI_STORAGE: "local" (por defecto) o "s3".
+func newBlobStore() (domain.BlobStore, error) {
+	switch os.Getenv("POSTAPI_STORAGE") {
+	case "", "local":
+		dir := os.Getenv("POSTAPI_MEDIA_DIR")
+		if dir == "" {
+			dir = "./uploads"
+		}
+		return storage.NewLocalBlobStore(dir)
+	case "s3":
+		return storage.NewS3BlobStore(storage.S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			Region:    os.Getenv("S3_REGION"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		})
+	default:
+		return nil, fmt.Errorf("unknown POSTAPI_STORAGE %q", os.Getenv("POSTAPI_STORAGE"))
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEnvDuration(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"2s", 2 * time.Second, false},
+		{"-1s", -time.Second, false},
+		{"1500ms", 1500 * time.Millisecond, false},
+		{"5", 0, true},
+		{"soon", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("POSTAPI_QUERY_TIMEOUT", tt.value)
+			got, err := envDuration("POSTAPI_QUERY_TIMEOUT")
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("envDuration() = %v, %v, want %v (error %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestQueryTimeout(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		args  []string
+		want  time.Duration
+	}{
+		{"server", "", nil, 0},
+		{"command", "", []string{"recount"}, -1},
+		{"reindex", "", []string{"reindex"}, -1},
+		{"version", "", []string{"-v"}, 0},
+		{"command with a timeout", "2s", []string{"recount"}, 2 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("POSTAPI_QUERY_TIMEOUT", tt.value)
+			if got, err := queryTimeout(tt.args); err != nil || got != tt.want {
+				t.Errorf("queryTimeout(%v) = %v, %v, want %v", tt.args, got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package application
+
+import (
+	"bytes"
+	"html"
+	"log"
+	models "postapi/internal/domain"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// Raw HTML in the source is dropped by goldmark (no html.WithUnsafe), and the
+// output is sanitized again so only the elements below can reach a client.
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.Table, extension.Linkify, extension.Strikethrough),
+)
+
+var contentPolicy = newContentPolicy()
+
+func newContentPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowElements(
+		"p", "br", "hr", "blockquote",
+		"h1", "h2", "h3", "h4", "h5", "h6",
+		"ul", "ol", "li",
+		"pre", "code", "em", "strong", "del",
+		"table", "thead", "tbody", "tr", "th", "td",
+	)
+	p.AllowStandardURLs()
+	p.AllowURLSchemes("http", "https", "mailto")
+	p.AllowAttrs("href").OnElements("a")
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	p.AllowAttrs("class").Matching(bluemonday.SpaceSeparatedTokens).OnElements("code")
+	p.AllowAttrs("align").Matching(bluemonday.CellAlign).OnElements("th", "td")
+	p.AllowAttrs("start").Matching(bluemonday.Integer).OnElements("ol")
+	return p
+}
+
+// RenderContent turns the stored source of a post into sanitized HTML.
+func RenderContent(format string, content string) string {
+	if format == models.PostFormatMarkdown {
+		var buf bytes.Buffer
+		if err := markdown.Convert([]byte(content), &buf); err != nil {
+			log.Printf("Cannot render markdown. err = %v\n", err)
+			return renderPlainText(content)
+		}
+		return contentPolicy.Sanitize(buf.String())
+	}
+	return renderPlainText(content)
+}
+
+// renderPlainText escapes the text and keeps its paragraphs and line breaks.
+func renderPlainText(content string) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	var b strings.Builder
+	for _, paragraph := range strings.Split(content, "\n\n") {
+		paragraph = strings.Trim(paragraph, "\n")
+		if paragraph == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(html.EscapeString(paragraph), "\n", "<br>"))
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,96 @@
+package application
+
+import (
+	"postapi/internal/domain"
+	"strings"
+	"testing"
+)
+
+func TestRenderContent_PlainText(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "Single paragraph",
+			content: "Hello world",
+			want:    "<p>Hello world</p>\n",
+		},
+		{
+			name:    "Line breaks and paragraphs",
+			content: "line one\nline two\n\nsecond paragraph",
+			want:    "<p>line one<br>line two</p>\n<p>second paragraph</p>\n",
+		},
+		{
+			name:    "HTML is escaped",
+			content: "<script>alert(1)</script> **not bold**",
+			want:    "<p>&lt;script&gt;alert(1)&lt;/script&gt; **not bold**</p>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RenderContent(domain.PostFormatPlain, tt.content)
+			if got != tt.want {
+				t.Errorf("RenderContent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderContent_Markdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "Emphasis",
+			content:  "**bold** and _italic_",
+			contains: []string{"<strong>bold</strong>", "<em>italic</em>"},
+		},
+		{
+			name:     "Fenced code",
+			content:  "```go\nfmt.Println(\"hi\")\n```",
+			contains: []string{"<pre><code class=\"language-go\">", "fmt.Println(&#34;hi&#34;)"},
+		},
+		{
+			name:     "Table",
+			content:  "| a | b |\n|---|---|\n| 1 | 2 |",
+			contains: []string{"<table>", "<th>a</th>", "<td>2</td>"},
+		},
+		{
+			name:     "Autolink",
+			content:  "see https://example.com",
+			contains: []string{`href="https://example.com"`, `rel="nofollow noopener"`},
+		},
+		{
+			name:     "Raw HTML is dropped",
+			content:  "<script>alert(1)</script>\n\n<img src=x onerror=alert(1)>",
+			excludes: []string{"<script", "onerror", "<img"},
+		},
+		{
+			name:     "Javascript links are removed",
+			content:  "[click](javascript:alert(1))",
+			excludes: []string{"javascript:"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RenderContent(domain.PostFormatMarkdown, tt.content)
+			for _, want := range tt.contains {
+				if !strings.Contains(got, want) {
+					t.Errorf("RenderContent() = %q, want it to contain %q", got, want)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(got, unwanted) {
+					t.Errorf("RenderContent() = %q, must not contain %q", got, unwanted)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	models "postapi/internal/domain"
+	"slices"
+	"strings"
+	"time"
+)
+
+const maxMessageLength = 4000
+
+var (
+	ErrNotAllowed      = models.Forbidden(models.CodeNotAllowed, "not allowed to message this user")
+	ErrNotMember       = models.NotFound(models.CodeConversationNotFound, "conversation not found")
+	ErrInvalidMembers  = models.Invalid(models.CodeInvalidMembers, "invalid conversation members")
+	ErrInvalidMessage  = models.Invalid(models.CodeInvalidMessage, "invalid message body")
+	ErrUnknownUsername = models.Invalid(models.CodeUnknownUsername, "unknown username")
+	ErrUnknownMessage  = models.Invalid(models.CodeUnknownMessage, "message is not in the conversation")
+)
+
+type ConversationUseCase struct {
+	ConversationRepo models.ConversationRepository
+	UserRepo         models.UserRepository
+	FollowRepo       models.UserFollowRepository
+	BlockRepo        models.UserBlockRepository
+	ProfileRepo      models.ProfileRepository
+}
+
+// CanMessage checks whether sender may start a conversation with recipient:
+// neither may have blocked the other, and a private account only accepts
+// messages from the people it follows.
+func (c *ConversationUseCase) CanMessage(ctx context.Context, sender string, recipient string) error {
+	if _, err := c.UserRepo.FindByUsername(ctx, recipient); err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return fmt.Errorf("%w: %s", ErrUnknownUsername, recipient)
+		}
+		return err
+	}
+
+	blocked, err := c.BlockRepo.IsBlocked(ctx, sender, recipient)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrNotAllowed
+	}
+
+	profile, err := c.ProfileRepo.FindByUsername(ctx, recipient)
+	if err != nil && !errors.Is(err, models.ErrNotFound) {
+		return err
+	}
+	if profile == nil || !profile.Private {
+		return nil
+	}
+	follows, err := c.FollowRepo.Exists(ctx, &models.UserFollow{FollowerUsername: recipient, FollowedUsername: sender})
+	if err != nil {
+		return err
+	}
+	if !follows {
+		return ErrNotAllowed
+	}
+	return nil
+}
+
+// StartConversation opens a conversation between creator and members. A
+// one-to-one conversation that already exists is returned instead of a new one.
+func (c *ConversationUseCase) StartConversation(ctx context.Context, creator string, members []string) (*models.Conversation, error) {
+	others := []string{}
+	for _, member := range members {
+		member = strings.TrimSpace(member)
+		if member == "" || member == creator || slices.Contains(others, member) {
+			continue
+		}
+		others = append(others, member)
+	}
+	if len(others) == 0 || len(others)+1 > models.MaxConversationMembers {
+		return nil, ErrInvalidMembers
+	}
+
+	for _, member := range others {
+		if err := c.CanMessage(ctx, creator, member); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(others) == 1 {
+		existing, err := c.ConversationRepo.FindDirect(ctx, creator, others[0])
+		if err == nil {
+			return existing, nil
+		}
+		if !errors.Is(err, models.ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	conversation := &models.Conversation{
+		CreatedBy: creator,
+		IsGroup:   len(others) > 1,
+		CreatedAt: time.Now().UTC(),
+		Members:   append([]string{creator}, others...),
+	}
+	if err := c.ConversationRepo.Create(ctx, conversation); err != nil {
+		return nil, err
+	}
+	return conversation, nil
+}
+
+// GetConversation returns a conversation if username is one of its members.
+func (c *ConversationUseCase) GetConversation(ctx context.Context, id int64, username string) (*models.Conversation, error) {
+	conversation, err := c.ConversationRepo.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, ErrNotMember
+		}
+		return nil, err
+	}
+	if !slices.Contains(conversation.Members, username) {
+		return nil, ErrNotMember
+	}
+	return conversation, nil
+}
+
+// SendMessage posts a message to a conversation. Sending fails while any
+// other member and the sender have blocked each other.
+func (c *ConversationUseCase) SendMessage(ctx context.Context, conversationID int64, sender string, body string) (*models.Message, *models.Conversation, error) {
+	if strings.TrimSpace(body) == "" || len([]rune(body)) > maxMessageLength {
+		return nil, nil, ErrInvalidMessage
+	}
+	conversation, err := c.GetConversation(ctx, conversationID, sender)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, member := range conversation.Members {
+		if member == sender {
+			continue
+		}
+		blocked, err := c.BlockRepo.IsBlocked(ctx, sender, member)
+		if err != nil {
+			return nil, nil, err
+		}
+		if blocked {
+			return nil, nil, ErrNotAllowed
+		}
+	}
+
+	message := &models.Message{
+		ConversationID: conversationID,
+		Sender:         sender,
+		Body:           body,
+		CreatedAt:      time.Now().UTC(),
+	}
+	if err := c.ConversationRepo.CreateMessage(ctx, message); err != nil {
+		return nil, nil, err
+	}
+	return message, conversation, nil
+}
+
+// MarkRead marks the messages of a conversation up to messageID as read by
+// username, or all of them when messageID is zero.
+func (c *ConversationUseCase) MarkRead(ctx context.Context, conversationID int64, username string, messageID int64) error {
+	if _, err := c.GetConversation(ctx, conversationID, username); err != nil {
+		return err
+	}
+	if messageID < 0 {
+		return ErrUnknownMessage
+	}
+	if messageID != 0 {
+		if _, err := c.ConversationRepo.FindMessage(ctx, conversationID, messageID); err != nil {
+			if errors.Is(err, models.ErrNotFound) {
+				return ErrUnknownMessage
+			}
+			return err
+		}
+	}
+	return c.ConversationRepo.MarkRead(ctx, conversationID, username, messageID)
+}
+
+func MapConversationToJson(c *models.Conversation) models.JsonConversation {
+	return models.JsonConversation{
+		ID:                c.ID,
+		CreatedBy:         c.CreatedBy,
+		IsGroup:           c.IsGroup,
+		Members:           c.Members,
+		CreatedAt:         c.CreatedAt,
+		LastReadMessageID: c.LastReadMessageID,
+		UnreadCount:       c.UnreadCount,
+	}
+}
+
+func MapMessageToJson(m *models.Message) models.JsonMessage {
+	return models.JsonMessage{
+		ID:             m.ID,
+		ConversationID: m.ConversationID,
+		Sender:         m.Sender,
+		Body:           m.Body,
+		CreatedAt:      m.CreatedAt,
+	}
+}
+
+func MapBlockToJson(b *models.UserBlock) models.JsonUserBlock {
+	return models.JsonUserBlock{
+		BlockerUsername: b.BlockerUsername,
+		BlockedUsername: b.BlockedUsername,
+	}
+}
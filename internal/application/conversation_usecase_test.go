@@ -0,0 +1,229 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"postapi/internal/domain"
+	"testing"
+)
+
+type fakeConvUserRepo struct {
+	domain.UserRepository
+}
+
+func (f *fakeConvUserRepo) FindByUsername(ctx context.Context, username string) (*domain.User, error) {
+	if username == "ghost" {
+		return nil, domain.ErrNotFound
+	}
+	return &domain.User{Username: username}, nil
+}
+
+type fakeConvBlockRepo struct {
+	domain.UserBlockRepository
+	blocked map[[2]string]bool
+}
+
+func (f *fakeConvBlockRepo) IsBlocked(ctx context.Context, username string, other string) (bool, error) {
+	return f.blocked[[2]string{username, other}] || f.blocked[[2]string{other, username}], nil
+}
+
+type fakeConvFollowRepo struct {
+	domain.UserFollowRepository
+	follows map[[2]string]bool
+}
+
+func (f *fakeConvFollowRepo) Exists(ctx context.Context, follow *domain.UserFollow) (bool, error) {
+	return f.follows[[2]string{follow.FollowerUsername, follow.FollowedUsername}], nil
+}
+
+type fakeConvProfileRepo struct {
+	domain.ProfileRepository
+	private map[string]bool
+}
+
+func (f *fakeConvProfileRepo) FindByUsername(ctx context.Context, username string) (*domain.Profile, error) {
+	private, ok := f.private[username]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &domain.Profile{Username: username, Private: private}, nil
+}
+
+type fakeConversationRepo struct {
+	domain.ConversationRepository
+	conversations []*domain.Conversation
+	messages      []*domain.Message
+	read          []int64
+}
+
+func (f *fakeConversationRepo) Create(ctx context.Context, c *domain.Conversation) error {
+	c.ID = int64(len(f.conversations) + 1)
+	f.conversations = append(f.conversations, c)
+	return nil
+}
+
+func (f *fakeConversationRepo) FindByID(ctx context.Context, id int64) (*domain.Conversation, error) {
+	for _, c := range f.conversations {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (f *fakeConversationRepo) FindDirect(ctx context.Context, username string, other string) (*domain.Conversation, error) {
+	for _, c := range f.conversations {
+		if !c.IsGroup && len(c.Members) == 2 &&
+			((c.Members[0] == username && c.Members[1] == other) || (c.Members[0] == other && c.Members[1] == username)) {
+			return c, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (f *fakeConversationRepo) CreateMessage(ctx context.Context, m *domain.Message) error {
+	m.ID = int64(len(f.messages) + 1)
+	f.messages = append(f.messages, m)
+	return nil
+}
+
+func (f *fakeConversationRepo) FindMessage(ctx context.Context, conversationID int64, id int64) (*domain.Message, error) {
+	for _, m := range f.messages {
+		if m.ID == id && m.ConversationID == conversationID {
+			return m, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (f *fakeConversationRepo) MarkRead(ctx context.Context, conversationID int64, username string, messageID int64) error {
+	f.read = append(f.read, messageID)
+	return nil
+}
+
+func newTestConversationUseCase() (*ConversationUseCase, *fakeConversationRepo, *fakeConvBlockRepo) {
+	repo := &fakeConversationRepo{}
+	blocks := &fakeConvBlockRepo{blocked: map[[2]string]bool{}}
+	uc := &ConversationUseCase{
+		ConversationRepo: repo,
+		UserRepo:         &fakeConvUserRepo{},
+		BlockRepo:        blocks,
+		FollowRepo:       &fakeConvFollowRepo{follows: map[[2]string]bool{{"priv", "alice"}: true}},
+		ProfileRepo:      &fakeConvProfileRepo{private: map[string]bool{"priv": true, "bob": false}},
+	}
+	return uc, repo, blocks
+}
+
+func TestConversationUseCase_CanMessage(t *testing.T) {
+	uc, _, blocks := newTestConversationUseCase()
+	blocks.blocked[[2]string{"carol", "alice"}] = true
+
+	tests := []struct {
+		name      string
+		sender    string
+		recipient string
+		wantErr   error
+	}{
+		{"Public account", "alice", "bob", nil},
+		{"No profile", "alice", "dave", nil},
+		{"Blocked by recipient", "alice", "carol", ErrNotAllowed},
+		{"Private account following sender", "alice", "priv", nil},
+		{"Private account not following sender", "bob", "priv", ErrNotAllowed},
+		{"Unknown user", "alice", "ghost", ErrUnknownUsername},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := uc.CanMessage(context.Background(), tt.sender, tt.recipient)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("CanMessage() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConversationUseCase_StartConversation(t *testing.T) {
+	uc, repo, _ := newTestConversationUseCase()
+
+	direct, err := uc.StartConversation(context.Background(), "alice", []string{"bob", "alice", "bob"})
+	if err != nil {
+		t.Fatalf("StartConversation() error = %v", err)
+	}
+	if direct.IsGroup || len(direct.Members) != 2 {
+		t.Errorf("StartConversation() = %+v, want a one-to-one conversation", direct)
+	}
+
+	again, err := uc.StartConversation(context.Background(), "bob", []string{"alice"})
+	if err != nil {
+		t.Fatalf("StartConversation() error = %v", err)
+	}
+	if again.ID != direct.ID || len(repo.conversations) != 1 {
+		t.Error("StartConversation() should reuse the existing one-to-one conversation")
+	}
+
+	group, err := uc.StartConversation(context.Background(), "alice", []string{"bob", "dave"})
+	if err != nil {
+		t.Fatalf("StartConversation() error = %v", err)
+	}
+	if !group.IsGroup || len(group.Members) != 3 {
+		t.Errorf("StartConversation() = %+v, want a group of 3", group)
+	}
+
+	if _, err := uc.StartConversation(context.Background(), "alice", []string{"alice"}); !errors.Is(err, ErrInvalidMembers) {
+		t.Errorf("StartConversation() with no other members error = %v, want ErrInvalidMembers", err)
+	}
+	if _, err := uc.StartConversation(context.Background(), "bob", []string{"alice", "priv"}); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("StartConversation() with a private member error = %v, want ErrNotAllowed", err)
+	}
+}
+
+func TestConversationUseCase_SendMessage(t *testing.T) {
+	uc, repo, blocks := newTestConversationUseCase()
+	conversation, _ := uc.StartConversation(context.Background(), "alice", []string{"bob"})
+
+	message, _, err := uc.SendMessage(context.Background(), conversation.ID, "bob", "hi alice")
+	if err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+	if message.ID != 1 || message.Sender != "bob" || len(repo.messages) != 1 {
+		t.Errorf("SendMessage() = %+v", message)
+	}
+
+	if _, _, err := uc.SendMessage(context.Background(), conversation.ID, "dave", "let me in"); !errors.Is(err, ErrNotMember) {
+		t.Errorf("SendMessage() by a non-member error = %v, want ErrNotMember", err)
+	}
+	if _, _, err := uc.SendMessage(context.Background(), conversation.ID, "bob", "   "); !errors.Is(err, ErrInvalidMessage) {
+		t.Errorf("SendMessage() with an empty body error = %v, want ErrInvalidMessage", err)
+	}
+
+	blocks.blocked[[2]string{"alice", "bob"}] = true
+	if _, _, err := uc.SendMessage(context.Background(), conversation.ID, "bob", "still there?"); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("SendMessage() after a block error = %v, want ErrNotAllowed", err)
+	}
+}
+
+func TestConversationUseCase_MarkRead(t *testing.T) {
+	uc, repo, _ := newTestConversationUseCase()
+	conversation, _ := uc.StartConversation(context.Background(), "alice", []string{"bob"})
+	other, _ := uc.StartConversation(context.Background(), "alice", []string{"priv"})
+	message, _, _ := uc.SendMessage(context.Background(), conversation.ID, "alice", "hi bob")
+	elsewhere, _, _ := uc.SendMessage(context.Background(), other.ID, "alice", "hi priv")
+
+	if err := uc.MarkRead(context.Background(), conversation.ID, "bob", message.ID); err != nil {
+		t.Fatalf("MarkRead() error = %v", err)
+	}
+	if err := uc.MarkRead(context.Background(), conversation.ID, "bob", 0); err != nil {
+		t.Fatalf("MarkRead() of the whole conversation error = %v", err)
+	}
+	for _, id := range []int64{elsewhere.ID, elsewhere.ID + 1, -1} {
+		if err := uc.MarkRead(context.Background(), conversation.ID, "bob", id); !errors.Is(err, ErrUnknownMessage) {
+			t.Errorf("MarkRead(%d) error = %v, want ErrUnknownMessage", id, err)
+		}
+	}
+	if err := uc.MarkRead(context.Background(), conversation.ID, "dave", 0); !errors.Is(err, ErrNotMember) {
+		t.Errorf("MarkRead() by a non-member error = %v, want ErrNotMember", err)
+	}
+	if len(repo.read) != 2 || repo.read[0] != message.ID || repo.read[1] != 0 {
+		t.Errorf("marked %v as read, want [%d 0]", repo.read, message.ID)
+	}
+}
@@ -0,0 +1,68 @@
+package application
+
+import (
+	"context"
+	models "postapi/internal/domain"
+)
+
+// Tipos de eventos en tiempo real
+const (
+	EventPostCreated  = "post.created"
+	EventFollow       = "follow"
+	EventNotification = "notification"
+	EventMessage      = "message.created"
+)
+
+// EventPublisher fans real-time events out to the subscribers of any of the
+// given topics.
+type EventPublisher interface {
+	Publish(eventType string, data any, topics ...string)
+}
+
+func HomeTopic(username string) string          { return "home:" + username }
+func UserTopic(username string) string          { return "user:" + username }
+func TagTopic(tag string) string                { return "tag:" + tag }
+func NotificationsTopic(username string) string { return "notifications:" + username }
+
+type EventUseCase struct {
+	Publisher  EventPublisher
+	FollowRepo models.UserFollowRepository
+}
+
+// PostCreated pushes a new post to its author's followers, to the author's
+// own timeline and to the pages of its tags.
+func (e *EventUseCase) PostCreated(ctx context.Context, post *models.Post) error {
+	followers, err := e.FollowRepo.GetFollowers(ctx, post.Author)
+	if err != nil {
+		return err
+	}
+	topics := []string{UserTopic(post.Author), HomeTopic(post.Author)}
+	for _, follower := range followers {
+		topics = append(topics, HomeTopic(follower))
+	}
+	for _, tag := range ExtractHashtags(post.Title, post.Content) {
+		topics = append(topics, TagTopic(tag))
+	}
+	e.Publisher.Publish(EventPostCreated, MapPostToJson(post), topics...)
+	return nil
+}
+
+func (e *EventUseCase) Followed(follow *models.UserFollow) {
+	e.Publisher.Publish(EventFollow, MapFollowToJson(follow), NotificationsTopic(follow.FollowedUsername))
+}
+
+func (e *EventUseCase) NotificationCreated(n *models.Notification) {
+	e.Publisher.Publish(EventNotification, MapNotificationToJson(n), NotificationsTopic(n.Recipient))
+}
+
+// MessageSent pushes a direct message to the other members of its
+// conversation.
+func (e *EventUseCase) MessageSent(message *models.Message, members []string) {
+	topics := []string{}
+	for _, member := range members {
+		if member != message.Sender {
+			topics = append(topics, NotificationsTopic(member))
+		}
+	}
+	e.Publisher.Publish(EventMessage, MapMessageToJson(message), topics...)
+}
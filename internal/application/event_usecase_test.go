@@ -0,0 +1,77 @@
+package application
+
+import (
+	"context"
+	"postapi/internal/domain"
+	"reflect"
+	"testing"
+)
+
+type publishedEvent struct {
+	eventType string
+	data      any
+	topics    []string
+}
+
+type fakePublisher struct {
+	events []publishedEvent
+}
+
+func (f *fakePublisher) Publish(eventType string, data any, topics ...string) {
+	f.events = append(f.events, publishedEvent{eventType, data, topics})
+}
+
+type fakeEventFollowRepo struct {
+	domain.UserFollowRepository
+	followers []string
+}
+
+func (f *fakeEventFollowRepo) GetFollowers(ctx context.Context, username string) ([]string, error) {
+	return f.followers, nil
+}
+
+func TestEventUseCase_PostCreated(t *testing.T) {
+	publisher := &fakePublisher{}
+	uc := &EventUseCase{
+		Publisher:  publisher,
+		FollowRepo: &fakeEventFollowRepo{followers: []string{"bob", "carol"}},
+	}
+
+	post := &domain.Post{ID: 1, Title: "Hello", Content: "about #go", Author: "alice"}
+	if err := uc.PostCreated(context.Background(), post); err != nil {
+		t.Fatalf("PostCreated() error = %v", err)
+	}
+
+	if len(publisher.events) != 1 {
+		t.Fatalf("PostCreated() published %d events, want 1", len(publisher.events))
+	}
+	ev := publisher.events[0]
+	if ev.eventType != EventPostCreated {
+		t.Errorf("event type = %v, want %v", ev.eventType, EventPostCreated)
+	}
+	wantTopics := []string{"user:alice", "home:alice", "home:bob", "home:carol", "tag:go"}
+	if !reflect.DeepEqual(ev.topics, wantTopics) {
+		t.Errorf("topics = %v, want %v", ev.topics, wantTopics)
+	}
+	if data, ok := ev.data.(domain.JsonPost); !ok || data.ID != 1 {
+		t.Errorf("data = %#v, want the mapped post", ev.data)
+	}
+}
+
+func TestEventUseCase_NotificationCreated(t *testing.T) {
+	publisher := &fakePublisher{}
+	uc := &EventUseCase{Publisher: publisher}
+	notifications := &NotificationUseCase{NotificationRepo: &fakeNotificationRepo{}, Events: uc}
+
+	if _, err := notifications.Notify(context.Background(), "alice", "bob", domain.NotificationFollow, nil); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	if len(publisher.events) != 1 {
+		t.Fatalf("Notify() published %d events, want 1", len(publisher.events))
+	}
+	ev := publisher.events[0]
+	if ev.eventType != EventNotification || !reflect.DeepEqual(ev.topics, []string{"notifications:alice"}) {
+		t.Errorf("published %+v", ev)
+	}
+}
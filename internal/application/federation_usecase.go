@@ -0,0 +1,620 @@
+package application
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/url"
+	models "postapi/internal/domain"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// OutboxPageSize is the number of activities per outbox page.
+	OutboxPageSize = 20
+	// MaxDeliveryAttempts is how often a delivery is tried before it is
+	// dropped.
+	MaxDeliveryAttempts = 10
+	maxDeliveryBackoff  = 6 * time.Hour
+)
+
+var (
+	ErrActorMismatch   = models.Forbidden(models.CodeActorMismatch, "activity actor does not match the signature")
+	ErrUnknownResource = models.NotFound(models.CodeNotFound, "unknown resource")
+)
+
+// FederationClient talks to remote ActivityPub servers.
+type FederationClient interface {
+	FetchActor(id string) (*models.RemoteActor, error)
+	Deliver(inbox string, payload []byte, keyID string, privateKeyPem string) error
+}
+
+// FederationUseCase publishes local users and their posts over ActivityPub
+// and handles activities received from remote servers. BaseURL is the public
+// root of the site; the IDs built from it must never change.
+type FederationUseCase struct {
+	BaseURL      string
+	UserRepo     models.UserRepository
+	PostRepo     models.PostRepository
+	ProfileRepo  models.ProfileRepository
+	KeyRepo      models.ActorKeyRepository
+	FollowerRepo models.RemoteFollowerRepository
+	DeliveryRepo models.DeliveryRepository
+	// WebmentionRepo keeps the replies to local posts, shown with their
+	// webmentions.
+	WebmentionRepo models.WebmentionRepository
+	Client         FederationClient
+}
+
+func (f *FederationUseCase) Domain() string {
+	u, err := url.Parse(f.BaseURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+func (f *FederationUseCase) ActorID(username string) string {
+	return f.BaseURL + "/users/" + url.PathEscape(username)
+}
+
+func (f *FederationUseCase) KeyID(username string) string {
+	return f.ActorID(username) + "#main-key"
+}
+
+func (f *FederationUseCase) NoteID(post *models.Post) string {
+	return f.ActorID(post.Author) + "/notes/" + strconv.FormatInt(post.ID, 10)
+}
+
+func (f *FederationUseCase) followersID(username string) string {
+	return f.ActorID(username) + "/followers"
+}
+
+func (f *FederationUseCase) outboxID(username string) string {
+	return f.ActorID(username) + "/outbox"
+}
+
+// ActorKey returns the signing key of username, creating it on first use.
+func (f *FederationUseCase) ActorKey(ctx context.Context, username string) (*models.ActorKey, error) {
+	key, err := f.KeyRepo.FindByUsername(ctx, username)
+	if err == nil {
+		return key, nil
+	}
+	if !errors.Is(err, models.ErrNotFound) {
+		return nil, err
+	}
+
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	public, err := x509.MarshalPKIXPublicKey(&private.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	key = &models.ActorKey{
+		Username:      username,
+		PrivateKeyPem: string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(private)})),
+		PublicKeyPem:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public})),
+	}
+	if err := f.KeyRepo.Create(ctx, key); err != nil {
+		return nil, err
+	}
+	// Another request may have created a key first; use the stored one.
+	return f.KeyRepo.FindByUsername(ctx, username)
+}
+
+// WebFinger resolves an acct:user@domain resource or an actor URL.
+func (f *FederationUseCase) WebFinger(ctx context.Context, resource string) (*models.WebFinger, error) {
+	var username string
+	switch {
+	case strings.HasPrefix(resource, "acct:"):
+		name, host, ok := strings.Cut(strings.TrimPrefix(resource, "acct:"), "@")
+		if !ok || !strings.EqualFold(host, f.Domain()) {
+			return nil, ErrUnknownResource
+		}
+		username = name
+	case strings.HasPrefix(resource, f.BaseURL+"/users/"):
+		name, err := url.PathUnescape(strings.TrimPrefix(resource, f.BaseURL+"/users/"))
+		if err != nil {
+			return nil, ErrUnknownResource
+		}
+		username = name
+	default:
+		return nil, ErrUnknownResource
+	}
+	if _, err := f.UserRepo.FindByUsername(ctx, username); err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, ErrUnknownResource
+		}
+		return nil, err
+	}
+
+	actorID := f.ActorID(username)
+	return &models.WebFinger{
+		Subject: "acct:" + username + "@" + f.Domain(),
+		Aliases: []string{actorID},
+		Links: []models.WebFingerLink{
+			{Rel: "self", Type: models.ActivityContentType, Href: actorID},
+		},
+	}, nil
+}
+
+// Actor returns the actor document of a local user.
+func (f *FederationUseCase) Actor(ctx context.Context, username string) (*models.APActor, error) {
+	if _, err := f.UserRepo.FindByUsername(ctx, username); err != nil {
+		return nil, err
+	}
+	key, err := f.ActorKey(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	actorID := f.ActorID(username)
+	actor := &models.APActor{
+		Context:           []string{models.ActivityStreamsContext, models.SecurityContext},
+		ID:                actorID,
+		Type:              "Person",
+		PreferredUsername: username,
+		Name:              username,
+		URL:               f.BaseURL + "/api/users/" + url.PathEscape(username) + "/posts",
+		Inbox:             actorID + "/inbox",
+		Outbox:            f.outboxID(username),
+		Followers:         f.followersID(username),
+		PublicKey: models.APPublicKey{
+			ID:           f.KeyID(username),
+			Owner:        actorID,
+			PublicKeyPem: key.PublicKeyPem,
+		},
+	}
+
+	profile, err := f.ProfileRepo.FindByUsername(ctx, username)
+	if err != nil && !errors.Is(err, models.ErrNotFound) {
+		return nil, err
+	}
+	picture := DefaultAvatarURL(username) + "?format=png"
+	if profile != nil {
+		actor.Summary = renderPlainText(profile.Description)
+		if profile.ProfilePicture != "" {
+			picture = profile.ProfilePicture
+		}
+	}
+	if strings.HasPrefix(picture, "/") {
+		picture = f.BaseURL + picture
+	}
+	actor.Icon = &models.APImage{Type: "Image", URL: picture}
+	return actor, nil
+}
+
+// Note returns the ActivityPub object of a post.
+func (f *FederationUseCase) Note(post *models.Post) *models.APNote {
+	note := &models.APNote{
+		ID:           f.NoteID(post),
+		Type:         "Note",
+		AttributedTo: f.ActorID(post.Author),
+		Name:         post.Title,
+		Content:      "<p><strong>" + html.EscapeString(post.Title) + "</strong></p>" + RenderContent(post.Format, post.Content),
+		URL:          f.BaseURL + "/api/posts/" + strconv.FormatInt(post.ID, 10),
+		Published:    post.CreatedAt.UTC().Format(time.RFC3339),
+		To:           []string{models.PublicCollection},
+		Cc:           []string{f.followersID(post.Author)},
+	}
+	for _, tag := range ExtractHashtags(post.Title, post.Content) {
+		note.Tag = append(note.Tag, models.APTag{
+			Type: "Hashtag",
+			Name: "#" + tag,
+			Href: f.BaseURL + "/api/tags/" + url.PathEscape(tag) + "/posts",
+		})
+	}
+	return note
+}
+
+// FindNote returns the note of a post by username, which must be visible.
+func (f *FederationUseCase) FindNote(ctx context.Context, username string, postID int64) (*models.APNote, error) {
+	post, err := f.PostRepo.FindByID(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	if post.Author != username || post.DeletedAt != nil {
+		return nil, models.ErrNotFound
+	}
+	note := f.Note(post)
+	note.Context = models.ActivityStreamsContext
+	return note, nil
+}
+
+func (f *FederationUseCase) createActivity(post *models.Post) *models.APActivity {
+	note := f.Note(post)
+	return &models.APActivity{
+		ID:        note.ID + "/activity",
+		Type:      "Create",
+		Actor:     note.AttributedTo,
+		Published: note.Published,
+		To:        note.To,
+		Cc:        note.Cc,
+		Object:    note,
+	}
+}
+
+// Outbox returns the outbox collection of username, or one of its pages when
+// page is positive.
+func (f *FederationUseCase) Outbox(ctx context.Context, username string, page int) (any, error) {
+	user, err := f.UserRepo.FindByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	outboxID := f.outboxID(username)
+	if page <= 0 {
+		return &models.APOrderedCollection{
+			Context:    models.ActivityStreamsContext,
+			ID:         outboxID,
+			Type:       "OrderedCollection",
+			TotalItems: user.PostCount,
+			First:      outboxID + "?page=1",
+		}, nil
+	}
+
+	// One post more than a page tells whether there is a next one. Pages
+	// past the last post are not looked up, so their offset cannot overflow
+	var posts []*models.Post
+	if int64(page-1) <= user.PostCount/OutboxPageSize {
+		posts, err = f.PostRepo.FindLatestByAuthor(ctx, username, OutboxPageSize+1, (page-1)*OutboxPageSize)
+		if err != nil {
+			return nil, err
+		}
+	}
+	collection := &models.APOrderedCollectionPage{
+		Context:      models.ActivityStreamsContext,
+		ID:           outboxID + "?page=" + strconv.Itoa(page),
+		Type:         "OrderedCollectionPage",
+		PartOf:       outboxID,
+		OrderedItems: []any{},
+	}
+	for i := 0; i < len(posts) && i < OutboxPageSize; i++ {
+		collection.OrderedItems = append(collection.OrderedItems, f.createActivity(posts[i]))
+	}
+	if len(posts) > OutboxPageSize {
+		collection.Next = outboxID + "?page=" + strconv.Itoa(page+1)
+	}
+	return collection, nil
+}
+
+// Followers returns the follower collection of username. Only the count is
+// published.
+func (f *FederationUseCase) Followers(ctx context.Context, username string) (*models.APOrderedCollection, error) {
+	if _, err := f.UserRepo.FindByUsername(ctx, username); err != nil {
+		return nil, err
+	}
+	count, err := f.FollowerRepo.CountByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	return &models.APOrderedCollection{
+		Context:    models.ActivityStreamsContext,
+		ID:         f.followersID(username),
+		Type:       "OrderedCollection",
+		TotalItems: count,
+	}, nil
+}
+
+// HandleActivity processes an activity posted to the inbox of username by
+// signer, the actor whose key signed the request. Activity types the server
+// does not act on are ignored.
+func (f *FederationUseCase) HandleActivity(ctx context.Context, username string, signer *models.RemoteActor, body []byte) error {
+	if _, err := f.UserRepo.FindByUsername(ctx, username); err != nil {
+		return err
+	}
+	var activity models.APIncoming
+	if err := json.Unmarshal(body, &activity); err != nil {
+		return fmt.Errorf("invalid activity: %w", err)
+	}
+	if rawID(activity.Actor) != signer.ID {
+		return ErrActorMismatch
+	}
+
+	switch activity.Type {
+	case "Follow":
+		if rawID(activity.Object) != f.ActorID(username) {
+			return nil
+		}
+		err := f.FollowerRepo.Add(ctx, &models.RemoteFollower{
+			Username:  username,
+			ActorID:   signer.ID,
+			Inbox:     signer.DeliveryInbox(),
+			FollowID:  activity.ID,
+			CreatedAt: time.Now().UTC(),
+		})
+		if err != nil {
+			return err
+		}
+		accept := &models.APActivity{
+			Context: models.ActivityStreamsContext,
+			ID:      f.ActorID(username) + "#accepts/" + hashID(activity.ID),
+			Type:    "Accept",
+			Actor:   f.ActorID(username),
+			Object:  json.RawMessage(body),
+		}
+		return f.enqueue(ctx, username, accept, signer.Inbox)
+
+	case "Undo":
+		undone, err := f.undoesFollow(ctx, username, signer, activity.Object)
+		if err != nil || !undone {
+			return err
+		}
+		return f.FollowerRepo.Remove(ctx, username, signer.ID)
+
+	case "Create":
+		return f.receiveReply(ctx, username, signer, activity.Object)
+
+	case "Delete":
+		id := rawID(activity.Object)
+		if id == signer.ID {
+			return f.FollowerRepo.RemoveActor(ctx, signer.ID)
+		}
+		// Only a reply from the signer's own server can be its to delete
+		if id != "" && sameHost(id, signer.ID) {
+			return f.WebmentionRepo.DeleteBySource(ctx, id)
+		}
+	}
+	return nil
+}
+
+// receiveReply keeps a note by signer that replies to a post of username, as
+// a verified reply webmention whose source is the note. Other notes are
+// ignored: local users follow no one, so there is no timeline to show them in.
+func (f *FederationUseCase) receiveReply(ctx context.Context, username string, signer *models.RemoteActor, object json.RawMessage) error {
+	var note models.APIncomingNote
+	if json.Unmarshal(object, &note) != nil || note.Type != "Note" || note.ID == "" {
+		return nil
+	}
+	if rawID(note.AttributedTo) != signer.ID || !sameHost(note.ID, signer.ID) {
+		return nil
+	}
+	postID, ok := PostIDFromURL(f.BaseURL, rawID(note.InReplyTo))
+	if !ok {
+		return nil
+	}
+	post, err := f.PostRepo.FindByID(ctx, postID)
+	if errors.Is(err, models.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if post.Author != username {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	reply := &models.Webmention{
+		PostID:        post.ID,
+		Source:        note.ID,
+		Target:        f.NoteID(post),
+		Status:        models.WebmentionPending,
+		Type:          models.WebmentionReply,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := f.WebmentionRepo.Save(ctx, reply); err != nil {
+		return err
+	}
+	// The signature vouches for the note, so it needs no verification
+	reply.Status = models.WebmentionVerified
+	reply.AuthorName = signer.PreferredUsername
+	reply.AuthorURL = signer.ID
+	reply.Content = truncateText(htmlText(note.Content), maxWebmentionContent)
+	if published, err := time.Parse(time.RFC3339, note.Published); err == nil {
+		reply.Published = &published
+	}
+	reply.VerifiedAt = &now
+	return f.WebmentionRepo.Update(ctx, reply)
+}
+
+// sameHost tells whether the URLs a and b are on the same server.
+func sameHost(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	return err == nil && ua.Host != "" && strings.EqualFold(ua.Host, ub.Host)
+}
+
+// undoesFollow tells whether the object of an Undo by signer is its follow of
+// username. Undos of likes, announces and anything else are ignored.
+func (f *FederationUseCase) undoesFollow(ctx context.Context, username string, signer *models.RemoteActor, object json.RawMessage) (bool, error) {
+	var embedded models.APIncomingObject
+	if json.Unmarshal(object, &embedded) == nil && embedded.Type != "" {
+		if embedded.Type != "Follow" {
+			return false, nil
+		}
+		if rawID(embedded.Object) == f.ActorID(username) {
+			return true, nil
+		}
+	}
+
+	// Otherwise its ID must be the one of the Follow
+	id := rawID(object)
+	follower, err := f.FollowerRepo.Find(ctx, username, signer.ID)
+	if errors.Is(err, models.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return id != "" && id == follower.FollowID, nil
+}
+
+// PostCreated sends a new post to the remote followers of its author.
+func (f *FederationUseCase) PostCreated(ctx context.Context, post *models.Post) error {
+	activity := f.createActivity(post)
+	activity.Context = models.ActivityStreamsContext
+	return f.enqueueToFollowers(ctx, post.Author, activity)
+}
+
+// PostUpdated sends the new version of a post to remote followers.
+func (f *FederationUseCase) PostUpdated(ctx context.Context, post *models.Post) error {
+	note := f.Note(post)
+	now := time.Now().UTC()
+	return f.enqueueToFollowers(ctx, post.Author, &models.APActivity{
+		Context:   models.ActivityStreamsContext,
+		ID:        note.ID + "#updates/" + strconv.FormatInt(now.UnixNano(), 10),
+		Type:      "Update",
+		Actor:     note.AttributedTo,
+		Published: now.Format(time.RFC3339),
+		To:        note.To,
+		Cc:        note.Cc,
+		Object:    note,
+	})
+}
+
+// PostDeleted tells remote followers to remove a post.
+func (f *FederationUseCase) PostDeleted(ctx context.Context, post *models.Post) error {
+	noteID := f.NoteID(post)
+	return f.enqueueToFollowers(ctx, post.Author, &models.APActivity{
+		Context: models.ActivityStreamsContext,
+		ID:      noteID + "#delete",
+		Type:    "Delete",
+		Actor:   f.ActorID(post.Author),
+		To:      []string{models.PublicCollection},
+		Object:  map[string]string{"id": noteID, "type": "Tombstone"},
+	})
+}
+
+func (f *FederationUseCase) enqueueToFollowers(ctx context.Context, username string, activity *models.APActivity) error {
+	followers, err := f.FollowerRepo.FindByUsername(ctx, username)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	var errs []error
+	for _, follower := range followers {
+		if seen[follower.Inbox] {
+			continue
+		}
+		seen[follower.Inbox] = true
+		errs = append(errs, f.enqueue(ctx, username, activity, follower.Inbox))
+	}
+	return errors.Join(errs...)
+}
+
+func (f *FederationUseCase) enqueue(ctx context.Context, sender string, activity *models.APActivity, inbox string) error {
+	payload, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	return f.DeliveryRepo.Enqueue(ctx, &models.Delivery{
+		Sender:        sender,
+		Inbox:         inbox,
+		Payload:       string(payload),
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	})
+}
+
+// DeliverDue posts the deliveries that are due. Failed deliveries are
+// retried with exponential backoff and dropped after MaxDeliveryAttempts.
+func (f *FederationUseCase) DeliverDue(ctx context.Context, now time.Time) (delivered int, err error) {
+	due, err := f.DeliveryRepo.FindDue(ctx, now, 50)
+	if err != nil {
+		return 0, err
+	}
+	for _, d := range due {
+		sendErr := f.deliver(ctx, d)
+		if sendErr == nil {
+			delivered++
+			if err := f.DeliveryRepo.Delete(ctx, d.ID); err != nil {
+				return delivered, err
+			}
+			continue
+		}
+
+		attempts := d.Attempts + 1
+		if attempts >= MaxDeliveryAttempts {
+			log.Printf("Dropping delivery %d to %s after %d attempts. err = %v\n", d.ID, d.Inbox, attempts, sendErr)
+			if err := f.DeliveryRepo.Delete(ctx, d.ID); err != nil {
+				return delivered, err
+			}
+			continue
+		}
+		if err := f.DeliveryRepo.Reschedule(ctx, d.ID, attempts, now.Add(DeliveryBackoff(attempts)), sendErr.Error()); err != nil {
+			return delivered, err
+		}
+	}
+	return delivered, nil
+}
+
+func (f *FederationUseCase) deliver(ctx context.Context, d *models.Delivery) error {
+	key, err := f.ActorKey(ctx, d.Sender)
+	if err != nil {
+		return err
+	}
+	return f.Client.Deliver(d.Inbox, []byte(d.Payload), f.KeyID(d.Sender), key.PrivateKeyPem)
+}
+
+// DeliveryBackoff is the wait before retrying after the given number of
+// failed attempts: one minute, doubling up to six hours.
+func DeliveryBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	if attempts > 16 {
+		return maxDeliveryBackoff
+	}
+	return min(time.Minute<<(attempts-1), maxDeliveryBackoff)
+}
+
+// RunDeliveryJob delivers due activities every interval until ctx is
+// cancelled.
+func (f *FederationUseCase) RunDeliveryJob(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := f.DeliverDue(ctx, now); err != nil {
+				log.Printf("Cannot deliver activities. err = %v\n", err)
+			}
+		}
+	}
+}
+
+// rawID returns the ID of a JSON-LD value given either as a string or as an
+// object with an id.
+func rawID(raw json.RawMessage) string {
+	var id string
+	if json.Unmarshal(raw, &id) == nil {
+		return id
+	}
+	var object struct {
+		ID   string `json:"id"`
+		Href string `json:"href"`
+	}
+	if json.Unmarshal(raw, &object) == nil {
+		if object.ID != "" {
+			return object.ID
+		}
+		return object.Href
+	}
+	return ""
+}
+
+func hashID(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:8])
+}
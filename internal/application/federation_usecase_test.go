@@ -0,0 +1,432 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"postapi/internal/domain"
+	"testing"
+	"time"
+)
+
+type fakeFedUserRepo struct {
+	domain.UserRepository
+	postCount int64
+}
+
+func (f *fakeFedUserRepo) FindByUsername(ctx context.Context, username string) (*domain.User, error) {
+	if username != "alice" {
+		return nil, domain.ErrNotFound
+	}
+	return &domain.User{Username: username, UserCounts: domain.UserCounts{PostCount: f.postCount}}, nil
+}
+
+// fakeOutboxPostRepo holds the posts of alice, newest first.
+type fakeOutboxPostRepo struct {
+	domain.PostRepository
+	posts   []*domain.Post
+	lookups int
+}
+
+func (f *fakeOutboxPostRepo) FindLatestByAuthor(ctx context.Context, author string, limit int, offset int) ([]*domain.Post, error) {
+	f.lookups++
+	if offset >= len(f.posts) {
+		return nil, nil
+	}
+	return f.posts[offset:min(offset+limit, len(f.posts))], nil
+}
+
+type fakeKeyRepo struct {
+	keys map[string]*domain.ActorKey
+}
+
+func (f *fakeKeyRepo) Create(ctx context.Context, key *domain.ActorKey) error {
+	if _, ok := f.keys[key.Username]; !ok {
+		f.keys[key.Username] = key
+	}
+	return nil
+}
+
+func (f *fakeKeyRepo) FindByUsername(ctx context.Context, username string) (*domain.ActorKey, error) {
+	if key, ok := f.keys[username]; ok {
+		return key, nil
+	}
+	return nil, domain.ErrNotFound
+}
+
+type fakeFollowerRepo struct {
+	domain.RemoteFollowerRepository
+	followers map[string]*domain.RemoteFollower
+}
+
+func (f *fakeFollowerRepo) Add(ctx context.Context, follower *domain.RemoteFollower) error {
+	f.followers[follower.ActorID] = follower
+	return nil
+}
+
+func (f *fakeFollowerRepo) Remove(ctx context.Context, username string, actorID string) error {
+	delete(f.followers, actorID)
+	return nil
+}
+
+func (f *fakeFollowerRepo) Find(ctx context.Context, username string, actorID string) (*domain.RemoteFollower, error) {
+	if follower, ok := f.followers[actorID]; ok {
+		return follower, nil
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (f *fakeFollowerRepo) RemoveActor(ctx context.Context, actorID string) error {
+	delete(f.followers, actorID)
+	return nil
+}
+
+func (f *fakeFollowerRepo) FindByUsername(ctx context.Context, username string) ([]*domain.RemoteFollower, error) {
+	var followers []*domain.RemoteFollower
+	for _, follower := range f.followers {
+		followers = append(followers, follower)
+	}
+	return followers, nil
+}
+
+type fakeDeliveryRepo struct {
+	deliveries []*domain.Delivery
+}
+
+func (f *fakeDeliveryRepo) Enqueue(ctx context.Context, d *domain.Delivery) error {
+	d.ID = int64(len(f.deliveries) + 1)
+	f.deliveries = append(f.deliveries, d)
+	return nil
+}
+
+func (f *fakeDeliveryRepo) FindDue(ctx context.Context, now time.Time, limit int) ([]*domain.Delivery, error) {
+	var due []*domain.Delivery
+	for _, d := range f.deliveries {
+		if !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	return due, nil
+}
+
+func (f *fakeDeliveryRepo) Delete(ctx context.Context, id int64) error {
+	for i, d := range f.deliveries {
+		if d.ID == id {
+			f.deliveries = append(f.deliveries[:i], f.deliveries[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (f *fakeDeliveryRepo) Reschedule(ctx context.Context, id int64, attempts int, next time.Time, lastError string) error {
+	for _, d := range f.deliveries {
+		if d.ID == id {
+			d.Attempts, d.NextAttemptAt, d.LastError = attempts, next, lastError
+		}
+	}
+	return nil
+}
+
+type fakeFederationClient struct {
+	fail      error
+	delivered []string
+}
+
+func (f *fakeFederationClient) FetchActor(id string) (*domain.RemoteActor, error) {
+	return nil, errors.New("not used")
+}
+
+func (f *fakeFederationClient) Deliver(inbox string, payload []byte, keyID string, privateKeyPem string) error {
+	if f.fail != nil {
+		return f.fail
+	}
+	f.delivered = append(f.delivered, inbox)
+	return nil
+}
+
+func newTestFederationUseCase() (*FederationUseCase, *fakeFollowerRepo, *fakeDeliveryRepo, *fakeFederationClient) {
+	followers := &fakeFollowerRepo{followers: map[string]*domain.RemoteFollower{}}
+	deliveries := &fakeDeliveryRepo{}
+	client := &fakeFederationClient{}
+	uc := &FederationUseCase{
+		BaseURL:      "https://blog.example",
+		UserRepo:     &fakeFedUserRepo{},
+		KeyRepo:      &fakeKeyRepo{keys: map[string]*domain.ActorKey{}},
+		FollowerRepo: followers,
+		DeliveryRepo: deliveries,
+		Client:       client,
+	}
+	return uc, followers, deliveries, client
+}
+
+var remoteActor = &domain.RemoteActor{
+	ID:          "https://remote.example/users/bob",
+	Inbox:       "https://remote.example/users/bob/inbox",
+	SharedInbox: "https://remote.example/inbox",
+}
+
+func TestFederationUseCase_WebFinger(t *testing.T) {
+	uc, _, _, _ := newTestFederationUseCase()
+
+	jrd, err := uc.WebFinger(context.Background(), "acct:alice@blog.example")
+	if err != nil {
+		t.Fatalf("WebFinger() error = %v", err)
+	}
+	if jrd.Subject != "acct:alice@blog.example" || jrd.Links[0].Href != "https://blog.example/users/alice" {
+		t.Errorf("WebFinger() = %+v", jrd)
+	}
+
+	for _, resource := range []string{"acct:alice@other.example", "acct:nobody@blog.example", "mailto:alice@blog.example"} {
+		if _, err := uc.WebFinger(context.Background(), resource); !errors.Is(err, ErrUnknownResource) {
+			t.Errorf("WebFinger(%q) error = %v, want ErrUnknownResource", resource, err)
+		}
+	}
+}
+
+func TestFederationUseCase_FollowAndUndo(t *testing.T) {
+	uc, followers, deliveries, _ := newTestFederationUseCase()
+
+	follow := `{"id": "https://remote.example/follows/1", "type": "Follow",
+		"actor": "https://remote.example/users/bob", "object": "https://blog.example/users/alice"}`
+	if err := uc.HandleActivity(context.Background(), "alice", remoteActor, []byte(follow)); err != nil {
+		t.Fatalf("HandleActivity(Follow) error = %v", err)
+	}
+	follower, ok := followers.followers[remoteActor.ID]
+	if !ok || follower.Inbox != remoteActor.SharedInbox {
+		t.Fatalf("follower = %+v, want stored with the shared inbox", follower)
+	}
+	if len(deliveries.deliveries) != 1 || deliveries.deliveries[0].Inbox != remoteActor.Inbox {
+		t.Fatalf("deliveries = %+v, want an Accept for the follower", deliveries.deliveries)
+	}
+	var accept struct {
+		Type   string          `json:"type"`
+		Object json.RawMessage `json:"object"`
+	}
+	json.Unmarshal([]byte(deliveries.deliveries[0].Payload), &accept)
+	if accept.Type != "Accept" || rawID(accept.Object) != "https://remote.example/follows/1" {
+		t.Errorf("Accept = %s", deliveries.deliveries[0].Payload)
+	}
+
+	undo := `{"type": "Undo", "actor": "https://remote.example/users/bob",
+		"object": {"id": "https://remote.example/follows/1", "type": "Follow"}}`
+	if err := uc.HandleActivity(context.Background(), "alice", remoteActor, []byte(undo)); err != nil {
+		t.Fatalf("HandleActivity(Undo) error = %v", err)
+	}
+	if len(followers.followers) != 0 {
+		t.Errorf("followers = %v, want none after Undo", followers.followers)
+	}
+}
+
+func TestFederationUseCase_UndoByID(t *testing.T) {
+	uc, followers, _, _ := newTestFederationUseCase()
+	follow := `{"id": "https://remote.example/follows/1", "type": "Follow",
+		"actor": "https://remote.example/users/bob", "object": "https://blog.example/users/alice"}`
+	if err := uc.HandleActivity(context.Background(), "alice", remoteActor, []byte(follow)); err != nil {
+		t.Fatalf("HandleActivity(Follow) error = %v", err)
+	}
+
+	// Undoing a like or an announce by its ID keeps the follow
+	for _, undo := range []string{
+		`{"type": "Undo", "actor": "https://remote.example/users/bob", "object": "https://remote.example/likes/1"}`,
+		`{"type": "Undo", "actor": "https://remote.example/users/bob",
+			"object": {"id": "https://remote.example/likes/2", "type": "Like", "object": "https://blog.example/users/alice"}}`,
+		`{"type": "Undo", "actor": "https://remote.example/users/bob",
+			"object": {"id": "https://remote.example/follows/2", "type": "Follow", "object": "https://blog.example/users/carol"}}`,
+	} {
+		if err := uc.HandleActivity(context.Background(), "alice", remoteActor, []byte(undo)); err != nil {
+			t.Fatalf("HandleActivity(%s) error = %v", undo, err)
+		}
+		if _, ok := followers.followers[remoteActor.ID]; !ok {
+			t.Fatalf("HandleActivity(%s) removed the follower", undo)
+		}
+	}
+
+	undo := `{"type": "Undo", "actor": "https://remote.example/users/bob", "object": "https://remote.example/follows/1"}`
+	if err := uc.HandleActivity(context.Background(), "alice", remoteActor, []byte(undo)); err != nil {
+		t.Fatalf("HandleActivity(Undo) error = %v", err)
+	}
+	if len(followers.followers) != 0 {
+		t.Errorf("followers = %v, want none after undoing the follow by its ID", followers.followers)
+	}
+}
+
+func TestFederationUseCase_RepliesAndDeletes(t *testing.T) {
+	uc, followers, deliveries, _ := newTestFederationUseCase()
+	webmentions := &fakeWebmentionRepo{}
+	uc.PostRepo = &fakeWebmentionPostRepo{}
+	uc.WebmentionRepo = webmentions
+	followers.followers[remoteActor.ID] = &domain.RemoteFollower{Username: "alice", ActorID: remoteActor.ID}
+
+	create := `{"type": "Create", "actor": "https://remote.example/users/bob", "object": {
+		"id": "https://remote.example/notes/1", "type": "Note", "attributedTo": "https://remote.example/users/bob",
+		"inReplyTo": "https://blog.example/users/alice/notes/7", "content": "<p>Nice <b>post</b></p>",
+		"published": "2026-03-01T12:00:00Z"}}`
+	if err := uc.HandleActivity(context.Background(), "alice", remoteActor, []byte(create)); err != nil {
+		t.Fatalf("HandleActivity(Create) error = %v", err)
+	}
+	if len(webmentions.saved) != 1 {
+		t.Fatalf("saved %d webmentions, want the reply", len(webmentions.saved))
+	}
+	reply := webmentions.saved[0]
+	if reply.PostID != 7 || reply.Status != domain.WebmentionVerified || reply.Type != domain.WebmentionReply ||
+		reply.Source != "https://remote.example/notes/1" || reply.AuthorURL != remoteActor.ID ||
+		reply.Content != "Nice post" || reply.Published == nil {
+		t.Errorf("reply = %+v", reply)
+	}
+
+	// Notes that are not replies to alice's posts by their signer are ignored
+	for _, note := range []string{
+		`{"id": "https://remote.example/notes/2", "type": "Note", "attributedTo": "https://remote.example/users/bob", "content": "Hi"}`,
+		`{"id": "https://remote.example/notes/3", "type": "Note", "attributedTo": "https://remote.example/users/bob",
+			"inReplyTo": "https://blog.example/users/alice/notes/8", "content": "Hi"}`,
+		`{"id": "https://other.example/notes/4", "type": "Note", "attributedTo": "https://remote.example/users/bob",
+			"inReplyTo": "https://blog.example/users/alice/notes/7", "content": "Hi"}`,
+		`{"id": "https://remote.example/notes/5", "type": "Note", "attributedTo": "https://remote.example/users/mallory",
+			"inReplyTo": "https://blog.example/users/alice/notes/7", "content": "Hi"}`,
+	} {
+		create := `{"type": "Create", "actor": "https://remote.example/users/bob", "object": ` + note + `}`
+		if err := uc.HandleActivity(context.Background(), "alice", remoteActor, []byte(create)); err != nil {
+			t.Fatalf("HandleActivity(%s) error = %v", create, err)
+		}
+	}
+	if len(webmentions.saved) != 1 {
+		t.Errorf("saved %d webmentions, want only the reply", len(webmentions.saved))
+	}
+
+	// Only the signer's server can delete the reply
+	eve := &domain.RemoteActor{ID: "https://other.example/users/eve", Inbox: "https://other.example/users/eve/inbox"}
+	deleteNote := `{"type": "Delete", "actor": "https://other.example/users/eve", "object": "https://remote.example/notes/1"}`
+	if err := uc.HandleActivity(context.Background(), "alice", eve, []byte(deleteNote)); err != nil {
+		t.Fatalf("HandleActivity(Delete note by eve) error = %v", err)
+	}
+	if len(webmentions.saved) != 1 {
+		t.Fatalf("webmentions = %+v, want the reply kept", webmentions.saved)
+	}
+	deleteNote = `{"type": "Delete", "actor": "https://remote.example/users/bob", "object": "https://remote.example/notes/1"}`
+	if err := uc.HandleActivity(context.Background(), "alice", remoteActor, []byte(deleteNote)); err != nil {
+		t.Fatalf("HandleActivity(Delete note) error = %v", err)
+	}
+	if len(webmentions.saved) != 0 {
+		t.Errorf("webmentions = %+v, want the reply deleted", webmentions.saved)
+	}
+	if len(followers.followers) != 1 || len(deliveries.deliveries) != 0 {
+		t.Errorf("notes changed followers %v or deliveries %v", followers.followers, deliveries.deliveries)
+	}
+
+	deleteActor := `{"type": "Delete", "actor": "https://remote.example/users/bob", "object": "https://remote.example/users/bob"}`
+	if err := uc.HandleActivity(context.Background(), "alice", remoteActor, []byte(deleteActor)); err != nil {
+		t.Fatalf("HandleActivity(Delete actor) error = %v", err)
+	}
+	if len(followers.followers) != 0 {
+		t.Errorf("followers = %v, want none after the actor is deleted", followers.followers)
+	}
+}
+
+func TestFederationUseCase_Outbox(t *testing.T) {
+	uc, _, _, _ := newTestFederationUseCase()
+	posts := &fakeOutboxPostRepo{}
+	for i := OutboxPageSize + 1; i > 0; i-- {
+		posts.posts = append(posts.posts, &domain.Post{ID: int64(i), Author: "alice", CreatedAt: time.Now()})
+	}
+	uc.UserRepo = &fakeFedUserRepo{postCount: int64(len(posts.posts))}
+	uc.PostRepo = posts
+
+	outbox, err := uc.Outbox(context.Background(), "alice", 0)
+	if err != nil {
+		t.Fatalf("Outbox() error = %v", err)
+	}
+	if c := outbox.(*domain.APOrderedCollection); c.TotalItems != int64(OutboxPageSize+1) || posts.lookups != 0 {
+		t.Errorf("Outbox() = %+v after %d lookups, want the post count without looking up posts", c, posts.lookups)
+	}
+
+	outbox, _ = uc.Outbox(context.Background(), "alice", 1)
+	if p := outbox.(*domain.APOrderedCollectionPage); len(p.OrderedItems) != OutboxPageSize || p.Next != "https://blog.example/users/alice/outbox?page=2" {
+		t.Errorf("Outbox(page 1) = %d items, next %q, want a full page and a next one", len(p.OrderedItems), p.Next)
+	}
+	outbox, _ = uc.Outbox(context.Background(), "alice", 2)
+	if p := outbox.(*domain.APOrderedCollectionPage); len(p.OrderedItems) != 1 || p.Next != "" {
+		t.Errorf("Outbox(page 2) = %d items, next %q, want the last post alone", len(p.OrderedItems), p.Next)
+	}
+
+	// Pages past the last post are empty and not looked up
+	outbox, err = uc.Outbox(context.Background(), "alice", math.MaxInt)
+	if p, ok := outbox.(*domain.APOrderedCollectionPage); err != nil || !ok || len(p.OrderedItems) != 0 || posts.lookups != 2 {
+		t.Errorf("Outbox(last page) = %+v, %v after %d lookups, want an empty page and 2 lookups", outbox, err, posts.lookups)
+	}
+}
+
+func TestFederationUseCase_ActorMismatch(t *testing.T) {
+	uc, _, _, _ := newTestFederationUseCase()
+
+	follow := `{"type": "Follow", "actor": "https://remote.example/users/mallory", "object": "https://blog.example/users/alice"}`
+	if err := uc.HandleActivity(context.Background(), "alice", remoteActor, []byte(follow)); !errors.Is(err, ErrActorMismatch) {
+		t.Errorf("HandleActivity() error = %v, want ErrActorMismatch", err)
+	}
+	if err := uc.HandleActivity(context.Background(), "nobody", remoteActor, []byte(follow)); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("HandleActivity(unknown user) error = %v, want domain.ErrNotFound", err)
+	}
+}
+
+func TestFederationUseCase_DeliverDue(t *testing.T) {
+	uc, followers, deliveries, client := newTestFederationUseCase()
+	followers.followers["a"] = &domain.RemoteFollower{ActorID: "a", Inbox: "https://remote.example/inbox"}
+	followers.followers["b"] = &domain.RemoteFollower{ActorID: "b", Inbox: "https://remote.example/inbox"}
+
+	post := &domain.Post{ID: 5, Title: "Hello", Content: "World", Author: "alice", CreatedAt: time.Now()}
+	if err := uc.PostCreated(context.Background(), post); err != nil {
+		t.Fatalf("PostCreated() error = %v", err)
+	}
+	if len(deliveries.deliveries) != 1 {
+		t.Fatalf("got %d deliveries, want one per shared inbox", len(deliveries.deliveries))
+	}
+
+	now := time.Now()
+	client.fail = errors.New("connection refused")
+	if n, err := uc.DeliverDue(context.Background(), now); err != nil || n != 0 {
+		t.Fatalf("DeliverDue() = %d, %v", n, err)
+	}
+	d := deliveries.deliveries[0]
+	if d.Attempts != 1 || !d.NextAttemptAt.Equal(now.Add(time.Minute)) || d.LastError == "" {
+		t.Errorf("after a failure delivery = %+v, want a retry in a minute", d)
+	}
+
+	client.fail = nil
+	if n, _ := uc.DeliverDue(context.Background(), now); n != 0 {
+		t.Errorf("DeliverDue() delivered %d before the retry was due", n)
+	}
+	if n, err := uc.DeliverDue(context.Background(), now.Add(time.Minute)); err != nil || n != 1 {
+		t.Fatalf("DeliverDue() = %d, %v, want 1", n, err)
+	}
+	if len(deliveries.deliveries) != 0 || len(client.delivered) != 1 {
+		t.Errorf("delivered = %v, remaining = %v", client.delivered, deliveries.deliveries)
+	}
+}
+
+func TestFederationUseCase_DeliveryDropped(t *testing.T) {
+	uc, _, deliveries, client := newTestFederationUseCase()
+	client.fail = errors.New("gone")
+	deliveries.Enqueue(context.Background(), &domain.Delivery{Sender: "alice", Inbox: "https://remote.example/inbox", Attempts: MaxDeliveryAttempts - 1})
+
+	uc.DeliverDue(context.Background(), time.Now())
+	if len(deliveries.deliveries) != 0 {
+		t.Errorf("delivery kept after %d attempts", MaxDeliveryAttempts)
+	}
+}
+
+func TestDeliveryBackoff(t *testing.T) {
+	tests := map[int]time.Duration{
+		1:  time.Minute,
+		2:  2 * time.Minute,
+		5:  16 * time.Minute,
+		9:  256 * time.Minute,
+		10: maxDeliveryBackoff,
+		40: maxDeliveryBackoff,
+	}
+	for attempts, want := range tests {
+		if got := DeliveryBackoff(attempts); got != want {
+			t.Errorf("DeliveryBackoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
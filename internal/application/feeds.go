@@ -0,0 +1,256 @@
+package application
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	models "postapi/internal/domain"
+	"strconv"
+	"time"
+)
+
+// FeedSize is the number of most recent posts included in a feed.
+const FeedSize = 20
+
+// Feed is the format-neutral content of a user's feed. URLs are absolute.
+type Feed struct {
+	Title   string
+	HomeURL string
+	FeedURL string
+	Author  string
+	Updated time.Time
+	Items   []FeedItem
+}
+
+type FeedItem struct {
+	ID          string
+	URL         string
+	Title       string
+	ContentHTML string
+	Tags        []string
+	Published   time.Time
+}
+
+// NewUserFeed builds the feed of username from its latest posts, newest
+// first, as PostUseCase.Feed returns them. feedURL is the address the feed is
+// served from; baseURL is the site root, without a trailing slash.
+func NewUserFeed(username, baseURL, feedURL string, posts []*models.Post) *Feed {
+
+	feed := &Feed{
+		Title:   username + "'s posts",
+		HomeURL: baseURL + "/api/users/" + url.PathEscape(username) + "/posts",
+		FeedURL: feedURL,
+		Author:  username,
+		Items:   make([]FeedItem, len(posts)),
+	}
+	for i, post := range posts {
+		link := baseURL + "/api/posts/" + strconv.FormatInt(post.ID, 10)
+		feed.Items[i] = FeedItem{
+			ID:          link,
+			URL:         link,
+			Title:       post.Title,
+			ContentHTML: RenderContent(post.Format, post.Content),
+			Tags:        ExtractHashtags(post.Title, post.Content),
+			Published:   post.CreatedAt.UTC(),
+		}
+		if post.CreatedAt.After(feed.Updated) {
+			feed.Updated = post.CreatedAt.UTC()
+		}
+	}
+	return feed
+}
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	AtomLink      rssLink   `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Categories  []string `xml:"category"`
+	Description string   `xml:"description"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// EncodeRSS renders the feed as RSS 2.0.
+func EncodeRSS(feed *Feed) ([]byte, error) {
+	doc := rssDocument{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:       feed.Title,
+			Link:        feed.HomeURL,
+			Description: "Posts by " + feed.Author,
+			AtomLink:    rssLink{Href: feed.FeedURL, Rel: "self", Type: "application/rss+xml"},
+		},
+	}
+	if !feed.Updated.IsZero() {
+		doc.Channel.LastBuildDate = feed.Updated.Format(time.RFC1123Z)
+	}
+	for _, item := range feed.Items {
+		doc.Channel.Items = append(doc.Channel.Items, rssItem{
+			Title:       item.Title,
+			Link:        item.URL,
+			GUID:        rssGUID{IsPermaLink: item.ID == item.URL, Value: item.ID},
+			PubDate:     item.Published.Format(time.RFC1123Z),
+			Categories:  item.Tags,
+			Description: item.ContentHTML,
+		})
+	}
+	return marshalXML(doc)
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomPerson  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Categories []atomCategory `xml:"category"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// EncodeAtom renders the feed as Atom 1.0.
+func EncodeAtom(feed *Feed) ([]byte, error) {
+	doc := atomFeed{
+		ID:      feed.FeedURL,
+		Title:   feed.Title,
+		Updated: atomTime(feed.Updated),
+		Author:  atomPerson{Name: feed.Author},
+		Links: []atomLink{
+			{Href: feed.FeedURL, Rel: "self", Type: "application/atom+xml"},
+			{Href: feed.HomeURL, Rel: "alternate"},
+		},
+	}
+	for _, item := range feed.Items {
+		entry := atomEntry{
+			ID:        item.ID,
+			Title:     item.Title,
+			Link:      atomLink{Href: item.URL, Rel: "alternate"},
+			Published: atomTime(item.Published),
+			Updated:   atomTime(item.Published),
+			Content:   atomContent{Type: "html", Value: item.ContentHTML},
+		}
+		for _, tag := range item.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	return marshalXML(doc)
+}
+
+// atomTime formats t for Atom, which requires an updated date even for a
+// feed without entries.
+func atomTime(t time.Time) string {
+	if t.IsZero() {
+		t = time.Unix(0, 0)
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+type jsonFeed struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageURL string           `json:"home_page_url"`
+	FeedURL     string           `json:"feed_url"`
+	Authors     []jsonFeedAuthor `json:"authors"`
+	Items       []jsonFeedItem   `json:"items"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonFeedItem struct {
+	ID            string   `json:"id"`
+	URL           string   `json:"url"`
+	Title         string   `json:"title"`
+	ContentHTML   string   `json:"content_html"`
+	DatePublished string   `json:"date_published"`
+	Tags          []string `json:"tags,omitempty"`
+}
+
+// EncodeJSONFeed renders the feed as JSON Feed 1.1.
+func EncodeJSONFeed(feed *Feed) ([]byte, error) {
+	doc := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       feed.Title,
+		HomePageURL: feed.HomeURL,
+		FeedURL:     feed.FeedURL,
+		Authors:     []jsonFeedAuthor{{Name: feed.Author}},
+		Items:       make([]jsonFeedItem, len(feed.Items)),
+	}
+	for i, item := range feed.Items {
+		doc.Items[i] = jsonFeedItem{
+			ID:            item.ID,
+			URL:           item.URL,
+			Title:         item.Title,
+			ContentHTML:   item.ContentHTML,
+			DatePublished: item.Published.Format(time.RFC3339),
+			Tags:          item.Tags,
+		}
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func marshalXML(v any) ([]byte, error) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encode feed: %w", err)
+	}
+	return append([]byte(xml.Header), body...), nil
+}
@@ -0,0 +1,139 @@
+package application
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"postapi/internal/domain"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testFeed() *Feed {
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	posts := []*domain.Post{
+		{ID: 2, Title: "Second", Content: "**bold** & <b>", Format: domain.PostFormatMarkdown, Author: "alice", CreatedAt: base.Add(time.Hour)},
+		{ID: 1, Title: "First", Content: "Hello #golang", Format: domain.PostFormatPlain, Author: "alice", CreatedAt: base},
+	}
+	return NewUserFeed("alice", "https://blog.example", "https://blog.example/users/alice/feed.rss", posts)
+}
+
+func TestNewUserFeed(t *testing.T) {
+	feed := testFeed()
+
+	if len(feed.Items) != 2 || feed.Items[0].Title != "Second" {
+		t.Fatalf("NewUserFeed() items = %+v, want newest first", feed.Items)
+	}
+	if feed.Items[0].ID != "https://blog.example/api/posts/2" {
+		t.Errorf("item ID = %s, want the post permalink", feed.Items[0].ID)
+	}
+	if !feed.Updated.Equal(feed.Items[0].Published) {
+		t.Errorf("Updated = %v, want date of newest post", feed.Updated)
+	}
+	if !strings.Contains(feed.Items[0].ContentHTML, "<strong>bold</strong>") {
+		t.Errorf("ContentHTML = %s, want rendered markdown", feed.Items[0].ContentHTML)
+	}
+}
+
+func TestEncodeRSS(t *testing.T) {
+	body, err := EncodeRSS(testFeed())
+	if err != nil {
+		t.Fatalf("EncodeRSS() error = %v", err)
+	}
+
+	var doc struct {
+		Channel struct {
+			Items []struct {
+				GUID    string `xml:"guid"`
+				PubDate string `xml:"pubDate"`
+				Desc    string `xml:"description"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("EncodeRSS() is not valid XML: %v\n%s", err, body)
+	}
+	if len(doc.Channel.Items) != 2 {
+		t.Fatalf("got %d items, want 2", len(doc.Channel.Items))
+	}
+	item := doc.Channel.Items[0]
+	if item.GUID != "https://blog.example/api/posts/2" {
+		t.Errorf("guid = %s", item.GUID)
+	}
+	if item.PubDate != "Fri, 01 Mar 2024 13:00:00 +0000" {
+		t.Errorf("pubDate = %s, want RFC 1123", item.PubDate)
+	}
+	if item.Desc != "<p><strong>bold</strong> &amp; </p>\n" {
+		t.Errorf("description = %q, want the rendered post", item.Desc)
+	}
+	if !strings.Contains(string(body), "&lt;strong&gt;") {
+		t.Errorf("HTML in description is not escaped:\n%s", body)
+	}
+	if !strings.Contains(string(body), `<atom:link href="https://blog.example/users/alice/feed.rss" rel="self"`) {
+		t.Errorf("missing self link:\n%s", body)
+	}
+}
+
+func TestEncodeAtom(t *testing.T) {
+	body, err := EncodeAtom(testFeed())
+	if err != nil {
+		t.Fatalf("EncodeAtom() error = %v", err)
+	}
+
+	var doc struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		Updated string   `xml:"updated"`
+		Entries []struct {
+			ID        string `xml:"id"`
+			Published string `xml:"published"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("EncodeAtom() is not a valid Atom feed: %v\n%s", err, body)
+	}
+	if doc.Updated != "2024-03-01T13:00:00Z" {
+		t.Errorf("updated = %s", doc.Updated)
+	}
+	if len(doc.Entries) != 2 || doc.Entries[1].Published != "2024-03-01T12:00:00Z" {
+		t.Errorf("entries = %+v", doc.Entries)
+	}
+}
+
+func TestEncodeJSONFeed(t *testing.T) {
+	body, err := EncodeJSONFeed(testFeed())
+	if err != nil {
+		t.Fatalf("EncodeJSONFeed() error = %v", err)
+	}
+
+	var doc struct {
+		Version string `json:"version"`
+		Items   []struct {
+			ID            string   `json:"id"`
+			DatePublished string   `json:"date_published"`
+			Tags          []string `json:"tags"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("EncodeJSONFeed() is not valid JSON: %v", err)
+	}
+	if doc.Version != "https://jsonfeed.org/version/1.1" {
+		t.Errorf("version = %s", doc.Version)
+	}
+	if len(doc.Items) != 2 || doc.Items[1].DatePublished != "2024-03-01T12:00:00Z" {
+		t.Fatalf("items = %+v", doc.Items)
+	}
+	if len(doc.Items[1].Tags) != 1 || doc.Items[1].Tags[0] != "golang" {
+		t.Errorf("tags = %v, want [golang]", doc.Items[1].Tags)
+	}
+}
+
+func TestEncodeEmptyFeed(t *testing.T) {
+	feed := NewUserFeed("alice", "https://blog.example", "https://blog.example/users/alice/feed.atom", nil)
+	for name, encode := range map[string]func(*Feed) ([]byte, error){
+		"rss": EncodeRSS, "atom": EncodeAtom, "json": EncodeJSONFeed,
+	} {
+		if _, err := encode(feed); err != nil {
+			t.Errorf("%s: error = %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package application
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const maxTagLength = 64
+
+// A tag starts after whitespace or punctuation, so "a#b", "&#39;" and URL
+// fragments are not picked up.
+var hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&/#])#([\p{L}\p{N}_]+)`)
+
+// ExtractHashtags returns the distinct lower-cased tags found in texts, in
+// order of first appearance. Purely numeric tags such as "#1" are ignored.
+func ExtractHashtags(texts ...string) []string {
+	seen := make(map[string]bool)
+	tags := []string{}
+	for _, text := range texts {
+		for _, match := range hashtagPattern.FindAllStringSubmatch(text, -1) {
+			tag := NormalizeTag(match[1])
+			if tag == "" || seen[tag] {
+				continue
+			}
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// NormalizeTag lower-cases a tag and strips a leading '#'. It returns "" when
+// the tag is not valid.
+func NormalizeTag(tag string) string {
+	tag = strings.ToLower(strings.TrimPrefix(tag, "#"))
+	if tag == "" || len(tag) > maxTagLength {
+		return ""
+	}
+	hasLetter := false
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			return ""
+		}
+		if unicode.IsLetter(r) {
+			hasLetter = true
+		}
+	}
+	if !hasLetter {
+		return ""
+	}
+	return tag
+}
@@ -0,0 +1,71 @@
+package application
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractHashtags(t *testing.T) {
+	tests := []struct {
+		name  string
+		texts []string
+		want  []string
+	}{
+		{
+			name:  "Title and content",
+			texts: []string{"Learning #Go", "Notes on #golang and #go"},
+			want:  []string{"go", "golang"},
+		},
+		{
+			name:  "Punctuation around tags",
+			texts: []string{"(#one), #two! #three."},
+			want:  []string{"one", "two", "three"},
+		},
+		{
+			name:  "Unicode tags",
+			texts: []string{"#café y #programación"},
+			want:  []string{"café", "programación"},
+		},
+		{
+			name:  "Ignored matches",
+			texts: []string{"issue #42, a#b, https://example.com/#anchor, &#39; and ## heading"},
+			want:  []string{},
+		},
+		{
+			name:  "Markdown heading is not a tag",
+			texts: []string{"# Title\n\nbody #real"},
+			want:  []string{"real"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtractHashtags(tt.texts...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtractHashtags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeTag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"GoLang", "golang"},
+		{"#Go", "go"},
+		{"go_lang2", "go_lang2"},
+		{"123", ""},
+		{"", ""},
+		{"bad-tag", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			if got := NormalizeTag(tt.tag); got != tt.want {
+				t.Errorf("NormalizeTag(%q) = %q, want %q", tt.tag, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package application
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"net/url"
+	"strings"
+)
+
+const (
+	// identiconGrid is the number of cells per side; the left half is
+	// mirrored onto the right.
+	identiconGrid = 5
+	// identiconMargin is the blank border around the grid, in cells.
+	identiconMargin = 1
+)
+
+var identiconBackground = color.RGBA{R: 0xF0, G: 0xF0, B: 0xF0, A: 0xFF}
+
+// DefaultAvatarURL is the generated avatar of a user without a picture.
+func DefaultAvatarURL(username string) string {
+	return "/api/avatars/" + url.PathEscape(username)
+}
+
+// Identicon is a symmetric pattern of cells and a colour derived from the
+// hash of a username, so the same name always gets the same avatar.
+type Identicon struct {
+	Color color.RGBA
+	Cells [identiconGrid][identiconGrid]bool
+}
+
+func NewIdenticon(username string) *Identicon {
+	sum := sha256.Sum256([]byte(username))
+
+	hue := float64(uint16(sum[0])<<8|uint16(sum[1])) / 65536 * 360
+	saturation := 0.45 + float64(sum[2])/255*0.25
+	lightness := 0.40 + float64(sum[3])/255*0.20
+
+	ident := &Identicon{Color: hslToRGB(hue, saturation, lightness)}
+	half := (identiconGrid + 1) / 2
+	for y := 0; y < identiconGrid; y++ {
+		for x := 0; x < half; x++ {
+			bit := y*half + x
+			on := sum[4+bit/8]&(1<<(bit%8)) != 0
+			ident.Cells[y][x] = on
+			ident.Cells[y][identiconGrid-1-x] = on
+		}
+	}
+	return ident
+}
+
+// SVG renders the identicon as a scalable SVG document.
+func (i *Identicon) SVG() []byte {
+	side := identiconGrid + 2*identiconMargin
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, side, side)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="%s"/>`, side, side, hexColor(identiconBackground))
+	fmt.Fprintf(&b, `<g fill="%s">`, hexColor(i.Color))
+	for y, row := range i.Cells {
+		for x, on := range row {
+			if on {
+				fmt.Fprintf(&b, `<rect x="%d" y="%d" width="1" height="1"/>`, x+identiconMargin, y+identiconMargin)
+			}
+		}
+	}
+	b.WriteString(`</g></svg>`)
+	return []byte(b.String())
+}
+
+// PNG renders the identicon as a size by size PNG image.
+func (i *Identicon) PNG(size int) ([]byte, error) {
+	side := identiconGrid + 2*identiconMargin
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		cy := y*side/size - identiconMargin
+		for x := 0; x < size; x++ {
+			cx := x*side/size - identiconMargin
+			c := identiconBackground
+			if cx >= 0 && cx < identiconGrid && cy >= 0 && cy < identiconGrid && i.Cells[cy][cx] {
+				c = i.Color
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// hslToRGB converts a hue in degrees and saturation and lightness in [0, 1].
+func hslToRGB(h, s, l float64) color.RGBA {
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := l - c/2
+
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	return color.RGBA{
+		R: uint8(math.Round((r + m) * 255)),
+		G: uint8(math.Round((g + m) * 255)),
+		B: uint8(math.Round((b + m) * 255)),
+		A: 0xFF,
+	}
+}
@@ -0,0 +1,67 @@
+package application
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestNewIdenticon_Deterministic(t *testing.T) {
+	a := NewIdenticon("alice")
+	if b := NewIdenticon("alice"); *a != *b {
+		t.Error("NewIdenticon() differs for the same username")
+	}
+	if c := NewIdenticon("bob"); *a == *c {
+		t.Error("NewIdenticon() is the same for different usernames")
+	}
+}
+
+func TestNewIdenticon_Symmetric(t *testing.T) {
+	for _, name := range []string{"alice", "bob", "carol", "ünïcode"} {
+		ident := NewIdenticon(name)
+		for y, row := range ident.Cells {
+			for x := range row {
+				if row[x] != row[len(row)-1-x] {
+					t.Errorf("%s: cell (%d, %d) is not mirrored", name, x, y)
+				}
+			}
+		}
+	}
+}
+
+func TestIdenticon_SVG(t *testing.T) {
+	svg := string(NewIdenticon("alice").SVG())
+	if !strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg"`) || !strings.HasSuffix(svg, "</svg>") {
+		t.Errorf("SVG() = %s, want an svg document", svg)
+	}
+}
+
+func TestIdenticon_PNG(t *testing.T) {
+	ident := NewIdenticon("alice")
+	data, err := ident.PNG(70)
+	if err != nil {
+		t.Fatalf("PNG() error = %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("PNG() is not a png: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 70 || b.Dy() != 70 {
+		t.Errorf("PNG() size = %v, want 70x70", b)
+	}
+
+	// Each cell is 10 pixels wide; sample the centre of every cell.
+	for y, row := range ident.Cells {
+		for x, on := range row {
+			r, g, b, _ := img.At((x+1)*10+5, (y+1)*10+5).RGBA()
+			want := identiconBackground
+			if on {
+				want = ident.Color
+			}
+			if uint8(r>>8) != want.R || uint8(g>>8) != want.G || uint8(b>>8) != want.B {
+				t.Errorf("cell (%d, %d) has the wrong colour", x, y)
+			}
+		}
+	}
+}
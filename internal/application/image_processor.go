@@ -0,0 +1,231 @@
+package application
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	models "postapi/internal/domain"
+
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// MaxImagePixels bounds the size of decoded images so that a small file
+// cannot expand into an enormous bitmap.
+const MaxImagePixels = 40_000_000
+
+var ErrInvalidImage = models.Invalid(models.CodeInvalidImage, "invalid image")
+
+// imageVariantSizes is the bounding box each variant is scaled down to.
+// Images smaller than the box are not enlarged.
+var imageVariantSizes = []struct {
+	Name string
+	Size int
+}{
+	{models.MediaVariantThumbnail, 256},
+	{models.MediaVariantMedium, 1024},
+}
+
+// ProcessedImage is an uploaded image re-encoded for serving.
+type ProcessedImage struct {
+	ContentType string
+	Extension   string
+	Width       int
+	Height      int
+	Blurhash    string
+	Variants    []EncodedVariant
+}
+
+type EncodedVariant struct {
+	Name   string
+	Data   []byte
+	Width  int
+	Height int
+}
+
+// ProcessImage decodes an uploaded image and re-encodes it, dropping any
+// metadata such as EXIF and GPS tags. The EXIF orientation is applied to the
+// pixels first so that photos keep the right way up. Opaque photos become
+// JPEG and everything else PNG; animated GIFs keep their first frame.
+func ProcessImage(data []byte) (*ProcessedImage, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if config.Width*config.Height > MaxImagePixels {
+		return nil, fmt.Errorf("%w: %dx%d pixels", ErrMediaTooLarge, config.Width, config.Height)
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, src.Bounds().Dx(), src.Bounds().Dy()))
+	draw.Draw(img, img.Bounds(), src, src.Bounds().Min, draw.Src)
+	if format == "jpeg" {
+		img = applyOrientation(img, jpegOrientation(data))
+	}
+
+	processed := &ProcessedImage{
+		ContentType: "image/png",
+		Extension:   ".png",
+		Width:       img.Bounds().Dx(),
+		Height:      img.Bounds().Dy(),
+	}
+	encode := func(m image.Image) ([]byte, error) {
+		var buf bytes.Buffer
+		err := png.Encode(&buf, m)
+		return buf.Bytes(), err
+	}
+	if (format == "jpeg" || format == "webp") && img.Opaque() {
+		processed.ContentType = "image/jpeg"
+		processed.Extension = ".jpg"
+		encode = func(m image.Image) ([]byte, error) {
+			var buf bytes.Buffer
+			err := jpeg.Encode(&buf, m, &jpeg.Options{Quality: 85})
+			return buf.Bytes(), err
+		}
+	}
+
+	for _, v := range imageVariantSizes {
+		w, h := FitImage(processed.Width, processed.Height, v.Size)
+		scaled := scaleImage(img, w, h)
+		encoded, err := encode(scaled)
+		if err != nil {
+			return nil, err
+		}
+		processed.Variants = append(processed.Variants, EncodedVariant{Name: v.Name, Data: encoded, Width: w, Height: h})
+		if v.Name == models.MediaVariantThumbnail {
+			bw, bh := FitImage(w, h, 32)
+			processed.Blurhash = Blurhash(scaleImage(scaled, bw, bh), 4, 3)
+		}
+	}
+	encoded, err := encode(img)
+	if err != nil {
+		return nil, err
+	}
+	processed.Variants = append(processed.Variants, EncodedVariant{
+		Name: models.MediaVariantOriginal, Data: encoded, Width: processed.Width, Height: processed.Height,
+	})
+	return processed, nil
+}
+
+// FitImage returns the dimensions of a width by height image scaled down to
+// fit in a size by size box, keeping its aspect ratio.
+func FitImage(width, height, size int) (int, int) {
+	if width <= size && height <= size {
+		return width, height
+	}
+	if width >= height {
+		return size, max(1, (height*size+width/2)/width)
+	}
+	return max(1, (width*size+height/2)/height), size
+}
+
+func scaleImage(src *image.RGBA, width, height int) *image.RGBA {
+	if src.Bounds().Dx() == width && src.Bounds().Dy() == height {
+		return src
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Src, nil)
+	return dst
+}
+
+// jpegOrientation returns the EXIF orientation of a JPEG file, or 1 when it
+// has none.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if marker == 0xDA || length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for n := 0; n < count; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			if o := int(order.Uint16(tiff[entry+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// applyOrientation turns an image stored with the given EXIF orientation the
+// right way up.
+func applyOrientation(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			dst.SetRGBA(x, y, src.RGBAAt(sx, sy))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,160 @@
+package application
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"postapi/internal/domain"
+	"testing"
+)
+
+// withExif inserts an EXIF segment carrying the given orientation and a GPS
+// marker right after the start of a JPEG file.
+func withExif(data []byte, orientation byte) []byte {
+	tiff := []byte{
+		'M', 'M', 0x00, 0x2A, 0x00, 0x00, 0x00, 0x08,
+		0x00, 0x01,
+		0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01, 0x00, orientation, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+	}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	payload = append(payload, []byte("GPSLatitude 48.8584")...)
+	segment := []byte{0xFF, 0xE1, byte((len(payload) + 2) >> 8), byte(len(payload) + 2)}
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func testPhoto(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 20 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcessImage_StripsExifAndAppliesOrientation(t *testing.T) {
+	data := withExif(testPhoto(t), 6)
+	if got := jpegOrientation(data); got != 6 {
+		t.Fatalf("jpegOrientation() = %d, want 6", got)
+	}
+
+	processed, err := ProcessImage(data)
+	if err != nil {
+		t.Fatalf("ProcessImage() error = %v", err)
+	}
+	if processed.ContentType != "image/jpeg" || processed.Width != 20 || processed.Height != 40 {
+		t.Fatalf("ProcessImage() = %s %dx%d, want rotated jpeg 20x40",
+			processed.ContentType, processed.Width, processed.Height)
+	}
+
+	for _, v := range processed.Variants {
+		if bytes.Contains(v.Data, []byte("Exif")) || bytes.Contains(v.Data, []byte("GPS")) {
+			t.Errorf("variant %s still carries metadata", v.Name)
+		}
+	}
+
+	original := processed.Variants[len(processed.Variants)-1]
+	img, err := jpeg.Decode(bytes.NewReader(original.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Rotating clockwise moves the red left half to the top.
+	if r, _, b, _ := img.At(10, 5).RGBA(); r < b {
+		t.Errorf("top of rotated image is not red")
+	}
+	if r, _, b, _ := img.At(10, 35).RGBA(); b < r {
+		t.Errorf("bottom of rotated image is not blue")
+	}
+}
+
+func TestProcessImage_Variants(t *testing.T) {
+	processed, err := ProcessImage(testImage(t, 2000, 1000))
+	if err != nil {
+		t.Fatalf("ProcessImage() error = %v", err)
+	}
+	if processed.ContentType != "image/png" {
+		t.Errorf("ContentType = %s, want image/png for a transparent image", processed.ContentType)
+	}
+
+	want := map[string][2]int{
+		domain.MediaVariantThumbnail: {256, 128},
+		domain.MediaVariantMedium:    {1024, 512},
+		domain.MediaVariantOriginal:  {2000, 1000},
+	}
+	if len(processed.Variants) != len(want) {
+		t.Fatalf("got %d variants, want %d", len(processed.Variants), len(want))
+	}
+	for _, v := range processed.Variants {
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(v.Data))
+		if err != nil {
+			t.Fatalf("variant %s: %v", v.Name, err)
+		}
+		size := want[v.Name]
+		if cfg.Width != size[0] || cfg.Height != size[1] || v.Width != size[0] || v.Height != size[1] {
+			t.Errorf("variant %s is %dx%d, want %dx%d", v.Name, cfg.Width, cfg.Height, size[0], size[1])
+		}
+	}
+}
+
+func TestProcessImage_Invalid(t *testing.T) {
+	if _, err := ProcessImage([]byte("not an image")); !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("ProcessImage(garbage) error = %v, want ErrInvalidImage", err)
+	}
+}
+
+func TestFitImage(t *testing.T) {
+	tests := []struct {
+		w, h, size   int
+		wantW, wantH int
+	}{
+		{100, 50, 256, 100, 50},
+		{1000, 500, 256, 256, 128},
+		{500, 1000, 256, 128, 256},
+		{3000, 1, 256, 256, 1},
+	}
+	for _, tt := range tests {
+		if w, h := FitImage(tt.w, tt.h, tt.size); w != tt.wantW || h != tt.wantH {
+			t.Errorf("FitImage(%d, %d, %d) = %d, %d, want %d, %d", tt.w, tt.h, tt.size, w, h, tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestBlurhash_SolidColor(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			img.Set(x, y, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+		}
+	}
+
+	hash := Blurhash(img, 4, 3)
+	// Size flag, maximum AC value, four DC characters and two per AC component.
+	if len(hash) != 2+4+2*11 {
+		t.Fatalf("Blurhash() = %s, want 28 characters", hash)
+	}
+	if hash[0] != 'L' {
+		t.Errorf("size flag = %c, want L for 4x3 components", hash[0])
+	}
+	if dc := hash[2:6]; dc != encode83(0xFFFFFF, 4) {
+		t.Errorf("DC = %s, want white", dc)
+	}
+	if again := Blurhash(img, 4, 3); again != hash {
+		t.Errorf("Blurhash() is not deterministic: %s then %s", hash, again)
+	}
+}
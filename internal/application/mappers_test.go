@@ -62,14 +62,35 @@ func TestMapPostToJson(t *testing.T) {
 				ID:      1,
 				Title:   "Test Post",
 				Content: "This is test content",
+				Format:  domain.PostFormatPlain,
 				Author:  "testuser",
 			},
 			want: domain.JsonPost{
-				ID:      1,
-				Title:   "Test Post",
-				Content: "This is test content",
+				ID:          1,
+				Title:       "Test Post",
+				Content:     "This is test content",
+				ContentHTML: "<p>This is test content</p>\n",
+				Format:      domain.PostFormatPlain,
+				Author:      "testuser",
+			},
+		},
+		{
+			name: "Markdown post",
+			post: &domain.Post{
+				ID:      2,
+				Title:   "Markdown",
+				Content: "# Heading",
+				Format:  domain.PostFormatMarkdown,
 				Author:  "testuser",
 			},
+			want: domain.JsonPost{
+				ID:          2,
+				Title:       "Markdown",
+				Content:     "# Heading",
+				ContentHTML: "<h1>Heading</h1>\n",
+				Format:      domain.PostFormatMarkdown,
+				Author:      "testuser",
+			},
 		},
 	}
 
@@ -88,6 +109,12 @@ func TestMapPostToJson(t *testing.T) {
 			if got.Author != tt.want.Author {
 				t.Errorf("MapPostToJson() Author = %v, want %v", got.Author, tt.want.Author)
 			}
+			if got.Format != tt.want.Format {
+				t.Errorf("MapPostToJson() Format = %v, want %v", got.Format, tt.want.Format)
+			}
+			if got.ContentHTML != tt.want.ContentHTML {
+				t.Errorf("MapPostToJson() ContentHTML = %q, want %q", got.ContentHTML, tt.want.ContentHTML)
+			}
 		})
 	}
 }
@@ -127,3 +154,11 @@ func TestMapProfileToJson(t *testing.T) {
 		t.Errorf("MapProfileToJson() ProfilePicture = %v, want URL", got.ProfilePicture)
 	}
 }
+
+func TestMapProfileToJson_DefaultAvatar(t *testing.T) {
+	got := MapProfileToJson(&domain.Profile{Username: "test user"})
+
+	if got.ProfilePicture != "/api/avatars/test%20user" {
+		t.Errorf("MapProfileToJson() ProfilePicture = %v, want generated avatar", got.ProfilePicture)
+	}
+}
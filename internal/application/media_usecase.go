@@ -0,0 +1,254 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	models "postapi/internal/domain"
+	"strings"
+	"time"
+)
+
+// MaxMediaSize is the largest upload accepted, in bytes.
+const MaxMediaSize = 10 << 20
+
+// MediaURLPrefix is where uploaded media are served from.
+const MediaURLPrefix = "/media/"
+
+var (
+	ErrMediaTooLarge       = models.TooLarge(models.CodePayloadTooLarge, "file too large")
+	ErrUnsupportedMedia    = models.UnsupportedMedia(models.CodeUnsupportedMedia, "unsupported media type")
+	ErrInvalidAttachment   = models.Invalid(models.CodeInvalidAttachment, "invalid media reference")
+	ErrMediaNotFound       = models.NotFound(models.CodeMediaNotFound, "media not found")
+	allowedMediaExtensions = map[string]string{
+		"image/jpeg": ".jpg",
+		"image/png":  ".png",
+		"image/gif":  ".gif",
+		"image/webp": ".webp",
+	}
+)
+
+type MediaUseCase struct {
+	MediaRepo models.MediaRepository
+	BlobStore models.BlobStore
+}
+
+// Upload validates an image for owner and stores its processed variants. The
+// type is sniffed from the content itself; the name and type sent by the
+// client are not trusted.
+func (m *MediaUseCase) Upload(ctx context.Context, owner string, r io.Reader, size int64) (*models.Media, error) {
+	if size > MaxMediaSize {
+		return nil, ErrMediaTooLarge
+	}
+	data, err := io.ReadAll(io.LimitReader(r, MaxMediaSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxMediaSize {
+		return nil, ErrMediaTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	if _, ok := allowedMediaExtensions[contentType]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedMedia, contentType)
+	}
+
+	processed, err := ProcessImage(data)
+	if err != nil {
+		return nil, err
+	}
+	key, err := newMediaKey(processed.Extension)
+	if err != nil {
+		return nil, err
+	}
+
+	media := &models.Media{
+		Owner:       owner,
+		Key:         key,
+		ContentType: processed.ContentType,
+		Width:       processed.Width,
+		Height:      processed.Height,
+		Blurhash:    processed.Blurhash,
+		CreatedAt:   time.Now().UTC(),
+	}
+	var stored []string
+	for _, v := range processed.Variants {
+		variantKey := MediaVariantKey(key, v.Name)
+		err := m.BlobStore.Put(variantKey, bytes.NewReader(v.Data), int64(len(v.Data)), processed.ContentType)
+		if err != nil {
+			return nil, errors.Join(err, m.deleteBlobs(stored))
+		}
+		stored = append(stored, variantKey)
+		if v.Name == models.MediaVariantOriginal {
+			media.Size = int64(len(v.Data))
+		}
+	}
+
+	if err := m.MediaRepo.Create(ctx, media); err != nil {
+		return nil, errors.Join(err, m.deleteBlobs(stored))
+	}
+	return media, nil
+}
+
+// Open returns the file stored under key, the key of one of the variants of
+// an uploaded media. Files whose media is gone are not served, even if
+// deleting them failed.
+func (m *MediaUseCase) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	found, err := m.MediaRepo.FindByKeys(ctx, []string{mediaKeyOf(key)})
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, ErrMediaNotFound
+	}
+	return m.BlobStore.Get(key)
+}
+
+// DeleteFiles removes every stored variant of media, whose rows are gone or
+// about to be.
+func (m *MediaUseCase) DeleteFiles(media []*models.Media) error {
+	var keys []string
+	for _, item := range media {
+		keys = append(keys, item.Key)
+		for _, v := range imageVariantSizes {
+			keys = append(keys, MediaVariantKey(item.Key, v.Name))
+		}
+	}
+	return m.deleteBlobs(keys)
+}
+
+func (m *MediaUseCase) deleteBlobs(keys []string) error {
+	var errs []error
+	for _, key := range keys {
+		errs = append(errs, m.BlobStore.Delete(key))
+	}
+	return errors.Join(errs...)
+}
+
+// FindOwned returns the media with the given ids, failing unless all of them
+// exist and belong to owner.
+func (m *MediaUseCase) FindOwned(ctx context.Context, owner string, ids []int64) ([]*models.Media, error) {
+	if len(ids) == 0 {
+		return []*models.Media{}, nil
+	}
+	found, err := m.MediaRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]*models.Media, len(found))
+	for _, media := range found {
+		byID[media.ID] = media
+	}
+	owned := make([]*models.Media, 0, len(ids))
+	for _, id := range ids {
+		media, ok := byID[id]
+		if !ok || media.Owner != owner {
+			return nil, fmt.Errorf("%w: %d", ErrInvalidAttachment, id)
+		}
+		owned = append(owned, media)
+	}
+	return owned, nil
+}
+
+// FindOwnedByKeys is like FindOwned for media identified by their keys.
+func (m *MediaUseCase) FindOwnedByKeys(ctx context.Context, owner string, keys []string) ([]*models.Media, error) {
+	if len(keys) == 0 {
+		return []*models.Media{}, nil
+	}
+	found, err := m.MediaRepo.FindByKeys(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]*models.Media, len(found))
+	for _, media := range found {
+		byKey[media.Key] = media
+	}
+	owned := make([]*models.Media, 0, len(keys))
+	for _, key := range keys {
+		media, ok := byKey[key]
+		if !ok || media.Owner != owner {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidAttachment, key)
+		}
+		owned = append(owned, media)
+	}
+	return owned, nil
+}
+
+func newMediaKey(ext string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b) + ext, nil
+}
+
+// MediaContentType returns the type a stored key was uploaded as, based on the
+// extension Upload gave it.
+func MediaContentType(key string) string {
+	ext := path.Ext(key)
+	for contentType, e := range allowedMediaExtensions {
+		if e == ext {
+			return contentType
+		}
+	}
+	return "application/octet-stream"
+}
+
+// MediaVariantKey returns the storage key of a variant of the media stored
+// under key: "abc.jpg" has its thumbnail at "abc-thumbnail.jpg".
+func MediaVariantKey(key, variant string) string {
+	if variant == models.MediaVariantOriginal {
+		return key
+	}
+	ext := path.Ext(key)
+	return strings.TrimSuffix(key, ext) + "-" + variant + ext
+}
+
+// mediaKeyOf returns the key of the media a variant is stored for, undoing
+// MediaVariantKey.
+func mediaKeyOf(variantKey string) string {
+	ext := path.Ext(variantKey)
+	base := strings.TrimSuffix(variantKey, ext)
+	for _, v := range imageVariantSizes {
+		if key, ok := strings.CutSuffix(base, "-"+v.Name); ok {
+			return key + ext
+		}
+	}
+	return variantKey
+}
+
+func MediaURL(m *models.Media) string {
+	return MediaURLPrefix + m.Key
+}
+
+func MapMediaToJson(m *models.Media) models.JsonMedia {
+	variants := map[string]models.JsonMediaVariant{
+		models.MediaVariantOriginal: {URL: MediaURL(m), Width: m.Width, Height: m.Height},
+	}
+	// Media uploaded before processing existed have no other variants.
+	if m.Width > 0 {
+		for _, v := range imageVariantSizes {
+			w, h := FitImage(m.Width, m.Height, v.Size)
+			variants[v.Name] = models.JsonMediaVariant{
+				URL:    MediaURLPrefix + MediaVariantKey(m.Key, v.Name),
+				Width:  w,
+				Height: h,
+			}
+		}
+	}
+	return models.JsonMedia{
+		ID:          m.ID,
+		URL:         MediaURL(m),
+		ContentType: m.ContentType,
+		Size:        m.Size,
+		Width:       m.Width,
+		Height:      m.Height,
+		Blurhash:    m.Blurhash,
+		Variants:    variants,
+	}
+}
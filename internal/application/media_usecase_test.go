@@ -0,0 +1,218 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"postapi/internal/domain"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeMediaRepo struct {
+	domain.MediaRepository
+	created []*domain.Media
+	// deletedOwners are the accounts FindByDeletedOwners finds deleted.
+	deletedOwners []string
+}
+
+func (f *fakeMediaRepo) Create(ctx context.Context, media *domain.Media) error {
+	media.ID = int64(len(f.created) + 1)
+	f.created = append(f.created, media)
+	return nil
+}
+
+func (f *fakeMediaRepo) FindByIDs(ctx context.Context, ids []int64) ([]*domain.Media, error) {
+	var found []*domain.Media
+	for _, id := range ids {
+		if id >= 1 && int(id) <= len(f.created) {
+			found = append(found, f.created[id-1])
+		}
+	}
+	return found, nil
+}
+
+func (f *fakeMediaRepo) FindByKeys(ctx context.Context, keys []string) ([]*domain.Media, error) {
+	var found []*domain.Media
+	for _, media := range f.created {
+		if slices.Contains(keys, media.Key) {
+			found = append(found, media)
+		}
+	}
+	return found, nil
+}
+
+func (f *fakeMediaRepo) FindByDeletedOwners(ctx context.Context, deletedBefore time.Time) ([]*domain.Media, error) {
+	var found []*domain.Media
+	for _, media := range f.created {
+		if slices.Contains(f.deletedOwners, media.Owner) {
+			found = append(found, media)
+		}
+	}
+	return found, nil
+}
+
+type fakeBlobStore struct {
+	blobs map[string][]byte
+}
+
+func (f *fakeBlobStore) Put(key string, r io.Reader, size int64, contentType string) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	f.blobs[key] = b
+	return nil
+}
+
+func (f *fakeBlobStore) Get(key string) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(f.blobs[key])), nil
+}
+
+func (f *fakeBlobStore) Delete(key string) error {
+	delete(f.blobs, key)
+	return nil
+}
+
+// testImage returns an encoded width by height image with a transparent
+// corner, so that it stays a PNG after processing.
+func testImage(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 200, A: 255})
+		}
+	}
+	img.Set(0, 0, color.NRGBA{})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func newTestMediaUseCase() (*MediaUseCase, *fakeBlobStore) {
+	store := &fakeBlobStore{blobs: map[string][]byte{}}
+	return &MediaUseCase{MediaRepo: &fakeMediaRepo{}, BlobStore: store}, store
+}
+
+func TestMediaUseCase_Upload(t *testing.T) {
+	uc, store := newTestMediaUseCase()
+
+	data := testImage(t, 600, 300)
+	media, err := uc.Upload(context.Background(), "alice", bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+	if media.ContentType != "image/png" || !strings.HasSuffix(media.Key, ".png") {
+		t.Errorf("Upload() = %+v, want a png", media)
+	}
+	if media.Width != 600 || media.Height != 300 || media.Blurhash == "" {
+		t.Errorf("Upload() = %+v, want dimensions and blurhash", media)
+	}
+	if got := MediaContentType(media.Key); got != "image/png" {
+		t.Errorf("MediaContentType() = %q", got)
+	}
+
+	jsonMedia := MapMediaToJson(media)
+	if len(store.blobs) != len(jsonMedia.Variants) {
+		t.Errorf("stored %d blobs for %d variants", len(store.blobs), len(jsonMedia.Variants))
+	}
+	for name, v := range jsonMedia.Variants {
+		key := strings.TrimPrefix(v.URL, MediaURLPrefix)
+		if _, ok := store.blobs[key]; !ok {
+			t.Errorf("variant %s not stored at %s", name, key)
+		}
+	}
+	if thumb := jsonMedia.Variants[domain.MediaVariantThumbnail]; thumb.Width != 256 || thumb.Height != 128 {
+		t.Errorf("thumbnail = %+v, want 256x128", thumb)
+	}
+}
+
+func TestMediaUseCase_UploadRejected(t *testing.T) {
+	uc, store := newTestMediaUseCase()
+
+	_, err := uc.Upload(context.Background(), "alice", strings.NewReader("<html><script>alert(1)</script>"), 31)
+	if !errors.Is(err, ErrUnsupportedMedia) {
+		t.Errorf("Upload(html) error = %v, want ErrUnsupportedMedia", err)
+	}
+	_, err = uc.Upload(context.Background(), "alice", strings.NewReader("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), 16)
+	if !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("Upload(truncated) error = %v, want ErrInvalidImage", err)
+	}
+	_, err = uc.Upload(context.Background(), "alice", strings.NewReader("x"), MaxMediaSize+1)
+	if !errors.Is(err, ErrMediaTooLarge) {
+		t.Errorf("Upload(large) error = %v, want ErrMediaTooLarge", err)
+	}
+	if len(store.blobs) != 0 {
+		t.Errorf("rejected uploads were stored: %v", store.blobs)
+	}
+}
+
+func TestMediaUseCase_FindOwned(t *testing.T) {
+	uc, _ := newTestMediaUseCase()
+	data := testImage(t, 10, 10)
+	mine, _ := uc.Upload(context.Background(), "alice", bytes.NewReader(data), int64(len(data)))
+	theirs, _ := uc.Upload(context.Background(), "bob", bytes.NewReader(data), int64(len(data)))
+
+	owned, err := uc.FindOwned(context.Background(), "alice", []int64{mine.ID})
+	if err != nil || len(owned) != 1 || owned[0].ID != mine.ID {
+		t.Errorf("FindOwned(own) = %v, %v", owned, err)
+	}
+	if _, err := uc.FindOwned(context.Background(), "alice", []int64{theirs.ID}); !errors.Is(err, ErrInvalidAttachment) {
+		t.Errorf("FindOwned(other's) error = %v, want ErrInvalidAttachment", err)
+	}
+	if _, err := uc.FindOwned(context.Background(), "alice", []int64{99}); !errors.Is(err, ErrInvalidAttachment) {
+		t.Errorf("FindOwned(unknown) error = %v, want ErrInvalidAttachment", err)
+	}
+}
+
+func TestMediaUseCase_Open(t *testing.T) {
+	uc, store := newTestMediaUseCase()
+	data := testImage(t, 600, 300)
+	media, _ := uc.Upload(context.Background(), "alice", bytes.NewReader(data), int64(len(data)))
+
+	for _, variant := range []string{domain.MediaVariantOriginal, domain.MediaVariantThumbnail} {
+		key := MediaVariantKey(media.Key, variant)
+		blob, err := uc.Open(context.Background(), key)
+		if err != nil {
+			t.Fatalf("Open(%s) error = %v", variant, err)
+		}
+		got, _ := io.ReadAll(blob)
+		if !bytes.Equal(got, store.blobs[key]) {
+			t.Errorf("Open(%s) returned another file", variant)
+		}
+	}
+
+	// A file with no media row is not served
+	store.blobs["orphan.png"] = data
+	if _, err := uc.Open(context.Background(), "orphan.png"); !errors.Is(err, ErrMediaNotFound) {
+		t.Errorf("Open(orphan) error = %v, want ErrMediaNotFound", err)
+	}
+}
+
+func TestMediaUseCase_DeleteFiles(t *testing.T) {
+	uc, store := newTestMediaUseCase()
+	data := testImage(t, 600, 300)
+	media, _ := uc.Upload(context.Background(), "alice", bytes.NewReader(data), int64(len(data)))
+	kept, _ := uc.Upload(context.Background(), "bob", bytes.NewReader(data), int64(len(data)))
+
+	if err := uc.DeleteFiles([]*domain.Media{media}); err != nil {
+		t.Fatalf("DeleteFiles() error = %v", err)
+	}
+	for key := range store.blobs {
+		if mediaKeyOf(key) != kept.Key {
+			t.Errorf("DeleteFiles() left %s behind", key)
+		}
+	}
+	if len(store.blobs) != len(MapMediaToJson(kept).Variants) {
+		t.Errorf("DeleteFiles() removed the files of other media: %d left", len(store.blobs))
+	}
+}
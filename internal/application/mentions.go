@@ -0,0 +1,38 @@
+package application
+
+import (
+	models "postapi/internal/domain"
+	"regexp"
+	"unicode/utf8"
+)
+
+// A mention starts after whitespace or punctuation, so e-mail addresses such
+// as "john@example.com" are not picked up.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@.])@([A-Za-z0-9_]+)`)
+
+// ExtractMentions returns every @username found in text with its rune offsets.
+func ExtractMentions(field string, text string) []*models.Mention {
+	mentions := []*models.Mention{}
+	for _, loc := range mentionPattern.FindAllStringSubmatchIndex(text, -1) {
+		// loc[2]:loc[3] is the username, the '@' is the byte right before it.
+		at := loc[2] - 1
+		start := utf8.RuneCountInString(text[:at])
+		username := text[loc[2]:loc[3]]
+		mentions = append(mentions, &models.Mention{
+			Username: username,
+			Field:    field,
+			Start:    start,
+			End:      start + 1 + utf8.RuneCountInString(username),
+		})
+	}
+	return mentions
+}
+
+func MapMentionToJson(m *models.Mention) models.JsonMention {
+	return models.JsonMention{
+		Username: m.Username,
+		Field:    m.Field,
+		Start:    m.Start,
+		End:      m.End,
+	}
+}
@@ -0,0 +1,154 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"postapi/internal/domain"
+	"testing"
+)
+
+func TestExtractMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []domain.Mention
+	}{
+		{
+			name: "Single mention",
+			text: "hi @alice!",
+			want: []domain.Mention{{Username: "alice", Start: 3, End: 9}},
+		},
+		{
+			name: "Offsets count characters, not bytes",
+			text: "¡olé @bob y @carol_2",
+			want: []domain.Mention{
+				{Username: "bob", Start: 5, End: 9},
+				{Username: "carol_2", Start: 12, End: 20},
+			},
+		},
+		{
+			name: "E-mail addresses are ignored",
+			text: "write to john@example.com or @@nobody",
+			want: []domain.Mention{},
+		},
+		{
+			name: "Mention at the start",
+			text: "@dave: thanks",
+			want: []domain.Mention{{Username: "dave", Start: 0, End: 5}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtractMentions(domain.MentionFieldContent, tt.text)
+			if len(got) != len(tt.want) {
+				t.Fatalf("ExtractMentions() returned %d mentions, want %d", len(got), len(tt.want))
+			}
+			for i, want := range tt.want {
+				if got[i].Username != want.Username || got[i].Start != want.Start || got[i].End != want.End {
+					t.Errorf("ExtractMentions()[%d] = %+v, want %+v", i, *got[i], want)
+				}
+				if got[i].Field != domain.MentionFieldContent {
+					t.Errorf("ExtractMentions()[%d] Field = %v, want content", i, got[i].Field)
+				}
+			}
+		})
+	}
+}
+
+type fakeIndexTagRepo struct {
+	domain.TagRepository
+	tags []string
+	// indexed lists the post ids and tags set, in order.
+	indexed []string
+}
+
+func (f *fakeIndexTagRepo) SetPostTags(ctx context.Context, postID int64, tags []string) error {
+	f.tags = tags
+	f.indexed = append(f.indexed, fmt.Sprint(postID, tags))
+	return nil
+}
+
+type fakeIndexMentionRepo struct {
+	domain.MentionRepository
+	previous []*domain.Mention
+	mentions []*domain.Mention
+}
+
+func (f *fakeIndexMentionRepo) FindByPosts(ctx context.Context, postIDs []int64) ([]*domain.Mention, error) {
+	return f.previous, nil
+}
+
+func (f *fakeIndexMentionRepo) SetPostMentions(ctx context.Context, postID int64, mentions []*domain.Mention) error {
+	f.mentions = mentions
+	return nil
+}
+
+type fakeIndexUserRepo struct {
+	domain.UserRepository
+	users map[string]bool
+}
+
+func (f *fakeIndexUserRepo) FindByUsername(ctx context.Context, username string) (*domain.User, error) {
+	if !f.users[username] {
+		return nil, domain.ErrNotFound
+	}
+	return &domain.User{Username: username}, nil
+}
+
+func TestPostUseCase_IndexPost(t *testing.T) {
+	tagRepo := &fakeIndexTagRepo{}
+	mentionRepo := &fakeIndexMentionRepo{}
+	uc := &PostUseCase{
+		TagRepo:     tagRepo,
+		MentionRepo: mentionRepo,
+		UserRepo:    &fakeIndexUserRepo{users: map[string]bool{"alice": true}},
+	}
+
+	post := &domain.Post{ID: 7, Title: "Hi @alice", Content: "#go with @alice and @ghost"}
+	mentioned, err := uc.IndexPost(context.Background(), post)
+	if err != nil {
+		t.Fatalf("IndexPost() error = %v", err)
+	}
+	if len(mentioned) != 1 || mentioned[0] != "alice" {
+		t.Errorf("IndexPost() newly mentioned = %v, want [alice]", mentioned)
+	}
+
+	if len(tagRepo.tags) != 1 || tagRepo.tags[0] != "go" {
+		t.Errorf("IndexPost() tags = %v, want [go]", tagRepo.tags)
+	}
+	if len(mentionRepo.mentions) != 2 {
+		t.Fatalf("IndexPost() stored %d mentions, want 2", len(mentionRepo.mentions))
+	}
+	for _, m := range mentionRepo.mentions {
+		if m.Username != "alice" || m.PostID != 7 {
+			t.Errorf("IndexPost() stored mention %+v, want alice on post 7", *m)
+		}
+	}
+	if len(post.Mentions) != 2 {
+		t.Errorf("IndexPost() set %d mentions on the post, want 2", len(post.Mentions))
+	}
+}
+
+func TestPostUseCase_IndexPost_OnlyNewMentions(t *testing.T) {
+	mentionRepo := &fakeIndexMentionRepo{
+		previous: []*domain.Mention{{PostID: 7, Username: "alice"}},
+	}
+	uc := &PostUseCase{
+		TagRepo:     &fakeIndexTagRepo{},
+		MentionRepo: mentionRepo,
+		UserRepo:    &fakeIndexUserRepo{users: map[string]bool{"alice": true, "bob": true}},
+	}
+
+	post := &domain.Post{ID: 7, Title: "Edited", Content: "@alice and now @bob"}
+	mentioned, err := uc.IndexPost(context.Background(), post)
+	if err != nil {
+		t.Fatalf("IndexPost() error = %v", err)
+	}
+	if len(mentioned) != 1 || mentioned[0] != "bob" {
+		t.Errorf("IndexPost() newly mentioned = %v, want [bob]", mentioned)
+	}
+	if len(mentionRepo.mentions) != 2 {
+		t.Errorf("IndexPost() stored %d mentions, want 2", len(mentionRepo.mentions))
+	}
+}
@@ -0,0 +1,458 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"postapi/internal/domain"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// micropubTitleLength is the length of the titles made up for entries sent
+// without a name, such as notes.
+const micropubTitleLength = 60
+
+var ErrInvalidMicropubRequest = domain.Invalid(domain.CodeInvalidMicropub, "invalid micropub request")
+
+// MicropubProperties are the properties of an h-entry: the "properties" of
+// a JSON request, or the fields of a form without their "[]" suffix.
+type MicropubProperties map[string][]any
+
+// MicropubUpdate is the body of a Micropub update request.
+type MicropubUpdate struct {
+	Replace MicropubProperties
+	Add     MicropubProperties
+	// DeleteProperties are removed entirely; DeleteValues lists values to
+	// remove from a property.
+	DeleteProperties []string
+	DeleteValues     MicropubProperties
+}
+
+// MicropubUseCase maps h-entries to posts. Posts have no separate list of
+// categories: they become hashtags, kept on a last line of the content.
+type MicropubUseCase struct {
+	// BaseURL is the public root of the site, without a trailing slash.
+	BaseURL      string
+	PostRepo     domain.PostRepository
+	MediaUseCase *MediaUseCase
+}
+
+// MediaEndpoint is the URL files are uploaded to.
+func (m *MicropubUseCase) MediaEndpoint() string {
+	return m.BaseURL + "/micropub/media"
+}
+
+// Config answers q=config.
+func (m *MicropubUseCase) Config() map[string]any {
+	return map[string]any{
+		"media-endpoint": m.MediaEndpoint(),
+		"syndicate-to":   []any{},
+		"q":              []string{"config", "source", "syndicate-to"},
+	}
+}
+
+// NewPost builds the post for a new h-entry of username, along with the
+// attachments for its photos. Photos must have been uploaded to the media
+// endpoint.
+func (m *MicropubUseCase) NewPost(ctx context.Context, username string, props MicropubProperties) (*domain.Post, []*domain.Media, error) {
+	content, format, err := micropubContent(props["content"])
+	if err != nil {
+		return nil, nil, err
+	}
+	title := firstString(props["name"])
+	if content == "" {
+		content = title
+	}
+	if strings.TrimSpace(content) == "" {
+		return nil, nil, fmt.Errorf("%w: content is required", ErrInvalidMicropubRequest)
+	}
+	if title == "" {
+		title = titleFromContent(content)
+	}
+	photos, err := m.photos(ctx, username, props["photo"])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	post := &domain.Post{
+		Title:   title,
+		Content: withTagLine(content, categoryTags(props["category"])),
+		Format:  format,
+		Author:  username,
+	}
+	if err := validateMicropubPost(post); err != nil {
+		return nil, nil, err
+	}
+	return post, photos, nil
+}
+
+// FindPost returns the post of username at postURL.
+func (m *MicropubUseCase) FindPost(ctx context.Context, username, postURL string) (*domain.Post, error) {
+	id, ok := PostIDFromURL(m.BaseURL, postURL)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not a post on this site", ErrInvalidMicropubRequest, postURL)
+	}
+	post, err := m.PostRepo.FindByID(ctx, id)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %s does not exist", ErrInvalidMicropubRequest, postURL)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if post.Author != username {
+		return nil, ErrNotAuthor
+	}
+	return post, nil
+}
+
+// ApplyUpdate changes post as requested. Only name, content and category
+// can be updated, and name and content can only be replaced.
+func (m *MicropubUseCase) ApplyUpdate(post *domain.Post, update *MicropubUpdate) error {
+	for _, props := range []MicropubProperties{update.Replace, update.Add, update.DeleteValues} {
+		for name := range props {
+			if name != "name" && name != "content" && name != "category" {
+				return fmt.Errorf("%w: %s cannot be updated", ErrInvalidMicropubRequest, name)
+			}
+		}
+	}
+	for name := range update.Add {
+		if name != "category" {
+			return fmt.Errorf("%w: %s can only be replaced", ErrInvalidMicropubRequest, name)
+		}
+	}
+
+	body, tags := splitTagLine(post.Content)
+	if values, ok := update.Replace["name"]; ok {
+		post.Title = firstString(values)
+	}
+	if values, ok := update.Replace["content"]; ok {
+		content, format, err := micropubContent(values)
+		if err != nil {
+			return err
+		}
+		body, post.Format = content, format
+	}
+	if values, ok := update.Replace["category"]; ok {
+		tags = categoryTags(values)
+	}
+	tags = append(tags, categoryTags(update.Add["category"])...)
+	for _, name := range update.DeleteProperties {
+		switch name {
+		case "name":
+			post.Title = ""
+		case "category":
+			tags = nil
+		default:
+			return fmt.Errorf("%w: %s cannot be deleted", ErrInvalidMicropubRequest, name)
+		}
+	}
+	for _, tag := range categoryTags(update.DeleteValues["category"]) {
+		tags = slices.DeleteFunc(tags, func(t string) bool { return t == tag })
+	}
+
+	if strings.TrimSpace(body) == "" {
+		return fmt.Errorf("%w: content is required", ErrInvalidMicropubRequest)
+	}
+	if post.Title == "" {
+		post.Title = titleFromContent(body)
+	}
+	post.Content = withTagLine(body, tags)
+	return validateMicropubPost(post)
+}
+
+// validateMicropubPost applies the rules of posts created through the API.
+func validateMicropubPost(post *domain.Post) error {
+	return Validate(domain.PostRequest{Title: post.Title, Content: post.Content, Format: post.Format})
+}
+
+// Source answers q=source: the given properties of post, or all of them
+// along with its type when none are asked for.
+func (m *MicropubUseCase) Source(post *domain.Post, properties []string) map[string]any {
+	body, _ := splitTagLine(post.Content)
+	photos := []any{}
+	for _, media := range post.Attachments {
+		photos = append(photos, m.BaseURL+MediaURL(media))
+	}
+	categories := []any{}
+	for _, tag := range ExtractHashtags(post.Title, post.Content) {
+		categories = append(categories, tag)
+	}
+	all := map[string][]any{
+		"name":      {post.Title},
+		"content":   {body},
+		"category":  categories,
+		"photo":     photos,
+		"published": {post.CreatedAt.UTC().Format(time.RFC3339)},
+		"url":       {PostURL(m.BaseURL, post.ID)},
+	}
+	if len(properties) == 0 {
+		return map[string]any{"type": []string{"h-entry"}, "properties": all}
+	}
+	selected := map[string][]any{}
+	for _, name := range properties {
+		if values, ok := all[name]; ok {
+			selected[name] = values
+		}
+	}
+	return map[string]any{"properties": selected}
+}
+
+func (m *MicropubUseCase) photos(ctx context.Context, username string, values []any) ([]*domain.Media, error) {
+	prefix := m.BaseURL + MediaURLPrefix
+	keys := make([]string, 0, len(values))
+	for _, v := range values {
+		u := stringValue(v)
+		key, ok := strings.CutPrefix(u, prefix)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%w: photo %s must be uploaded to the media endpoint", ErrInvalidMicropubRequest, u)
+		}
+		keys = append(keys, key)
+	}
+	media, err := m.MediaUseCase.FindOwnedByKeys(ctx, username, keys)
+	if errors.Is(err, ErrInvalidAttachment) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMicropubRequest, err)
+	}
+	return media, err
+}
+
+// micropubContent reads a content property: plain text, {"html": ...},
+// which is converted to Markdown, or {"value": ...}.
+func micropubContent(values []any) (content, format string, err error) {
+	if len(values) == 0 {
+		return "", domain.PostFormatPlain, nil
+	}
+	switch v := values[0].(type) {
+	case string:
+		return v, domain.PostFormatPlain, nil
+	case map[string]any:
+		if h, ok := v["html"].(string); ok {
+			return htmlToMarkdown(h), domain.PostFormatMarkdown, nil
+		}
+		if s, ok := v["value"].(string); ok {
+			return s, domain.PostFormatPlain, nil
+		}
+	}
+	return "", "", fmt.Errorf("%w: content must be text or {\"html\": ...}", ErrInvalidMicropubRequest)
+}
+
+func firstString(values []any) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(stringValue(values[0]))
+}
+
+// stringValue reads a property value given as a string or as an object
+// with a value, such as a photo with alt text.
+func stringValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case map[string]any:
+		s, _ := v["value"].(string)
+		return s
+	}
+	return ""
+}
+
+func titleFromContent(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(line, "#> "))
+		if line != "" {
+			return truncateText(line, micropubTitleLength)
+		}
+	}
+	return ""
+}
+
+// categoryTags turns categories into hashtags, joining words with "_".
+func categoryTags(values []any) []string {
+	tags := []string{}
+	for _, v := range values {
+		tag := NormalizeTag(strings.Map(func(r rune) rune {
+			switch {
+			case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+				return r
+			case unicode.IsSpace(r) || r == '-' || r == '.':
+				return '_'
+			}
+			return -1
+		}, strings.TrimSpace(stringValue(v))))
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+var tagLinePattern = regexp.MustCompile(`^#[\p{L}\p{N}_]+(\s+#[\p{L}\p{N}_]+)*$`)
+
+// splitTagLine separates the last line of content, when it only has
+// hashtags, from the rest.
+func splitTagLine(content string) (body string, tags []string) {
+	trimmed := strings.TrimRight(content, " \t\r\n")
+	i := strings.LastIndex(trimmed, "\n")
+	line := strings.TrimSpace(trimmed[i+1:])
+	if !tagLinePattern.MatchString(line) {
+		return content, []string{}
+	}
+	if i < 0 {
+		return "", ExtractHashtags(line)
+	}
+	return strings.TrimRight(trimmed[:i], " \t\r\n"), ExtractHashtags(line)
+}
+
+// withTagLine appends the tags that body does not already have as a line of
+// hashtags.
+func withTagLine(body string, tags []string) string {
+	present := ExtractHashtags(body)
+	var line []string
+	for _, tag := range tags {
+		if !slices.Contains(present, tag) {
+			present = append(present, tag)
+			line = append(line, "#"+tag)
+		}
+	}
+	if len(line) == 0 {
+		return body
+	}
+	if strings.TrimSpace(body) == "" {
+		return strings.Join(line, " ")
+	}
+	return body + "\n\n" + strings.Join(line, " ")
+}
+
+var blankLinesPattern = regexp.MustCompile(`\n{3,}`)
+
+// htmlToMarkdown converts the HTML content sent by editors to Markdown, as
+// raw HTML is not kept in posts. Unknown elements are reduced to their text.
+func htmlToMarkdown(fragment string) string {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), body)
+	if err != nil {
+		return ""
+	}
+	for _, n := range nodes {
+		body.AppendChild(n)
+	}
+
+	var b strings.Builder
+	writeMarkdown(&b, body)
+	lines := strings.Split(b.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.TrimSpace(blankLinesPattern.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+func writeMarkdown(b *strings.Builder, n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch c.Type {
+		case html.TextNode:
+			b.WriteString(collapseSpace(c.Data))
+		case html.ElementNode:
+			writeMarkdownElement(b, c)
+		}
+	}
+}
+
+func writeMarkdownElement(b *strings.Builder, n *html.Node) {
+	switch n.DataAtom {
+	case atom.Script, atom.Style:
+	case atom.P, atom.Div:
+		b.WriteString("\n\n")
+		writeMarkdown(b, n)
+		b.WriteString("\n\n")
+	case atom.Br:
+		b.WriteString("\\\n")
+	case atom.Strong, atom.B:
+		b.WriteString("**")
+		writeMarkdown(b, n)
+		b.WriteString("**")
+	case atom.Em, atom.I:
+		b.WriteString("*")
+		writeMarkdown(b, n)
+		b.WriteString("*")
+	case atom.Code:
+		b.WriteString("`" + textContent(n) + "`")
+	case atom.Pre:
+		var code strings.Builder
+		for c := range n.Descendants() {
+			if c.Type == html.TextNode {
+				code.WriteString(c.Data)
+			}
+		}
+		b.WriteString("\n\n```\n" + strings.Trim(code.String(), "\n") + "\n```\n\n")
+	case atom.A:
+		href, ok := attribute(n, "href")
+		if !ok {
+			writeMarkdown(b, n)
+			return
+		}
+		b.WriteString("[")
+		writeMarkdown(b, n)
+		b.WriteString("](" + href + ")")
+	case atom.Img:
+		src, _ := attribute(n, "src")
+		alt, _ := attribute(n, "alt")
+		b.WriteString("![" + alt + "](" + src + ")")
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		b.WriteString("\n\n" + strings.Repeat("#", int(n.Data[1]-'0')) + " ")
+		writeMarkdown(b, n)
+		b.WriteString("\n\n")
+	case atom.Ul, atom.Ol:
+		b.WriteString("\n\n")
+		i := 0
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode || c.DataAtom != atom.Li {
+				continue
+			}
+			i++
+			if n.DataAtom == atom.Ol {
+				fmt.Fprintf(b, "%d. ", i)
+			} else {
+				b.WriteString("- ")
+			}
+			var item strings.Builder
+			writeMarkdown(&item, c)
+			b.WriteString(strings.TrimSpace(item.String()) + "\n")
+		}
+		b.WriteString("\n")
+	case atom.Blockquote:
+		var quote strings.Builder
+		writeMarkdown(&quote, n)
+		b.WriteString("\n\n")
+		for _, line := range strings.Split(strings.TrimSpace(blankLinesPattern.ReplaceAllString(quote.String(), "\n\n")), "\n") {
+			b.WriteString(strings.TrimRight("> "+strings.TrimSpace(line), " ") + "\n")
+		}
+		b.WriteString("\n")
+	default:
+		writeMarkdown(b, n)
+	}
+}
+
+// collapseSpace reduces runs of whitespace to a single space, as browsers
+// do when rendering.
+func collapseSpace(s string) string {
+	collapsed := strings.Join(strings.Fields(s), " ")
+	if collapsed == "" {
+		if s != "" {
+			return " "
+		}
+		return ""
+	}
+	if unicode.IsSpace(rune(s[0])) {
+		collapsed = " " + collapsed
+	}
+	if unicode.IsSpace(rune(s[len(s)-1])) {
+		collapsed += " "
+	}
+	return collapsed
+}
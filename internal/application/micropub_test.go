@@ -0,0 +1,212 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"postapi/internal/domain"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeMicropubMediaRepo struct {
+	domain.MediaRepository
+}
+
+func (f *fakeMicropubMediaRepo) FindByKeys(ctx context.Context, keys []string) ([]*domain.Media, error) {
+	var media []*domain.Media
+	for i, key := range keys {
+		owner := "alice"
+		if key == "bob.png" {
+			owner = "bob"
+		}
+		media = append(media, &domain.Media{ID: int64(i + 1), Owner: owner, Key: key})
+	}
+	return media, nil
+}
+
+func newTestMicropubUseCase() *MicropubUseCase {
+	return &MicropubUseCase{
+		BaseURL:      "https://blog.example",
+		PostRepo:     &fakeWebmentionPostRepo{},
+		MediaUseCase: &MediaUseCase{MediaRepo: &fakeMicropubMediaRepo{}},
+	}
+}
+
+func TestMicropubUseCase_NewPost(t *testing.T) {
+	uc := newTestMicropubUseCase()
+
+	post, photos, err := uc.NewPost(context.Background(), "alice", MicropubProperties{
+		"name":     {"My trip"},
+		"content":  {"We went to the coast"},
+		"category": {"travel", "Indie Web", "travel"},
+		"photo":    {"https://blog.example/media/a.jpg", map[string]any{"value": "https://blog.example/media/b.jpg", "alt": "Beach"}},
+	})
+	if err != nil {
+		t.Fatalf("NewPost() error = %v", err)
+	}
+	want := &domain.Post{
+		Title:   "My trip",
+		Content: "We went to the coast\n\n#travel #indie_web",
+		Format:  domain.PostFormatPlain,
+		Author:  "alice",
+	}
+	if !reflect.DeepEqual(post, want) {
+		t.Errorf("NewPost() = %+v, want %+v", post, want)
+	}
+	if len(photos) != 2 || photos[0].Key != "a.jpg" || photos[1].Key != "b.jpg" {
+		t.Errorf("photos = %+v", photos)
+	}
+}
+
+func TestMicropubUseCase_NewNote(t *testing.T) {
+	uc := newTestMicropubUseCase()
+
+	post, _, err := uc.NewPost(context.Background(), "alice", MicropubProperties{
+		"content": {map[string]any{"html": `<p>Hello <b>world</b>, see <a href="https://a.example/">this</a></p><p>Second #go</p>`}},
+	})
+	if err != nil {
+		t.Fatalf("NewPost() error = %v", err)
+	}
+	if post.Title != "Hello **world**, see [this](https://a.example/)" ||
+		post.Content != "Hello **world**, see [this](https://a.example/)\n\nSecond #go" ||
+		post.Format != domain.PostFormatMarkdown {
+		t.Errorf("NewPost() = %+v", post)
+	}
+}
+
+func TestMicropubUseCase_NewPostInvalid(t *testing.T) {
+	uc := newTestMicropubUseCase()
+
+	tests := []MicropubProperties{
+		{},
+		{"content": {"  "}},
+		{"content": {"hi"}, "photo": {"https://elsewhere.example/a.jpg"}},
+		{"content": {"hi"}, "photo": {"https://blog.example/media/bob.png"}},
+		{"content": {42.0}},
+	}
+	for _, props := range tests {
+		if _, _, err := uc.NewPost(context.Background(), "alice", props); !errors.Is(err, ErrInvalidMicropubRequest) {
+			t.Errorf("NewPost(%v) error = %v, want ErrInvalidMicropubRequest", props, err)
+		}
+	}
+	long := MicropubProperties{"name": {strings.Repeat("a", 201)}, "content": {"hi"}}
+	if _, _, err := uc.NewPost(context.Background(), "alice", long); !errors.Is(err, domain.ErrValidation) {
+		t.Errorf("NewPost() error = %v, want a validation error for a long name", err)
+	}
+}
+
+func TestMicropubUseCase_FindPost(t *testing.T) {
+	uc := newTestMicropubUseCase()
+
+	if post, err := uc.FindPost(context.Background(), "alice", "https://blog.example/api/posts/7"); err != nil || post.ID != 7 {
+		t.Errorf("FindPost() = %+v, %v", post, err)
+	}
+	if _, err := uc.FindPost(context.Background(), "bob", "https://blog.example/api/posts/7"); !errors.Is(err, ErrNotAuthor) {
+		t.Errorf("FindPost(other author) error = %v, want ErrNotAuthor", err)
+	}
+	for _, u := range []string{"https://blog.example/api/posts/8", "https://other.example/api/posts/7"} {
+		if _, err := uc.FindPost(context.Background(), "alice", u); !errors.Is(err, ErrInvalidMicropubRequest) {
+			t.Errorf("FindPost(%s) error = %v, want ErrInvalidMicropubRequest", u, err)
+		}
+	}
+}
+
+func TestMicropubUseCase_ApplyUpdate(t *testing.T) {
+	uc := newTestMicropubUseCase()
+	newPost := func() *domain.Post {
+		return &domain.Post{Title: "Trip", Content: "Went to the #coast\n\n#travel #summer", Format: domain.PostFormatPlain}
+	}
+
+	tests := []struct {
+		name        string
+		update      MicropubUpdate
+		wantTitle   string
+		wantContent string
+	}{
+		{"replace content keeps categories", MicropubUpdate{Replace: MicropubProperties{"content": {"Back home"}}},
+			"Trip", "Back home\n\n#travel #summer"},
+		{"replace name", MicropubUpdate{Replace: MicropubProperties{"name": {"Holiday"}}},
+			"Holiday", "Went to the #coast\n\n#travel #summer"},
+		{"add category", MicropubUpdate{Add: MicropubProperties{"category": {"beach", "coast"}}},
+			"Trip", "Went to the #coast\n\n#travel #summer #beach"},
+		{"delete category value", MicropubUpdate{DeleteValues: MicropubProperties{"category": {"summer"}}},
+			"Trip", "Went to the #coast\n\n#travel"},
+		{"delete categories", MicropubUpdate{DeleteProperties: []string{"category"}},
+			"Trip", "Went to the #coast"},
+		{"delete name", MicropubUpdate{DeleteProperties: []string{"name"}},
+			"Went to the #coast", "Went to the #coast\n\n#travel #summer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post := newPost()
+			if err := uc.ApplyUpdate(post, &tt.update); err != nil {
+				t.Fatalf("ApplyUpdate() error = %v", err)
+			}
+			if post.Title != tt.wantTitle || post.Content != tt.wantContent {
+				t.Errorf("ApplyUpdate() = %q, %q, want %q, %q", post.Title, post.Content, tt.wantTitle, tt.wantContent)
+			}
+		})
+	}
+
+	invalid := []MicropubUpdate{
+		{Replace: MicropubProperties{"photo": {"https://blog.example/media/a.jpg"}}},
+		{Add: MicropubProperties{"content": {"more"}}},
+		{DeleteProperties: []string{"content"}},
+		{Replace: MicropubProperties{"content": {""}}},
+	}
+	for _, update := range invalid {
+		if err := uc.ApplyUpdate(newPost(), &update); !errors.Is(err, ErrInvalidMicropubRequest) {
+			t.Errorf("ApplyUpdate(%+v) error = %v, want ErrInvalidMicropubRequest", update, err)
+		}
+	}
+}
+
+func TestMicropubUseCase_Source(t *testing.T) {
+	uc := newTestMicropubUseCase()
+	post := &domain.Post{
+		ID:          7,
+		Title:       "Trip",
+		Content:     "Went to the #coast\n\n#travel",
+		CreatedAt:   time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC),
+		Attachments: []*domain.Media{{Key: "a.jpg"}},
+	}
+
+	all := uc.Source(post, nil)
+	want := map[string]any{
+		"type": []string{"h-entry"},
+		"properties": map[string][]any{
+			"name":      {"Trip"},
+			"content":   {"Went to the #coast"},
+			"category":  {"coast", "travel"},
+			"photo":     {"https://blog.example/media/a.jpg"},
+			"published": {"2026-03-01T10:00:00Z"},
+			"url":       {"https://blog.example/api/posts/7"},
+		},
+	}
+	if !reflect.DeepEqual(all, want) {
+		t.Errorf("Source() = %v, want %v", all, want)
+	}
+
+	some := uc.Source(post, []string{"name", "unknown"})
+	if !reflect.DeepEqual(some, map[string]any{"properties": map[string][]any{"name": {"Trip"}}}) {
+		t.Errorf("Source(name) = %v", some)
+	}
+}
+
+func TestHTMLToMarkdown(t *testing.T) {
+	tests := []struct{ html, want string }{
+		{`<p>One</p><p>Two<br>lines</p>`, "One\n\nTwo\\\nlines"},
+		{`<h2>Title</h2><ul><li>a</li><li><i>b</i></li></ul>`, "## Title\n\n- a\n- *b*"},
+		{`<ol><li>x</li><li>y</li></ol>`, "1. x\n2. y"},
+		{`<blockquote><p>Quote</p></blockquote><pre><code>a := 1
+b := 2</code></pre>`, "> Quote\n\n```\na := 1\nb := 2\n```"},
+		{`<p>Use <code>go test</code> <img src="/x.png" alt="x"></p><script>alert(1)</script>`, "Use `go test` ![x](/x.png)"},
+	}
+	for _, tt := range tests {
+		if got := htmlToMarkdown(tt.html); got != tt.want {
+			t.Errorf("htmlToMarkdown(%q) = %q, want %q", tt.html, got, tt.want)
+		}
+	}
+}
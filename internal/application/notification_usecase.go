@@ -0,0 +1,45 @@
+package application
+
+import (
+	"context"
+	models "postapi/internal/domain"
+	"time"
+)
+
+type NotificationUseCase struct {
+	NotificationRepo models.NotificationRepository
+	Events           *EventUseCase
+}
+
+// Notify records an event for recipient. Users are never notified of their
+// own actions.
+func (n *NotificationUseCase) Notify(ctx context.Context, recipient string, actor string, notificationType string, postID *int64) (*models.Notification, error) {
+	if recipient == actor {
+		return nil, nil
+	}
+	notification := &models.Notification{
+		Recipient: recipient,
+		Actor:     actor,
+		Type:      notificationType,
+		PostID:    postID,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := n.NotificationRepo.Create(ctx, notification); err != nil {
+		return nil, err
+	}
+	if n.Events != nil {
+		n.Events.NotificationCreated(notification)
+	}
+	return notification, nil
+}
+
+func MapNotificationToJson(n *models.Notification) models.JsonNotification {
+	return models.JsonNotification{
+		ID:        n.ID,
+		Actor:     n.Actor,
+		Type:      n.Type,
+		PostID:    n.PostID,
+		Read:      n.ReadAt != nil,
+		CreatedAt: n.CreatedAt,
+	}
+}
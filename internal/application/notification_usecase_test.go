@@ -0,0 +1,76 @@
+package application
+
+import (
+	"context"
+	"postapi/internal/domain"
+	"testing"
+	"time"
+)
+
+type fakeNotificationRepo struct {
+	domain.NotificationRepository
+	created []*domain.Notification
+}
+
+func (f *fakeNotificationRepo) Create(ctx context.Context, notification *domain.Notification) error {
+	notification.ID = int64(len(f.created) + 1)
+	f.created = append(f.created, notification)
+	return nil
+}
+
+func TestNotificationUseCase_Notify(t *testing.T) {
+	repo := &fakeNotificationRepo{}
+	uc := &NotificationUseCase{NotificationRepo: repo}
+	postID := int64(3)
+
+	n, err := uc.Notify(context.Background(), "alice", "bob", domain.NotificationMention, &postID)
+	if err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if n == nil || n.ID != 1 {
+		t.Fatalf("Notify() = %+v, want stored notification", n)
+	}
+	if n.Recipient != "alice" || n.Actor != "bob" || n.Type != domain.NotificationMention || *n.PostID != 3 {
+		t.Errorf("Notify() stored %+v", *n)
+	}
+	if n.CreatedAt.IsZero() {
+		t.Error("Notify() should set CreatedAt")
+	}
+}
+
+func TestNotificationUseCase_NotifySelf(t *testing.T) {
+	repo := &fakeNotificationRepo{}
+	uc := &NotificationUseCase{NotificationRepo: repo}
+
+	n, err := uc.Notify(context.Background(), "alice", "alice", domain.NotificationFollow, nil)
+	if err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if n != nil || len(repo.created) != 0 {
+		t.Error("Notify() should not notify users of their own actions")
+	}
+}
+
+func TestMapNotificationToJson(t *testing.T) {
+	readAt := time.Now()
+	tests := []struct {
+		name         string
+		notification *domain.Notification
+		wantRead     bool
+	}{
+		{"Unread", &domain.Notification{ID: 1, Actor: "bob", Type: domain.NotificationFollow}, false},
+		{"Read", &domain.Notification{ID: 2, Actor: "bob", Type: domain.NotificationFollow, ReadAt: &readAt}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MapNotificationToJson(tt.notification)
+			if got.ID != tt.notification.ID || got.Actor != "bob" || got.Type != domain.NotificationFollow {
+				t.Errorf("MapNotificationToJson() = %+v", got)
+			}
+			if got.Read != tt.wantRead {
+				t.Errorf("MapNotificationToJson() Read = %v, want %v", got.Read, tt.wantRead)
+			}
+		})
+	}
+}
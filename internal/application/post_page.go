@@ -0,0 +1,89 @@
+package application
+
+import (
+	"bytes"
+	"html/template"
+	"net/url"
+	models "postapi/internal/domain"
+	"time"
+)
+
+// postPageTemplate marks a post up as an h-entry, so that other sites can
+// verify our webmentions and show our posts as replies. The rendered content
+// has already been sanitized.
+var postPageTemplate = template.Must(template.New("post").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<link rel="webmention" href="{{.Endpoint}}">
+<link rel="alternate" type="application/json" href="{{.URL}}">
+</head>
+<body>
+<article class="h-entry">
+<h1 class="p-name">{{.Title}}</h1>
+<p>By <a class="p-author h-card" href="{{.AuthorURL}}">{{.Author}}</a> on
+<a class="u-url" href="{{.URL}}"><time class="dt-published" datetime="{{.Published.Format "2006-01-02T15:04:05Z07:00"}}">{{.Published.Format "2 January 2006"}}</time></a></p>
+<div class="e-content">{{.ContentHTML}}</div>
+{{- range .Photos}}
+<img class="u-photo" src="{{.}}" alt="">
+{{- end}}
+{{- if .Tags}}
+<p>{{range .Tags}}<a class="p-category" href="{{.URL}}">#{{.Name}}</a> {{end}}</p>
+{{- end}}
+{{- if .Responses}}
+<section>
+<h2>Responses</h2>
+<ul>
+{{- range .Responses}}
+<li class="h-cite u-comment"><a class="p-author h-card" href="{{.AuthorURL}}">{{.AuthorName}}</a>
+{{if eq .Type "reply"}}replied{{else if eq .Type "like"}}liked this{{else if eq .Type "repost"}}reposted this{{else if eq .Type "bookmark"}}bookmarked this{{else}}mentioned this{{end}}
+at <a class="u-url" href="{{.Source}}">{{.Source}}</a>{{if .Content}}: <span class="p-content">{{.Content}}</span>{{end}}</li>
+{{- end}}
+</ul>
+</section>
+{{- end}}
+</article>
+</body>
+</html>
+`))
+
+type postPage struct {
+	URL         string
+	Endpoint    string
+	Title       string
+	Author      string
+	AuthorURL   string
+	Published   time.Time
+	ContentHTML template.HTML
+	Photos      []string
+	Tags        []struct{ Name, URL string }
+	Responses   []*models.Webmention
+}
+
+// EncodePostPage renders a post, with its attachments and responses, as an
+// HTML page. baseURL is the site root, without a trailing slash.
+func EncodePostPage(post *models.Post, baseURL, webmentionEndpoint string) ([]byte, error) {
+	page := postPage{
+		URL:         PostURL(baseURL, post.ID),
+		Endpoint:    webmentionEndpoint,
+		Title:       post.Title,
+		Author:      post.Author,
+		AuthorURL:   baseURL + "/api/users/" + url.PathEscape(post.Author),
+		Published:   post.CreatedAt.UTC(),
+		ContentHTML: template.HTML(RenderContent(post.Format, post.Content)),
+		Responses:   post.Responses,
+	}
+	for _, m := range post.Attachments {
+		page.Photos = append(page.Photos, baseURL+MediaURL(m))
+	}
+	for _, tag := range ExtractHashtags(post.Title, post.Content) {
+		page.Tags = append(page.Tags, struct{ Name, URL string }{tag, baseURL + "/api/tags/" + url.PathEscape(tag) + "/posts"})
+	}
+
+	var b bytes.Buffer
+	if err := postPageTemplate.Execute(&b, page); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -1,18 +1,518 @@
 package application
 
 import (
+	"context"
+	"errors"
 	repo "postapi/internal/domain"
+	"time"
 )
 
+// ErrNotAuthor is returned when someone changes a post they did not write.
+var ErrNotAuthor = repo.Forbidden(repo.CodeNotAuthor, "only the author can change this post")
+
 type PostUseCase struct {
-	PostRepo repo.PostRepository
+	PostRepo    repo.PostRepository
+	TagRepo     repo.TagRepository
+	MentionRepo repo.MentionRepository
+	UserRepo    repo.UserRepository
+	MediaRepo   repo.MediaRepository
+	// WebmentionRepo holds the verified responses shown on posts.
+	WebmentionRepo repo.WebmentionRepository
+	// MediaUseCase checks that attachments belong to the author.
+	MediaUseCase *MediaUseCase
+	// TrashUseCase tells how long deleted posts can be restored.
+	TrashUseCase *TrashUseCase
+	// UnitOfWork stores a post with its attachments, tags and mentions in
+	// one transaction. Without it they are stored one after another.
+	UnitOfWork repo.UnitOfWork
+}
+
+// inTx runs fn with a copy of p whose repositories are bound to one
+// transaction, or with p itself when there is no unit of work.
+func (p *PostUseCase) inTx(ctx context.Context, fn func(ctx context.Context, tx *PostUseCase) error) error {
+	if p.UnitOfWork == nil {
+		return fn(ctx, p)
+	}
+	return p.UnitOfWork.Do(ctx, func(ctx context.Context, repos repo.Repositories) error {
+		tx := *p
+		tx.PostRepo = repos.Posts
+		tx.TagRepo = repos.Tags
+		tx.MentionRepo = repos.Mentions
+		tx.UserRepo = repos.Users
+		tx.MediaRepo = repos.Media
+		tx.WebmentionRepo = repos.Webmentions
+		return fn(ctx, &tx)
+	})
+}
+
+// Create publishes the post req describes, with the media it attaches. It
+// returns the post and the users it mentions.
+func (p *PostUseCase) Create(ctx context.Context, author string, req repo.PostRequest) (*repo.Post, []string, error) {
+	post, err := p.NewPost(author, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	media, err := p.MediaUseCase.FindOwned(ctx, author, req.MediaIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	mentioned, err := p.Publish(ctx, post, media)
+	if err != nil {
+		return nil, nil, err
+	}
+	return post, mentioned, nil
+}
+
+// Publish stores a new post built elsewhere, such as from a Micropub
+// request, with its media, tags and mentions. It returns the users the post
+// mentions.
+func (p *PostUseCase) Publish(ctx context.Context, post *repo.Post, media []*repo.Media) (mentioned []string, err error) {
+	err = p.inTx(ctx, func(ctx context.Context, tx *PostUseCase) error {
+		if err := tx.PostRepo.Create(ctx, post); err != nil {
+			return err
+		}
+		if err := tx.AttachMedia(ctx, post, media); err != nil {
+			return err
+		}
+		indexed, err := tx.IndexPost(ctx, post)
+		mentioned = indexed
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mentioned, nil
+}
+
+// Update applies the fields req sets to a post of editor. Attachments are
+// only replaced when req lists them. It returns the post before and after
+// the edit, and the users the edit newly mentions.
+func (p *PostUseCase) Update(ctx context.Context, editor string, id int64, req repo.PostRequest) (post, previous *repo.Post, mentioned []string, err error) {
+	previous, err = p.findOwn(ctx, editor, id)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	post, err = p.EditPost(previous, editor, req)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var media []*repo.Media
+	if req.MediaIDs != nil {
+		media, err = p.MediaUseCase.FindOwned(ctx, editor, req.MediaIDs)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	mentioned, err = p.Revise(ctx, post, media)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return post, previous, mentioned, nil
+}
+
+// Revise stores the edited post and indexes it again. Its attachments are
+// replaced by media, or kept when media is nil. It returns the users the
+// post newly mentions.
+func (p *PostUseCase) Revise(ctx context.Context, post *repo.Post, media []*repo.Media) (mentioned []string, err error) {
+	err = p.inTx(ctx, func(ctx context.Context, tx *PostUseCase) error {
+		if err := tx.PostRepo.Update(ctx, post); err != nil {
+			return err
+		}
+		if media != nil {
+			if err := tx.AttachMedia(ctx, post, media); err != nil {
+				return err
+			}
+		} else if err := tx.LoadAttachments(ctx, post); err != nil {
+			return err
+		}
+		indexed, err := tx.IndexPost(ctx, post)
+		mentioned = indexed
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mentioned, nil
+}
+
+// Delete moves a post of author to the trash and returns it.
+func (p *PostUseCase) Delete(ctx context.Context, author string, id int64) (*repo.Post, error) {
+	post, err := p.findOwn(ctx, author, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.PostRepo.Delete(ctx, id, author); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// Restore takes a post of author out of the trash, if it was deleted
+// recently enough.
+func (p *PostUseCase) Restore(ctx context.Context, author string, id int64) (*repo.Post, error) {
+	since := p.TrashUseCase.RestorableSince(time.Now())
+	if err := p.PostRepo.Restore(ctx, id, author, since); err != nil {
+		return nil, err
+	}
+	return p.Get(ctx, id)
+}
+
+// findOwn returns the post with the given id, which must be by author.
+func (p *PostUseCase) findOwn(ctx context.Context, author string, id int64) (*repo.Post, error) {
+	post, err := p.PostRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if post.Author != author {
+		return nil, ErrNotAuthor
+	}
+	return post, nil
+}
+
+// Get returns a post with its details.
+func (p *PostUseCase) Get(ctx context.Context, id int64) (*repo.Post, error) {
+	post, err := p.PostRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.LoadDetails(ctx, post); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// ByAuthor returns the posts of username, newest first, with their details.
+func (p *PostUseCase) ByAuthor(ctx context.Context, username string) ([]*repo.Post, error) {
+	posts, err := p.PostRepo.FindByAuthor(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	return p.withDetails(ctx, posts)
+}
+
+// Trash returns the deleted posts of author that can still be restored.
+func (p *PostUseCase) Trash(ctx context.Context, author string) ([]*repo.Post, error) {
+	since := p.TrashUseCase.RestorableSince(time.Now())
+	posts, err := p.PostRepo.FindDeletedByAuthor(ctx, author, since)
+	if err != nil {
+		return nil, err
+	}
+	return p.withDetails(ctx, posts)
+}
+
+// Mentioning returns a page of the posts mentioning username.
+func (p *PostUseCase) Mentioning(ctx context.Context, username string, limit, offset int) ([]*repo.Post, error) {
+	posts, err := p.MentionRepo.FindPostsMentioning(ctx, username, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return p.withDetails(ctx, posts)
+}
+
+// ByTag returns a page of the posts with tag.
+func (p *PostUseCase) ByTag(ctx context.Context, tag string, limit, offset int) ([]*repo.Post, error) {
+	posts, err := p.TagRepo.FindPostsByTag(ctx, tag, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return p.withDetails(ctx, posts)
+}
+
+// Trending returns the tags used most since the given time.
+func (p *PostUseCase) Trending(ctx context.Context, since time.Time, limit int) ([]*repo.TagCount, error) {
+	return p.TagRepo.Trending(ctx, since, limit)
+}
+
+// Feed returns the FeedSize latest posts of username, who must exist, newest
+// first.
+func (p *PostUseCase) Feed(ctx context.Context, username string) ([]*repo.Post, error) {
+	if _, err := p.UserRepo.FindByUsername(ctx, username); err != nil {
+		return nil, err
+	}
+	return p.PostRepo.FindLatestByAuthor(ctx, username, FeedSize, 0)
+}
+
+func (p *PostUseCase) withDetails(ctx context.Context, posts []*repo.Post) ([]*repo.Post, error) {
+	if err := p.LoadDetails(ctx, posts...); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// NewPost validates req and builds the post it describes. Posts are plain
+// text unless req asks for another format.
+func (p *PostUseCase) NewPost(author string, req repo.PostRequest) (*repo.Post, error) {
+	if err := Validate(req); err != nil {
+		return nil, err
+	}
+	format := req.Format
+	if format == "" {
+		format = repo.PostFormatPlain
+	}
+	return &repo.Post{
+		Title:   req.Title,
+		Content: req.Content,
+		Format:  format,
+		Author:  author,
+	}, nil
+}
+
+// EditPost validates req and returns post with the fields req sets replaced.
+// The result has editor as its author so that only their own posts are
+// updated.
+func (p *PostUseCase) EditPost(post *repo.Post, editor string, req repo.PostRequest) (*repo.Post, error) {
+	if err := ValidatePartial(req); err != nil {
+		return nil, err
+	}
+	edited := &repo.Post{
+		ID:        post.ID,
+		Title:     post.Title,
+		Content:   post.Content,
+		Format:    post.Format,
+		Author:    editor,
+		CreatedAt: post.CreatedAt,
+	}
+	if req.Title != "" {
+		edited.Title = req.Title
+	}
+	if req.Content != "" {
+		edited.Content = req.Content
+	}
+	if req.Format != "" {
+		edited.Format = req.Format
+	}
+	return edited, nil
+}
+
+// IndexPost refreshes the data derived from the text of a post: its tags and
+// the mentions of existing users, which are also set on post.Mentions. It
+// returns the users that were not mentioned by the post before.
+func (p *PostUseCase) IndexPost(ctx context.Context, post *repo.Post) ([]string, error) {
+	err := p.TagRepo.SetPostTags(ctx, post.ID, ExtractHashtags(post.Title, post.Content))
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := p.MentionRepo.FindByPosts(ctx, []int64{post.ID})
+	if err != nil {
+		return nil, err
+	}
+	mentions, err := p.resolveMentions(ctx, post)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.MentionRepo.SetPostMentions(ctx, post.ID, mentions); err != nil {
+		return nil, err
+	}
+	post.Mentions = mentions
+
+	seen := make(map[string]bool)
+	for _, m := range previous {
+		seen[m.Username] = true
+	}
+	mentioned := []string{}
+	for _, m := range mentions {
+		if !seen[m.Username] {
+			seen[m.Username] = true
+			mentioned = append(mentioned, m.Username)
+		}
+	}
+	return mentioned, nil
+}
+
+// reindexBatch is how many posts Reindex loads at a time.
+const reindexBatch = 100
+
+// Reindex indexes every post again, deleted posts included, for posts
+// written before tags and mentions existed or indexed by older parsing. Each
+// post is indexed in its own transaction. It returns how many posts it went
+// through.
+func (p *PostUseCase) Reindex(ctx context.Context) (int64, error) {
+	var indexed, after int64
+	for {
+		posts, err := p.PostRepo.FindAfter(ctx, after, reindexBatch)
+		if err != nil {
+			return indexed, err
+		}
+		for _, post := range posts {
+			err := p.inTx(ctx, func(ctx context.Context, tx *PostUseCase) error {
+				_, err := tx.IndexPost(ctx, post)
+				return err
+			})
+			if err != nil {
+				return indexed, err
+			}
+			indexed++
+			after = post.ID
+		}
+		if len(posts) < reindexBatch {
+			return indexed, nil
+		}
+	}
+}
+
+// resolveMentions parses the mentions of a post and keeps the ones that
+// reference an existing user.
+func (p *PostUseCase) resolveMentions(ctx context.Context, post *repo.Post) ([]*repo.Mention, error) {
+	parsed := append(
+		ExtractMentions(repo.MentionFieldTitle, post.Title),
+		ExtractMentions(repo.MentionFieldContent, post.Content)...,
+	)
+
+	exists := make(map[string]bool)
+	mentions := []*repo.Mention{}
+	for _, m := range parsed {
+		found, checked := exists[m.Username]
+		if !checked {
+			_, err := p.UserRepo.FindByUsername(ctx, m.Username)
+			if err != nil && !errors.Is(err, repo.ErrNotFound) {
+				return nil, err
+			}
+			found = err == nil
+			exists[m.Username] = found
+		}
+		if found {
+			m.PostID = post.ID
+			mentions = append(mentions, m)
+		}
+	}
+	return mentions, nil
+}
+
+// LoadMentions sets the stored mentions on each of the posts.
+func (p *PostUseCase) LoadMentions(ctx context.Context, posts ...*repo.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(posts))
+	byID := make(map[int64]*repo.Post, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+		byID[post.ID] = post
+		post.Mentions = []*repo.Mention{}
+	}
+
+	mentions, err := p.MentionRepo.FindByPosts(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, m := range mentions {
+		if post, ok := byID[m.PostID]; ok {
+			post.Mentions = append(post.Mentions, m)
+		}
+	}
+	return nil
+}
+
+// AttachMedia replaces the attachments of a post. The media must have been
+// validated as owned by the author.
+func (p *PostUseCase) AttachMedia(ctx context.Context, post *repo.Post, media []*repo.Media) error {
+	ids := make([]int64, len(media))
+	for i, m := range media {
+		ids[i] = m.ID
+	}
+	if err := p.MediaRepo.SetPostAttachments(ctx, post.ID, ids); err != nil {
+		return err
+	}
+	post.Attachments = media
+	return nil
+}
+
+// LoadAttachments sets the attached media on each of the posts.
+func (p *PostUseCase) LoadAttachments(ctx context.Context, posts ...*repo.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(posts))
+	byID := make(map[int64]*repo.Post, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+		byID[post.ID] = post
+		post.Attachments = []*repo.Media{}
+	}
+
+	attachments, err := p.MediaRepo.FindByPosts(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, a := range attachments {
+		if post, ok := byID[a.PostID]; ok {
+			media := a.Media
+			post.Attachments = append(post.Attachments, &media)
+		}
+	}
+	return nil
+}
+
+// LoadResponses sets the verified webmentions on each of the posts.
+func (p *PostUseCase) LoadResponses(ctx context.Context, posts ...*repo.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(posts))
+	byID := make(map[int64]*repo.Post, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+		byID[post.ID] = post
+		post.Responses = []*repo.Webmention{}
+	}
+
+	webmentions, err := p.WebmentionRepo.FindVerifiedByPosts(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, w := range webmentions {
+		if post, ok := byID[w.PostID]; ok {
+			post.Responses = append(post.Responses, w)
+		}
+	}
+	return nil
+}
+
+// LoadDetails sets the mentions, attachments and responses on each of the
+// posts.
+func (p *PostUseCase) LoadDetails(ctx context.Context, posts ...*repo.Post) error {
+	if err := p.LoadMentions(ctx, posts...); err != nil {
+		return err
+	}
+	if err := p.LoadAttachments(ctx, posts...); err != nil {
+		return err
+	}
+	return p.LoadResponses(ctx, posts...)
 }
 
 func MapPostToJson(p *repo.Post) repo.JsonPost {
+	mentions := make([]repo.JsonMention, len(p.Mentions))
+	for i, m := range p.Mentions {
+		mentions[i] = MapMentionToJson(m)
+	}
+	attachments := make([]repo.JsonMedia, len(p.Attachments))
+	for i, m := range p.Attachments {
+		attachments[i] = MapMediaToJson(m)
+	}
+	responses := make([]repo.JsonWebmention, len(p.Responses))
+	for i, w := range p.Responses {
+		responses[i] = MapWebmentionToJson(w)
+	}
 	return repo.JsonPost{
-		ID:      p.ID,
-		Author:  p.Author,
-		Content: p.Content,
-		Title:   p.Title,
+		ID:          p.ID,
+		Author:      p.Author,
+		Content:     p.Content,
+		ContentHTML: RenderContent(p.Format, p.Content),
+		Format:      p.Format,
+		Title:       p.Title,
+		Tags:        ExtractHashtags(p.Title, p.Content),
+		Mentions:    mentions,
+		Attachments: attachments,
+		Responses:   responses,
+		CreatedAt:   p.CreatedAt,
+		DeletedAt:   p.DeletedAt,
+	}
+}
+
+func MapTagCountToJson(t *repo.TagCount) repo.JsonTagCount {
+	return repo.JsonTagCount{
+		Tag:   t.Tag,
+		Count: t.Count,
 	}
 }
@@ -0,0 +1,217 @@
+package application
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"postapi/internal/domain"
+	"slices"
+	"testing"
+)
+
+type fakePostRepo struct {
+	domain.PostRepository
+	posts   map[int64]*domain.Post
+	updated *domain.Post
+	deleted int64
+}
+
+func (f *fakePostRepo) FindByID(ctx context.Context, id int64) (*domain.Post, error) {
+	if post, ok := f.posts[id]; ok {
+		copied := *post
+		return &copied, nil
+	}
+	return nil, domain.NotFound(domain.CodePostNotFound, "post not found")
+}
+
+func (f *fakePostRepo) FindLatestByAuthor(ctx context.Context, author string, limit int, offset int) ([]*domain.Post, error) {
+	var posts []*domain.Post
+	for _, post := range f.posts {
+		if post.Author == author {
+			posts = append(posts, post)
+		}
+	}
+	slices.SortFunc(posts, func(a, b *domain.Post) int { return cmp.Compare(b.ID, a.ID) })
+	return posts[min(offset, len(posts)):min(offset+limit, len(posts))], nil
+}
+
+func (f *fakePostRepo) FindAfter(ctx context.Context, afterID int64, limit int) ([]*domain.Post, error) {
+	var posts []*domain.Post
+	for _, post := range f.posts {
+		if post.ID > afterID {
+			posts = append(posts, post)
+		}
+	}
+	slices.SortFunc(posts, func(a, b *domain.Post) int { return cmp.Compare(a.ID, b.ID) })
+	return posts[:min(limit, len(posts))], nil
+}
+
+func (f *fakePostRepo) Update(ctx context.Context, post *domain.Post) error {
+	f.updated = post
+	return nil
+}
+
+func (f *fakePostRepo) Delete(ctx context.Context, id int64, author string) error {
+	f.deleted = id
+	return nil
+}
+
+type fakePostMediaRepo struct {
+	domain.MediaRepository
+	media    map[int64]*domain.Media
+	attached []int64
+}
+
+func (f *fakePostMediaRepo) FindByIDs(ctx context.Context, ids []int64) ([]*domain.Media, error) {
+	found := []*domain.Media{}
+	for _, id := range ids {
+		if m, ok := f.media[id]; ok {
+			found = append(found, m)
+		}
+	}
+	return found, nil
+}
+
+func (f *fakePostMediaRepo) SetPostAttachments(ctx context.Context, postID int64, mediaIDs []int64) error {
+	f.attached = mediaIDs
+	return nil
+}
+
+func (f *fakePostMediaRepo) FindByPosts(ctx context.Context, postIDs []int64) ([]*domain.PostAttachment, error) {
+	return nil, nil
+}
+
+func newFakePostUseCase() (*PostUseCase, *fakePostRepo, *fakePostMediaRepo) {
+	postRepo := &fakePostRepo{posts: map[int64]*domain.Post{
+		7: {ID: 7, Title: "Hello", Content: "World", Format: domain.PostFormatPlain, Author: "alice"},
+	}}
+	mediaRepo := &fakePostMediaRepo{media: map[int64]*domain.Media{
+		1: {ID: 1, Owner: "alice"},
+		2: {ID: 2, Owner: "bob"},
+	}}
+	uc := &PostUseCase{
+		PostRepo:     postRepo,
+		MediaRepo:    mediaRepo,
+		MediaUseCase: &MediaUseCase{MediaRepo: mediaRepo},
+		TagRepo:      &fakeIndexTagRepo{},
+		MentionRepo:  &fakeIndexMentionRepo{},
+		UserRepo:     &fakeIndexUserRepo{users: map[string]bool{"bob": true}},
+	}
+	return uc, postRepo, mediaRepo
+}
+
+func TestPostUseCase_Update(t *testing.T) {
+	uc, postRepo, mediaRepo := newFakePostUseCase()
+
+	post, previous, mentioned, err := uc.Update(context.Background(), "alice", 7, domain.PostRequest{Content: "Again @bob"})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if post.Title != "Hello" || post.Content != "Again @bob" {
+		t.Errorf("Update() = %+v, want the title kept and the content replaced", post)
+	}
+	if previous.Content != "World" {
+		t.Errorf("Update() previous content = %q, want %q", previous.Content, "World")
+	}
+	if postRepo.updated != post {
+		t.Error("Update() did not store the edited post")
+	}
+	if len(mentioned) != 1 || mentioned[0] != "bob" {
+		t.Errorf("Update() newly mentioned = %v, want [bob]", mentioned)
+	}
+	if mediaRepo.attached != nil {
+		t.Errorf("Update() replaced the attachments with %v, want them kept", mediaRepo.attached)
+	}
+
+	if _, _, _, err := uc.Update(context.Background(), "alice", 7, domain.PostRequest{MediaIDs: []int64{1}}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if len(mediaRepo.attached) != 1 || mediaRepo.attached[0] != 1 {
+		t.Errorf("Update() attached %v, want [1]", mediaRepo.attached)
+	}
+}
+
+func TestPostUseCase_Update_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		editor string
+		id     int64
+		req    domain.PostRequest
+		want   error
+	}{
+		{"Someone else's post", "bob", 7, domain.PostRequest{Title: "Mine"}, ErrNotAuthor},
+		{"Unknown post", "alice", 8, domain.PostRequest{Title: "Mine"}, domain.ErrNotFound},
+		{"Invalid format", "alice", 7, domain.PostRequest{Format: "html"}, domain.ErrValidation},
+		{"Someone else's media", "alice", 7, domain.PostRequest{MediaIDs: []int64{2}}, ErrInvalidAttachment},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, postRepo, _ := newFakePostUseCase()
+			_, _, _, err := uc.Update(context.Background(), tt.editor, tt.id, tt.req)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Update() error = %v, want %v", err, tt.want)
+			}
+			if postRepo.updated != nil {
+				t.Error("Update() stored the post despite the error")
+			}
+		})
+	}
+}
+
+func TestPostUseCase_Delete(t *testing.T) {
+	uc, postRepo, _ := newFakePostUseCase()
+
+	if _, err := uc.Delete(context.Background(), "bob", 7); !errors.Is(err, ErrNotAuthor) {
+		t.Errorf("Delete() error = %v, want %v", err, ErrNotAuthor)
+	}
+	if postRepo.deleted != 0 {
+		t.Fatal("Delete() removed a post of someone else")
+	}
+
+	post, err := uc.Delete(context.Background(), "alice", 7)
+	if err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if post.ID != 7 || postRepo.deleted != 7 {
+		t.Errorf("Delete() = post %d, deleted %d, want 7", post.ID, postRepo.deleted)
+	}
+}
+
+func TestPostUseCase_Feed(t *testing.T) {
+	uc, postRepo, _ := newFakePostUseCase()
+	uc.UserRepo = &fakeIndexUserRepo{users: map[string]bool{"alice": true}}
+	for id := int64(8); id < 8+FeedSize+5; id++ {
+		postRepo.posts[id] = &domain.Post{ID: id, Author: "alice"}
+	}
+
+	posts, err := uc.Feed(context.Background(), "alice")
+	if err != nil {
+		t.Fatalf("Feed() error = %v", err)
+	}
+	if len(posts) != FeedSize || posts[0].ID != 7+FeedSize+5 {
+		t.Errorf("Feed() = %d posts starting at %d, want the %d latest", len(posts), posts[0].ID, FeedSize)
+	}
+	if _, err := uc.Feed(context.Background(), "bob"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Feed(unknown user) error = %v, want not found", err)
+	}
+}
+
+func TestPostUseCase_Reindex(t *testing.T) {
+	uc, postRepo, _ := newFakePostUseCase()
+	postRepo.posts[3] = &domain.Post{ID: 3, Title: "Old", Content: "About #Go", Author: "bob"}
+	postRepo.posts[9] = &domain.Post{ID: 9, Title: "Hi", Content: "Hello @bob and @ghost", Author: "alice"}
+
+	indexed, err := uc.Reindex(context.Background())
+	if err != nil || indexed != 3 {
+		t.Fatalf("Reindex() = %d, %v, want 3", indexed, err)
+	}
+	want := []string{"3 [go]", "7 []", "9 []"}
+	if got := uc.TagRepo.(*fakeIndexTagRepo).indexed; !slices.Equal(got, want) {
+		t.Errorf("Reindex() set tags %q, want %q", got, want)
+	}
+	mentions := uc.MentionRepo.(*fakeIndexMentionRepo).mentions
+	if len(mentions) != 1 || mentions[0].PostID != 9 || mentions[0].Username != "bob" || mentions[0].Start != 6 {
+		t.Errorf("Reindex() set the mentions of the last post to %+v, want @bob", mentions)
+	}
+}
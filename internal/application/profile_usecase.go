@@ -1,17 +1,159 @@
 package application
 
 import (
+	"context"
 	models "postapi/internal/domain"
 )
 
 type ProfileUseCase struct {
 	ProfileRepository models.ProfileRepository
+	MediaRepo         models.MediaRepository
+	// MediaUseCase checks that avatars belong to the user.
+	MediaUseCase *MediaUseCase
+}
+
+// Get returns the profile of username with its avatar.
+func (p *ProfileUseCase) Get(ctx context.Context, username string) (*models.Profile, error) {
+	profile, err := p.ProfileRepository.FindByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.LoadAvatar(ctx, profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// Create stores the profile of username that req describes.
+func (p *ProfileUseCase) Create(ctx context.Context, username string, req models.ProfileRequest) (*models.Profile, error) {
+	profile, err := p.NewProfile(username, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.applyAvatar(ctx, profile, req.AvatarMediaID); err != nil {
+		return nil, err
+	}
+	if err := p.ProfileRepository.Create(ctx, profile); err != nil {
+		return nil, err
+	}
+	// La cuenta puede tener ya seguidores y posts
+	created, err := p.ProfileRepository.FindByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	profile.UserCounts = created.UserCounts
+	return profile, nil
+}
+
+// Update applies the fields req sets to the profile of username.
+func (p *ProfileUseCase) Update(ctx context.Context, username string, req models.ProfileRequest) (*models.Profile, error) {
+	current, err := p.ProfileRepository.FindByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := p.EditProfile(current, req)
+	if err != nil {
+		return nil, err
+	}
+	if req.AvatarMediaID != nil {
+		err = p.applyAvatar(ctx, profile, req.AvatarMediaID)
+	} else {
+		err = p.LoadAvatar(ctx, profile)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := p.ProfileRepository.Update(ctx, profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// applyAvatar points the profile picture at an uploaded media item of the
+// user, if mediaID names one.
+func (p *ProfileUseCase) applyAvatar(ctx context.Context, profile *models.Profile, mediaID *int64) error {
+	if mediaID == nil {
+		return nil
+	}
+	media, err := p.MediaUseCase.FindOwned(ctx, profile.Username, []int64{*mediaID})
+	if err != nil {
+		return err
+	}
+	profile.AvatarMediaID = &media[0].ID
+	profile.Avatar = media[0]
+	profile.ProfilePicture = MediaURL(media[0])
+	return nil
+}
+
+// NewProfile validates req and builds the profile of username it describes.
+func (p *ProfileUseCase) NewProfile(username string, req models.ProfileRequest) (*models.Profile, error) {
+	if err := Validate(req); err != nil {
+		return nil, err
+	}
+	return &models.Profile{
+		Username:       username,
+		Description:    req.Description,
+		ProfilePicture: req.ProfilePicture,
+		Private:        req.Private != nil && *req.Private,
+	}, nil
+}
+
+// EditProfile validates req and returns profile with the fields req sets
+// replaced. A new picture URL replaces the uploaded avatar.
+func (p *ProfileUseCase) EditProfile(profile *models.Profile, req models.ProfileRequest) (*models.Profile, error) {
+	if err := Validate(req); err != nil {
+		return nil, err
+	}
+	edited := *profile
+	edited.Avatar = nil
+	if req.Description != "" {
+		edited.Description = req.Description
+	}
+	if req.ProfilePicture != "" {
+		edited.ProfilePicture = req.ProfilePicture
+		edited.AvatarMediaID = nil
+	}
+	if req.Private != nil {
+		edited.Private = *req.Private
+	}
+	return &edited, nil
+}
+
+// LoadAvatar sets the uploaded avatar of a profile, if it has one.
+func (p *ProfileUseCase) LoadAvatar(ctx context.Context, profile *models.Profile) error {
+	if profile.AvatarMediaID == nil {
+		profile.Avatar = nil
+		return nil
+	}
+	media, err := p.MediaRepo.FindByIDs(ctx, []int64{*profile.AvatarMediaID})
+	if err != nil {
+		return err
+	}
+	if len(media) > 0 {
+		profile.Avatar = media[0]
+	}
+	return nil
 }
 
 func MapProfileToJson(f *models.Profile) models.JsonProfile {
+	var avatar *models.JsonMedia
+	if f.Avatar != nil {
+		jsonMedia := MapMediaToJson(f.Avatar)
+		avatar = &jsonMedia
+	}
+	picture := f.ProfilePicture
+	if picture == "" {
+		picture = DefaultAvatarURL(f.Username)
+	}
 	return models.JsonProfile{
 		Username:       f.Username,
 		Description:    f.Description,
-		ProfilePicture: f.ProfilePicture,
+		ProfilePicture: picture,
+		Private:        f.Private,
+		AvatarMediaID:  f.AvatarMediaID,
+		Avatar:         avatar,
+		FollowerCount:  f.FollowerCount,
+		FollowingCount: f.FollowingCount,
+		PostCount:      f.PostCount,
 	}
 }
@@ -0,0 +1,74 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"log"
+	models "postapi/internal/domain"
+	"time"
+)
+
+// TrashRetention is how long soft-deleted posts and accounts can be restored
+// before the purge job removes them for good.
+const TrashRetention = 30 * 24 * time.Hour
+
+type TrashUseCase struct {
+	PostRepo models.PostRepository
+	UserRepo models.UserRepository
+	// MediaUseCase deletes the files of the media that purged accounts
+	// uploaded.
+	MediaUseCase *MediaUseCase
+	Retention    time.Duration
+}
+
+// RestorableSince returns the oldest deletion time that can still be undone.
+func (t *TrashUseCase) RestorableSince(now time.Time) time.Time {
+	retention := t.Retention
+	if retention <= 0 {
+		retention = TrashRetention
+	}
+	return now.Add(-retention)
+}
+
+// Purge permanently removes accounts and posts deleted before the retention
+// window. Accounts go first so their posts are removed by the cascade, and
+// the files of their media are deleted once the rows are gone.
+func (t *TrashUseCase) Purge(ctx context.Context, now time.Time) (users int64, posts int64, err error) {
+	cutoff := t.RestorableSince(now)
+	media, err := t.MediaUseCase.MediaRepo.FindByDeletedOwners(ctx, cutoff)
+	if err != nil {
+		return 0, 0, err
+	}
+	users, err = t.UserRepo.PurgeDeleted(ctx, cutoff)
+	if err != nil {
+		return 0, 0, err
+	}
+	// A file left behind is no longer served, so carry on with the posts
+	filesErr := t.MediaUseCase.DeleteFiles(media)
+	posts, err = t.PostRepo.PurgeDeleted(ctx, cutoff)
+	if err != nil {
+		return users, 0, errors.Join(err, filesErr)
+	}
+	return users, posts, filesErr
+}
+
+// RunPurgeJob purges the trash every interval until ctx is cancelled.
+func (t *TrashUseCase) RunPurgeJob(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			users, posts, err := t.Purge(ctx, now)
+			if err != nil {
+				log.Printf("Cannot purge trash. err = %v\n", err)
+				continue
+			}
+			if users > 0 || posts > 0 {
+				log.Printf("Purged %d users and %d posts from trash\n", users, posts)
+			}
+		}
+	}
+}
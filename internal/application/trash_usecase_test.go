@@ -0,0 +1,112 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"postapi/internal/domain"
+	"testing"
+	"time"
+)
+
+type fakeTrashPostRepo struct {
+	domain.PostRepository
+	purgedBefore time.Time
+	purged       int64
+}
+
+func (f *fakeTrashPostRepo) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	f.purgedBefore = deletedBefore
+	return f.purged, nil
+}
+
+type fakeTrashUserRepo struct {
+	domain.UserRepository
+	purgedBefore time.Time
+	purged       int64
+	err          error
+}
+
+func (f *fakeTrashUserRepo) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	f.purgedBefore = deletedBefore
+	return f.purged, f.err
+}
+
+func TestTrashUseCase_RestorableSince(t *testing.T) {
+	now := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		retention time.Duration
+		want      time.Time
+	}{
+		{"Default retention", 0, now.Add(-TrashRetention)},
+		{"Custom retention", 48 * time.Hour, time.Date(2024, 3, 29, 12, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := &TrashUseCase{Retention: tt.retention}
+			if got := uc.RestorableSince(now); !got.Equal(tt.want) {
+				t.Errorf("RestorableSince() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrashUseCase_Purge(t *testing.T) {
+	now := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+	postRepo := &fakeTrashPostRepo{purged: 3}
+	userRepo := &fakeTrashUserRepo{purged: 1}
+	mediaUseCase, _ := newTestMediaUseCase()
+	uc := &TrashUseCase{PostRepo: postRepo, UserRepo: userRepo, MediaUseCase: mediaUseCase, Retention: 24 * time.Hour}
+
+	users, posts, err := uc.Purge(context.Background(), now)
+	if err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	if users != 1 || posts != 3 {
+		t.Errorf("Purge() = (%d, %d), want (1, 3)", users, posts)
+	}
+
+	cutoff := now.Add(-24 * time.Hour)
+	if !userRepo.purgedBefore.Equal(cutoff) {
+		t.Errorf("users purged before %v, want %v", userRepo.purgedBefore, cutoff)
+	}
+	if !postRepo.purgedBefore.Equal(cutoff) {
+		t.Errorf("posts purged before %v, want %v", postRepo.purgedBefore, cutoff)
+	}
+}
+
+func TestTrashUseCase_PurgeUserError(t *testing.T) {
+	postRepo := &fakeTrashPostRepo{}
+	userRepo := &fakeTrashUserRepo{err: errors.New("db down")}
+	mediaUseCase, _ := newTestMediaUseCase()
+	uc := &TrashUseCase{PostRepo: postRepo, UserRepo: userRepo, MediaUseCase: mediaUseCase}
+
+	if _, _, err := uc.Purge(context.Background(), time.Now()); err == nil {
+		t.Error("Purge() expected error, got nil")
+	}
+	if !postRepo.purgedBefore.IsZero() {
+		t.Error("Posts should not be purged when purging users fails")
+	}
+}
+
+func TestTrashUseCase_PurgeMediaFiles(t *testing.T) {
+	mediaUseCase, store := newTestMediaUseCase()
+	data := testImage(t, 600, 300)
+	gone, _ := mediaUseCase.Upload(context.Background(), "alice", bytes.NewReader(data), int64(len(data)))
+	kept, _ := mediaUseCase.Upload(context.Background(), "bob", bytes.NewReader(data), int64(len(data)))
+	mediaUseCase.MediaRepo.(*fakeMediaRepo).deletedOwners = []string{"alice"}
+	uc := &TrashUseCase{PostRepo: &fakeTrashPostRepo{}, UserRepo: &fakeTrashUserRepo{purged: 1}, MediaUseCase: mediaUseCase}
+
+	if _, _, err := uc.Purge(context.Background(), time.Now()); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	if _, ok := store.blobs[gone.Key]; ok {
+		t.Errorf("Purge() kept the files of %s", gone.Owner)
+	}
+	if _, ok := store.blobs[kept.Key]; !ok {
+		t.Errorf("Purge() deleted the files of %s", kept.Owner)
+	}
+}
@@ -1,18 +1,202 @@
 package application
 
 import (
+	"context"
+	"errors"
 	models "postapi/internal/domain"
+	"time"
+)
+
+var (
+	// ErrInvalidCredentials is returned alike for unknown users and wrong
+	// passwords, so that clients cannot tell which usernames exist.
+	ErrInvalidCredentials = models.Unauthorized(models.CodeInvalidCredentials, "invalid credentials")
+	ErrFollowBlocked      = models.Forbidden(models.CodeBlocked, "cannot follow this user")
+	ErrSelfFollow         = models.Invalid(models.CodeSelfFollow, "cannot follow yourself")
+	ErrSelfBlock          = models.Invalid(models.CodeSelfBlock, "cannot block yourself")
 )
 
 type UserUseCase struct {
 	UserRepo   models.UserRepository
 	FollowRepo models.UserFollowRepository
+	BlockRepo  models.UserBlockRepository
+	// TrashUseCase tells how long deleted accounts can be restored.
+	TrashUseCase *TrashUseCase
+	// UnitOfWork keeps follows and blocks consistent when they change at
+	// the same time. Without it their writes run one after another.
+	UnitOfWork models.UnitOfWork
+}
+
+// inTx is the UserUseCase counterpart of PostUseCase.inTx.
+func (u *UserUseCase) inTx(ctx context.Context, fn func(ctx context.Context, tx *UserUseCase) error) error {
+	if u.UnitOfWork == nil {
+		return fn(ctx, u)
+	}
+	return u.UnitOfWork.Do(ctx, func(ctx context.Context, repos models.Repositories) error {
+		tx := *u
+		tx.UserRepo = repos.Users
+		tx.FollowRepo = repos.Follows
+		tx.BlockRepo = repos.Blocks
+		return fn(ctx, &tx)
+	})
+}
+
+// Register validates req and creates the account it describes.
+func (u *UserUseCase) Register(ctx context.Context, req models.RegisterRequest) (*models.User, error) {
+	if err := Validate(req); err != nil {
+		return nil, err
+	}
+	user := &models.User{
+		Username: req.Username,
+		Password: req.Password,
+		Email:    req.Email,
+	}
+	if err := u.UserRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Login checks the password of username and returns the user.
+func (u *UserUseCase) Login(ctx context.Context, username, password string) (*models.User, error) {
+	user, err := u.UserRepo.LoginUser(ctx, &models.User{Username: username, Password: password})
+	return user, credentialsError(err)
+}
+
+// Restore undeletes the account of username, if the password is right and
+// the account was deleted recently enough.
+func (u *UserUseCase) Restore(ctx context.Context, username, password string) (*models.User, error) {
+	since := u.TrashUseCase.RestorableSince(time.Now())
+	user, err := u.UserRepo.Restore(ctx, &models.User{Username: username, Password: password}, since)
+	return user, credentialsError(err)
+}
+
+func credentialsError(err error) error {
+	if errors.Is(err, models.ErrNotFound) || errors.Is(err, models.ErrUnauthorized) {
+		return ErrInvalidCredentials.Wrap(err)
+	}
+	return err
+}
+
+func (u *UserUseCase) Get(ctx context.Context, username string) (*models.User, error) {
+	return u.UserRepo.FindByUsername(ctx, username)
+}
+
+// Delete moves the account of username to the trash.
+func (u *UserUseCase) Delete(ctx context.Context, username string) error {
+	return u.UserRepo.Delete(ctx, username)
+}
+
+// Follow makes follower follow followed, unless either blocked the other.
+func (u *UserUseCase) Follow(ctx context.Context, follower, followed string) (*models.UserFollow, error) {
+	if follower == followed {
+		return nil, ErrSelfFollow
+	}
+	f := &models.UserFollow{FollowerUsername: follower, FollowedUsername: followed}
+	// En la misma transacción, un bloqueo simultáneo no deja el seguimiento
+	err := u.inTx(ctx, func(ctx context.Context, tx *UserUseCase) error {
+		blocked, err := tx.BlockRepo.IsBlocked(ctx, follower, followed)
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return ErrFollowBlocked
+		}
+		return tx.FollowRepo.Create(ctx, f)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (u *UserUseCase) Unfollow(ctx context.Context, follower, followed string) (*models.UserFollow, error) {
+	f := &models.UserFollow{FollowerUsername: follower, FollowedUsername: followed}
+	if err := u.FollowRepo.Delete(ctx, f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Followers returns the users following username, with their profiles and
+// counts.
+func (u *UserUseCase) Followers(ctx context.Context, username string) ([]*models.UserSummary, error) {
+	return u.FollowRepo.FindFollowers(ctx, username)
+}
+
+// Following returns the users username follows, with their profiles and
+// counts.
+func (u *UserUseCase) Following(ctx context.Context, username string) ([]*models.UserSummary, error) {
+	return u.FollowRepo.FindFollowing(ctx, username)
+}
+
+// Recount fixes the follower, following and post counts of every user and
+// returns how many users had them wrong.
+func (u *UserUseCase) Recount(ctx context.Context) (int64, error) {
+	return u.UserRepo.Recount(ctx)
+}
+
+// Block makes blocker block blocked.
+func (u *UserUseCase) Block(ctx context.Context, blocker, blocked string) (*models.UserBlock, error) {
+	if blocker == blocked {
+		return nil, ErrSelfBlock
+	}
+	b := &models.UserBlock{BlockerUsername: blocker, BlockedUsername: blocked}
+	err := u.inTx(ctx, func(ctx context.Context, tx *UserUseCase) error {
+		if err := tx.BlockRepo.Create(ctx, b); err != nil {
+			return err
+		}
+		// Un bloqueo corta el seguimiento en ambos sentidos
+		for _, f := range []*models.UserFollow{
+			{FollowerUsername: blocker, FollowedUsername: blocked},
+			{FollowerUsername: blocked, FollowedUsername: blocker},
+		} {
+			if err := tx.FollowRepo.Delete(ctx, f); err != nil && !errors.Is(err, models.ErrNotFound) {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (u *UserUseCase) Unblock(ctx context.Context, blocker, blocked string) (*models.UserBlock, error) {
+	b := &models.UserBlock{BlockerUsername: blocker, BlockedUsername: blocked}
+	if err := u.BlockRepo.Delete(ctx, b); err != nil {
+		return nil, err
+	}
+	return b, nil
 }
 
 func MapUserToJson(u *models.User) models.JsonUser {
 	return models.JsonUser{
-		Username: u.Username,
-		Email:    u.Email,
+		Username:       u.Username,
+		Email:          u.Email,
+		FollowerCount:  u.FollowerCount,
+		FollowingCount: u.FollowingCount,
+		PostCount:      u.PostCount,
+	}
+}
+
+// MapUserSummaryToJson maps s, giving users without a picture the default
+// avatar like MapProfileToJson.
+func MapUserSummaryToJson(s *models.UserSummary) models.JsonUserSummary {
+	picture := s.ProfilePicture
+	if picture == "" {
+		picture = DefaultAvatarURL(s.Username)
+	}
+	return models.JsonUserSummary{
+		Username:       s.Username,
+		Email:          s.Email,
+		Description:    s.Description,
+		ProfilePicture: picture,
+		Private:        s.Private,
+		FollowerCount:  s.FollowerCount,
+		FollowingCount: s.FollowingCount,
+		PostCount:      s.PostCount,
 	}
 }
 
@@ -0,0 +1,163 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"postapi/internal/domain"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+type fakeUserFollowRepo struct {
+	domain.UserFollowRepository
+	created []domain.UserFollow
+	deleted []domain.UserFollow
+}
+
+func (f *fakeUserFollowRepo) Create(ctx context.Context, follow *domain.UserFollow) error {
+	f.created = append(f.created, *follow)
+	return nil
+}
+
+func (f *fakeUserFollowRepo) Delete(ctx context.Context, follow *domain.UserFollow) error {
+	f.deleted = append(f.deleted, *follow)
+	if !slices.Contains(f.created, *follow) {
+		return domain.NotFound(domain.CodeFollowNotFound, "not following this user")
+	}
+	return nil
+}
+
+type fakeUserBlockRepo struct {
+	domain.UserBlockRepository
+	blocked bool
+}
+
+func (f *fakeUserBlockRepo) Create(ctx context.Context, block *domain.UserBlock) error {
+	return nil
+}
+
+func (f *fakeUserBlockRepo) IsBlocked(ctx context.Context, username string, other string) (bool, error) {
+	return f.blocked, nil
+}
+
+type fakeLoginUserRepo struct {
+	domain.UserRepository
+	err error
+}
+
+func (f *fakeLoginUserRepo) LoginUser(ctx context.Context, p *domain.User) (*domain.User, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &domain.User{Username: p.Username}, nil
+}
+
+func TestUserUseCase_Follow(t *testing.T) {
+	tests := []struct {
+		name     string
+		follower string
+		blocked  bool
+		want     error
+	}{
+		{"Follow", "alice", false, nil},
+		{"Yourself", "bob", false, ErrSelfFollow},
+		{"Blocked", "alice", true, ErrFollowBlocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			followRepo := &fakeUserFollowRepo{}
+			uc := &UserUseCase{FollowRepo: followRepo, BlockRepo: &fakeUserBlockRepo{blocked: tt.blocked}}
+
+			_, err := uc.Follow(context.Background(), tt.follower, "bob")
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Follow() error = %v, want %v", err, tt.want)
+			}
+			if created := len(followRepo.created) == 1; created != (tt.want == nil) {
+				t.Errorf("Follow() stored %v, want a follow only on success", followRepo.created)
+			}
+		})
+	}
+}
+
+func TestUserUseCase_Block(t *testing.T) {
+	// Only alice follows bob; the missing follow back is no error
+	followRepo := &fakeUserFollowRepo{created: []domain.UserFollow{{FollowerUsername: "alice", FollowedUsername: "bob"}}}
+	uc := &UserUseCase{FollowRepo: followRepo, BlockRepo: &fakeUserBlockRepo{}}
+
+	if _, err := uc.Block(context.Background(), "alice", "alice"); !errors.Is(err, ErrSelfBlock) {
+		t.Errorf("Block() error = %v, want %v", err, ErrSelfBlock)
+	}
+
+	if _, err := uc.Block(context.Background(), "alice", "bob"); err != nil {
+		t.Fatalf("Block() error = %v", err)
+	}
+	want := []domain.UserFollow{
+		{FollowerUsername: "alice", FollowedUsername: "bob"},
+		{FollowerUsername: "bob", FollowedUsername: "alice"},
+	}
+	if !reflect.DeepEqual(followRepo.deleted, want) {
+		t.Errorf("Block() removed follows %v, want %v", followRepo.deleted, want)
+	}
+}
+
+func TestUserUseCase_Login(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"Valid", nil, nil},
+		{"Unknown user", domain.NotFound(domain.CodeUserNotFound, "user not found"), ErrInvalidCredentials},
+		{"Wrong password", domain.ErrUnauthorized, ErrInvalidCredentials},
+		{"Database error", errors.New("db down"), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := &UserUseCase{UserRepo: &fakeLoginUserRepo{err: tt.err}}
+			_, err := uc.Login(context.Background(), "alice", "secret")
+
+			var domainErr *domain.Error
+			switch {
+			case tt.want != nil:
+				if !errors.As(err, &domainErr) || domainErr.Code != domain.CodeInvalidCredentials {
+					t.Errorf("Login() error = %v, want %v", err, tt.want)
+				}
+			case tt.err != nil:
+				if !errors.Is(err, tt.err) || errors.As(err, &domainErr) {
+					t.Errorf("Login() error = %v, want %v unchanged", err, tt.err)
+				}
+			case err != nil:
+				t.Errorf("Login() error = %v", err)
+			}
+		})
+	}
+}
+
+type fakeUnitOfWork struct {
+	repos domain.Repositories
+	calls int
+}
+
+func (f *fakeUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos domain.Repositories) error) error {
+	f.calls++
+	return fn(ctx, f.repos)
+}
+
+func TestUserUseCase_Block_UnitOfWork(t *testing.T) {
+	followRepo := &fakeUserFollowRepo{}
+	uow := &fakeUnitOfWork{repos: domain.Repositories{Follows: followRepo, Blocks: &fakeUserBlockRepo{}}}
+	uc := &UserUseCase{UnitOfWork: uow}
+
+	if _, err := uc.Block(context.Background(), "alice", "bob"); err != nil {
+		t.Fatalf("Block() error = %v", err)
+	}
+	if uow.calls != 1 {
+		t.Errorf("Block() ran %d units of work, want 1", uow.calls)
+	}
+	if len(followRepo.deleted) != 2 {
+		t.Errorf("Block() removed %d follows in the transaction, want 2", len(followRepo.deleted))
+	}
+}
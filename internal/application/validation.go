@@ -0,0 +1,127 @@
+package application
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	models "postapi/internal/domain"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// usernamePattern is the charset of usernames, the one mentions recognize.
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// Validate checks the string fields of the struct v against their validate
+// tags and returns a validation error listing every invalid field. The rules
+// are:
+//
+//	required      not blank
+//	min=N, max=N  length in characters
+//	username      letters, digits and underscores
+//	email         an email address
+//	url           an absolute http or https URL
+//	oneof=a b     one of the listed values
+//
+// Rules other than required do not apply to empty fields.
+func Validate(v any) error {
+	return validate(v, false)
+}
+
+// ValidatePartial works like Validate for requests that leave empty fields
+// unchanged: required fields may be empty, but not blank.
+func ValidatePartial(v any) error {
+	return validate(v, true)
+}
+
+func validate(v any, partial bool) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	t := rv.Type()
+
+	var fields []models.FieldError
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("validate")
+		if tag == "" {
+			continue
+		}
+		if f.Type.Kind() != reflect.String {
+			panic(fmt.Sprintf("validate: %s.%s is not a string", t.Name(), f.Name))
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" {
+			name = f.Name
+		}
+		if fieldErr := checkField(name, rv.Field(i).String(), tag, partial); fieldErr != nil {
+			fields = append(fields, *fieldErr)
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return models.InvalidFields(fields)
+}
+
+// checkField returns the first rule of tag that value breaks, if any.
+func checkField(name, value, tag string, partial bool) *models.FieldError {
+	fail := func(code, format string, args ...any) *models.FieldError {
+		return &models.FieldError{Field: name, Code: code, Message: name + " " + fmt.Sprintf(format, args...)}
+	}
+
+	for _, rule := range strings.Split(tag, ",") {
+		rule, arg, _ := strings.Cut(rule, "=")
+		if rule == "required" {
+			if (value != "" || !partial) && strings.TrimSpace(value) == "" {
+				return fail(models.CodeRequired, "is required")
+			}
+			continue
+		}
+		if value == "" {
+			return nil
+		}
+
+		switch rule {
+		case "min":
+			if utf8.RuneCountInString(value) < ruleLength(rule, arg) {
+				return fail(models.CodeTooShort, "must be at least %s characters", arg)
+			}
+		case "max":
+			if utf8.RuneCountInString(value) > ruleLength(rule, arg) {
+				return fail(models.CodeTooLong, "must be at most %s characters", arg)
+			}
+		case "username":
+			if !usernamePattern.MatchString(value) {
+				return fail(models.CodeInvalidFormat, "may only contain letters, digits and underscores")
+			}
+		case "email":
+			if _, err := mail.ParseAddress(value); err != nil {
+				return fail(models.CodeInvalidFormat, "must be an email address")
+			}
+		case "url":
+			u, err := url.Parse(value)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fail(models.CodeInvalidFormat, "must be an http or https URL")
+			}
+		case "oneof":
+			choices := strings.Fields(arg)
+			if !slices.Contains(choices, value) {
+				return fail(models.CodeInvalidChoice, "must be one of %s", strings.Join(choices, ", "))
+			}
+		default:
+			panic(fmt.Sprintf("validate: unknown rule %q", rule))
+		}
+	}
+	return nil
+}
+
+func ruleLength(rule, arg string) int {
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		panic(fmt.Sprintf("validate: %s needs a number, got %q", rule, arg))
+	}
+	return n
+}
@@ -0,0 +1,151 @@
+package application
+
+import (
+	"errors"
+	"postapi/internal/domain"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fieldCodes returns the code of each invalid field of a validation error.
+func fieldCodes(t *testing.T, err error) map[string]string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var domainErr *domain.Error
+	if !errors.As(err, &domainErr) || !errors.Is(err, domain.ErrValidation) {
+		t.Fatalf("error = %v, want a validation error", err)
+	}
+	codes := map[string]string{}
+	for _, f := range domainErr.Fields {
+		codes[f.Field] = f.Code
+	}
+	return codes
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		req  any
+		want map[string]string
+	}{
+		{
+			name: "Valid registration",
+			req:  domain.RegisterRequest{Username: "alice_1", Password: "correct horse", Email: "alice@example.com"},
+		},
+		{
+			name: "Every field of a registration at once",
+			req:  domain.RegisterRequest{Username: "al", Password: "short", Email: "not-an-email"},
+			want: map[string]string{
+				"username": domain.CodeTooShort,
+				"password": domain.CodeTooShort,
+				"email":    domain.CodeInvalidFormat,
+			},
+		},
+		{
+			name: "Empty registration",
+			req:  domain.RegisterRequest{},
+			want: map[string]string{
+				"username": domain.CodeRequired,
+				"password": domain.CodeRequired,
+				"email":    domain.CodeRequired,
+			},
+		},
+		{
+			name: "Username charset",
+			req:  domain.RegisterRequest{Username: "alice smith", Password: "correct horse", Email: "alice@example.com"},
+			want: map[string]string{"username": domain.CodeInvalidFormat},
+		},
+		{
+			name: "Valid post",
+			req:  domain.PostRequest{Title: "Hello", Content: "World", Format: domain.PostFormatMarkdown},
+		},
+		{
+			name: "Post without format",
+			req:  domain.PostRequest{Title: "Hello", Content: "World"},
+		},
+		{
+			name: "Blank post with unknown format",
+			req:  domain.PostRequest{Title: "  ", Content: "", Format: "html"},
+			want: map[string]string{
+				"title":   domain.CodeRequired,
+				"content": domain.CodeRequired,
+				"format":  domain.CodeInvalidChoice,
+			},
+		},
+		{
+			name: "Title too long",
+			req:  domain.PostRequest{Title: strings.Repeat("é", 201), Content: "World"},
+			want: map[string]string{"title": domain.CodeTooLong},
+		},
+		{
+			name: "Title at the limit in characters",
+			req:  domain.PostRequest{Title: strings.Repeat("é", 200), Content: "World"},
+		},
+		{
+			name: "Empty profile",
+			req:  domain.ProfileRequest{},
+		},
+		{
+			name: "Profile picture URL",
+			req:  domain.ProfileRequest{ProfilePicture: "https://cdn.example/alice.png"},
+		},
+		{
+			name: "Profile picture without scheme",
+			req:  domain.ProfileRequest{ProfilePicture: "cdn.example/alice.png"},
+			want: map[string]string{"profile_picture": domain.CodeInvalidFormat},
+		},
+		{
+			name: "Profile picture with another scheme",
+			req:  domain.ProfileRequest{ProfilePicture: "javascript:alert(1)", Description: strings.Repeat("a", 501)},
+			want: map[string]string{
+				"profile_picture": domain.CodeInvalidFormat,
+				"description":     domain.CodeTooLong,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fieldCodes(t, Validate(tt.req))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePartial(t *testing.T) {
+	if err := ValidatePartial(domain.PostRequest{}); err != nil {
+		t.Errorf("ValidatePartial() = %v, want empty fields to be allowed", err)
+	}
+
+	got := fieldCodes(t, ValidatePartial(domain.PostRequest{Title: " ", Content: strings.Repeat("a", 20001)}))
+	want := map[string]string{"title": domain.CodeRequired, "content": domain.CodeTooLong}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ValidatePartial() fields = %v, want %v", got, want)
+	}
+}
+
+func TestPostUseCase_EditPost(t *testing.T) {
+	uc := &PostUseCase{}
+	post := &domain.Post{ID: 7, Title: "Old", Content: "Body", Format: domain.PostFormatPlain, Author: "alice"}
+
+	edited, err := uc.EditPost(post, "alice", domain.PostRequest{Content: "New body", Format: domain.PostFormatMarkdown})
+	if err != nil {
+		t.Fatalf("EditPost() error = %v", err)
+	}
+	want := domain.Post{ID: 7, Title: "Old", Content: "New body", Format: domain.PostFormatMarkdown, Author: "alice"}
+	if !reflect.DeepEqual(*edited, want) {
+		t.Errorf("EditPost() = %+v, want %+v", *edited, want)
+	}
+	if post.Content != "Body" {
+		t.Error("EditPost() should not change the original post")
+	}
+
+	if _, err := uc.EditPost(post, "alice", domain.PostRequest{Format: "html"}); !errors.Is(err, domain.ErrValidation) {
+		t.Errorf("EditPost() error = %v, want a validation error", err)
+	}
+}
@@ -0,0 +1,310 @@
+package application
+
+import (
+	"bytes"
+	"mime"
+	"net/url"
+	"postapi/internal/domain"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// maxWebmentionContent is how many characters of the source are kept.
+const maxWebmentionContent = 500
+
+// WebmentionSource is what was found on the source page of a webmention.
+type WebmentionSource struct {
+	// LinksTarget tells whether the page links to the target at all.
+	LinksTarget bool
+	Type        string
+	AuthorName  string
+	AuthorURL   string
+	AuthorPhoto string
+	Content     string
+	Published   *time.Time
+}
+
+// responseProperties are the microformats2 properties that make an h-entry a
+// particular kind of response, strongest first.
+var responseProperties = []struct{ class, kind string }{
+	{"u-like-of", domain.WebmentionLike},
+	{"u-repost-of", domain.WebmentionRepost},
+	{"u-bookmark-of", domain.WebmentionBookmark},
+	{"u-in-reply-to", domain.WebmentionReply},
+}
+
+// ParseWebmentionSource checks that body, fetched from sourceURL, links to
+// target and reads the author and content of its first h-entry. Documents
+// other than HTML only need to contain the target URL.
+func ParseWebmentionSource(body []byte, contentType, sourceURL, target string) *WebmentionSource {
+	source := &WebmentionSource{Type: domain.WebmentionMention}
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		source.LinksTarget = bytes.Contains(body, []byte(target))
+		return source
+	}
+
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return source
+	}
+	base, _ := url.Parse(sourceURL)
+	for n := range doc.Descendants() {
+		if n.Type != html.ElementNode {
+			continue
+		}
+		for _, attr := range []string{"href", "src"} {
+			if v, ok := attribute(n, attr); ok && resolveURL(base, v) == target {
+				source.LinksTarget = true
+			}
+		}
+	}
+
+	if entry := findMicroformat(doc, "h-entry"); entry != nil {
+		parseEntry(source, entry, base, target)
+	} else if title := findElement(doc, atom.Title); title != nil {
+		source.Content = truncateText(textContent(title), maxWebmentionContent)
+	}
+	if source.AuthorURL == "" && base != nil {
+		source.AuthorURL = base.Scheme + "://" + base.Host
+	}
+	if source.AuthorName == "" && base != nil {
+		source.AuthorName = base.Hostname()
+	}
+	return source
+}
+
+func parseEntry(source *WebmentionSource, entry *html.Node, base *url.URL, target string) {
+	kind := len(responseProperties)
+	var content, name string
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			classes := strings.Fields(classAttr(c))
+			switch {
+			case slices.Contains(classes, "p-author") && slices.Contains(classes, "h-card"):
+				if source.AuthorName == "" && source.AuthorURL == "" {
+					source.AuthorName, source.AuthorURL, source.AuthorPhoto = parseCard(c, base)
+				}
+				continue
+			case slices.Contains(classes, "p-author"), slices.Contains(classes, "u-author"):
+				if source.AuthorName == "" && source.AuthorURL == "" {
+					source.AuthorName = strings.TrimSpace(textContent(c))
+					if href, ok := attribute(c, "href"); ok {
+						source.AuthorURL = resolveURL(base, href)
+					}
+				}
+				continue
+			}
+			for i, p := range responseProperties {
+				if slices.Contains(classes, p.class) && i < kind && propertyURL(c, base) == target {
+					kind = i
+				}
+			}
+			if slices.Contains(classes, "e-content") || slices.Contains(classes, "p-content") {
+				if content == "" {
+					content = textContent(c)
+				}
+			}
+			if slices.Contains(classes, "p-name") && name == "" {
+				name = textContent(c)
+			}
+			if slices.Contains(classes, "dt-published") && source.Published == nil {
+				source.Published = parseDateTime(c)
+			}
+			if isMicroformat(classes) {
+				// Nested microformats, such as an h-cite of the post being
+				// replied to, do not describe this entry.
+				continue
+			}
+			walk(c)
+		}
+	}
+	walk(entry)
+
+	if kind < len(responseProperties) {
+		source.Type = responseProperties[kind].kind
+	}
+	if content == "" {
+		content = name
+	}
+	source.Content = truncateText(content, maxWebmentionContent)
+}
+
+// parseCard reads the name, URL and photo of an h-card.
+func parseCard(card *html.Node, base *url.URL) (name, link, photo string) {
+	for n := range card.Descendants() {
+		if n.Type != html.ElementNode {
+			continue
+		}
+		classes := strings.Fields(classAttr(n))
+		if name == "" && slices.Contains(classes, "p-name") {
+			name = strings.TrimSpace(textContent(n))
+		}
+		if link == "" && slices.Contains(classes, "u-url") {
+			link = propertyURL(n, base)
+		}
+		if photo == "" && slices.Contains(classes, "u-photo") {
+			photo = propertyURL(n, base)
+		}
+	}
+	if name == "" {
+		name = strings.TrimSpace(textContent(card))
+	}
+	if link == "" {
+		if href, ok := attribute(card, "href"); ok {
+			link = resolveURL(base, href)
+		}
+	}
+	return name, link, photo
+}
+
+// propertyURL is the value of a u-* property: the link of the element, or
+// the u-url of an embedded h-cite.
+func propertyURL(n *html.Node, base *url.URL) string {
+	for _, attr := range []string{"href", "src"} {
+		if v, ok := attribute(n, attr); ok {
+			return resolveURL(base, v)
+		}
+	}
+	for c := range n.Descendants() {
+		if c.Type == html.ElementNode && slices.Contains(strings.Fields(classAttr(c)), "u-url") {
+			if href, ok := attribute(c, "href"); ok {
+				return resolveURL(base, href)
+			}
+		}
+	}
+	return strings.TrimSpace(textContent(n))
+}
+
+func parseDateTime(n *html.Node) *time.Time {
+	value, ok := attribute(n, "datetime")
+	if !ok {
+		value = textContent(n)
+	}
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05Z07:00", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			t = t.UTC()
+			return &t
+		}
+	}
+	return nil
+}
+
+// ExtractLinks returns the absolute http(s) links of an HTML fragment, such
+// as a rendered post, in order and without repeats.
+func ExtractLinks(fragment string) []string {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), body)
+	if err != nil {
+		return nil
+	}
+	for _, n := range nodes {
+		body.AppendChild(n)
+	}
+
+	var links []string
+	for n := range body.Descendants() {
+		if n.Type != html.ElementNode || n.DataAtom != atom.A {
+			continue
+		}
+		href, _ := attribute(n, "href")
+		u, err := url.Parse(href)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			continue
+		}
+		u.Fragment = ""
+		if link := u.String(); !slices.Contains(links, link) {
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+func findMicroformat(root *html.Node, class string) *html.Node {
+	for n := range root.Descendants() {
+		if n.Type == html.ElementNode && slices.Contains(strings.Fields(classAttr(n)), class) {
+			return n
+		}
+	}
+	return nil
+}
+
+func findElement(root *html.Node, a atom.Atom) *html.Node {
+	for n := range root.Descendants() {
+		if n.Type == html.ElementNode && n.DataAtom == a {
+			return n
+		}
+	}
+	return nil
+}
+
+func isMicroformat(classes []string) bool {
+	for _, c := range classes {
+		if strings.HasPrefix(c, "h-") {
+			return true
+		}
+	}
+	return false
+}
+
+func attribute(n *html.Node, name string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == name {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func classAttr(n *html.Node) string {
+	class, _ := attribute(n, "class")
+	return class
+}
+
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	for c := range n.Descendants() {
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// htmlText is the text of an HTML fragment, its whitespace collapsed.
+func htmlText(fragment string) string {
+	doc, err := html.Parse(strings.NewReader(fragment))
+	if err != nil {
+		return ""
+	}
+	return textContent(doc)
+}
+
+func resolveURL(base *url.URL, ref string) string {
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	return u.String()
+}
+
+func truncateText(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:n-1])) + "…"
+}
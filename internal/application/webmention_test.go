@@ -0,0 +1,158 @@
+package application
+
+import (
+	"postapi/internal/domain"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const webmentionTarget = "https://blog.example/api/posts/7"
+
+func TestParseWebmentionSource_Reply(t *testing.T) {
+	page := `<html><body>
+	<article class="h-entry">
+		<a class="p-author h-card" href="/"><img class="u-photo" src="/me.jpg"> <span class="p-name">Bob Smith</span></a>
+		<div class="u-in-reply-to h-cite"><a class="u-url" href="https://blog.example/api/posts/7">Alice's post</a>
+			<span class="p-name">Quoted title</span></div>
+		<time class="dt-published" datetime="2026-03-01T10:00:00+01:00">1 March</time>
+		<div class="e-content"><p>Great   post,
+			thanks!</p></div>
+	</article></body></html>`
+
+	source := ParseWebmentionSource([]byte(page), "text/html; charset=utf-8", "https://bob.example/replies/1", webmentionTarget)
+	if !source.LinksTarget {
+		t.Fatal("LinksTarget = false, want true")
+	}
+	published := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	want := &WebmentionSource{
+		LinksTarget: true,
+		Type:        domain.WebmentionReply,
+		AuthorName:  "Bob Smith",
+		AuthorURL:   "https://bob.example/",
+		AuthorPhoto: "https://bob.example/me.jpg",
+		Content:     "Great post, thanks!",
+		Published:   &published,
+	}
+	if !reflect.DeepEqual(source, want) {
+		t.Errorf("ParseWebmentionSource() = %+v, want %+v", source, want)
+	}
+}
+
+func TestParseWebmentionSource_Types(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry string
+		want  string
+	}{
+		{"like", `<a class="u-like-of" href="` + webmentionTarget + `">liked</a>`, domain.WebmentionLike},
+		{"repost", `<a class="u-repost-of" href="` + webmentionTarget + `">reposted</a>`, domain.WebmentionRepost},
+		{"bookmark", `<a class="u-bookmark-of" href="` + webmentionTarget + `">saved</a>`, domain.WebmentionBookmark},
+		{"reply to another post", `<a class="u-in-reply-to" href="https://other.example/1">re</a>
+			<p class="e-content">see <a href="` + webmentionTarget + `">this</a></p>`, domain.WebmentionMention},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := `<div class="h-entry">` + tt.entry + `</div>`
+			source := ParseWebmentionSource([]byte(page), "text/html", "https://bob.example/1", webmentionTarget)
+			if !source.LinksTarget || source.Type != tt.want {
+				t.Errorf("ParseWebmentionSource() = %+v, want type %s", source, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWebmentionSource_NoLink(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+		want        bool
+	}{
+		{"text/html", `<p>I read https://blog.example/api/posts/7 today</p>`, false},
+		{"text/html", `<p><a href="https://blog.example/api/posts/70">post</a></p>`, false},
+		{"text/plain", `I read https://blog.example/api/posts/7 today`, true},
+		{"application/json", `{"url": "https://blog.example/api/posts/8"}`, false},
+	}
+	for _, tt := range tests {
+		source := ParseWebmentionSource([]byte(tt.body), tt.contentType, "https://bob.example/1", webmentionTarget)
+		if source.LinksTarget != tt.want {
+			t.Errorf("LinksTarget(%s %q) = %v, want %v", tt.contentType, tt.body, source.LinksTarget, tt.want)
+		}
+	}
+}
+
+func TestParseWebmentionSource_Fallbacks(t *testing.T) {
+	page := `<html><head><title>Bob's notes</title></head>
+		<body><a href="` + webmentionTarget + `">a post</a></body></html>`
+	source := ParseWebmentionSource([]byte(page), "text/html", "https://bob.example/notes", webmentionTarget)
+	if source.Type != domain.WebmentionMention || source.Content != "Bob's notes" ||
+		source.AuthorName != "bob.example" || source.AuthorURL != "https://bob.example" {
+		t.Errorf("ParseWebmentionSource() = %+v", source)
+	}
+
+	long := `<div class="h-entry"><p class="e-content">` + strings.Repeat("word ", 200) +
+		`<a href="` + webmentionTarget + `">x</a></p></div>`
+	source = ParseWebmentionSource([]byte(long), "text/html", "https://bob.example/notes", webmentionTarget)
+	if n := len([]rune(source.Content)); n != maxWebmentionContent || !strings.HasSuffix(source.Content, "…") {
+		t.Errorf("content has %d characters, want %d ending in an ellipsis", n, maxWebmentionContent)
+	}
+}
+
+func TestExtractLinks(t *testing.T) {
+	html := `<p>See <a href="https://a.example/x#frag">this</a>, <a href="/local">that</a>,
+		<a href="mailto:me@example.com">mail</a> and <a href="https://a.example/x">again</a></p>
+		<ul><li><a href="http://b.example/">b</a></li></ul>`
+
+	want := []string{"https://a.example/x", "http://b.example/"}
+	if got := ExtractLinks(html); !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractLinks() = %v, want %v", got, want)
+	}
+}
+
+func TestEncodePostPage(t *testing.T) {
+	post := &domain.Post{
+		ID:        7,
+		Title:     "Hello <world>",
+		Content:   "Read [this](https://a.example/) #go",
+		Format:    domain.PostFormatMarkdown,
+		Author:    "alice",
+		CreatedAt: time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC),
+		Responses: []*domain.Webmention{{
+			Type:       domain.WebmentionReply,
+			Source:     "https://bob.example/1",
+			AuthorName: "Bob",
+			AuthorURL:  "javascript:alert(1)",
+			Content:    "<script>nice</script>",
+		}},
+	}
+
+	page, err := EncodePostPage(post, "https://blog.example", "https://blog.example/webmention")
+	if err != nil {
+		t.Fatalf("EncodePostPage() error = %v", err)
+	}
+	html := string(page)
+	for _, want := range []string{
+		`<link rel="webmention" href="https://blog.example/webmention">`,
+		`<h1 class="p-name">Hello &lt;world&gt;</h1>`,
+		`<a class="u-url" href="https://blog.example/api/posts/7">`,
+		`datetime="2026-03-01T10:00:00Z"`,
+		`<a href="https://a.example/"`,
+		`<a class="p-category" href="https://blog.example/api/tags/go/posts">#go</a>`,
+		`replied`,
+		`&lt;script&gt;nice&lt;/script&gt;`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("page does not contain %q:\n%s", want, html)
+		}
+	}
+	if strings.Contains(html, "javascript:") {
+		t.Errorf("page links to an unsafe author URL:\n%s", html)
+	}
+
+	// Our own page has to pass the check other sites make on it.
+	source := ParseWebmentionSource(page, "text/html", "https://blog.example/api/posts/7", "https://a.example/")
+	if !source.LinksTarget || source.AuthorName != "alice" {
+		t.Errorf("ParseWebmentionSource(own page) = %+v", source)
+	}
+}
@@ -0,0 +1,271 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"postapi/internal/domain"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxWebmentionAttempts is how many times a source that cannot be
+	// fetched is tried before its webmention is rejected.
+	MaxWebmentionAttempts = 5
+	webmentionRetryDelay  = 15 * time.Minute
+)
+
+var ErrInvalidWebmention = domain.Invalid(domain.CodeInvalidWebmention, "invalid webmention")
+
+// WebmentionClient fetches source pages and sends webmentions to other
+// sites.
+type WebmentionClient interface {
+	Fetch(url string) (*domain.WebPage, error)
+	// DiscoverEndpoint returns the webmention endpoint advertised by the
+	// page at target, or "" when it has none.
+	DiscoverEndpoint(target string) (string, error)
+	Send(endpoint, source, target string) error
+}
+
+type WebmentionUseCase struct {
+	// BaseURL is the public root of the site, without a trailing slash.
+	BaseURL        string
+	PostRepo       domain.PostRepository
+	WebmentionRepo domain.WebmentionRepository
+	Client         WebmentionClient
+}
+
+// PostURL is the permalink of a post.
+func PostURL(baseURL string, postID int64) string {
+	return baseURL + "/api/posts/" + strconv.FormatInt(postID, 10)
+}
+
+// Endpoint is the URL where webmentions are received.
+func (w *WebmentionUseCase) Endpoint() string {
+	return w.BaseURL + "/webmention"
+}
+
+// Receive queues a webmention for verification. The target has to be one of
+// our posts; the source is only fetched later, by VerifyPending.
+func (w *WebmentionUseCase) Receive(ctx context.Context, source, target string) (*domain.Webmention, error) {
+	if !isWebURL(source) {
+		return nil, fmt.Errorf("%w: source must be an http or https URL", ErrInvalidWebmention)
+	}
+	if !isWebURL(target) {
+		return nil, fmt.Errorf("%w: target must be an http or https URL", ErrInvalidWebmention)
+	}
+	if source == target {
+		return nil, fmt.Errorf("%w: source and target are the same", ErrInvalidWebmention)
+	}
+	postID, ok := PostIDFromURL(w.BaseURL, target)
+	if !ok {
+		return nil, fmt.Errorf("%w: target is not a post on this site", ErrInvalidWebmention)
+	}
+	if _, err := w.PostRepo.FindByID(ctx, postID); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, fmt.Errorf("%w: target does not exist", ErrInvalidWebmention)
+		}
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	webmention := &domain.Webmention{
+		PostID:        postID,
+		Source:        source,
+		Target:        target,
+		Status:        domain.WebmentionPending,
+		Type:          domain.WebmentionMention,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := w.WebmentionRepo.Save(ctx, webmention); err != nil {
+		return nil, err
+	}
+	return webmention, nil
+}
+
+// PostIDFromURL finds the post a URL of the site at baseURL points to: its
+// permalink or its ActivityPub note.
+func PostIDFromURL(baseURL, target string) (int64, bool) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return 0, false
+	}
+	u, err := url.Parse(target)
+	if err != nil || !strings.EqualFold(u.Host, base.Host) {
+		return 0, false
+	}
+	path, ok := strings.CutPrefix(u.Path, base.Path)
+	if !ok {
+		return 0, false
+	}
+
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	var id string
+	switch {
+	case len(segments) == 3 && segments[0] == "api" && segments[1] == "posts":
+		id = segments[2]
+	case len(segments) == 4 && segments[0] == "users" && segments[2] == "notes":
+		id = segments[3]
+	default:
+		return 0, false
+	}
+	postID, err := strconv.ParseInt(id, 10, 64)
+	return postID, err == nil
+}
+
+// VerifyPending fetches the sources of pending webmentions and checks that
+// they link to their targets. Sources that cannot be fetched are tried
+// again with exponential backoff, up to MaxWebmentionAttempts.
+func (w *WebmentionUseCase) VerifyPending(ctx context.Context, now time.Time) (verified int, err error) {
+	pending, err := w.WebmentionRepo.FindPending(ctx, now.UTC(), 20)
+	if err != nil {
+		return 0, err
+	}
+	for _, webmention := range pending {
+		if err := w.verify(ctx, webmention, now.UTC()); err != nil {
+			log.Printf("Cannot verify webmention from %s. err = %v\n", webmention.Source, err)
+		}
+		if webmention.Status == domain.WebmentionVerified {
+			verified++
+		}
+	}
+	return verified, nil
+}
+
+func (w *WebmentionUseCase) verify(ctx context.Context, webmention *domain.Webmention, now time.Time) error {
+	webmention.UpdatedAt = now
+	page, fetchErr := w.Client.Fetch(webmention.Source)
+	if fetchErr == nil && page.StatusCode >= 500 {
+		fetchErr = fmt.Errorf("fetch %s: status %d", webmention.Source, page.StatusCode)
+	}
+
+	switch {
+	case fetchErr != nil:
+		webmention.Attempts++
+		if webmention.Attempts >= MaxWebmentionAttempts {
+			webmention.Status = domain.WebmentionRejected
+			break
+		}
+		webmention.NextAttemptAt = now.Add(WebmentionBackoff(webmention.Attempts))
+	case page.StatusCode < 200 || page.StatusCode > 299:
+		// Includes 410 Gone, for sources that have been deleted.
+		webmention.Status = domain.WebmentionRejected
+	default:
+		source := ParseWebmentionSource(page.Body, page.ContentType, page.URL, webmention.Target)
+		if !source.LinksTarget {
+			webmention.Status = domain.WebmentionRejected
+			break
+		}
+		webmention.Status = domain.WebmentionVerified
+		webmention.Type = source.Type
+		webmention.AuthorName = source.AuthorName
+		webmention.AuthorURL = source.AuthorURL
+		webmention.AuthorPhoto = source.AuthorPhoto
+		webmention.Content = source.Content
+		webmention.Published = source.Published
+		webmention.VerifiedAt = &now
+	}
+
+	if err := w.WebmentionRepo.Update(ctx, webmention); err != nil {
+		return err
+	}
+	return fetchErr
+}
+
+// WebmentionBackoff is the wait before fetching a source again after the
+// given number of failed attempts: fifteen minutes, doubling, so that a
+// source is given about four hours to come back.
+func WebmentionBackoff(attempts int) time.Duration {
+	attempts = min(max(attempts, 1), MaxWebmentionAttempts)
+	return webmentionRetryDelay << (attempts - 1)
+}
+
+// RunVerificationJob verifies pending webmentions every interval until ctx
+// is cancelled.
+func (w *WebmentionUseCase) RunVerificationJob(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := w.VerifyPending(ctx, now); err != nil {
+				log.Printf("Cannot verify webmentions. err = %v\n", err)
+			}
+		}
+	}
+}
+
+// SendWebmentions notifies the pages linked from a post. previous is the
+// post before an edit, so that pages whose links were removed learn about
+// it too; it is nil for new posts. Links to this site are skipped.
+func (w *WebmentionUseCase) SendWebmentions(post, previous *domain.Post) (sent int, err error) {
+	links := ExtractLinks(RenderContent(post.Format, post.Content))
+	if previous != nil {
+		for _, link := range ExtractLinks(RenderContent(previous.Format, previous.Content)) {
+			if !slices.Contains(links, link) {
+				links = append(links, link)
+			}
+		}
+	}
+
+	source := PostURL(w.BaseURL, post.ID)
+	var errs []error
+	for _, target := range links {
+		if w.isLocal(target) {
+			continue
+		}
+		endpoint, err := w.Client.DiscoverEndpoint(target)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if endpoint == "" {
+			continue
+		}
+		if err := w.Client.Send(endpoint, source, target); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		sent++
+	}
+	return sent, errors.Join(errs...)
+}
+
+func (w *WebmentionUseCase) isLocal(link string) bool {
+	base, err := url.Parse(w.BaseURL)
+	if err != nil {
+		return false
+	}
+	u, err := url.Parse(link)
+	return err == nil && strings.EqualFold(u.Host, base.Host)
+}
+
+func isWebURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func MapWebmentionToJson(w *domain.Webmention) domain.JsonWebmention {
+	return domain.JsonWebmention{
+		ID:     w.ID,
+		Type:   w.Type,
+		Source: w.Source,
+		Author: domain.JsonWebmentionAuthor{
+			Name:  w.AuthorName,
+			URL:   w.AuthorURL,
+			Photo: w.AuthorPhoto,
+		},
+		Content:    w.Content,
+		Published:  w.Published,
+		VerifiedAt: w.VerifiedAt,
+	}
+}
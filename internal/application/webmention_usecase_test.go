@@ -0,0 +1,200 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"postapi/internal/domain"
+	"slices"
+	"testing"
+	"time"
+)
+
+type fakeWebmentionPostRepo struct {
+	domain.PostRepository
+}
+
+func (f *fakeWebmentionPostRepo) FindByID(ctx context.Context, id int64) (*domain.Post, error) {
+	if id != 7 {
+		return nil, domain.ErrNotFound
+	}
+	return &domain.Post{ID: id, Author: "alice"}, nil
+}
+
+type fakeWebmentionRepo struct {
+	domain.WebmentionRepository
+	saved []*domain.Webmention
+}
+
+func (f *fakeWebmentionRepo) Save(ctx context.Context, w *domain.Webmention) error {
+	w.ID = int64(len(f.saved) + 1)
+	f.saved = append(f.saved, w)
+	return nil
+}
+
+func (f *fakeWebmentionRepo) FindPending(ctx context.Context, now time.Time, limit int) ([]*domain.Webmention, error) {
+	var pending []*domain.Webmention
+	for _, w := range f.saved {
+		if w.Status == domain.WebmentionPending && !w.NextAttemptAt.After(now) {
+			pending = append(pending, w)
+		}
+	}
+	return pending, nil
+}
+
+func (f *fakeWebmentionRepo) Update(ctx context.Context, w *domain.Webmention) error {
+	return nil
+}
+
+func (f *fakeWebmentionRepo) DeleteBySource(ctx context.Context, source string) error {
+	f.saved = slices.DeleteFunc(f.saved, func(w *domain.Webmention) bool { return w.Source == source })
+	return nil
+}
+
+type fakeWebmentionClient struct {
+	pages     map[string]*domain.WebPage
+	endpoints map[string]string
+	sent      []string
+}
+
+func (f *fakeWebmentionClient) Fetch(url string) (*domain.WebPage, error) {
+	if page, ok := f.pages[url]; ok {
+		return page, nil
+	}
+	return nil, errors.New("connection refused")
+}
+
+func (f *fakeWebmentionClient) DiscoverEndpoint(target string) (string, error) {
+	return f.endpoints[target], nil
+}
+
+func (f *fakeWebmentionClient) Send(endpoint, source, target string) error {
+	f.sent = append(f.sent, source+" -> "+target+" via "+endpoint)
+	return nil
+}
+
+func newTestWebmentionUseCase() (*WebmentionUseCase, *fakeWebmentionRepo, *fakeWebmentionClient) {
+	repo := &fakeWebmentionRepo{}
+	client := &fakeWebmentionClient{pages: map[string]*domain.WebPage{}, endpoints: map[string]string{}}
+	return &WebmentionUseCase{
+		BaseURL:        "https://blog.example",
+		PostRepo:       &fakeWebmentionPostRepo{},
+		WebmentionRepo: repo,
+		Client:         client,
+	}, repo, client
+}
+
+func TestWebmentionUseCase_Receive(t *testing.T) {
+	uc, repo, _ := newTestWebmentionUseCase()
+
+	for _, target := range []string{"https://blog.example/api/posts/7", "https://BLOG.example/users/alice/notes/7"} {
+		w, err := uc.Receive(context.Background(), "https://bob.example/1", target)
+		if err != nil {
+			t.Fatalf("Receive(%s) error = %v", target, err)
+		}
+		if w.PostID != 7 || w.Status != domain.WebmentionPending {
+			t.Errorf("Receive(%s) = %+v", target, w)
+		}
+	}
+	if len(repo.saved) != 2 {
+		t.Errorf("saved %d webmentions, want 2", len(repo.saved))
+	}
+
+	tests := []struct{ source, target string }{
+		{"", "https://blog.example/api/posts/7"},
+		{"ftp://bob.example/1", "https://blog.example/api/posts/7"},
+		{"https://blog.example/api/posts/7", "https://blog.example/api/posts/7"},
+		{"https://bob.example/1", "https://other.example/api/posts/7"},
+		{"https://bob.example/1", "https://blog.example/api/tags/go/posts"},
+		{"https://bob.example/1", "https://blog.example/api/posts/8"},
+	}
+	for _, tt := range tests {
+		if _, err := uc.Receive(context.Background(), tt.source, tt.target); !errors.Is(err, ErrInvalidWebmention) {
+			t.Errorf("Receive(%q, %q) error = %v, want ErrInvalidWebmention", tt.source, tt.target, err)
+		}
+	}
+}
+
+func TestWebmentionUseCase_VerifyPending(t *testing.T) {
+	uc, _, client := newTestWebmentionUseCase()
+	target := "https://blog.example/api/posts/7"
+	client.pages["https://bob.example/reply"] = &domain.WebPage{
+		URL:         "https://bob.example/reply",
+		StatusCode:  200,
+		ContentType: "text/html",
+		Body:        []byte(`<div class="h-entry"><a class="u-in-reply-to" href="` + target + `">re</a><p class="e-content">Nice</p></div>`),
+	}
+	client.pages["https://bob.example/spam"] = &domain.WebPage{StatusCode: 200, ContentType: "text/html", Body: []byte(`<p>buy now</p>`)}
+	client.pages["https://bob.example/gone"] = &domain.WebPage{StatusCode: 410}
+
+	reply, _ := uc.Receive(context.Background(), "https://bob.example/reply", target)
+	spam, _ := uc.Receive(context.Background(), "https://bob.example/spam", target)
+	gone, _ := uc.Receive(context.Background(), "https://bob.example/gone", target)
+	down, _ := uc.Receive(context.Background(), "https://bob.example/down", target)
+
+	now := time.Now().UTC()
+	verified, err := uc.VerifyPending(context.Background(), now)
+	if err != nil || verified != 1 {
+		t.Fatalf("VerifyPending() = %d, %v, want 1", verified, err)
+	}
+	if reply.Status != domain.WebmentionVerified || reply.Type != domain.WebmentionReply ||
+		reply.Content != "Nice" || !reply.VerifiedAt.Equal(now) {
+		t.Errorf("reply = %+v", reply)
+	}
+	if spam.Status != domain.WebmentionRejected || gone.Status != domain.WebmentionRejected {
+		t.Errorf("spam = %s, gone = %s, want both rejected", spam.Status, gone.Status)
+	}
+	if down.Status != domain.WebmentionPending || down.Attempts != 1 || !down.NextAttemptAt.Equal(now.Add(WebmentionBackoff(1))) {
+		t.Errorf("unreachable source = %+v, want pending for a retry", down)
+	}
+
+	// It is not fetched again before its next attempt
+	uc.VerifyPending(context.Background(), now.Add(time.Minute))
+	if down.Attempts != 1 {
+		t.Errorf("unreachable source fetched again after a minute, %d attempts", down.Attempts)
+	}
+
+	for range MaxWebmentionAttempts - 1 {
+		now = down.NextAttemptAt
+		uc.VerifyPending(context.Background(), now)
+	}
+	if down.Status != domain.WebmentionRejected {
+		t.Errorf("unreachable source after %d attempts = %s, want rejected", down.Attempts, down.Status)
+	}
+}
+
+func TestWebmentionBackoff(t *testing.T) {
+	tests := map[int]time.Duration{
+		0:  15 * time.Minute,
+		1:  15 * time.Minute,
+		2:  30 * time.Minute,
+		4:  2 * time.Hour,
+		40: 4 * time.Hour,
+	}
+	for attempts, want := range tests {
+		if got := WebmentionBackoff(attempts); got != want {
+			t.Errorf("WebmentionBackoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestWebmentionUseCase_SendWebmentions(t *testing.T) {
+	uc, _, client := newTestWebmentionUseCase()
+	client.endpoints["https://a.example/post"] = "https://a.example/webmention"
+	client.endpoints["https://c.example/removed"] = "https://c.example/wm"
+
+	previous := &domain.Post{ID: 7, Format: domain.PostFormatMarkdown, Content: "[old](https://c.example/removed)"}
+	post := &domain.Post{ID: 7, Format: domain.PostFormatMarkdown,
+		Content: "[a](https://a.example/post), [no endpoint](https://b.example/), [own](https://blog.example/api/posts/1)"}
+
+	sent, err := uc.SendWebmentions(post, previous)
+	if err != nil || sent != 2 {
+		t.Fatalf("SendWebmentions() = %d, %v, want 2", sent, err)
+	}
+	want := []string{
+		"https://blog.example/api/posts/7 -> https://a.example/post via https://a.example/webmention",
+		"https://blog.example/api/posts/7 -> https://c.example/removed via https://c.example/wm",
+	}
+	if len(client.sent) != 2 || client.sent[0] != want[0] || client.sent[1] != want[1] {
+		t.Errorf("sent %v, want %v", client.sent, want)
+	}
+}
@@ -0,0 +1,62 @@
+package domain
+
+import "time"
+
+// MaxConversationMembers limits group conversations, creator included.
+const MaxConversationMembers = 8
+
+type Conversation struct {
+	ID        int64     `db:"id"`
+	CreatedBy string    `db:"created_by"`
+	IsGroup   bool      `db:"is_group"`
+	CreatedAt time.Time `db:"created_at"`
+	Members   []string  `db:"-"`
+	// LastReadMessageID and UnreadCount are those of the member the
+	// conversations were listed for. Unread messages are the ones others sent
+	// after the last read.
+	LastReadMessageID int64 `db:"last_read_message_id"`
+	UnreadCount       int64 `db:"unread_count"`
+}
+
+type Message struct {
+	ID             int64     `db:"id"`
+	ConversationID int64     `db:"conversation_id"`
+	Sender         string    `db:"sender"`
+	Body           string    `db:"body"`
+	CreatedAt      time.Time `db:"created_at"`
+}
+
+type JsonConversation struct {
+	ID                int64     `json:"id"`
+	CreatedBy         string    `json:"created_by"`
+	IsGroup           bool      `json:"is_group"`
+	Members           []string  `json:"members"`
+	CreatedAt         time.Time `json:"created_at"`
+	LastReadMessageID int64     `json:"last_read_message_id"`
+	UnreadCount       int64     `json:"unread_count"`
+}
+
+type JsonMessage struct {
+	ID             int64     `json:"id"`
+	ConversationID int64     `json:"conversation_id"`
+	Sender         string    `json:"sender"`
+	Body           string    `json:"body"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type JsonMessagePage struct {
+	Messages   []JsonMessage `json:"messages"`
+	NextCursor *int64        `json:"next_cursor"`
+}
+
+type ConversationRequest struct {
+	Members []string `json:"members"`
+}
+
+type MessageRequest struct {
+	Body string `json:"body"`
+}
+
+type MessageReadRequest struct {
+	MessageID int64 `json:"message_id"`
+}
@@ -0,0 +1,167 @@
+package domain
+
+import "strings"
+
+// ErrorKind classifies errors so that adapters can translate them, for
+// instance to HTTP status codes.
+type ErrorKind int
+
+// Tipos de error
+const (
+	KindNotFound ErrorKind = iota + 1
+	KindConflict
+	KindForbidden
+	KindValidation
+	KindUnauthorized
+	KindTooLarge
+	KindUnsupportedMedia
+)
+
+// Códigos de error
+const (
+	CodeNotFound           = "not_found"
+	CodeConflict           = "conflict"
+	CodeForbidden          = "forbidden"
+	CodeValidation         = "validation_failed"
+	CodeUnauthorized       = "unauthorized"
+	CodeInvalidToken       = "invalid_token"
+	CodeInvalidCredentials = "invalid_credentials"
+	CodeInvalidSignature   = "invalid_signature"
+	CodeInvalidBody        = "invalid_body"
+	CodeInvalidParameter   = "invalid_parameter"
+	CodePayloadTooLarge    = "payload_too_large"
+	CodeUnsupportedMedia   = "unsupported_media_type"
+	CodeUnavailable        = "service_unavailable"
+	CodeInternal           = "internal_error"
+
+	CodeUserNotFound         = "user_not_found"
+	CodePostNotFound         = "post_not_found"
+	CodeProfileNotFound      = "profile_not_found"
+	CodeMediaNotFound        = "media_not_found"
+	CodeConversationNotFound = "conversation_not_found"
+	CodeMessageNotFound      = "message_not_found"
+	CodeFollowNotFound       = "follow_not_found"
+	CodeUsernameTaken        = "username_taken"
+	CodeEmailTaken           = "email_taken"
+	CodeProfileExists        = "profile_exists"
+	CodeAlreadyFollowing     = "already_following"
+	CodeAlreadyBlocked       = "already_blocked"
+	CodeSelfFollow           = "cannot_follow_self"
+	CodeSelfBlock            = "cannot_block_self"
+	CodeNotAllowed           = "not_allowed"
+	CodeNotAuthor            = "not_author"
+	CodeBlocked              = "blocked"
+	CodeInvalidMembers       = "invalid_members"
+	CodeInvalidMessage       = "invalid_message"
+	CodeUnknownUsername      = "unknown_username"
+	CodeUnknownMessage       = "unknown_message"
+	CodeActorMismatch        = "actor_mismatch"
+	CodeInvalidImage         = "invalid_image"
+	CodeInvalidAttachment    = "invalid_attachment"
+	CodeInvalidWebmention    = "invalid_webmention"
+	CodeInvalidMicropub      = "invalid_micropub_request"
+)
+
+// Códigos de error de campo
+const (
+	CodeRequired      = "required"
+	CodeTooShort      = "too_short"
+	CodeTooLong       = "too_long"
+	CodeInvalidFormat = "invalid_format"
+	CodeInvalidChoice = "invalid_choice"
+)
+
+// FieldError is the reason one field of a request was rejected. Field is the
+// name of the field in JSON.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is an error with a kind and a stable code that clients can rely on.
+// Message is meant for people and may change.
+type Error struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+	// Fields lists every invalid field of a rejected request.
+	Fields []FieldError
+	// Err is the underlying cause, if any. It is not part of Error() so
+	// that driver details do not reach clients.
+	Err error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is makes every error match the generic error of its kind, so
+// errors.Is(err, ErrNotFound) holds for a missing post as well as a missing
+// user.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrNotFound, ErrConflict, ErrForbidden, ErrValidation, ErrUnauthorized:
+		return target.(*Error).Kind == e.Kind
+	}
+	return false
+}
+
+// Wrap returns a copy of e caused by err.
+func (e *Error) Wrap(err error) *Error {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+var (
+	ErrNotFound     = NotFound(CodeNotFound, "not found")
+	ErrConflict     = Conflict(CodeConflict, "conflict")
+	ErrForbidden    = Forbidden(CodeForbidden, "forbidden")
+	ErrValidation   = Invalid(CodeValidation, "validation failed")
+	ErrUnauthorized = Unauthorized(CodeUnauthorized, "unauthorized")
+)
+
+func NotFound(code, message string) *Error {
+	return &Error{Kind: KindNotFound, Code: code, Message: message}
+}
+
+func Conflict(code, message string) *Error {
+	return &Error{Kind: KindConflict, Code: code, Message: message}
+}
+
+func Forbidden(code, message string) *Error {
+	return &Error{Kind: KindForbidden, Code: code, Message: message}
+}
+
+func Invalid(code, message string) *Error {
+	return &Error{Kind: KindValidation, Code: code, Message: message}
+}
+
+// InvalidFields is the validation error of a request with the given invalid
+// fields; its message lists them all.
+func InvalidFields(fields []FieldError) *Error {
+	messages := make([]string, len(fields))
+	for i, f := range fields {
+		messages[i] = f.Message
+	}
+	err := Invalid(CodeValidation, strings.Join(messages, "; "))
+	err.Fields = fields
+	return err
+}
+
+func Unauthorized(code, message string) *Error {
+	return &Error{Kind: KindUnauthorized, Code: code, Message: message}
+}
+
+func TooLarge(code, message string) *Error {
+	return &Error{Kind: KindTooLarge, Code: code, Message: message}
+}
+
+func UnsupportedMedia(code, message string) *Error {
+	return &Error{Kind: KindUnsupportedMedia, Code: code, Message: message}
+}
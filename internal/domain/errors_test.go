@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestError_Is(t *testing.T) {
+	missingPost := NotFound(CodePostNotFound, "post not found")
+
+	tests := []struct {
+		name   string
+		err    error
+		target error
+		want   bool
+	}{
+		{"Same kind", missingPost, ErrNotFound, true},
+		{"Other kind", missingPost, ErrConflict, false},
+		{"Wrapped", fmt.Errorf("find post: %w", missingPost), ErrNotFound, true},
+		{"Specific error", missingPost, missingPost, true},
+		{"Specific errors of a kind differ", missingPost, NotFound(CodeUserNotFound, "user not found"), false},
+		{"Plain error", errors.New("boom"), ErrNotFound, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errors.Is(tt.err, tt.target); got != tt.want {
+				t.Errorf("errors.Is() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestError_Wrap(t *testing.T) {
+	base := NotFound(CodeUserNotFound, "user not found")
+	err := base.Wrap(sql.ErrNoRows)
+
+	if base.Err != nil {
+		t.Error("Wrap() should not change the original error")
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Error("Wrapped error should match its cause")
+	}
+	if !errors.Is(err, ErrNotFound) {
+		t.Error("Wrapped error should keep its kind")
+	}
+	if err.Error() != "user not found" {
+		t.Errorf("Error() = %q, want the message without the cause", err.Error())
+	}
+
+	var domainErr *Error
+	if !errors.As(fmt.Errorf("restore: %w", err), &domainErr) || domainErr.Code != CodeUserNotFound {
+		t.Errorf("errors.As() should find the code %s", CodeUserNotFound)
+	}
+}
@@ -0,0 +1,53 @@
+package domain
+
+import "time"
+
+// ActorKey is the RSA key pair a local user signs federated requests with.
+type ActorKey struct {
+	Username      string `db:"username"`
+	PrivateKeyPem string `db:"private_key_pem"`
+	PublicKeyPem  string `db:"public_key_pem"`
+}
+
+// RemoteActor is an ActivityPub actor on another server.
+type RemoteActor struct {
+	ID                string
+	PreferredUsername string
+	Inbox             string
+	SharedInbox       string
+	PublicKeyID       string
+	PublicKeyPem      string
+}
+
+// DeliveryInbox is where activities for the actor should be posted, its
+// server's shared inbox when it has one.
+func (a *RemoteActor) DeliveryInbox() string {
+	if a.SharedInbox != "" {
+		return a.SharedInbox
+	}
+	return a.Inbox
+}
+
+// RemoteFollower is a remote actor following a local user.
+type RemoteFollower struct {
+	Username string `db:"username"`
+	ActorID  string `db:"actor_id"`
+	Inbox    string `db:"inbox"`
+	// FollowID is the ID of the Follow activity, which an Undo may name
+	// instead of embedding it.
+	FollowID  string    `db:"follow_id"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// Delivery is an activity waiting to be posted to a remote inbox, signed with
+// the key of Sender.
+type Delivery struct {
+	ID            int64     `db:"id"`
+	Sender        string    `db:"sender"`
+	Inbox         string    `db:"inbox"`
+	Payload       string    `db:"payload"`
+	Attempts      int       `db:"attempts"`
+	NextAttemptAt time.Time `db:"next_attempt_at"`
+	LastError     string    `db:"last_error"`
+	CreatedAt     time.Time `db:"created_at"`
+}
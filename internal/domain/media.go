@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"io"
+	"time"
+)
+
+// Names of the stored renditions of an uploaded image.
+const (
+	MediaVariantThumbnail = "thumbnail"
+	MediaVariantMedium    = "medium"
+	MediaVariantOriginal  = "original"
+)
+
+// Media is an uploaded image. Key names the original rendition; the other
+// variants are stored next to it. Width and Height are zero for files
+// uploaded before images were processed, which only have the original.
+type Media struct {
+	ID          int64     `db:"id"`
+	Owner       string    `db:"owner"`
+	Key         string    `db:"storage_key"`
+	ContentType string    `db:"content_type"`
+	Size        int64     `db:"size"`
+	Width       int       `db:"width"`
+	Height      int       `db:"height"`
+	Blurhash    string    `db:"blurhash"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+// PostAttachment is a media item attached to a post.
+type PostAttachment struct {
+	PostID int64 `db:"post_id"`
+	Media
+}
+
+type JsonMedia struct {
+	ID          int64                       `json:"id"`
+	URL         string                      `json:"url"`
+	ContentType string                      `json:"content_type"`
+	Size        int64                       `json:"size"`
+	Width       int                         `json:"width,omitempty"`
+	Height      int                         `json:"height,omitempty"`
+	Blurhash    string                      `json:"blurhash,omitempty"`
+	Variants    map[string]JsonMediaVariant `json:"variants"`
+}
+
+type JsonMediaVariant struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+}
+
+// BlobStore keeps the bytes of uploaded media.
+type BlobStore interface {
+	Put(key string, r io.Reader, size int64, contentType string) error
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
@@ -0,0 +1,24 @@
+package domain
+
+// Campos de un post en los que puede aparecer una mención
+const (
+	MentionFieldTitle   = "title"
+	MentionFieldContent = "content"
+)
+
+// Mention is an @username reference inside a post. Start and End are
+// character (rune) offsets into Field, End exclusive, covering the '@'.
+type Mention struct {
+	PostID   int64  `db:"post_id"`
+	Username string `db:"username"`
+	Field    string `db:"field"`
+	Start    int    `db:"start_offset"`
+	End      int    `db:"end_offset"`
+}
+
+type JsonMention struct {
+	Username string `json:"username"`
+	Field    string `json:"field"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+}
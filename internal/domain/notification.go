@@ -0,0 +1,33 @@
+package domain
+
+import "time"
+
+// Tipos de notificación
+const (
+	NotificationFollow  = "follow"
+	NotificationMention = "mention"
+)
+
+type Notification struct {
+	ID        int64      `db:"id"`
+	Recipient string     `db:"recipient"`
+	Actor     string     `db:"actor"`
+	Type      string     `db:"type"`
+	PostID    *int64     `db:"post_id"`
+	CreatedAt time.Time  `db:"created_at"`
+	ReadAt    *time.Time `db:"read_at"`
+}
+
+type JsonNotification struct {
+	ID        int64     `json:"id"`
+	Actor     string    `json:"actor"`
+	Type      string    `json:"type"`
+	PostID    *int64    `json:"post_id,omitempty"`
+	Read      bool      `json:"read"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type NotificationReadRequest struct {
+	IDs []int64 `json:"ids"`
+	All bool    `json:"all"`
+}
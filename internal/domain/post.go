@@ -1,20 +1,44 @@
 package domain
 
+import "time"
+
+// Formatos admitidos para el contenido de un post
+const (
+	PostFormatPlain    = "plain"
+	PostFormatMarkdown = "markdown"
+)
+
 type Post struct {
-	ID      int64  `db:"id"`
-	Title   string `db:"title"`
-	Content string `db:"content"`
-	Author  string `db:"author"`
+	ID          int64         `db:"id"`
+	Title       string        `db:"title"`
+	Content     string        `db:"content"`
+	Format      string        `db:"format"`
+	Author      string        `db:"author"`
+	CreatedAt   time.Time     `db:"created_at"`
+	DeletedAt   *time.Time    `db:"deleted_at"`
+	Mentions    []*Mention    `db:"-"`
+	Attachments []*Media      `db:"-"`
+	Responses   []*Webmention `db:"-"`
 }
 
 type JsonPost struct {
-	ID      int64  `json:"id"`
-	Title   string `json:"title"`
-	Content string `json:"content"`
-	Author  string `json:"author"`
+	ID          int64            `json:"id"`
+	Title       string           `json:"title"`
+	Content     string           `json:"content"`
+	ContentHTML string           `json:"content_html"`
+	Format      string           `json:"format"`
+	Author      string           `json:"author"`
+	Tags        []string         `json:"tags"`
+	Mentions    []JsonMention    `json:"mentions"`
+	Attachments []JsonMedia      `json:"attachments"`
+	Responses   []JsonWebmention `json:"responses"`
+	CreatedAt   time.Time        `json:"created_at"`
+	DeletedAt   *time.Time       `json:"deleted_at,omitempty"`
 }
 
 type PostRequest struct {
-	Title   string `json:"title"`
-	Content string `json:"content"`
+	Title    string  `json:"title" validate:"required,max=200"`
+	Content  string  `json:"content" validate:"required,max=20000"`
+	Format   string  `json:"format" validate:"oneof=plain markdown"`
+	MediaIDs []int64 `json:"media_ids"`
 }
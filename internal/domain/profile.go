@@ -4,16 +4,29 @@ type Profile struct {
 	Username       string `db:"username"`
 	Description    string `db:"description"`
 	ProfilePicture string `db:"profile_picture"`
+	Private        bool   `db:"private"`
+	AvatarMediaID  *int64 `db:"avatar_media_id"`
+	Avatar         *Media `db:"-"`
+	// UserCounts are those of the user the profile belongs to.
+	UserCounts
 }
 
 type JsonProfile struct {
-	Username       string `json:"username"`
-	Description    string `json:"description"`
-	ProfilePicture string `json:"profile_picture"`
+	Username       string     `json:"username"`
+	Description    string     `json:"description"`
+	ProfilePicture string     `json:"profile_picture"`
+	Private        bool       `json:"private"`
+	AvatarMediaID  *int64     `json:"avatar_media_id,omitempty"`
+	Avatar         *JsonMedia `json:"avatar,omitempty"`
+	FollowerCount  int64      `json:"follower_count"`
+	FollowingCount int64      `json:"following_count"`
+	PostCount      int64      `json:"post_count"`
 }
 
 type ProfileRequest struct {
 	Username       string `json:"username"`
-	Description    string `json:"description"`
-	ProfilePicture string `json:"profile_picture"`
+	Description    string `json:"description" validate:"max=500"`
+	ProfilePicture string `json:"profile_picture" validate:"max=2048,url"`
+	Private        *bool  `json:"private"`
+	AvatarMediaID  *int64 `json:"avatar_media_id"`
 }
@@ -1,30 +1,170 @@
 package domain
 
+import (
+	"context"
+	"time"
+)
+
 // Definimos los métodos para la persistencia de cada tabla
 
 type UserRepository interface {
-	Create(user *User) error
-	FindByUsername(username string) (*User, error)
-	LoginUser(p *User) (*User, error)
+	Create(ctx context.Context, user *User) error
+	FindByUsername(ctx context.Context, username string) (*User, error)
+	LoginUser(ctx context.Context, p *User) (*User, error)
+	Delete(ctx context.Context, username string) error
+	Restore(ctx context.Context, p *User, deletedSince time.Time) (*User, error)
+	PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error)
+	// Recount recomputes the UserCounts of every user and returns how many
+	// users had them wrong.
+	Recount(ctx context.Context) (int64, error)
 }
 
 type PostRepository interface {
-	Create(post *Post) error
-	Update(post *Post) error
-	Delete(id int64, author string) error
-	FindByID(id int64) (*Post, error)
-	FindByAuthor(author string) ([]*Post, error)
+	Create(ctx context.Context, post *Post) error
+	Update(ctx context.Context, post *Post) error
+	Delete(ctx context.Context, id int64, author string) error
+	FindByID(ctx context.Context, id int64) (*Post, error)
+	// FindByAuthor returns the posts of author, newest first.
+	FindByAuthor(ctx context.Context, author string) ([]*Post, error)
+	// FindLatestByAuthor returns a page of the posts FindByAuthor returns.
+	FindLatestByAuthor(ctx context.Context, author string, limit int, offset int) ([]*Post, error)
+	FindDeletedByAuthor(ctx context.Context, author string, deletedSince time.Time) ([]*Post, error)
+	// FindAfter returns up to limit posts with an id above afterID, in id
+	// order, deleted ones included, so that maintenance can go through them all.
+	FindAfter(ctx context.Context, afterID int64, limit int) ([]*Post, error)
+	Restore(ctx context.Context, id int64, author string, deletedSince time.Time) error
+	PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error)
+}
+
+type TagRepository interface {
+	SetPostTags(ctx context.Context, postID int64, tags []string) error
+	FindPostsByTag(ctx context.Context, tag string, limit int, offset int) ([]*Post, error)
+	Trending(ctx context.Context, since time.Time, limit int) ([]*TagCount, error)
+}
+
+type MentionRepository interface {
+	SetPostMentions(ctx context.Context, postID int64, mentions []*Mention) error
+	FindByPosts(ctx context.Context, postIDs []int64) ([]*Mention, error)
+	FindPostsMentioning(ctx context.Context, username string, limit int, offset int) ([]*Post, error)
+}
+
+type NotificationRepository interface {
+	Create(ctx context.Context, notification *Notification) error
+	FindByRecipient(ctx context.Context, recipient string, unreadOnly bool, limit int, offset int) ([]*Notification, error)
+	MarkRead(ctx context.Context, recipient string, ids []int64) (int64, error)
+	MarkAllRead(ctx context.Context, recipient string) (int64, error)
+	CountUnread(ctx context.Context, recipient string) (int64, error)
+}
+
+type MediaRepository interface {
+	Create(ctx context.Context, media *Media) error
+	FindByIDs(ctx context.Context, ids []int64) ([]*Media, error)
+	FindByKeys(ctx context.Context, keys []string) ([]*Media, error)
+	SetPostAttachments(ctx context.Context, postID int64, mediaIDs []int64) error
+	FindByPosts(ctx context.Context, postIDs []int64) ([]*PostAttachment, error)
+	// FindByDeletedOwners returns the media of the accounts deleted before
+	// deletedBefore, which purging those accounts removes.
+	FindByDeletedOwners(ctx context.Context, deletedBefore time.Time) ([]*Media, error)
+}
+
+type ActorKeyRepository interface {
+	// Create stores the key unless the user already has one.
+	Create(ctx context.Context, key *ActorKey) error
+	FindByUsername(ctx context.Context, username string) (*ActorKey, error)
+}
+
+type RemoteFollowerRepository interface {
+	Add(ctx context.Context, follower *RemoteFollower) error
+	Remove(ctx context.Context, username string, actorID string) error
+	// Find returns the follow of username by actorID, or ErrNotFound.
+	Find(ctx context.Context, username string, actorID string) (*RemoteFollower, error)
+	// RemoveActor drops every follow by an actor, when it is deleted.
+	RemoveActor(ctx context.Context, actorID string) error
+	FindByUsername(ctx context.Context, username string) ([]*RemoteFollower, error)
+	CountByUsername(ctx context.Context, username string) (int64, error)
+}
+
+type DeliveryRepository interface {
+	Enqueue(ctx context.Context, delivery *Delivery) error
+	FindDue(ctx context.Context, now time.Time, limit int) ([]*Delivery, error)
+	Delete(ctx context.Context, id int64) error
+	Reschedule(ctx context.Context, id int64, attempts int, next time.Time, lastError string) error
+}
+
+type WebmentionRepository interface {
+	// Save stores a webmention as pending, resetting it when the same
+	// source and target were received before.
+	Save(ctx context.Context, webmention *Webmention) error
+	// FindPending returns up to limit pending webmentions due by now.
+	FindPending(ctx context.Context, now time.Time, limit int) ([]*Webmention, error)
+	Update(ctx context.Context, webmention *Webmention) error
+	FindVerifiedByPosts(ctx context.Context, postIDs []int64) ([]*Webmention, error)
+	// DeleteBySource removes the webmentions of source, when it is deleted.
+	DeleteBySource(ctx context.Context, source string) error
 }
 
 type ProfileRepository interface {
-	Create(profile *Profile) error
-	Update(profile *Profile) error
-	FindByUsername(username string) (*Profile, error)
+	Create(ctx context.Context, profile *Profile) error
+	Update(ctx context.Context, profile *Profile) error
+	FindByUsername(ctx context.Context, username string) (*Profile, error)
 }
 
 type UserFollowRepository interface {
-	Create(follow *UserFollow) error
-	Delete(follow *UserFollow) error
-	GetFollowers(username string) ([]string, error)
-	GetFollowing(username string) ([]string, error)
+	Create(ctx context.Context, follow *UserFollow) error
+	Delete(ctx context.Context, follow *UserFollow) error
+	GetFollowers(ctx context.Context, username string) ([]string, error)
+	GetFollowing(ctx context.Context, username string) ([]string, error)
+	// FindFollowers returns the users following username like GetFollowers,
+	// sorted by username and with their profiles and counts, in one query.
+	FindFollowers(ctx context.Context, username string) ([]*UserSummary, error)
+	// FindFollowing is the FindFollowers counterpart of GetFollowing.
+	FindFollowing(ctx context.Context, username string) ([]*UserSummary, error)
+	Exists(ctx context.Context, follow *UserFollow) (bool, error)
+}
+
+type UserBlockRepository interface {
+	Create(ctx context.Context, block *UserBlock) error
+	Delete(ctx context.Context, block *UserBlock) error
+	// IsBlocked reports whether either user has blocked the other.
+	IsBlocked(ctx context.Context, username string, other string) (bool, error)
+}
+
+type ConversationRepository interface {
+	Create(ctx context.Context, conversation *Conversation) error
+	FindByID(ctx context.Context, id int64) (*Conversation, error)
+	FindDirect(ctx context.Context, username string, other string) (*Conversation, error)
+	FindByMember(ctx context.Context, username string, limit int, offset int) ([]*Conversation, error)
+	CreateMessage(ctx context.Context, message *Message) error
+	// FindMessages returns up to limit messages older than the before cursor,
+	// newest first. A zero cursor starts from the latest message.
+	FindMessages(ctx context.Context, conversationID int64, before int64, limit int) ([]*Message, error)
+	FindMessage(ctx context.Context, conversationID int64, id int64) (*Message, error)
+	MarkRead(ctx context.Context, conversationID int64, username string, messageID int64) error
+}
+
+// Repositories groups the repositories bound to the same database or
+// transaction.
+type Repositories struct {
+	Users           UserRepository
+	Posts           PostRepository
+	Profiles        ProfileRepository
+	Follows         UserFollowRepository
+	Blocks          UserBlockRepository
+	Tags            TagRepository
+	Mentions        MentionRepository
+	Notifications   NotificationRepository
+	Conversations   ConversationRepository
+	Media           MediaRepository
+	ActorKeys       ActorKeyRepository
+	RemoteFollowers RemoteFollowerRepository
+	Deliveries      DeliveryRepository
+	Webmentions     WebmentionRepository
+}
+
+// UnitOfWork runs several writes as one: fn gets repositories bound to a
+// transaction that is committed if fn succeeds and rolled back otherwise.
+// fn may be run again when the transaction conflicts with a concurrent one,
+// so it should have no effects outside the repositories.
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error
 }
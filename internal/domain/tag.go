@@ -0,0 +1,11 @@
+package domain
+
+type TagCount struct {
+	Tag   string `db:"tag"`
+	Count int64  `db:"count"`
+}
+
+type JsonTagCount struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
@@ -1,14 +1,52 @@
 package domain
 
+import "time"
+
 type User struct {
-	Username string `db:"username"`
-	Password string `db:"password"`
-	Email    string `db:"email"`
+	Username  string     `db:"username"`
+	Password  string     `db:"password"`
+	Email     string     `db:"email"`
+	DeletedAt *time.Time `db:"deleted_at"`
+	UserCounts
+}
+
+// UserCounts are the active users following a user and followed by it, and
+// its posts not in the trash. The repositories keep them as the follows,
+// posts and accounts they count change.
+type UserCounts struct {
+	FollowerCount  int64 `db:"follower_count"`
+	FollowingCount int64 `db:"following_count"`
+	PostCount      int64 `db:"post_count"`
 }
 
 type JsonUser struct {
-	Username string `json:"username"`
-	Email    string `json:"email"`
+	Username       string `json:"username"`
+	Email          string `json:"email"`
+	FollowerCount  int64  `json:"follower_count"`
+	FollowingCount int64  `json:"following_count"`
+	PostCount      int64  `json:"post_count"`
+}
+
+// UserSummary is a user as lists of users show it: the account, the profile
+// if there is one, and how many followers, follows and posts it has.
+type UserSummary struct {
+	Username       string `db:"username"`
+	Email          string `db:"email"`
+	Description    string `db:"description"`
+	ProfilePicture string `db:"profile_picture"`
+	Private        bool   `db:"private"`
+	UserCounts
+}
+
+type JsonUserSummary struct {
+	Username       string `json:"username"`
+	Email          string `json:"email"`
+	Description    string `json:"description"`
+	ProfilePicture string `json:"profile_picture"`
+	Private        bool   `json:"private"`
+	FollowerCount  int64  `json:"follower_count"`
+	FollowingCount int64  `json:"following_count"`
+	PostCount      int64  `json:"post_count"`
 }
 
 type UserResponse struct {
@@ -17,6 +55,14 @@ type UserResponse struct {
 	Email    string `json:"email"`
 }
 
+// RegisterRequest is the payload of a registration. Usernames use the
+// characters mentions recognize.
+type RegisterRequest struct {
+	Username string `json:"username" validate:"required,min=3,max=30,username"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+	Email    string `json:"email" validate:"required,max=254,email"`
+}
+
 func (u *User) ToResponse() UserResponse {
 	return UserResponse{
 		Username: u.Username,
@@ -0,0 +1,11 @@
+package domain
+
+type UserBlock struct {
+	BlockerUsername string `db:"blocker_username"`
+	BlockedUsername string `db:"blocked_username"`
+}
+
+type JsonUserBlock struct {
+	BlockerUsername string `json:"blocker_username"`
+	BlockedUsername string `json:"blocked_username"`
+}
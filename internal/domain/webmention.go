@@ -0,0 +1,66 @@
+package domain
+
+import "time"
+
+// Estados de una Webmention recibida
+const (
+	WebmentionPending  = "pending"
+	WebmentionVerified = "verified"
+	WebmentionRejected = "rejected"
+)
+
+// Tipos de respuesta, según la propiedad microformats2 que enlaza al post
+const (
+	WebmentionMention  = "mention"
+	WebmentionReply    = "reply"
+	WebmentionLike     = "like"
+	WebmentionRepost   = "repost"
+	WebmentionBookmark = "bookmark"
+)
+
+// Webmention is a notification that the page at Source links to one of our
+// posts. It is shown on the post once the source has been fetched and found
+// to really link to Target.
+type Webmention struct {
+	ID          int64      `db:"id"`
+	PostID      int64      `db:"post_id"`
+	Source      string     `db:"source"`
+	Target      string     `db:"target"`
+	Status      string     `db:"status"`
+	Type        string     `db:"type"`
+	AuthorName  string     `db:"author_name"`
+	AuthorURL   string     `db:"author_url"`
+	AuthorPhoto string     `db:"author_photo"`
+	Content     string     `db:"content"`
+	Published   *time.Time `db:"published"`
+	Attempts    int        `db:"attempts"`
+	// NextAttemptAt is when a pending webmention is due to be verified.
+	NextAttemptAt time.Time  `db:"next_attempt_at"`
+	CreatedAt     time.Time  `db:"created_at"`
+	UpdatedAt     time.Time  `db:"updated_at"`
+	VerifiedAt    *time.Time `db:"verified_at"`
+}
+
+// WebPage is a document fetched from another site.
+type WebPage struct {
+	URL         string
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+type JsonWebmentionAuthor struct {
+	Name  string `json:"name"`
+	URL   string `json:"url"`
+	Photo string `json:"photo,omitempty"`
+}
+
+type JsonWebmention struct {
+	ID         int64                `json:"id"`
+	Type       string               `json:"type"`
+	Source     string               `json:"source"`
+	Author     JsonWebmentionAuthor `json:"author"`
+	Content    string               `json:"content"`
+	Published  *time.Time           `json:"published,omitempty"`
+	VerifiedAt *time.Time           `json:"verified_at"`
+}
@@ -0,0 +1,289 @@
+package activitypub
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"postapi/internal/infrastructure/publicnet"
+	"testing"
+	"time"
+)
+
+func testKey(t *testing.T) (*rsa.PrivateKey, string, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	private := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	return key, string(private), string(public)
+}
+
+func signedRequest(t *testing.T, key *rsa.PrivateKey, body []byte, now time.Time) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "https://blog.example/users/alice/inbox", bytes.NewReader(body))
+	if err := SignRequest(req, body, "https://remote.example/users/bob#main-key", key, now); err != nil {
+		t.Fatalf("SignRequest() error = %v", err)
+	}
+	return req
+}
+
+func TestSignAndVerify(t *testing.T) {
+	key, _, public := testKey(t)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"type":"Follow"}`)
+
+	req := signedRequest(t, key, body, now)
+	sig, err := ParseSignature(req)
+	if err != nil {
+		t.Fatalf("ParseSignature() error = %v", err)
+	}
+	if sig.KeyID != "https://remote.example/users/bob#main-key" || len(sig.Headers) != 4 {
+		t.Errorf("ParseSignature() = %+v", sig)
+	}
+	if err := VerifyRequest(req, body, sig, public, now.Add(time.Minute)); err != nil {
+		t.Errorf("VerifyRequest() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(req *http.Request) ([]byte, time.Time)
+	}{
+		{"tampered body", func(req *http.Request) ([]byte, time.Time) {
+			return []byte(`{"type":"Delete"}`), now
+		}},
+		{"other target", func(req *http.Request) ([]byte, time.Time) {
+			req.URL.Path = "/users/carol/inbox"
+			return body, now
+		}},
+		{"stale date", func(req *http.Request) ([]byte, time.Time) {
+			return body, now.Add(MaxClockSkew + time.Minute)
+		}},
+		{"forged digest", func(req *http.Request) ([]byte, time.Time) {
+			forged := []byte(`{"type":"Delete"}`)
+			req.Header.Set("Digest", digest(forged))
+			return forged, now
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := signedRequest(t, key, body, now)
+			sig, _ := ParseSignature(req)
+			body, at := tt.modify(req)
+			if err := VerifyRequest(req, body, sig, public, at); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("VerifyRequest() error = %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
+
+func TestVerifyRequest_RequiredHeaders(t *testing.T) {
+	key, _, public := testKey(t)
+	now := time.Now()
+	req := signedRequest(t, key, nil, now)
+	sig, _ := ParseSignature(req)
+	sig.Headers = []string{"date"}
+
+	if err := VerifyRequest(req, nil, sig, public, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("VerifyRequest() error = %v, want ErrInvalidSignature", err)
+	}
+	if _, err := ParseSignature(httptest.NewRequest(http.MethodPost, "/", nil)); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("ParseSignature() error = %v, want ErrInvalidSignature", err)
+	}
+}
+
+// fakeRemote is a remote server with one actor whose inbox records the
+// activities it receives once their signature checks out.
+type fakeRemote struct {
+	*httptest.Server
+	key       *rsa.PrivateKey
+	public    string
+	senderPem string
+	received  [][]byte
+	fetches   int
+	// keyPath, when set, serves the actor at a URL of its own that is the
+	// ID of its key, instead of using a fragment of the actor ID.
+	keyPath string
+}
+
+func newFakeRemote(t *testing.T) *fakeRemote {
+	f := &fakeRemote{}
+	f.key, _, f.public = testKey(t)
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/bob", f.keyPath:
+			f.fetches++
+			json.NewEncoder(w).Encode(map[string]any{
+				"id":    f.ActorID(),
+				"type":  "Person",
+				"inbox": f.URL + "/users/bob/inbox",
+				"publicKey": map[string]string{
+					"id":           f.KeyID(),
+					"owner":        f.ActorID(),
+					"publicKeyPem": f.public,
+				},
+			})
+		case "/users/bob/inbox":
+			body, _ := io.ReadAll(r.Body)
+			sig, err := ParseSignature(r)
+			if err == nil {
+				err = VerifyRequest(r, body, sig, f.senderPem, time.Now())
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			f.received = append(f.received, body)
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeRemote) ActorID() string {
+	return f.URL + "/users/bob"
+}
+
+func (f *fakeRemote) KeyID() string {
+	if f.keyPath != "" {
+		return f.URL + f.keyPath
+	}
+	return f.ActorID() + "#main-key"
+}
+
+// Client returns a client for the remote. NewClient would refuse it, as it
+// listens on a loopback address.
+func (f *fakeRemote) Client() *Client {
+	return &Client{HTTP: f.Server.Client(), UserAgent: "postapi-test", Now: time.Now}
+}
+
+func TestClient_Deliver(t *testing.T) {
+	remote := newFakeRemote(t)
+	_, private, public := testKey(t)
+	remote.senderPem = public
+	client := remote.Client()
+
+	payload := []byte(`{"type":"Accept"}`)
+	if err := client.Deliver(remote.URL+"/users/bob/inbox", payload, "https://blog.example/users/alice#main-key", private); err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+	if len(remote.received) != 1 || !bytes.Equal(remote.received[0], payload) {
+		t.Errorf("remote received %q", remote.received)
+	}
+
+	_, other, _ := testKey(t)
+	if err := client.Deliver(remote.URL+"/users/bob/inbox", payload, "https://blog.example/users/alice#main-key", other); err == nil {
+		t.Error("Deliver() signed with the wrong key succeeded")
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	remote := newFakeRemote(t)
+	verifier := &Verifier{Client: remote.Client()}
+	body := []byte(`{"type":"Follow"}`)
+
+	req := httptest.NewRequest(http.MethodPost, "https://blog.example/users/alice/inbox", bytes.NewReader(body))
+	if err := SignRequest(req, body, remote.ActorID()+"#main-key", remote.key, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	actor, err := verifier.Verify(req, body)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if actor.ID != remote.ActorID() || actor.Inbox != remote.URL+"/users/bob/inbox" {
+		t.Errorf("Verify() actor = %+v", actor)
+	}
+
+	// A key the actor document does not list is rejected, once the actor
+	// has been fetched again in case it changed its key.
+	other, _, _ := testKey(t)
+	req = httptest.NewRequest(http.MethodPost, "https://blog.example/users/alice/inbox", bytes.NewReader(body))
+	SignRequest(req, body, remote.ActorID()+"#main-key", other, time.Now())
+	if _, err := verifier.Verify(req, body); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify() error = %v, want ErrInvalidSignature", err)
+	}
+	if remote.fetches != 2 {
+		t.Errorf("actor fetched %d times, want 2: once for each request", remote.fetches)
+	}
+
+	// The key is cached while signatures check out
+	req = httptest.NewRequest(http.MethodPost, "https://blog.example/users/alice/inbox", bytes.NewReader(body))
+	SignRequest(req, body, remote.ActorID()+"#main-key", remote.key, time.Now())
+	if _, err := verifier.Verify(req, body); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	if remote.fetches != 2 {
+		t.Errorf("actor fetched %d times, want 2 as the key is cached", remote.fetches)
+	}
+}
+
+func TestVerifier_RotatedKey(t *testing.T) {
+	remote := newFakeRemote(t)
+	verifier := &Verifier{Client: remote.Client()}
+	body := []byte(`{"type":"Follow"}`)
+
+	req := httptest.NewRequest(http.MethodPost, "https://blog.example/users/alice/inbox", bytes.NewReader(body))
+	SignRequest(req, body, remote.KeyID(), remote.key, time.Now())
+	if _, err := verifier.Verify(req, body); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	remote.key, _, remote.public = testKey(t)
+	req = httptest.NewRequest(http.MethodPost, "https://blog.example/users/alice/inbox", bytes.NewReader(body))
+	SignRequest(req, body, remote.KeyID(), remote.key, time.Now())
+	if _, err := verifier.Verify(req, body); err != nil {
+		t.Errorf("Verify() with the rotated key error = %v", err)
+	}
+	if remote.fetches != 2 {
+		t.Errorf("actor fetched %d times, want 2", remote.fetches)
+	}
+}
+
+func TestVerifier_KeyOfItsOwn(t *testing.T) {
+	remote := newFakeRemote(t)
+	remote.keyPath = "/users/bob/main-key"
+	verifier := &Verifier{Client: remote.Client()}
+	body := []byte(`{"type":"Follow"}`)
+
+	req := httptest.NewRequest(http.MethodPost, "https://blog.example/users/alice/inbox", bytes.NewReader(body))
+	SignRequest(req, body, remote.KeyID(), remote.key, time.Now())
+	actor, err := verifier.Verify(req, body)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if actor.ID != remote.ActorID() || actor.PublicKeyID != remote.KeyID() {
+		t.Errorf("Verify() actor = %+v, want %s with key %s", actor, remote.ActorID(), remote.KeyID())
+	}
+}
+
+func TestVerifier_RefusesPrivateKeyIDs(t *testing.T) {
+	key, _, _ := testKey(t)
+	verifier := &Verifier{Client: NewClient("postapi-test")}
+	body := []byte(`{"type":"Follow"}`)
+
+	for _, keyID := range []string{"http://127.0.0.1/users/bob#main-key", "http://169.254.169.254/latest/meta-data#key"} {
+		req := httptest.NewRequest(http.MethodPost, "https://blog.example/users/alice/inbox", bytes.NewReader(body))
+		if err := SignRequest(req, body, keyID, key, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		_, err := verifier.Verify(req, body)
+		if !errors.Is(err, ErrInvalidSignature) || !errors.Is(err, publicnet.ErrPrivateAddress) {
+			t.Errorf("Verify() with key %s error = %v, want ErrInvalidSignature and ErrPrivateAddress", keyID, err)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package activitypub
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"postapi/internal/domain"
+	"postapi/internal/infrastructure/publicnet"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxDocumentSize bounds the remote documents the client reads.
+const maxDocumentSize = 1 << 20
+
+const (
+	// actorCacheTTL is how long the Verifier keeps an actor it fetched
+	// while its signatures check out.
+	actorCacheTTL = time.Hour
+	// maxCachedActors bounds the actors the Verifier keeps.
+	maxCachedActors = 10000
+)
+
+// Client fetches remote actors and delivers activities to remote inboxes.
+// Actor and inbox URLs come from remote servers, so NewClient only connects
+// to public addresses.
+type Client struct {
+	HTTP      *http.Client
+	UserAgent string
+	Now       func() time.Time
+}
+
+func NewClient(userAgent string) *Client {
+	return &Client{
+		HTTP:      publicnet.NewHTTPClient(15 * time.Second),
+		UserAgent: userAgent,
+		Now:       time.Now,
+	}
+}
+
+type remoteActorDocument struct {
+	ID                string `json:"id"`
+	PreferredUsername string `json:"preferredUsername"`
+	Inbox             string `json:"inbox"`
+	Endpoints         struct {
+		SharedInbox string `json:"sharedInbox"`
+	} `json:"endpoints"`
+	PublicKey struct {
+		ID           string `json:"id"`
+		Owner        string `json:"owner"`
+		PublicKeyPem string `json:"publicKeyPem"`
+	} `json:"publicKey"`
+}
+
+// FetchActor retrieves the actor document at id, the ID of an actor or of
+// its key. Keys are either a fragment of the actor document, as in
+// ".../alice#main-key", or a URL of their own serving it, as in
+// ".../alice/main-key".
+func (c *Client) FetchActor(id string) (*domain.RemoteActor, error) {
+	keyID := id
+	id, _, _ = strings.Cut(id, "#")
+	req, err := http.NewRequest(http.MethodGet, id, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", domain.ActivityContentType+`, application/ld+json; profile="https://www.w3.org/ns/activitystreams"`)
+	req.Header.Set("User-Agent", c.UserAgent)
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch actor %s: %s", id, resp.Status)
+	}
+
+	var doc remoteActorDocument
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxDocumentSize)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("fetch actor %s: %w", id, err)
+	}
+	if doc.ID != id && (doc.PublicKey.ID != keyID || !sameOrigin(doc.ID, id)) {
+		return nil, fmt.Errorf("fetch actor %s: document has id %s", id, doc.ID)
+	}
+	if doc.Inbox == "" {
+		return nil, fmt.Errorf("fetch actor %s: no inbox", id)
+	}
+	if doc.PublicKey.Owner != "" && doc.PublicKey.Owner != doc.ID {
+		return nil, fmt.Errorf("fetch actor %s: key is owned by %s", id, doc.PublicKey.Owner)
+	}
+	return &domain.RemoteActor{
+		ID:                doc.ID,
+		PreferredUsername: doc.PreferredUsername,
+		Inbox:             doc.Inbox,
+		SharedInbox:       doc.Endpoints.SharedInbox,
+		PublicKeyID:       doc.PublicKey.ID,
+		PublicKeyPem:      doc.PublicKey.PublicKeyPem,
+	}, nil
+}
+
+// sameOrigin reports whether both URLs have the same scheme and host, so
+// that a server can only speak for its own actors.
+func sameOrigin(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return ua.Scheme == ub.Scheme && ua.Host == ub.Host
+}
+
+// Deliver posts a signed activity to a remote inbox.
+func (c *Client) Deliver(inbox string, payload []byte, keyID string, privateKeyPem string) error {
+	key, err := ParsePrivateKey(privateKeyPem)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, inbox, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", domain.ActivityContentType)
+	req.Header.Set("User-Agent", c.UserAgent)
+	if err := SignRequest(req, payload, keyID, key, c.Now()); err != nil {
+		return err
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxDocumentSize))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("deliver to %s: %s", inbox, resp.Status)
+	}
+	return nil
+}
+
+// Verifier authenticates requests to the inbox by their HTTP signature. It
+// caches the actors it fetches by key ID, so that a sender's key is not
+// fetched again for every activity.
+type Verifier struct {
+	Client *Client
+
+	mu     sync.Mutex
+	actors map[string]cachedActor
+}
+
+type cachedActor struct {
+	actor   *domain.RemoteActor
+	fetched time.Time
+}
+
+// Verify checks the signature of r and returns the remote actor that signed
+// it. A signature that fails against a cached key is checked again against
+// a fresh copy of the actor, in case its server rotated the key.
+func (v *Verifier) Verify(r *http.Request, body []byte) (*domain.RemoteActor, error) {
+	sig, err := ParseSignature(r)
+	if err != nil {
+		return nil, err
+	}
+	actor, cached, err := v.actor(sig.KeyID, false)
+	if err != nil {
+		return nil, errors.Join(ErrInvalidSignature, err)
+	}
+	err = v.check(r, body, sig, actor)
+	if err != nil && cached {
+		actor, _, err = v.actor(sig.KeyID, true)
+		if err != nil {
+			return nil, errors.Join(ErrInvalidSignature, err)
+		}
+		err = v.check(r, body, sig, actor)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return actor, nil
+}
+
+// check verifies the signature of r with the key of actor.
+func (v *Verifier) check(r *http.Request, body []byte, sig *Signature, actor *domain.RemoteActor) error {
+	if actor.PublicKeyID != sig.KeyID {
+		return fmt.Errorf("%w: key %s is not the key of %s", ErrInvalidSignature, sig.KeyID, actor.ID)
+	}
+	return VerifyRequest(r, body, sig, actor.PublicKeyPem, v.Client.Now())
+}
+
+// actor returns the actor owning keyID, from the cache while it is fresh
+// unless refresh is set, and whether it came from the cache.
+func (v *Verifier) actor(keyID string, refresh bool) (*domain.RemoteActor, bool, error) {
+	now := v.Client.Now()
+	v.mu.Lock()
+	cached, ok := v.actors[keyID]
+	v.mu.Unlock()
+	if ok && !refresh && now.Sub(cached.fetched) < actorCacheTTL {
+		return cached.actor, true, nil
+	}
+
+	actor, err := v.Client.FetchActor(keyID)
+	if err != nil {
+		return nil, false, err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.actors == nil {
+		v.actors = make(map[string]cachedActor)
+	}
+	if len(v.actors) >= maxCachedActors {
+		for id, c := range v.actors {
+			if now.Sub(c.fetched) >= actorCacheTTL {
+				delete(v.actors, id)
+			}
+		}
+		if len(v.actors) >= maxCachedActors {
+			clear(v.actors)
+		}
+	}
+	v.actors[keyID] = cachedActor{actor: actor, fetched: now}
+	return actor, false, nil
+}
@@ -0,0 +1,204 @@
+package activitypub
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MaxClockSkew is how far the Date of a signed request may be from now.
+const MaxClockSkew = 12 * time.Hour
+
+var ErrInvalidSignature = errors.New("invalid http signature")
+
+// Signature is a parsed Signature header, as defined by the
+// draft-cavage-http-signatures scheme used across the fediverse.
+type Signature struct {
+	KeyID     string
+	Algorithm string
+	Headers   []string
+	Value     []byte
+}
+
+// SignRequest adds Date, Digest (when there is a body) and Signature headers
+// to req, signing with the given key.
+func SignRequest(req *http.Request, body []byte, keyID string, key *rsa.PrivateKey, now time.Time) error {
+	if req.Header.Get("Date") == "" {
+		req.Header.Set("Date", now.UTC().Format(http.TimeFormat))
+	}
+	headers := []string{"(request-target)", "host", "date"}
+	if body != nil {
+		req.Header.Set("Digest", digest(body))
+		headers = append(headers, "digest")
+	}
+
+	signingString, err := buildSigningString(req, headers)
+	if err != nil {
+		return err
+	}
+	hashed := sha256.Sum256([]byte(signingString))
+	value, err := rsa.SignPKCS1v15(nil, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Signature", fmt.Sprintf(`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(value)))
+	return nil
+}
+
+// ParseSignature reads the Signature header of req.
+func ParseSignature(req *http.Request) (*Signature, error) {
+	header := req.Header.Get("Signature")
+	if header == "" {
+		return nil, fmt.Errorf("%w: missing Signature header", ErrInvalidSignature)
+	}
+
+	sig := &Signature{Headers: []string{"date"}}
+	for _, param := range strings.Split(header, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(value, `"`)
+		switch name {
+		case "keyId":
+			sig.KeyID = value
+		case "algorithm":
+			sig.Algorithm = value
+		case "headers":
+			sig.Headers = strings.Fields(strings.ToLower(value))
+		case "signature":
+			decoded, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+			}
+			sig.Value = decoded
+		}
+	}
+	if sig.KeyID == "" || sig.Value == nil {
+		return nil, fmt.Errorf("%w: missing keyId or signature", ErrInvalidSignature)
+	}
+	return sig, nil
+}
+
+// VerifyRequest checks sig against req and its body. The signature has to
+// cover the request target, host and date, and the digest when there is a
+// body, so that it cannot be replayed elsewhere or with another payload.
+func VerifyRequest(req *http.Request, body []byte, sig *Signature, publicKeyPem string, now time.Time) error {
+	if sig.Algorithm != "" && sig.Algorithm != "rsa-sha256" && sig.Algorithm != "hs2019" {
+		return fmt.Errorf("%w: unsupported algorithm %s", ErrInvalidSignature, sig.Algorithm)
+	}
+	required := []string{"(request-target)", "host", "date"}
+	if len(body) > 0 {
+		required = append(required, "digest")
+	}
+	for _, h := range required {
+		if !slices.Contains(sig.Headers, h) {
+			return fmt.Errorf("%w: %s is not signed", ErrInvalidSignature, h)
+		}
+	}
+
+	date, err := http.ParseTime(req.Header.Get("Date"))
+	if err != nil {
+		return fmt.Errorf("%w: bad Date header", ErrInvalidSignature)
+	}
+	if skew := now.Sub(date); skew > MaxClockSkew || skew < -MaxClockSkew {
+		return fmt.Errorf("%w: Date is too far from now", ErrInvalidSignature)
+	}
+	if len(body) > 0 && req.Header.Get("Digest") != digest(body) {
+		return fmt.Errorf("%w: Digest does not match the body", ErrInvalidSignature)
+	}
+
+	key, err := ParsePublicKey(publicKeyPem)
+	if err != nil {
+		return err
+	}
+	signingString, err := buildSigningString(req, sig.Headers)
+	if err != nil {
+		return err
+	}
+	hashed := sha256.Sum256([]byte(signingString))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], sig.Value); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	return nil
+}
+
+func buildSigningString(req *http.Request, headers []string) (string, error) {
+	lines := make([]string, len(headers))
+	for i, h := range headers {
+		var value string
+		switch h {
+		case "(request-target)":
+			value = strings.ToLower(req.Method) + " " + req.URL.RequestURI()
+		case "host":
+			value = req.Host
+			if value == "" {
+				value = req.URL.Host
+			}
+		default:
+			values := req.Header.Values(h)
+			if len(values) == 0 {
+				return "", fmt.Errorf("%w: signed header %s is missing", ErrInvalidSignature, h)
+			}
+			value = strings.Join(values, ", ")
+		}
+		lines[i] = h + ": " + value
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func digest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// ParsePrivateKey reads a PKCS#8 or PKCS#1 PEM encoded RSA private key.
+func ParsePrivateKey(privateKeyPem string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privateKeyPem))
+	if block == nil {
+		return nil, errors.New("no PEM data in private key")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not RSA")
+	}
+	return key, nil
+}
+
+// ParsePublicKey reads a PKIX or PKCS#1 PEM encoded RSA public key.
+func ParsePublicKey(publicKeyPem string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicKeyPem))
+	if block == nil {
+		return nil, errors.New("no PEM data in public key")
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not RSA")
+	}
+	return key, nil
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"postapi/internal/application"
+	models "postapi/internal/domain"
+	"postapi/internal/middleware"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// maxActivitySize bounds the body of activities posted to an inbox.
+const maxActivitySize = 1 << 20
+
+// SignatureVerifier authenticates a signed request and returns the remote
+// actor that signed it.
+type SignatureVerifier interface {
+	Verify(r *http.Request, body []byte) (*models.RemoteActor, error)
+}
+
+type ActivityPubHandler struct {
+	FederationUseCase application.FederationUseCase
+	Verifier          SignatureVerifier
+}
+
+func sendActivityJSON(w http.ResponseWriter, contentType string, data any, status int) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		log.Printf("Cannot format json. err = %v\n", err)
+	}
+}
+
+func (a *ActivityPubHandler) WebFingerHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resource := r.URL.Query().Get("resource")
+		if resource == "" {
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidParameter, "resource required")
+			return
+		}
+		jrd, err := a.FederationUseCase.WebFinger(r.Context(), resource)
+		if err != nil {
+			middleware.SendError(w, r, err, "Failed to resolve resource")
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		sendActivityJSON(w, "application/jrd+json", jrd, http.StatusOK)
+	}
+}
+
+func (a *ActivityPubHandler) ActorHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		actor, err := a.FederationUseCase.Actor(r.Context(), mux.Vars(r)["username"])
+		if err != nil {
+			middleware.SendError(w, r, err, "Failed to get actor")
+			return
+		}
+		sendActivityJSON(w, models.ActivityContentType, actor, http.StatusOK)
+	}
+}
+
+func (a *ActivityPubHandler) OutboxHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page := 0
+		if p := r.URL.Query().Get("page"); p != "" {
+			n, err := strconv.Atoi(p)
+			if err != nil || n < 1 {
+				middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidParameter, "Invalid page")
+				return
+			}
+			page = n
+		}
+		outbox, err := a.FederationUseCase.Outbox(r.Context(), mux.Vars(r)["username"], page)
+		if err != nil {
+			middleware.SendError(w, r, err, "Failed to get outbox")
+			return
+		}
+		sendActivityJSON(w, models.ActivityContentType, outbox, http.StatusOK)
+	}
+}
+
+func (a *ActivityPubHandler) FollowersHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		followers, err := a.FederationUseCase.Followers(r.Context(), mux.Vars(r)["username"])
+		if err != nil {
+			middleware.SendError(w, r, err, "Failed to get followers")
+			return
+		}
+		sendActivityJSON(w, models.ActivityContentType, followers, http.StatusOK)
+	}
+}
+
+func (a *ActivityPubHandler) NoteHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		postID, err := strconv.ParseInt(vars["post_id"], 10, 64)
+		if err != nil {
+			middleware.SendProblem(w, r, http.StatusNotFound, models.CodeNotFound, "Not found")
+			return
+		}
+		note, err := a.FederationUseCase.FindNote(r.Context(), vars["username"], postID)
+		if err != nil {
+			middleware.SendError(w, r, err, "Failed to get note")
+			return
+		}
+		sendActivityJSON(w, models.ActivityContentType, note, http.StatusOK)
+	}
+}
+
+// InboxHandler accepts activities signed by a remote actor.
+func (a *ActivityPubHandler) InboxHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxActivitySize))
+		if err != nil {
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidBody, "Invalid request body")
+			return
+		}
+
+		signer, err := a.Verifier.Verify(r, body)
+		if err != nil {
+			log.Printf("Rejected inbox request. err = %v\n", err)
+			middleware.SendProblem(w, r, http.StatusUnauthorized, models.CodeInvalidSignature, "Invalid signature")
+			return
+		}
+
+		err = a.FederationUseCase.HandleActivity(r.Context(), mux.Vars(r)["username"], signer, body)
+		if err != nil {
+			middleware.SendError(w, r, err, "Failed to handle activity")
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
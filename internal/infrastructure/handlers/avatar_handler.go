@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"postapi/internal/application"
+	models "postapi/internal/domain"
+	"postapi/internal/middleware"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultAvatarSize = 256
+	maxAvatarSize     = 1024
+)
+
+type AvatarHandler struct{}
+
+// GetAvatarHandler serves the generated avatar of a username, as SVG by
+// default or as PNG with ?format=png&size=N.
+func (a *AvatarHandler) GetAvatarHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username := mux.Vars(r)["username"]
+		if username == "" {
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidParameter, "Username required")
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "svg"
+		}
+		size := defaultAvatarSize
+		if s := r.URL.Query().Get("size"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 || n > maxAvatarSize {
+				middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidParameter, fmt.Sprintf("size must be between 1 and %d", maxAvatarSize))
+				return
+			}
+			size = n
+		}
+
+		ident := application.NewIdenticon(username)
+		var body []byte
+		switch format {
+		case "svg":
+			w.Header().Set("Content-Type", "image/svg+xml")
+			body = ident.SVG()
+		case "png":
+			png, err := ident.PNG(size)
+			if err != nil {
+				middleware.SendError(w, r, err, "Failed to render avatar")
+				return
+			}
+			w.Header().Set("Content-Type", "image/png")
+			body = png
+		default:
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidParameter, "format must be svg or png")
+			return
+		}
+
+		// Avatars only depend on the request, so they can be cached for long.
+		sum := sha256.Sum256(body)
+		etag := fmt.Sprintf(`"%x"`, sum[:16])
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write(body)
+	}
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"postapi/internal/application"
+	models "postapi/internal/domain"
+	"postapi/internal/middleware"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type ConversationHandler struct {
+	ConversationUseCase application.ConversationUseCase
+	EventUseCase        application.EventUseCase
+}
+
+func parseConversationID(r *http.Request) (int64, error) {
+	id := mux.Vars(r)["conversation_id"]
+	idAsNumber, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid ID %s", id)
+	}
+	return idAsNumber, nil
+}
+
+func (ch *ConversationHandler) CreateConversationHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := r.Context().Value(middleware.UsernameKey).(string)
+		if !ok {
+			middleware.SendProblem(w, r, http.StatusUnauthorized, models.CodeUnauthorized, "Unauthorized")
+			return
+		}
+
+		req := models.ConversationRequest{}
+		err := middleware.Parse(w, r, &req)
+		if err != nil {
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidBody, "Invalid request body")
+			return
+		}
+
+		conversation, err := ch.ConversationUseCase.StartConversation(r.Context(), username, req.Members)
+		if err != nil {
+			middleware.SendError(w, r, err, "Failed to create conversation")
+			return
+		}
+
+		resp := application.MapConversationToJson(conversation)
+		middleware.SendResponse(w, r, resp, http.StatusOK)
+	}
+}
+
+func (ch *ConversationHandler) GetConversationsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := r.Context().Value(middleware.UsernameKey).(string)
+		if !ok {
+			middleware.SendProblem(w, r, http.StatusUnauthorized, models.CodeUnauthorized, "Unauthorized")
+			return
+		}
+		limit, offset, err := parsePagination(r)
+		if err != nil {
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidParameter, err.Error())
+			return
+		}
+
+		conversations, err := ch.ConversationUseCase.ConversationRepo.FindByMember(r.Context(), username, limit, offset)
+		if err != nil {
+			middleware.SendError(w, r, err, "Failed to get conversations")
+			return
+		}
+		var resp = make([]models.JsonConversation, len(conversations))
+		for idx, conversation := range conversations {
+			resp[idx] = application.MapConversationToJson(conversation)
+		}
+		middleware.SendResponse(w, r, resp, http.StatusOK)
+	}
+}
+
+func (ch *ConversationHandler) GetMessagesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := r.Context().Value(middleware.UsernameKey).(string)
+		if !ok {
+			middleware.SendProblem(w, r, http.StatusUnauthorized, models.CodeUnauthorized, "Unauthorized")
+			return
+		}
+		conversationID, err := parseConversationID(r)
+		if err != nil {
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidParameter, err.Error())
+			return
+		}
+		limit, _, err := parsePagination(r)
+		if err != nil {
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidParameter, err.Error())
+			return
+		}
+		var before int64
+		if v := r.URL.Query().Get("before"); v != "" {
+			before, err = strconv.ParseInt(v, 10, 64)
+			if err != nil || before < 0 {
+				middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidParameter, fmt.Sprintf("Invalid cursor %s", v))
+				return
+			}
+		}
+
+		if _, err := ch.ConversationUseCase.GetConversation(r.Context(), conversationID, username); err != nil {
+			middleware.SendError(w, r, err, "Failed to get messages")
+			return
+		}
+		messages, err := ch.ConversationUseCase.ConversationRepo.FindMessages(r.Context(), conversationID, before, limit)
+		if err != nil {
+			middleware.SendError(w, r, err, "Failed to get messages")
+			return
+		}
+
+		resp := models.JsonMessagePage{Messages: make([]models.JsonMessage, len(messages))}
+		for idx, message := range messages {
+			resp.Messages[idx] = application.MapMessageToJson(message)
+		}
+		if len(messages) == limit {
+			next := messages[len(messages)-1].ID
+			resp.NextCursor = &next
+		}
+		middleware.SendResponse(w, r, resp, http.StatusOK)
+	}
+}
+
+func (ch *ConversationHandler) SendMessageHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := r.Context().Value(middleware.UsernameKey).(string)
+		if !ok {
+			middleware.SendProblem(w, r, http.StatusUnauthorized, models.CodeUnauthorized, "Unauthorized")
+			return
+		}
+		conversationID, err := parseConversationID(r)
+		if err != nil {
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidParameter, err.Error())
+			return
+		}
+
+		req := models.MessageRequest{}
+		err = middleware.Parse(w, r, &req)
+		if err != nil {
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidBody, "Invalid request body")
+			return
+		}
+
+		message, conversation, err := ch.ConversationUseCase.SendMessage(r.Context(), conversationID, username, req.Body)
+		if err != nil {
+			middleware.SendError(w, r, err, "Failed to send message")
+			return
+		}
+		ch.EventUseCase.MessageSent(message, conversation.Members)
+
+		resp := application.MapMessageToJson(message)
+		middleware.SendResponse(w, r, resp, http.StatusOK)
+	}
+}
+
+func (ch *ConversationHandler) MarkReadHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := r.Context().Value(middleware.UsernameKey).(string)
+		if !ok {
+			middleware.SendProblem(w, r, http.StatusUnauthorized, models.CodeUnauthorized, "Unauthorized")
+			return
+		}
+		conversationID, err := parseConversationID(r)
+		if err != nil {
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidParameter, err.Error())
+			return
+		}
+
+		// Sin cuerpo se marca como leída la conversación entera
+		req := models.MessageReadRequest{}
+		if r.ContentLength != 0 {
+			if err := middleware.Parse(w, r, &req); err != nil {
+				middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidBody, "Invalid request body")
+				return
+			}
+		}
+
+		err = ch.ConversationUseCase.MarkRead(r.Context(), conversationID, username, req.MessageID)
+		if err != nil {
+			middleware.SendError(w, r, err, "Failed to mark conversation as read")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
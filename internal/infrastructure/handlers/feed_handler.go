@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"postapi/internal/application"
+	"postapi/internal/middleware"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Feed formats served by GetFeedHandler.
+const (
+	FeedRSS  = "rss"
+	FeedAtom = "atom"
+	FeedJSON = "json"
+)
+
+var feedEncoders = map[string]struct {
+	contentType string
+	encode      func(*application.Feed) ([]byte, error)
+}{
+	FeedRSS:  {"application/rss+xml; charset=utf-8", application.EncodeRSS},
+	FeedAtom: {"application/atom+xml; charset=utf-8", application.EncodeAtom},
+	FeedJSON: {"application/feed+json; charset=utf-8", application.EncodeJSONFeed},
+}
+
+type FeedHandler struct {
+	PostUseCase application.PostUseCase
+	// BaseURL is the public root of the site used in feed links. It is never
+	// taken from the request: feeds are cached publicly, and the Host header
+	// is up to the client.
+	BaseURL string
+}
+
+// GetFeedHandler serves the latest posts of a user in the given format. The
+// ETag covers the whole document, so edits are noticed as well as new posts;
+// Last-Modified is the date of the newest post.
+func (f *FeedHandler) GetFeedHandler(format string) http.HandlerFunc {
+	encoder, ok := feedEncoders[format]
+	if !ok {
+		panic(fmt.Sprintf("unknown feed format %q", format))
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		username := mux.Vars(r)["username"]
+
+		posts, err := f.PostUseCase.Feed(r.Context(), username)
+		if err != nil {
+			middleware.SendError(w, r, err, "Failed to get feed")
+			return
+		}
+
+		base := strings.TrimRight(f.BaseURL, "/")
+		feed := application.NewUserFeed(username, base, base+r.URL.Path, posts)
+		body, err := encoder.encode(feed)
+		if err != nil {
+			middleware.SendError(w, r, err, "Failed to get feed")
+			return
+		}
+
+		sum := sha256.Sum256(body)
+		w.Header().Set("Content-Type", encoder.contentType)
+		w.Header().Set("ETag", fmt.Sprintf(`"%x"`, sum[:16]))
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		// ServeContent answers If-None-Match and If-Modified-Since with 304.
+		http.ServeContent(w, r, "", feed.Updated, bytes.NewReader(body))
+	}
+}
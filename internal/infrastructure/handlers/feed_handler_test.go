@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"postapi/internal/application"
+	"postapi/internal/domain"
+	"postapi/internal/infrastructure/memory"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestFeedHandler_IgnoresHost(t *testing.T) {
+	repos := memory.New().Repositories()
+	if err := repos.Users.Create(context.Background(), &domain.User{Username: "alice", Email: "alice@example.com", Password: "secret"}); err != nil {
+		t.Fatalf("Users.Create() error = %v", err)
+	}
+	post := &domain.Post{Title: "Hello", Content: "First post", Format: domain.PostFormatPlain, Author: "alice", CreatedAt: time.Now().UTC()}
+	if err := repos.Posts.Create(context.Background(), post); err != nil {
+		t.Fatalf("Posts.Create() error = %v", err)
+	}
+	fh := &FeedHandler{PostUseCase: application.PostUseCase{PostRepo: repos.Posts, UserRepo: repos.Users}, BaseURL: "https://blog.example/"}
+
+	for _, format := range []string{FeedRSS, FeedAtom, FeedJSON} {
+		r := httptest.NewRequest(http.MethodGet, "/users/alice/feed."+format, nil)
+		r.Host = "evil.example"
+		r.Header.Set("X-Forwarded-Proto", "http")
+		r = mux.SetURLVars(r, map[string]string{"username": "alice"})
+		w := httptest.NewRecorder()
+		fh.GetFeedHandler(format)(w, r)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s feed status = %d, body = %s", format, w.Code, w.Body)
+		}
+		body := w.Body.String()
+		if strings.Contains(body, "evil.example") || !strings.Contains(body, "https://blog.example/users/alice/feed."+format) {
+			t.Errorf("%s feed links are not built from BaseURL: %s", format, body)
+		}
+	}
+}
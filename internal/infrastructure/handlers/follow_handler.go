@@ -11,30 +11,33 @@ import (
 )
 
 type FollowHandler struct {
-	UserUseCase application.UserUseCase
+	UserUseCase         application.UserUseCase
+	NotificationUseCase application.NotificationUseCase
+	EventUseCase        application.EventUseCase
 }
 
 func (fh *FollowHandler) FollowHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		username, ok := r.Context().Value(middleware.UsernameKey).(string)
 		if !ok {
-			middleware.SendResponse(w, r, map[string]string{"error": "Unauthorized"}, http.StatusUnauthorized)
+			middleware.SendProblem(w, r, http.StatusUnauthorized, models.CodeUnauthorized, "Unauthorized")
 			return
 		}
 		vars := mux.Vars(r)
 		followed := vars["username"]
-		f := &models.UserFollow{
-			FollowerUsername: username,
-			FollowedUsername: followed,
-		}
 
-		err := fh.UserUseCase.FollowRepo.Create(f)
+		f, err := fh.UserUseCase.Follow(r.Context(), username, followed)
 		if err != nil {
-			log.Printf("Cannot save follow in DB. err = %v\n", err)
-			middleware.SendResponse(w, r, map[string]string{"error": "Failed to create follow"}, http.StatusInternalServerError)
+			middleware.SendError(w, r, err, "Failed to create follow")
 			return
 		}
 
+		_, err = fh.NotificationUseCase.Notify(r.Context(), followed, username, models.NotificationFollow, nil)
+		if err != nil {
+			log.Printf("Cannot save follow notification. err = %v\n", err)
+		}
+		fh.EventUseCase.Followed(f)
+
 		resp := application.MapFollowToJson(f)
 		middleware.SendResponse(w, r, resp, http.StatusOK)
 	}
@@ -44,23 +47,16 @@ func (fh *FollowHandler) UnfollowHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		username, ok := r.Context().Value(middleware.UsernameKey).(string)
 		if !ok {
-			middleware.SendResponse(w, r, map[string]string{"error": "Unauthorized"}, http.StatusUnauthorized)
+			middleware.SendProblem(w, r, http.StatusUnauthorized, models.CodeUnauthorized, "Unauthorized")
 			return
 		}
 
 		vars := mux.Vars(r)
 		unfollowed := vars["username"]
 
-		f := &models.UserFollow{
-			FollowerUsername: username,
-			FollowedUsername: unfollowed,
-		}
-
-		err := fh.UserUseCase.FollowRepo.Delete(f)
-
+		f, err := fh.UserUseCase.Unfollow(r.Context(), username, unfollowed)
 		if err != nil {
-			log.Printf("Cannot remove follow in DB. err = %v\n", err)
-			middleware.SendResponse(w, r, map[string]string{"error": "Failed to remove follow"}, http.StatusInternalServerError)
+			middleware.SendError(w, r, err, "Failed to remove follow")
 			return
 		}
 
@@ -74,31 +70,19 @@ func (fh *FollowHandler) GetFollowersHandler() http.HandlerFunc {
 		vars := mux.Vars(r)
 		username := vars["username"]
 		if username == "" {
-			middleware.SendResponse(w, r, map[string]string{"error": "Username required"}, http.StatusBadRequest)
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidParameter, "Username required")
 			return
 		}
-		usernames, err := fh.UserUseCase.FollowRepo.GetFollowers(username)
-
+		users, err := fh.UserUseCase.Followers(r.Context(), username)
 		if err != nil {
-			log.Printf("Cannot get followers from DB. err = %v\n", err)
-			middleware.SendResponse(w, r, map[string]string{"error": "Failed to get followers"}, http.StatusInternalServerError)
+			middleware.SendError(w, r, err, "Failed to get followers")
 			return
 		}
-		users := make([]models.JsonUser, len(usernames))
-		for i, followerUsername := range usernames {
-			user, err := fh.UserUseCase.UserRepo.FindByUsername(followerUsername)
-
-			if err != nil {
-				log.Printf("Cannot get follower from DB. err = %v\n", err)
-				middleware.SendResponse(w, r, map[string]string{"error": "Failed to get follower details"}, http.StatusInternalServerError)
-				return
-			}
-
-			users[i] = application.MapUserToJson(user)
-
+		resp := make([]models.JsonUserSummary, len(users))
+		for i, user := range users {
+			resp[i] = application.MapUserSummaryToJson(user)
 		}
-
-		middleware.SendResponse(w, r, users, http.StatusOK)
+		middleware.SendResponse(w, r, resp, http.StatusOK)
 	}
 }
 
@@ -107,30 +91,58 @@ func (fh *FollowHandler) GetFollowingHandler() http.HandlerFunc {
 		vars := mux.Vars(r)
 		username := vars["username"]
 		if username == "" {
-			middleware.SendResponse(w, r, map[string]string{"error": "Username required"}, http.StatusBadRequest)
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidParameter, "Username required")
+			return
+		}
+		users, err := fh.UserUseCase.Following(r.Context(), username)
+		if err != nil {
+			middleware.SendError(w, r, err, "Failed to get followings")
 			return
 		}
-		usernames, err := fh.UserUseCase.FollowRepo.GetFollowing(username)
+		resp := make([]models.JsonUserSummary, len(users))
+		for i, user := range users {
+			resp[i] = application.MapUserSummaryToJson(user)
+		}
+		middleware.SendResponse(w, r, resp, http.StatusOK)
+	}
+}
 
+func (fh *FollowHandler) BlockHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := r.Context().Value(middleware.UsernameKey).(string)
+		if !ok {
+			middleware.SendProblem(w, r, http.StatusUnauthorized, models.CodeUnauthorized, "Unauthorized")
+			return
+		}
+		vars := mux.Vars(r)
+
+		b, err := fh.UserUseCase.Block(r.Context(), username, vars["username"])
 		if err != nil {
-			log.Printf("Cannot get followings from DB. err = %v\n", err)
-			middleware.SendResponse(w, r, map[string]string{"error": "Failed to get followings"}, http.StatusInternalServerError)
+			middleware.SendError(w, r, err, "Failed to block user")
 			return
 		}
-		users := make([]models.JsonUser, len(usernames))
-		for i, followingUsername := range usernames {
-			user, err := fh.UserUseCase.UserRepo.FindByUsername(followingUsername)
 
-			if err != nil {
-				log.Printf("Cannot get following from DB. err = %v\n", err)
-				middleware.SendResponse(w, r, map[string]string{"error": "Failed to get following details"}, http.StatusInternalServerError)
-				return
-			}
+		resp := application.MapBlockToJson(b)
+		middleware.SendResponse(w, r, resp, http.StatusOK)
+	}
+}
 
-			users[i] = application.MapUserToJson(user)
+func (fh *FollowHandler) UnblockHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := r.Context().Value(middleware.UsernameKey).(string)
+		if !ok {
+			middleware.SendProblem(w, r, http.StatusUnauthorized, models.CodeUnauthorized, "Unauthorized")
+			return
+		}
+		vars := mux.Vars(r)
 
+		b, err := fh.UserUseCase.Unblock(r.Context(), username, vars["username"])
+		if err != nil {
+			middleware.SendError(w, r, err, "Failed to unblock user")
+			return
 		}
 
-		middleware.SendResponse(w, r, users, http.StatusOK)
+		resp := application.MapBlockToJson(b)
+		middleware.SendResponse(w, r, resp, http.StatusOK)
 	}
 }
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"postapi/internal/application"
+	"postapi/internal/domain"
+	"postapi/internal/infrastructure/memory"
+	"postapi/internal/middleware"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+type nopPublisher struct{}
+
+func (nopPublisher) Publish(eventType string, data any, topics ...string) {}
+
+// newFollowHandler returns a FollowHandler backed by an in-memory store
+// with the users alice and bob.
+func newFollowHandler(t *testing.T) (*FollowHandler, domain.Repositories) {
+	t.Helper()
+	store := memory.New()
+	repos := store.Repositories()
+	for _, username := range []string{"alice", "bob"} {
+		user := &domain.User{Username: username, Email: username + "@example.com", Password: "secret"}
+		if err := repos.Users.Create(context.Background(), user); err != nil {
+			t.Fatalf("Users.Create() error = %v", err)
+		}
+	}
+	events := &application.EventUseCase{Publisher: nopPublisher{}, FollowRepo: repos.Follows}
+	return &FollowHandler{
+		UserUseCase: application.UserUseCase{
+			UserRepo:   repos.Users,
+			FollowRepo: repos.Follows,
+			BlockRepo:  repos.Blocks,
+			UnitOfWork: store.UnitOfWork(),
+		},
+		NotificationUseCase: application.NotificationUseCase{NotificationRepo: repos.Notifications, Events: events},
+		EventUseCase:        *events,
+	}, repos
+}
+
+func serve(h http.HandlerFunc, method string, username string, target string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/", nil)
+	if username != "" {
+		r = r.WithContext(context.WithValue(r.Context(), middleware.UsernameKey, username))
+	}
+	r = mux.SetURLVars(r, map[string]string{"username": target})
+	w := httptest.NewRecorder()
+	h(w, r)
+	return w
+}
+
+func TestFollowHandler(t *testing.T) {
+	fh, repos := newFollowHandler(t)
+
+	if w := serve(fh.FollowHandler(), http.MethodPost, "alice", "bob"); w.Code != http.StatusOK {
+		t.Fatalf("FollowHandler() status = %d, body = %s", w.Code, w.Body)
+	}
+	if w := serve(fh.FollowHandler(), http.MethodPost, "alice", "bob"); w.Code != http.StatusConflict {
+		t.Errorf("FollowHandler() again status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	if w := serve(fh.FollowHandler(), http.MethodPost, "alice", "ghost"); w.Code != http.StatusNotFound {
+		t.Errorf("FollowHandler() of an unknown user status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	w := serve(fh.GetFollowersHandler(), http.MethodGet, "", "bob")
+	var followers []domain.JsonUserSummary
+	if err := json.NewDecoder(w.Body).Decode(&followers); err != nil {
+		t.Fatalf("GetFollowersHandler() body: %v", err)
+	}
+	if len(followers) != 1 || followers[0].Username != "alice" || followers[0].FollowingCount != 1 ||
+		followers[0].ProfilePicture != application.DefaultAvatarURL("alice") {
+		t.Errorf("GetFollowersHandler() = %+v, want alice following 1 user with the default avatar", followers)
+	}
+	if count, _ := repos.Notifications.CountUnread(context.Background(), "bob"); count != 1 {
+		t.Errorf("bob has %d unread notifications, want 1", count)
+	}
+}
+
+func TestFollowHandler_Block(t *testing.T) {
+	fh, repos := newFollowHandler(t)
+
+	serve(fh.FollowHandler(), http.MethodPost, "bob", "alice")
+	if w := serve(fh.BlockHandler(), http.MethodPost, "alice", "bob"); w.Code != http.StatusOK {
+		t.Fatalf("BlockHandler() status = %d, body = %s", w.Code, w.Body)
+	}
+	if followers, _ := repos.Follows.GetFollowers(context.Background(), "alice"); len(followers) != 0 {
+		t.Errorf("alice is still followed by %v after blocking", followers)
+	}
+	if w := serve(fh.FollowHandler(), http.MethodPost, "bob", "alice"); w.Code != http.StatusForbidden {
+		t.Errorf("FollowHandler() of a blocker status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
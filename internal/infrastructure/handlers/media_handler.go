@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"postapi/internal/application"
+	models "postapi/internal/domain"
+	"postapi/internal/infrastructure/storage"
+	"postapi/internal/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+type MediaHandler struct {
+	MediaUseCase application.MediaUseCase
+}
+
+func (m *MediaHandler) UploadMediaHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := r.Context().Value(middleware.UsernameKey).(string)
+		if !ok {
+			middleware.SendProblem(w, r, http.StatusUnauthorized, models.CodeUnauthorized, "Unauthorized")
+			return
+		}
+
+		media, ok := m.upload(w, r, username)
+		if !ok {
+			return
+		}
+
+		middleware.SendResponse(w, r, application.MapMediaToJson(media), http.StatusCreated)
+	}
+}
+
+// upload stores the file in the "file" field of a multipart request,
+// answering with an error when it cannot.
+func (m *MediaHandler) upload(w http.ResponseWriter, r *http.Request, username string) (*models.Media, bool) {
+	// Leave room for the multipart framing around the file itself.
+	r.Body = http.MaxBytesReader(w, r.Body, application.MaxMediaSize+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			middleware.SendError(w, r, application.ErrMediaTooLarge, "Failed to upload media")
+			return nil, false
+		}
+		middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidParameter, "Missing file")
+		return nil, false
+	}
+	defer file.Close()
+
+	media, err := m.MediaUseCase.Upload(r.Context(), username, file, header.Size)
+	if err != nil {
+		middleware.SendError(w, r, err, "Failed to upload media")
+		return nil, false
+	}
+
+	return media, true
+}
+
+func (m *MediaHandler) ServeMediaHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := mux.Vars(r)["key"]
+
+		blob, err := m.MediaUseCase.Open(r.Context(), key)
+		if errors.Is(err, application.ErrMediaNotFound) || errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			log.Printf("Cannot read media. err = %v\n", err)
+			http.Error(w, "Failed to read media", http.StatusInternalServerError)
+			return
+		}
+		defer blob.Close()
+
+		w.Header().Set("Content-Type", application.MediaContentType(key))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		// Keys are random and never reused, so the content never changes.
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		if _, err := io.Copy(w, blob); err != nil {
+			log.Printf("Cannot send media. err = %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"postapi/internal/application"
+	models "postapi/internal/domain"
+	"postapi/internal/middleware"
+	"strings"
+)
+
+// maxMicropubRequestSize bounds JSON and form requests. Multipart requests
+// may also carry a few photos.
+const (
+	maxMicropubRequestSize   = 1 << 20
+	maxMicropubMultipartSize = 4*application.MaxMediaSize + 1<<20
+)
+
+// MicropubHandler publishes posts from IndieWeb editors. Posts go through
+// the same steps as those created with the JSON API, so it uses the
+// PostHandler and MediaHandler for them.
+type MicropubHandler struct {
+	MicropubUseCase application.MicropubUseCase
+	Posts           *PostHandler
+	Media           *MediaHandler
+}
+
+// micropubJSONRequest is a JSON create, update, delete or undelete request.
+type micropubJSONRequest struct {
+	Type       []string                       `json:"type"`
+	Properties application.MicropubProperties `json:"properties"`
+	Action     string                         `json:"action"`
+	URL        string                         `json:"url"`
+	Replace    application.MicropubProperties `json:"replace"`
+	Add        application.MicropubProperties `json:"add"`
+	Delete     json.RawMessage                `json:"delete"`
+}
+
+// sendMicropubError answers with the error codes of the Micropub spec.
+func sendMicropubError(w http.ResponseWriter, r *http.Request, code, description string, status int) {
+	middleware.SendResponse(w, r, map[string]string{"error": code, "error_description": description}, status)
+}
+
+// sendError answers a domain error with the Micropub code of its kind. Other
+// errors are logged and answered with fallback.
+func (m *MicropubHandler) sendError(w http.ResponseWriter, r *http.Request, err error, fallback string) {
+	var domainErr *models.Error
+	if errors.As(err, &domainErr) {
+		switch domainErr.Kind {
+		case models.KindValidation, models.KindNotFound, models.KindConflict,
+			models.KindTooLarge, models.KindUnsupportedMedia:
+			sendMicropubError(w, r, "invalid_request", err.Error(), http.StatusBadRequest)
+			return
+		case models.KindForbidden:
+			sendMicropubError(w, r, "forbidden", err.Error(), http.StatusForbidden)
+			return
+		case models.KindUnauthorized:
+			sendMicropubError(w, r, "unauthorized", err.Error(), http.StatusUnauthorized)
+			return
+		}
+	}
+	log.Printf("%s. err = %v\n", fallback, err)
+	sendMicropubError(w, r, "server_error", fallback, http.StatusInternalServerError)
+}
+
+// QueryHandler answers q=config, q=source and q=syndicate-to.
+func (m *MicropubHandler) QueryHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := r.Context().Value(middleware.UsernameKey).(string)
+		if !ok {
+			sendMicropubError(w, r, "unauthorized", "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		query := r.URL.Query()
+		switch query.Get("q") {
+		case "config":
+			middleware.SendResponse(w, r, m.MicropubUseCase.Config(), http.StatusOK)
+		case "syndicate-to":
+			middleware.SendResponse(w, r, map[string]any{"syndicate-to": []any{}}, http.StatusOK)
+		case "source":
+			post, err := m.MicropubUseCase.FindPost(r.Context(), username, query.Get("url"))
+			if err != nil {
+				m.sendError(w, r, err, "Failed to get post")
+				return
+			}
+			if err := m.Posts.PostUseCase.LoadAttachments(r.Context(), post); err != nil {
+				m.sendError(w, r, err, "Failed to get post")
+				return
+			}
+			properties := append(query["properties[]"], query["properties"]...)
+			middleware.SendResponse(w, r, m.MicropubUseCase.Source(post, properties), http.StatusOK)
+		default:
+			sendMicropubError(w, r, "invalid_request", "unsupported query", http.StatusBadRequest)
+		}
+	}
+}
+
+// PostHandler creates, updates, deletes and undeletes posts.
+func (m *MicropubHandler) PostHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := r.Context().Value(middleware.UsernameKey).(string)
+		if !ok {
+			sendMicropubError(w, r, "unauthorized", "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType == "application/json" {
+			m.handleJSON(w, r, username)
+			return
+		}
+
+		if mediaType == "multipart/form-data" {
+			r.Body = http.MaxBytesReader(w, r.Body, maxMicropubMultipartSize)
+			err := r.ParseMultipartForm(32 << 20)
+			if err != nil {
+				sendMicropubError(w, r, "invalid_request", "Invalid request body", http.StatusBadRequest)
+				return
+			}
+		} else {
+			r.Body = http.MaxBytesReader(w, r.Body, maxMicropubRequestSize)
+			if err := r.ParseForm(); err != nil {
+				sendMicropubError(w, r, "invalid_request", "Invalid request body", http.StatusBadRequest)
+				return
+			}
+		}
+
+		switch action := r.PostForm.Get("action"); action {
+		case "":
+			if h := r.PostForm.Get("h"); h != "" && h != "entry" {
+				sendMicropubError(w, r, "invalid_request", "only h=entry is supported", http.StatusBadRequest)
+				return
+			}
+			props, ok := m.formProperties(w, r, username)
+			if !ok {
+				return
+			}
+			m.create(w, r, username, props)
+		case "delete", "undelete":
+			m.delete(w, r, username, action, r.PostForm.Get("url"))
+		default:
+			sendMicropubError(w, r, "invalid_request", "unsupported action "+action+" for a form request", http.StatusBadRequest)
+		}
+	}
+}
+
+func (m *MicropubHandler) handleJSON(w http.ResponseWriter, r *http.Request, username string) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxMicropubRequestSize)
+	req := micropubJSONRequest{}
+	if err := middleware.Parse(w, r, &req); err != nil {
+		sendMicropubError(w, r, "invalid_request", "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	switch req.Action {
+	case "":
+		if len(req.Type) != 1 || req.Type[0] != "h-entry" {
+			sendMicropubError(w, r, "invalid_request", "only h-entry is supported", http.StatusBadRequest)
+			return
+		}
+		m.create(w, r, username, req.Properties)
+	case "update":
+		update := &application.MicropubUpdate{Replace: req.Replace, Add: req.Add}
+		if len(req.Delete) > 0 && json.Unmarshal(req.Delete, &update.DeleteProperties) != nil {
+			if err := json.Unmarshal(req.Delete, &update.DeleteValues); err != nil {
+				sendMicropubError(w, r, "invalid_request", "delete must be a list of properties or an object", http.StatusBadRequest)
+				return
+			}
+		}
+		m.update(w, r, username, req.URL, update)
+	case "delete", "undelete":
+		m.delete(w, r, username, req.Action, req.URL)
+	default:
+		sendMicropubError(w, r, "invalid_request", "unsupported action "+req.Action, http.StatusBadRequest)
+	}
+}
+
+// formProperties collects the properties of a form, storing uploaded
+// photos and adding their URLs.
+func (m *MicropubHandler) formProperties(w http.ResponseWriter, r *http.Request, username string) (application.MicropubProperties, bool) {
+	props := application.MicropubProperties{}
+	for key, values := range r.PostForm {
+		if key == "h" || key == "access_token" || key == "action" || key == "url" {
+			continue
+		}
+		name := strings.TrimSuffix(key, "[]")
+		for _, v := range values {
+			props[name] = append(props[name], v)
+		}
+	}
+	if r.MultipartForm == nil {
+		return props, true
+	}
+
+	files := append(r.MultipartForm.File["photo"], r.MultipartForm.File["photo[]"]...)
+	for _, header := range files {
+		file, err := header.Open()
+		if err != nil {
+			sendMicropubError(w, r, "invalid_request", "Invalid photo", http.StatusBadRequest)
+			return nil, false
+		}
+		media, err := m.Media.MediaUseCase.Upload(r.Context(), username, file, header.Size)
+		file.Close()
+		if err != nil {
+			m.sendError(w, r, err, "Failed to upload media")
+			return nil, false
+		}
+		props["photo"] = append(props["photo"], m.MicropubUseCase.BaseURL+application.MediaURL(media))
+	}
+	return props, true
+}
+
+func (m *MicropubHandler) create(w http.ResponseWriter, r *http.Request, username string, props application.MicropubProperties) {
+	post, photos, err := m.MicropubUseCase.NewPost(r.Context(), username, props)
+	if err != nil {
+		m.sendError(w, r, err, "Failed to create post")
+		return
+	}
+	mentioned, err := m.Posts.PostUseCase.Publish(r.Context(), post, photos)
+	if err != nil {
+		m.sendError(w, r, err, "Failed to create post")
+		return
+	}
+	m.Posts.postCreated(r.Context(), post, mentioned)
+
+	w.Header().Set("Location", application.PostURL(m.MicropubUseCase.BaseURL, post.ID))
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (m *MicropubHandler) update(w http.ResponseWriter, r *http.Request, username, postURL string, update *application.MicropubUpdate) {
+	post, err := m.MicropubUseCase.FindPost(r.Context(), username, postURL)
+	if err != nil {
+		m.sendError(w, r, err, "Failed to get post")
+		return
+	}
+	previous := *post
+	if err := m.MicropubUseCase.ApplyUpdate(post, update); err != nil {
+		m.sendError(w, r, err, "Failed to update post")
+		return
+	}
+	mentioned, err := m.Posts.PostUseCase.Revise(r.Context(), post, nil)
+	if err != nil {
+		m.sendError(w, r, err, "Failed to update post")
+		return
+	}
+	m.Posts.postUpdated(r.Context(), post, &previous, mentioned)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// delete moves a post to the trash, or takes it out again for undelete.
+func (m *MicropubHandler) delete(w http.ResponseWriter, r *http.Request, username, action, postURL string) {
+	if action == "undelete" {
+		id, ok := application.PostIDFromURL(m.MicropubUseCase.BaseURL, postURL)
+		if !ok {
+			sendMicropubError(w, r, "invalid_request", postURL+" is not a post on this site", http.StatusBadRequest)
+			return
+		}
+		_, err := m.Posts.PostUseCase.Restore(r.Context(), username, id)
+		if errors.Is(err, models.ErrNotFound) {
+			sendMicropubError(w, r, "invalid_request", "the post is not in your trash", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			m.sendError(w, r, err, "Failed to restore post")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	post, err := m.MicropubUseCase.FindPost(r.Context(), username, postURL)
+	if err != nil {
+		m.sendError(w, r, err, "Failed to get post")
+		return
+	}
+	if _, err := m.Posts.PostUseCase.Delete(r.Context(), username, post.ID); err != nil {
+		m.sendError(w, r, err, "Failed to delete post")
+		return
+	}
+	m.Posts.postDeleted(r.Context(), post)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MediaEndpointHandler stores an uploaded file and answers with its URL in
+// the Location header.
+func (m *MicropubHandler) MediaEndpointHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := r.Context().Value(middleware.UsernameKey).(string)
+		if !ok {
+			sendMicropubError(w, r, "unauthorized", "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		media, ok := m.Media.upload(w, r, username)
+		if !ok {
+			return
+		}
+		w.Header().Set("Location", m.MicropubUseCase.BaseURL+application.MediaURL(media))
+		middleware.SendResponse(w, r, application.MapMediaToJson(media), http.StatusCreated)
+	}
+}
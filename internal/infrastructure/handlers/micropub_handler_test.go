@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"postapi/internal/application"
+	"postapi/internal/domain"
+	"testing"
+)
+
+func TestMicropubHandler_SendError(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantCode    string
+		description string
+	}{
+		{"invalid request", fmt.Errorf("%w: content is required", application.ErrInvalidMicropubRequest), http.StatusBadRequest, "invalid_request", "invalid micropub request: content is required"},
+		{"validation", domain.InvalidFields([]domain.FieldError{{Field: "title", Message: "title is too long"}}), http.StatusBadRequest, "invalid_request", "title is too long"},
+		{"unsupported photo", fmt.Errorf("%w: text/html", application.ErrUnsupportedMedia), http.StatusBadRequest, "invalid_request", "unsupported media type: text/html"},
+		{"not the author", application.ErrNotAuthor, http.StatusForbidden, "forbidden", application.ErrNotAuthor.Error()},
+		{"other error", errors.New("connection refused"), http.StatusInternalServerError, "server_error", "Failed to update post"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			(&MicropubHandler{}).sendError(rec, httptest.NewRequest(http.MethodPost, "/micropub", nil), tt.err, "Failed to update post")
+
+			var body map[string]string
+			json.NewDecoder(rec.Body).Decode(&body)
+			if rec.Code != tt.wantStatus || body["error"] != tt.wantCode || body["error_description"] != tt.description {
+				t.Errorf("sendError() = %d %v, want %d %s %q", rec.Code, body, tt.wantStatus, tt.wantCode, tt.description)
+			}
+		})
+	}
+}
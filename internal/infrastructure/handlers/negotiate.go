@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// prefersHTML tells whether the client asked for HTML over JSON, as
+// browsers and webmention verifiers do. Clients that send no Accept header,
+// or only */*, get JSON.
+func prefersHTML(r *http.Request) bool {
+	var html, json float64
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		switch mediaType {
+		case "text/html", "application/xhtml+xml":
+			html = max(html, q)
+		case "application/json", "*/*":
+			json = max(json, q)
+		}
+	}
+	return html > json
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+	"postapi/internal/application"
+	models "postapi/internal/domain"
+	"postapi/internal/middleware"
+)
+
+type NotificationHandler struct {
+	NotificationUseCase application.NotificationUseCase
+}
+
+func (nh *NotificationHandler) GetNotificationsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := r.Context().Value(middleware.UsernameKey).(string)
+		if !ok {
+			middleware.SendProblem(w, r, http.StatusUnauthorized, models.CodeUnauthorized, "Unauthorized")
+			return
+		}
+		limit, offset, err := parsePagination(r)
+		if err != nil {
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidParameter, err.Error())
+			return
+		}
+		unreadOnly := r.URL.Query().Get("unread") == "true"
+
+		notifications, err := nh.NotificationUseCase.NotificationRepo.FindByRecipient(r.Context(), username, unreadOnly, limit, offset)
+		if err != nil {
+			middleware.SendError(w, r, err, "Failed to get notifications")
+			return
+		}
+		var resp = make([]models.JsonNotification, len(notifications))
+		for idx, notification := range notifications {
+			resp[idx] = application.MapNotificationToJson(notification)
+		}
+		middleware.SendResponse(w, r, resp, http.StatusOK)
+	}
+}
+
+func (nh *NotificationHandler) MarkReadHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := r.Context().Value(middleware.UsernameKey).(string)
+		if !ok {
+			middleware.SendProblem(w, r, http.StatusUnauthorized, models.CodeUnauthorized, "Unauthorized")
+			return
+		}
+
+		req := models.NotificationReadRequest{}
+		err := middleware.Parse(w, r, &req)
+		if err != nil || (!req.All && len(req.IDs) == 0) {
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidBody, "Invalid request body")
+			return
+		}
+
+		var updated int64
+		if req.All {
+			updated, err = nh.NotificationUseCase.NotificationRepo.MarkAllRead(r.Context(), username)
+		} else {
+			updated, err = nh.NotificationUseCase.NotificationRepo.MarkRead(r.Context(), username, req.IDs)
+		}
+		if err != nil {
+			middleware.SendError(w, r, err, "Failed to mark notifications as read")
+			return
+		}
+		middleware.SendResponse(w, r, map[string]int64{"updated": updated}, http.StatusOK)
+	}
+}
+
+func (nh *NotificationHandler) GetUnreadCountHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := r.Context().Value(middleware.UsernameKey).(string)
+		if !ok {
+			middleware.SendProblem(w, r, http.StatusUnauthorized, models.CodeUnauthorized, "Unauthorized")
+			return
+		}
+
+		count, err := nh.NotificationUseCase.NotificationRepo.CountUnread(r.Context(), username)
+		if err != nil {
+			middleware.SendError(w, r, err, "Failed to count notifications")
+			return
+		}
+		middleware.SendResponse(w, r, map[string]int64{"unread": count}, http.StatusOK)
+	}
+}
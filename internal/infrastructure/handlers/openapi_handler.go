@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"net/http"
+	"postapi/internal/infrastructure/openapi"
+	"postapi/internal/middleware"
+)
+
+// OpenAPIHandler serves the OpenAPI document describing the API.
+type OpenAPIHandler struct {
+	Document *openapi.Document
+}
+
+func (o *OpenAPIHandler) GetDocumentHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		middleware.SendResponse(w, r, o.Document, http.StatusOK)
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
@@ -13,38 +14,82 @@ import (
 )
 
 type PostHandler struct {
-	PostUseCase application.PostUseCase
+	PostUseCase         application.PostUseCase
+	NotificationUseCase application.NotificationUseCase
+	EventUseCase        application.EventUseCase
+	FederationUseCase   application.FederationUseCase
+	WebmentionUseCase   application.WebmentionUseCase
+}
+
+func (p *PostHandler) notifyMentions(ctx context.Context, post *models.Post, mentioned []string) {
+	for _, username := range mentioned {
+		_, err := p.NotificationUseCase.Notify(ctx, username, post.Author, models.NotificationMention, &post.ID)
+		if err != nil {
+			log.Printf("Cannot save mention notification. err = %v\n", err)
+		}
+	}
+}
+
+// postCreated tells the mentioned users, followers, remote servers and the
+// pages it links to about a new post. Failures are only logged, as the post
+// has been saved.
+func (p *PostHandler) postCreated(ctx context.Context, post *models.Post, mentioned []string) {
+	p.notifyMentions(ctx, post, mentioned)
+	if err := p.EventUseCase.PostCreated(ctx, post); err != nil {
+		log.Printf("Cannot publish post. err = %v\n", err)
+	}
+	if err := p.FederationUseCase.PostCreated(ctx, post); err != nil {
+		log.Printf("Cannot federate post. err = %v\n", err)
+	}
+	p.sendWebmentions(post, nil)
+}
+
+// postUpdated is postCreated for edits; previous is the post before them.
+func (p *PostHandler) postUpdated(ctx context.Context, post, previous *models.Post, mentioned []string) {
+	p.notifyMentions(ctx, post, mentioned)
+	if err := p.FederationUseCase.PostUpdated(ctx, post); err != nil {
+		log.Printf("Cannot federate post. err = %v\n", err)
+	}
+	p.sendWebmentions(post, previous)
+}
+
+func (p *PostHandler) postDeleted(ctx context.Context, post *models.Post) {
+	if err := p.FederationUseCase.PostDeleted(ctx, post); err != nil {
+		log.Printf("Cannot federate post deletion. err = %v\n", err)
+	}
+}
+
+// sendWebmentions notifies the pages a post links to in the background, as
+// discovering and calling their endpoints can be slow.
+func (p *PostHandler) sendWebmentions(post, previous *models.Post) {
+	go func() {
+		if _, err := p.WebmentionUseCase.SendWebmentions(post, previous); err != nil {
+			log.Printf("Cannot send webmentions. err = %v\n", err)
+		}
+	}()
 }
 
 func (p *PostHandler) CreatePostHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		username, ok := r.Context().Value(middleware.UsernameKey).(string)
 		if !ok {
-			middleware.SendResponse(w, r, map[string]string{"error": "Unauthorized"}, http.StatusUnauthorized)
+			middleware.SendProblem(w, r, http.StatusUnauthorized, models.CodeUnauthorized, "Unauthorized")
 			return
 		}
 
 		req := models.PostRequest{}
 		err := middleware.Parse(w, r, &req)
 		if err != nil {
-			log.Printf("Cannot middleware.Parse body. err = %v \n", err)
-			middleware.SendResponse(w, r, map[string]string{"error": "Invalid request body"}, http.StatusBadRequest)
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidBody, "Invalid request body")
 			return
 		}
 
-		post := &models.Post{
-			ID:      0,
-			Title:   req.Title,
-			Author:  username,
-			Content: req.Content,
-		}
-
-		err = p.PostUseCase.PostRepo.Create(post)
+		post, mentioned, err := p.PostUseCase.Create(r.Context(), username, req)
 		if err != nil {
-			log.Printf("Cannot save post in DB. err = %v\n", err)
-			middleware.SendResponse(w, r, map[string]string{"error": "Failed to create post"}, http.StatusInternalServerError)
+			middleware.SendError(w, r, err, "Failed to create post")
 			return
 		}
+		p.postCreated(r.Context(), post, mentioned)
 
 		resp := application.MapPostToJson(post)
 		middleware.SendResponse(w, r, resp, http.StatusOK)
@@ -54,7 +99,7 @@ func (p *PostHandler) UpdatePostHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		username, ok := r.Context().Value(middleware.UsernameKey).(string)
 		if !ok {
-			middleware.SendResponse(w, r, map[string]string{"error": "Unauthorized"}, http.StatusUnauthorized)
+			middleware.SendProblem(w, r, http.StatusUnauthorized, models.CodeUnauthorized, "Unauthorized")
 			return
 		}
 
@@ -62,8 +107,7 @@ func (p *PostHandler) UpdatePostHandler() http.HandlerFunc {
 		err := middleware.Parse(w, r, &req)
 
 		if err != nil {
-			log.Printf("Cannot middleware.Parse body. err = %v \n", err)
-			middleware.SendResponse(w, r, map[string]string{"error": "Invalid request body"}, http.StatusBadRequest)
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidBody, "Invalid request body")
 			return
 		}
 		vars := mux.Vars(r)
@@ -71,38 +115,16 @@ func (p *PostHandler) UpdatePostHandler() http.HandlerFunc {
 		idAsNumber, err := strconv.ParseInt(id, 10, 64)
 
 		if err != nil {
-			middleware.SendResponse(w, r, map[string]string{"error": fmt.Sprintf("Invalid ID %s", id)}, http.StatusBadRequest)
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidParameter, fmt.Sprintf("Invalid ID %s", id))
 			return
 		}
 
-		content := req.Content
-		title := req.Title
-
-		oldPost, err := p.PostUseCase.PostRepo.FindByID(idAsNumber)
+		post, oldPost, mentioned, err := p.PostUseCase.Update(r.Context(), username, idAsNumber, req)
 		if err != nil {
-			log.Printf("Cannot get post, err = %v\n", err)
-			middleware.SendResponse(w, r, map[string]string{"error": "Failed to get post"}, http.StatusInternalServerError)
-			return
-		}
-		if req.Content == "" {
-			content = oldPost.Content
-		}
-		if req.Title == "" {
-			title = oldPost.Title
-		}
-		post := &models.Post{
-			ID:      idAsNumber,
-			Title:   title,
-			Author:  username,
-			Content: content,
-		}
-
-		err = p.PostUseCase.PostRepo.Update(post)
-		if err != nil {
-			log.Printf("Cannot save post in postRepo. err = %v\n", err)
-			middleware.SendResponse(w, r, map[string]string{"error": "Failed to update post"}, http.StatusInternalServerError)
+			middleware.SendError(w, r, err, "Failed to update post")
 			return
 		}
+		p.postUpdated(r.Context(), post, oldPost, mentioned)
 
 		resp := application.MapPostToJson(post)
 		middleware.SendResponse(w, r, resp, http.StatusOK)
@@ -112,7 +134,7 @@ func (p *PostHandler) DeletePostHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		username, ok := r.Context().Value(middleware.UsernameKey).(string)
 		if !ok {
-			middleware.SendResponse(w, r, map[string]string{"error": "Unauthorized"}, http.StatusUnauthorized)
+			middleware.SendProblem(w, r, http.StatusUnauthorized, models.CodeUnauthorized, "Unauthorized")
 			return
 		}
 		vars := mux.Vars(r)
@@ -120,16 +142,16 @@ func (p *PostHandler) DeletePostHandler() http.HandlerFunc {
 		idAsNumber, err := strconv.ParseInt(id, 10, 64)
 
 		if err != nil {
-			middleware.SendResponse(w, r, map[string]string{"error": fmt.Sprintf("Invalid ID %s", id)}, http.StatusBadRequest)
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidParameter, fmt.Sprintf("Invalid ID %s", id))
 			return
 		}
 
-		err = p.PostUseCase.PostRepo.Delete(idAsNumber, username)
+		post, err := p.PostUseCase.Delete(r.Context(), username, idAsNumber)
 		if err != nil {
-			log.Printf("Cannot delete post in postRepo. err = %v\n", err)
-			middleware.SendResponse(w, r, map[string]string{"error": "Failed to delete post"}, http.StatusInternalServerError)
+			middleware.SendError(w, r, err, "Failed to delete post")
 			return
 		}
+		p.postDeleted(r.Context(), post)
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
@@ -139,20 +161,15 @@ func (p *PostHandler) GetPostsByUserHandler() http.HandlerFunc { //TODO: PAGINAT
 		vars := mux.Vars(r)
 		username := vars["username"]
 		if username == "" {
-			middleware.SendResponse(w, r, map[string]string{"error": "Username required"}, http.StatusBadRequest)
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidParameter, "Username required")
 			return
 		}
-		posts, err := p.PostUseCase.PostRepo.FindByAuthor(username)
+		posts, err := p.PostUseCase.ByAuthor(r.Context(), username)
 		if err != nil {
-			log.Printf("Cannot get posts, err = %v\n", err)
-			middleware.SendResponse(w, r, map[string]string{"error": "Failed to get posts"}, http.StatusInternalServerError)
+			middleware.SendError(w, r, err, "Failed to get posts")
 			return
 		}
-		var resp = make([]models.JsonPost, len(posts))
-		for idx, post := range posts {
-			resp[idx] = application.MapPostToJson(post)
-		}
-		middleware.SendResponse(w, r, resp, http.StatusOK)
+		middleware.SendResponse(w, r, mapPosts(posts), http.StatusOK)
 	}
 }
 
@@ -163,14 +180,73 @@ func (p *PostHandler) GetPostHandler() http.HandlerFunc {
 
 		idAsNumber, err := strconv.ParseInt(id, 10, 64)
 		if err != nil {
-			middleware.SendResponse(w, r, map[string]string{"error": fmt.Sprintf("Invalid ID %s", id)}, http.StatusBadRequest)
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidParameter, fmt.Sprintf("Invalid ID %s", id))
+			return
+		}
+
+		post, err := p.PostUseCase.Get(r.Context(), idAsNumber)
+		if err != nil {
+			middleware.SendError(w, r, err, "Failed to get post")
+			return
+		}
+
+		// The permalink is also an h-entry page for browsers and for sites
+		// verifying our webmentions.
+		endpoint := p.WebmentionUseCase.Endpoint()
+		w.Header().Set("Link", "<"+endpoint+">; rel=\"webmention\"")
+		w.Header().Set("Vary", "Accept")
+		if prefersHTML(r) {
+			page, err := application.EncodePostPage(post, p.WebmentionUseCase.BaseURL, endpoint)
+			if err != nil {
+				middleware.SendError(w, r, err, "Failed to get post")
+				return
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write(page)
+			return
+		}
+
+		resp := application.MapPostToJson(post)
+		middleware.SendResponse(w, r, resp, http.StatusOK)
+	}
+}
+
+func (p *PostHandler) GetTrashHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := r.Context().Value(middleware.UsernameKey).(string)
+		if !ok {
+			middleware.SendProblem(w, r, http.StatusUnauthorized, models.CodeUnauthorized, "Unauthorized")
+			return
+		}
+
+		posts, err := p.PostUseCase.Trash(r.Context(), username)
+		if err != nil {
+			middleware.SendError(w, r, err, "Failed to get trash")
+			return
+		}
+		middleware.SendResponse(w, r, mapPosts(posts), http.StatusOK)
+	}
+}
+
+func (p *PostHandler) RestorePostHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := r.Context().Value(middleware.UsernameKey).(string)
+		if !ok {
+			middleware.SendProblem(w, r, http.StatusUnauthorized, models.CodeUnauthorized, "Unauthorized")
+			return
+		}
+		vars := mux.Vars(r)
+		id := vars["post_id"]
+		idAsNumber, err := strconv.ParseInt(id, 10, 64)
+
+		if err != nil {
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidParameter, fmt.Sprintf("Invalid ID %s", id))
 			return
 		}
 
-		post, err := p.PostUseCase.PostRepo.FindByID(idAsNumber)
+		post, err := p.PostUseCase.Restore(r.Context(), username, idAsNumber)
 		if err != nil {
-			log.Printf("Cannot get post, err = %v\n", err)
-			middleware.SendResponse(w, r, map[string]string{"error": "Failed to get post"}, http.StatusInternalServerError)
+			middleware.SendError(w, r, err, "Failed to restore post")
 			return
 		}
 
@@ -178,3 +254,34 @@ func (p *PostHandler) GetPostHandler() http.HandlerFunc {
 		middleware.SendResponse(w, r, resp, http.StatusOK)
 	}
 }
+
+func (p *PostHandler) GetMentionsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		username := vars["username"]
+		if username == "" {
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidParameter, "Username required")
+			return
+		}
+		limit, offset, err := parsePagination(r)
+		if err != nil {
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidParameter, err.Error())
+			return
+		}
+
+		posts, err := p.PostUseCase.Mentioning(r.Context(), username, limit, offset)
+		if err != nil {
+			middleware.SendError(w, r, err, "Failed to get mentions")
+			return
+		}
+		middleware.SendResponse(w, r, mapPosts(posts), http.StatusOK)
+	}
+}
+
+func mapPosts(posts []*models.Post) []models.JsonPost {
+	var resp = make([]models.JsonPost, len(posts))
+	for idx, post := range posts {
+		resp[idx] = application.MapPostToJson(post)
+	}
+	return resp
+}
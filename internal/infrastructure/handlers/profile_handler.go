@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"log"
 	"net/http"
 	"postapi/internal/application"
 	models "postapi/internal/domain"
@@ -20,15 +19,13 @@ func (p *ProfileHandler) GetProfileHandler() http.HandlerFunc {
 		username := vars["username"]
 
 		if username == "" {
-			middleware.SendResponse(w, r, map[string]string{"error": "Username required"}, http.StatusBadRequest)
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidParameter, "Username required")
 			return
 		}
 
-		profile, err := p.ProfileUseCase.ProfileRepository.FindByUsername(username)
-
+		profile, err := p.ProfileUseCase.Get(r.Context(), username)
 		if err != nil {
-			log.Printf("Cannot get profile from DB. err = %v\n", err)
-			middleware.SendResponse(w, r, map[string]string{"error": "Failed to get profile details"}, http.StatusInternalServerError)
+			middleware.SendError(w, r, err, "Failed to get profile details")
 			return
 		}
 
@@ -41,29 +38,20 @@ func (pH *ProfileHandler) CreateProfileHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		username, ok := r.Context().Value(middleware.UsernameKey).(string)
 		if !ok {
-			middleware.SendResponse(w, r, map[string]string{"error": "Unauthorized"}, http.StatusUnauthorized)
+			middleware.SendProblem(w, r, http.StatusUnauthorized, models.CodeUnauthorized, "Unauthorized")
 			return
 		}
 
 		req := models.ProfileRequest{}
 		err := middleware.Parse(w, r, &req)
 		if err != nil {
-			log.Printf("Cannot middleware.Parse body. err = %v \n", err)
-			middleware.SendResponse(w, r, map[string]string{"error": "Invalid request body"}, http.StatusBadRequest)
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidBody, "Invalid request body")
 			return
 		}
 
-		p := &models.Profile{
-			Username:       username,
-			Description:    req.Description,
-			ProfilePicture: req.ProfilePicture,
-		}
-
-		err = pH.ProfileUseCase.ProfileRepository.Create(p)
-
+		p, err := pH.ProfileUseCase.Create(r.Context(), username, req)
 		if err != nil {
-			log.Printf("Cannot create profile. err = %v \n", err)
-			middleware.SendResponse(w, r, map[string]string{"error": "Failed to create profile"}, http.StatusInternalServerError)
+			middleware.SendError(w, r, err, "Failed to create profile")
 			return
 		}
 
@@ -76,43 +64,20 @@ func (pH *ProfileHandler) UpdateProfileHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		username, ok := r.Context().Value(middleware.UsernameKey).(string)
 		if !ok {
-			middleware.SendResponse(w, r, map[string]string{"error": "Unauthorized"}, http.StatusUnauthorized)
+			middleware.SendProblem(w, r, http.StatusUnauthorized, models.CodeUnauthorized, "Unauthorized")
 			return
 		}
 
 		req := models.ProfileRequest{}
 		err := middleware.Parse(w, r, &req)
 		if err != nil {
-			log.Printf("Cannot middleware.Parse body. err = %v \n", err)
-			middleware.SendResponse(w, r, map[string]string{"error": "Invalid request body"}, http.StatusBadRequest)
-			return
-		}
-		currentProfile, err := pH.ProfileUseCase.ProfileRepository.FindByUsername(username)
-
-		if err != nil {
-			log.Printf("Cannot get profile. err = %v \n", err)
-			middleware.SendResponse(w, r, map[string]string{"error": "Failed to get profile"}, http.StatusInternalServerError)
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidBody, "Invalid request body")
 			return
 		}
-		description := req.Description
-		profilePicture := req.ProfilePicture
-		if req.Description == "" {
-			description = currentProfile.Description
-		}
-		if req.ProfilePicture == "" {
-			profilePicture = currentProfile.ProfilePicture
-		}
-		p := &models.Profile{
-			Username:       username,
-			Description:    description,
-			ProfilePicture: profilePicture,
-		}
-
-		err = pH.ProfileUseCase.ProfileRepository.Update(p)
 
+		p, err := pH.ProfileUseCase.Update(r.Context(), username, req)
 		if err != nil {
-			log.Printf("Cannot update profile. err = %v \n", err)
-			middleware.SendResponse(w, r, map[string]string{"error": "Failed to update profile"}, http.StatusInternalServerError)
+			middleware.SendError(w, r, err, "Failed to update profile")
 			return
 		}
 
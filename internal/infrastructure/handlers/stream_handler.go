@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"postapi/internal/application"
+	models "postapi/internal/domain"
+	"postapi/internal/infrastructure/realtime"
+	"postapi/internal/middleware"
+	"strconv"
+	"time"
+)
+
+const (
+	heartbeatInterval = 15 * time.Second
+	sseRetryMillis    = 3000
+)
+
+type StreamHandler struct {
+	Hub *realtime.Hub
+}
+
+// StreamHandler sends the authenticated user's home timeline and notifications
+// as Server-Sent Events.
+func (sh *StreamHandler) StreamHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := r.Context().Value(middleware.UsernameKey).(string)
+		if !ok {
+			middleware.SendProblem(w, r, http.StatusUnauthorized, models.CodeUnauthorized, "Unauthorized")
+			return
+		}
+
+		lastEventID, err := parseLastEventID(r)
+		if err != nil {
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidParameter, err.Error())
+			return
+		}
+
+		topics := []string{application.HomeTopic(username), application.NotificationsTopic(username)}
+		sub, err := sh.Hub.Subscribe(topics, lastEventID)
+		if err != nil {
+			middleware.SendProblem(w, r, http.StatusServiceUnavailable, models.CodeUnavailable, "Server shutting down")
+			return
+		}
+		defer sub.Close()
+
+		// The stream outlives the server write timeout.
+		rc := http.NewResponseController(w)
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+			log.Printf("Cannot disable write deadline. err = %v\n", err)
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "retry: %d\n\n", sseRetryMillis)
+		if err := rc.Flush(); err != nil {
+			log.Printf("Streaming not supported. err = %v\n", err)
+			return
+		}
+
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-heartbeat.C:
+				if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+					return
+				}
+			case ev, ok := <-sub.Events():
+				if !ok {
+					return
+				}
+				data, err := json.Marshal(ev.Data)
+				if err != nil {
+					log.Printf("Cannot format json. err = %v\n", err)
+					continue
+				}
+				if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data); err != nil {
+					return
+				}
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// parseLastEventID reads the Last-Event-ID header sent by reconnecting
+// EventSource clients, or the last_event_id query parameter.
+func parseLastEventID(r *http.Request) (uint64, error) {
+	v := r.Header.Get("Last-Event-ID")
+	if v == "" {
+		v = r.URL.Query().Get("last_event_id")
+	}
+	if v == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid Last-Event-ID %s", v)
+	}
+	return id, nil
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"postapi/internal/application"
+	models "postapi/internal/domain"
+	"postapi/internal/middleware"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultTrendingWindow = 24 * time.Hour
+	maxTrendingWindow     = 30 * 24 * time.Hour
+	trendingTagsLimit     = 10
+)
+
+type TagHandler struct {
+	PostUseCase application.PostUseCase
+}
+
+func (th *TagHandler) GetPostsByTagHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		tag := application.NormalizeTag(vars["tag"])
+		if tag == "" {
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidParameter, fmt.Sprintf("Invalid tag %s", vars["tag"]))
+			return
+		}
+		limit, offset, err := parsePagination(r)
+		if err != nil {
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidParameter, err.Error())
+			return
+		}
+
+		posts, err := th.PostUseCase.ByTag(r.Context(), tag, limit, offset)
+		if err != nil {
+			middleware.SendError(w, r, err, "Failed to get posts")
+			return
+		}
+		middleware.SendResponse(w, r, mapPosts(posts), http.StatusOK)
+	}
+}
+
+func (th *TagHandler) GetTrendingTagsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		window := defaultTrendingWindow
+		if v := r.URL.Query().Get("hours"); v != "" {
+			hours, err := strconv.Atoi(v)
+			if err != nil || hours < 1 {
+				middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidParameter, fmt.Sprintf("Invalid hours %s", v))
+				return
+			}
+			window = min(time.Duration(hours)*time.Hour, maxTrendingWindow)
+		}
+
+		tags, err := th.PostUseCase.Trending(r.Context(), time.Now().Add(-window), trendingTagsLimit)
+		if err != nil {
+			middleware.SendError(w, r, err, "Failed to get trending tags")
+			return
+		}
+		var resp = make([]models.JsonTagCount, len(tags))
+		for idx, tag := range tags {
+			resp[idx] = application.MapTagCountToJson(tag)
+		}
+		middleware.SendResponse(w, r, resp, http.StatusOK)
+	}
+}
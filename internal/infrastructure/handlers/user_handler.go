@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"log"
 	"net/http"
 	"postapi/internal/application"
 	models "postapi/internal/domain"
@@ -17,23 +16,16 @@ type UserHandler struct {
 
 func (uh *UserHandler) RegisterUserHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		req := models.UserResponse{}
+		req := models.RegisterRequest{}
 		err := middleware.Parse(w, r, &req)
 		if err != nil {
-			log.Printf("Cannot middleware.Parse body. err = %v \n", err)
-			middleware.SendResponse(w, r, map[string]string{"error": "Invalid request body"}, http.StatusBadRequest)
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidBody, "Invalid request body")
 			return
 		}
-		u := &models.User{
-			Username: req.Username,
-			Password: req.Password,
-			Email:    req.Email,
-		}
 
-		err = uh.UserUseCase.UserRepo.Create(u)
+		u, err := uh.UserUseCase.Register(r.Context(), req)
 		if err != nil {
-			log.Printf("Cannot save user in DB. err = %v\n", err)
-			middleware.SendResponse(w, r, map[string]string{"error": err.Error()}, http.StatusBadRequest)
+			middleware.SendError(w, r, err, "Failed to create user")
 			return
 		}
 
@@ -47,26 +39,18 @@ func (uh *UserHandler) LoginHandler() http.HandlerFunc {
 		req := models.UserResponse{}
 		err := middleware.Parse(w, r, &req)
 		if err != nil {
-			log.Printf("Cannot middleware.Parse body. err = %v \n", err)
-			middleware.SendResponse(w, r, map[string]string{"error": "Invalid request body"}, http.StatusBadRequest)
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidBody, "Invalid request body")
 			return
 		}
 
-		u := &models.User{
-			Username: req.Username,
-			Password: req.Password,
-		}
-
-		user, err := uh.UserUseCase.UserRepo.LoginUser(u)
+		user, err := uh.UserUseCase.Login(r.Context(), req.Username, req.Password)
 		if err != nil {
-			log.Printf("Login failed. err = %v\n", err)
-			middleware.SendResponse(w, r, map[string]string{"error": "Invalid credentials"}, http.StatusUnauthorized)
+			middleware.SendError(w, r, err, "Failed to log in")
 			return
 		}
 		tokenString, err := uh.JWTService.GenerateToken(user.Username)
 		if err != nil {
-			log.Printf("Cannot create token. err = %v\n", err)
-			middleware.SendResponse(w, r, map[string]string{"error": "Failed to generate token"}, http.StatusInternalServerError)
+			middleware.SendError(w, r, err, "Failed to generate token")
 			return
 		}
 
@@ -84,13 +68,12 @@ func (uh *UserHandler) GetUserByUsernameHandler() http.HandlerFunc {
 		username := vars["username"]
 
 		if username == "" {
-			middleware.SendResponse(w, r, map[string]string{"error": "Username required"}, http.StatusBadRequest)
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidParameter, "Username required")
 			return
 		}
-		user, err := uh.UserUseCase.UserRepo.FindByUsername(username)
+		user, err := uh.UserUseCase.Get(r.Context(), username)
 		if err != nil {
-			log.Printf("Cannot get user, err = %v\n", err)
-			middleware.SendResponse(w, r, map[string]string{"error": "Failed to get user"}, http.StatusInternalServerError)
+			middleware.SendError(w, r, err, "Failed to get user")
 			return
 		}
 		resp := application.MapUserToJson(user)
@@ -98,3 +81,40 @@ func (uh *UserHandler) GetUserByUsernameHandler() http.HandlerFunc {
 
 	}
 }
+
+func (uh *UserHandler) DeleteAccountHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := r.Context().Value(middleware.UsernameKey).(string)
+		if !ok {
+			middleware.SendProblem(w, r, http.StatusUnauthorized, models.CodeUnauthorized, "Unauthorized")
+			return
+		}
+
+		err := uh.UserUseCase.Delete(r.Context(), username)
+		if err != nil {
+			middleware.SendError(w, r, err, "Failed to delete account")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (uh *UserHandler) RestoreAccountHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := models.UserResponse{}
+		err := middleware.Parse(w, r, &req)
+		if err != nil {
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidBody, "Invalid request body")
+			return
+		}
+
+		user, err := uh.UserUseCase.Restore(r.Context(), req.Username, req.Password)
+		if err != nil {
+			middleware.SendError(w, r, err, "Failed to restore account")
+			return
+		}
+
+		resp := application.MapUserToJson(user)
+		middleware.SendResponse(w, r, resp, http.StatusOK)
+	}
+}
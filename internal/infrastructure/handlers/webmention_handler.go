@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"postapi/internal/application"
+	models "postapi/internal/domain"
+	"postapi/internal/middleware"
+)
+
+// maxWebmentionRequestSize bounds the form posted to the endpoint.
+const maxWebmentionRequestSize = 16 << 10
+
+type WebmentionHandler struct {
+	WebmentionUseCase application.WebmentionUseCase
+}
+
+// ReceiveWebmentionHandler accepts a form with source and target. The source
+// is verified later, so a valid request is answered with 202 Accepted.
+func (wh *WebmentionHandler) ReceiveWebmentionHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxWebmentionRequestSize)
+		if err := r.ParseForm(); err != nil {
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidBody, "Invalid request body")
+			return
+		}
+
+		webmention, err := wh.WebmentionUseCase.Receive(r.Context(), r.PostForm.Get("source"), r.PostForm.Get("target"))
+		if errors.Is(err, application.ErrInvalidWebmention) {
+			middleware.SendProblem(w, r, http.StatusBadRequest, models.CodeInvalidWebmention, err.Error())
+			return
+		}
+		if err != nil {
+			middleware.SendError(w, r, err, "Failed to save webmention")
+			return
+		}
+		middleware.SendResponse(w, r, map[string]any{"id": webmention.ID, "status": webmention.Status}, http.StatusAccepted)
+	}
+}
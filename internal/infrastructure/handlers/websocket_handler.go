@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"postapi/internal/application"
+	models "postapi/internal/domain"
+	"postapi/internal/infrastructure/realtime"
+	"postapi/internal/middleware"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteWait  = 10 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = 30 * time.Second
+	wsMaxMessage = 4096
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// wsRequest is a message sent by the client. Stream is one of "home",
+// "notifications", "user:{username}" or "tag:{tag}".
+type wsRequest struct {
+	Action string `json:"action"`
+	Stream string `json:"stream"`
+}
+
+// wsMessage is a message sent to the client. Seq numbers every message of a
+// connection so clients can detect gaps.
+type wsMessage struct {
+	Seq     uint64   `json:"seq"`
+	Type    string   `json:"type"`
+	EventID uint64   `json:"event_id,omitempty"`
+	Stream  string   `json:"stream,omitempty"`
+	Streams []string `json:"streams,omitempty"`
+	Data    any      `json:"data,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+type WebSocketHandler struct {
+	Hub *realtime.Hub
+}
+
+func (wh *WebSocketHandler) WebSocketHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := r.Context().Value(middleware.UsernameKey).(string)
+		if !ok {
+			middleware.SendProblem(w, r, http.StatusUnauthorized, models.CodeUnauthorized, "Unauthorized")
+			return
+		}
+
+		sub, err := wh.Hub.Subscribe(nil, 0)
+		if err != nil {
+			middleware.SendProblem(w, r, http.StatusServiceUnavailable, models.CodeUnavailable, "Server shutting down")
+			return
+		}
+		defer sub.Close()
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Printf("Cannot upgrade to websocket. err = %v\n", err)
+			return
+		}
+		defer conn.Close()
+
+		session := &wsSession{
+			username: username,
+			conn:     conn,
+			sub:      sub,
+			streams:  make(map[string]string),
+			replies:  make(chan wsMessage, 16),
+			done:     make(chan struct{}),
+		}
+		go session.readLoop()
+		session.writeLoop()
+	}
+}
+
+type wsSession struct {
+	username string
+	conn     *websocket.Conn
+	sub      *realtime.Subscription
+	seq      uint64
+	// streams maps hub topics to the stream names the client subscribed with.
+	mu      sync.Mutex
+	streams map[string]string
+	replies chan wsMessage
+	done    chan struct{}
+}
+
+// readLoop applies subscribe and unsubscribe requests until the client goes
+// away. It never writes to the connection; replies go through writeLoop.
+func (s *wsSession) readLoop() {
+	defer close(s.done)
+	s.conn.SetReadLimit(wsMaxMessage)
+	s.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	s.conn.SetPongHandler(func(string) error {
+		return s.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		req := wsRequest{}
+		if err := s.conn.ReadJSON(&req); err != nil {
+			var closeErr *websocket.CloseError
+			if !errors.As(err, &closeErr) && !errors.Is(err, net.ErrClosed) {
+				log.Printf("Cannot read websocket message. err = %v\n", err)
+			}
+			return
+		}
+
+		reply := s.handle(req)
+		select {
+		case s.replies <- reply:
+		case <-time.After(wsWriteWait):
+			return
+		}
+	}
+}
+
+func (s *wsSession) handle(req wsRequest) wsMessage {
+	topic, err := s.topicFor(req.Stream)
+	if err != nil {
+		return wsMessage{Type: "error", Stream: req.Stream, Error: err.Error()}
+	}
+
+	switch req.Action {
+	case "subscribe":
+		s.mu.Lock()
+		s.streams[topic] = req.Stream
+		s.mu.Unlock()
+		s.sub.AddTopics(topic)
+		return wsMessage{Type: "subscribed", Stream: req.Stream}
+	case "unsubscribe":
+		s.sub.RemoveTopics(topic)
+		s.mu.Lock()
+		delete(s.streams, topic)
+		s.mu.Unlock()
+		return wsMessage{Type: "unsubscribed", Stream: req.Stream}
+	default:
+		return wsMessage{Type: "error", Stream: req.Stream, Error: "Unknown action " + req.Action}
+	}
+}
+
+// topicFor maps a stream requested by the client to a hub topic. The home
+// timeline and notifications are always those of the authenticated user.
+func (s *wsSession) topicFor(stream string) (string, error) {
+	kind, arg, _ := strings.Cut(stream, ":")
+	switch kind {
+	case "home":
+		return application.HomeTopic(s.username), nil
+	case "notifications":
+		return application.NotificationsTopic(s.username), nil
+	case "user":
+		if arg != "" {
+			return application.UserTopic(arg), nil
+		}
+	case "tag":
+		if tag := application.NormalizeTag(arg); tag != "" {
+			return application.TagTopic(tag), nil
+		}
+	}
+	return "", errors.New("Invalid stream " + stream)
+}
+
+// streamsOf returns the client stream names an event was delivered for.
+func (s *wsSession) streamsOf(ev realtime.Event) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var streams []string
+	for _, topic := range ev.Topics {
+		if stream, ok := s.streams[topic]; ok {
+			streams = append(streams, stream)
+		}
+	}
+	return streams
+}
+
+// writeLoop is the only writer of the connection.
+func (s *wsSession) writeLoop() {
+	ping := time.NewTicker(wsPingPeriod)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case reply := <-s.replies:
+			if err := s.send(reply); err != nil {
+				return
+			}
+		case ev, ok := <-s.sub.Events():
+			if !ok {
+				// Hub shut down or the client fell behind.
+				s.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+				s.conn.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, "stream closed"))
+				return
+			}
+			streams := s.streamsOf(ev)
+			if len(streams) == 0 {
+				continue
+			}
+			msg := wsMessage{Type: ev.Type, EventID: ev.ID, Streams: streams, Data: ev.Data}
+			if err := s.send(msg); err != nil {
+				return
+			}
+		case <-ping.C:
+			s.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := s.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *wsSession) send(msg wsMessage) error {
+	s.seq++
+	msg.Seq = s.seq
+	s.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return s.conn.WriteJSON(msg)
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"postapi/internal/application"
+	"postapi/internal/domain"
+	"postapi/internal/infrastructure/memory"
+	"postapi/internal/infrastructure/realtime"
+	"postapi/internal/middleware"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newWebSocketServer serves the WebSocket handler behind the stream
+// authentication, for the user alice, and returns alice's token.
+func newWebSocketServer(t *testing.T) (*httptest.Server, *realtime.Hub, string) {
+	t.Helper()
+	users := memory.New().Repositories().Users
+	if err := users.Create(context.Background(), &domain.User{Username: "alice", Email: "alice@example.com", Password: "secret"}); err != nil {
+		t.Fatalf("Users.Create() error = %v", err)
+	}
+	jwtService := application.NewJWTService("test-secret")
+	token, err := jwtService.GenerateToken("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hub := realtime.NewHub(16, 16)
+	wh := &WebSocketHandler{Hub: hub}
+	srv := httptest.NewServer(middleware.NewAuthMiddleware(jwtService, users).StreamAuthMiddleware(wh.WebSocketHandler()))
+	t.Cleanup(func() {
+		hub.Close()
+		srv.Close()
+	})
+	return srv, hub, token
+}
+
+// wsClient reads the messages of one connection and checks that their seq
+// keeps growing.
+type wsClient struct {
+	t    *testing.T
+	conn *websocket.Conn
+	seq  uint64
+}
+
+func dialWebSocket(t *testing.T, srv *httptest.Server, token string) *wsClient {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "?access_token=" + token
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &wsClient{t: t, conn: conn}
+}
+
+func (c *wsClient) send(action string, stream string) {
+	c.t.Helper()
+	if err := c.conn.WriteJSON(wsRequest{Action: action, Stream: stream}); err != nil {
+		c.t.Fatalf("WriteJSON() error = %v", err)
+	}
+}
+
+func (c *wsClient) read() wsMessage {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg wsMessage
+	if err := c.conn.ReadJSON(&msg); err != nil {
+		c.t.Fatalf("ReadJSON() error = %v", err)
+	}
+	if msg.Seq <= c.seq {
+		c.t.Errorf("message %+v has seq %d after %d", msg, msg.Seq, c.seq)
+	}
+	c.seq = msg.Seq
+	return msg
+}
+
+// expect sends a request and checks the reply.
+func (c *wsClient) expect(action string, stream string, wantType string) wsMessage {
+	c.t.Helper()
+	c.send(action, stream)
+	msg := c.read()
+	if msg.Type != wantType || msg.Stream != stream {
+		c.t.Errorf("%s %q replied %+v, want %s", action, stream, msg, wantType)
+	}
+	return msg
+}
+
+func TestWebSocketHandler_Authentication(t *testing.T) {
+	srv, _, _ := newWebSocketServer(t)
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	for _, query := range []string{"", "?access_token=forged"} {
+		_, resp, err := websocket.DefaultDialer.Dial(url+query, nil)
+		if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Dial(%q) = %v, %v, want 401", query, resp, err)
+		}
+	}
+}
+
+func TestWebSocketHandler_Streams(t *testing.T) {
+	srv, hub, token := newWebSocketServer(t)
+	c := dialWebSocket(t, srv, token)
+
+	c.expect("subscribe", "home", "subscribed")
+	c.expect("subscribe", "user:bob", "subscribed")
+	// home and notifications are always those of the connected user
+	c.expect("subscribe", "notifications:bob", "subscribed")
+	if msg := c.expect("subscribe", "weather", "error"); msg.Error != "Invalid stream weather" {
+		t.Errorf("subscribe to an invalid stream error = %q", msg.Error)
+	}
+	if msg := c.expect("jump", "home", "error"); msg.Error != "Unknown action jump" {
+		t.Errorf("unknown action error = %q", msg.Error)
+	}
+
+	hub.Publish("post.created", "not subscribed", application.TagTopic("go"))
+	hub.Publish("notification.created", "bob's", application.NotificationsTopic("bob"))
+	hub.Publish("post.created", "home", application.HomeTopic("alice"))
+	hub.Publish("post.created", "bob's post", application.UserTopic("bob"), application.TagTopic("go"))
+	hub.Publish("notification.created", "alice's", application.NotificationsTopic("alice"))
+
+	for _, want := range []struct {
+		data   string
+		stream string
+	}{
+		{"home", "home"},
+		{"bob's post", "user:bob"},
+		{"alice's", "notifications:bob"},
+	} {
+		msg := c.read()
+		if msg.Data != want.data || len(msg.Streams) != 1 || msg.Streams[0] != want.stream || msg.EventID == 0 {
+			t.Errorf("received %+v, want %q on %s", msg, want.data, want.stream)
+		}
+	}
+
+	// Events are delivered in order, so the first one after unsubscribing
+	// shows that bob's post was dropped.
+	c.expect("unsubscribe", "user:bob", "unsubscribed")
+	hub.Publish("post.created", "bob's second post", application.UserTopic("bob"))
+	hub.Publish("post.created", "home again", application.HomeTopic("alice"))
+	if msg := c.read(); msg.Data != "home again" {
+		t.Errorf("received %+v after unsubscribing, want the home event", msg)
+	}
+}
@@ -14,6 +14,18 @@ type Router struct {
 	followHandler  *handlers.FollowHandler
 	userHandler    *handlers.UserHandler
 	profileHandler *handlers.ProfileHandler
+	tagHandler     *handlers.TagHandler
+	notifHandler   *handlers.NotificationHandler
+	streamHandler  *handlers.StreamHandler
+	wsHandler      *handlers.WebSocketHandler
+	convHandler    *handlers.ConversationHandler
+	mediaHandler   *handlers.MediaHandler
+	avatarHandler  *handlers.AvatarHandler
+	feedHandler    *handlers.FeedHandler
+	apHandler      *handlers.ActivityPubHandler
+	wmHandler      *handlers.WebmentionHandler
+	mpHandler      *handlers.MicropubHandler
+	docsHandler    *handlers.OpenAPIHandler
 	authMiddleware *middleware.AuthMiddleware
 }
 
@@ -22,6 +34,18 @@ func NewRouter(
 	followHandler *handlers.FollowHandler,
 	userHandler *handlers.UserHandler,
 	profileHandler *handlers.ProfileHandler,
+	tagHandler *handlers.TagHandler,
+	notifHandler *handlers.NotificationHandler,
+	streamHandler *handlers.StreamHandler,
+	wsHandler *handlers.WebSocketHandler,
+	convHandler *handlers.ConversationHandler,
+	mediaHandler *handlers.MediaHandler,
+	avatarHandler *handlers.AvatarHandler,
+	feedHandler *handlers.FeedHandler,
+	apHandler *handlers.ActivityPubHandler,
+	wmHandler *handlers.WebmentionHandler,
+	mpHandler *handlers.MicropubHandler,
+	docsHandler *handlers.OpenAPIHandler,
 	authMiddleware *middleware.AuthMiddleware,
 ) *Router {
 	return &Router{
@@ -30,15 +54,32 @@ func NewRouter(
 		followHandler:  followHandler,
 		userHandler:    userHandler,
 		profileHandler: profileHandler,
+		tagHandler:     tagHandler,
+		notifHandler:   notifHandler,
+		streamHandler:  streamHandler,
+		wsHandler:      wsHandler,
+		convHandler:    convHandler,
+		mediaHandler:   mediaHandler,
+		avatarHandler:  avatarHandler,
+		feedHandler:    feedHandler,
+		apHandler:      apHandler,
+		wmHandler:      wmHandler,
+		mpHandler:      mpHandler,
+		docsHandler:    docsHandler,
 		authMiddleware: authMiddleware,
 	}
 }
 
 func (r *Router) SetupRoutes() *mux.Router {
 
+	// Rutas de documentación
+	r.router.HandleFunc("/api/openapi.json", r.docsHandler.GetDocumentHandler()).Methods("GET")
+
 	// Rutas de autenticación
 	r.router.HandleFunc("/api/register", r.userHandler.RegisterUserHandler()).Methods("POST")
 	r.router.HandleFunc("/api/login", r.userHandler.LoginHandler()).Methods("POST")
+	r.router.HandleFunc("/api/users/me", r.authMiddleware.AuthMiddleware(r.userHandler.DeleteAccountHandler())).Methods("DELETE")
+	r.router.HandleFunc("/api/users/restore", r.userHandler.RestoreAccountHandler()).Methods("POST")
 
 	// Rutas de posts
 	r.router.HandleFunc("/api/posts", r.authMiddleware.AuthMiddleware(r.postHandler.CreatePostHandler())).Methods("POST")
@@ -46,11 +87,38 @@ func (r *Router) SetupRoutes() *mux.Router {
 	r.router.HandleFunc("/api/posts/{post_id}", r.authMiddleware.AuthMiddleware(r.postHandler.UpdatePostHandler())).Methods("PATCH")
 	r.router.HandleFunc("/api/posts/{post_id}", r.authMiddleware.AuthMiddleware(r.postHandler.DeletePostHandler())).Methods("DELETE")
 
+	// Rutas de etiquetas
+	r.router.HandleFunc("/api/tags/trending", r.tagHandler.GetTrendingTagsHandler()).Methods("GET")
+	r.router.HandleFunc("/api/tags/{tag}/posts", r.tagHandler.GetPostsByTagHandler()).Methods("GET")
+
+	// Rutas de notificaciones
+	r.router.HandleFunc("/api/notifications", r.authMiddleware.AuthMiddleware(r.notifHandler.GetNotificationsHandler())).Methods("GET")
+	r.router.HandleFunc("/api/notifications/read", r.authMiddleware.AuthMiddleware(r.notifHandler.MarkReadHandler())).Methods("POST")
+	r.router.HandleFunc("/api/notifications/unread-count", r.authMiddleware.AuthMiddleware(r.notifHandler.GetUnreadCountHandler())).Methods("GET")
+
+	// Rutas de mensajes directos
+	r.router.HandleFunc("/api/conversations", r.authMiddleware.AuthMiddleware(r.convHandler.CreateConversationHandler())).Methods("POST")
+	r.router.HandleFunc("/api/conversations", r.authMiddleware.AuthMiddleware(r.convHandler.GetConversationsHandler())).Methods("GET")
+	r.router.HandleFunc("/api/conversations/{conversation_id}/messages", r.authMiddleware.AuthMiddleware(r.convHandler.GetMessagesHandler())).Methods("GET")
+	r.router.HandleFunc("/api/conversations/{conversation_id}/messages", r.authMiddleware.AuthMiddleware(r.convHandler.SendMessageHandler())).Methods("POST")
+	r.router.HandleFunc("/api/conversations/{conversation_id}/read", r.authMiddleware.AuthMiddleware(r.convHandler.MarkReadHandler())).Methods("POST")
+
+	// Rutas de tiempo real
+	r.router.HandleFunc("/api/stream", r.authMiddleware.StreamAuthMiddleware(r.streamHandler.StreamHandler())).Methods("GET")
+	r.router.HandleFunc("/api/ws", r.authMiddleware.StreamAuthMiddleware(r.wsHandler.WebSocketHandler())).Methods("GET")
+
+	// Rutas de papelera
+	r.router.HandleFunc("/api/trash/posts", r.authMiddleware.AuthMiddleware(r.postHandler.GetTrashHandler())).Methods("GET")
+	r.router.HandleFunc("/api/trash/posts/{post_id}/restore", r.authMiddleware.AuthMiddleware(r.postHandler.RestorePostHandler())).Methods("POST")
+
 	// Rutas de usuarios
 	r.router.HandleFunc("/api/users/{username}", r.userHandler.GetUserByUsernameHandler()).Methods("GET")
 	r.router.HandleFunc("/api/users/{username}/posts", r.postHandler.GetPostsByUserHandler()).Methods("GET")
+	r.router.HandleFunc("/api/users/{username}/mentions", r.postHandler.GetMentionsHandler()).Methods("GET")
 	r.router.HandleFunc("/api/follow/{username}", r.authMiddleware.AuthMiddleware(r.followHandler.FollowHandler())).Methods("POST")
 	r.router.HandleFunc("/api/unfollow/{username}", r.authMiddleware.AuthMiddleware(r.followHandler.UnfollowHandler())).Methods("DELETE")
+	r.router.HandleFunc("/api/block/{username}", r.authMiddleware.AuthMiddleware(r.followHandler.BlockHandler())).Methods("POST")
+	r.router.HandleFunc("/api/unblock/{username}", r.authMiddleware.AuthMiddleware(r.followHandler.UnblockHandler())).Methods("DELETE")
 	r.router.HandleFunc("/api/users/{username}/followers", r.followHandler.GetFollowersHandler()).Methods("GET")
 	r.router.HandleFunc("/api/users/{username}/following", r.followHandler.GetFollowingHandler()).Methods("GET")
 
@@ -58,6 +126,32 @@ func (r *Router) SetupRoutes() *mux.Router {
 	r.router.HandleFunc("/api/profiles/{username}", r.profileHandler.GetProfileHandler()).Methods("GET")
 	r.router.HandleFunc("/api/profiles/me", r.authMiddleware.AuthMiddleware(r.profileHandler.CreateProfileHandler())).Methods("POST")
 	r.router.HandleFunc("/api/profiles/me", r.authMiddleware.AuthMiddleware(r.profileHandler.UpdateProfileHandler())).Methods("PATCH")
+	r.router.HandleFunc("/api/avatars/{username}", r.avatarHandler.GetAvatarHandler()).Methods("GET")
+
+	// Rutas de archivos multimedia
+	r.router.HandleFunc("/api/media", r.authMiddleware.AuthMiddleware(r.mediaHandler.UploadMediaHandler())).Methods("POST")
+	r.router.HandleFunc("/media/{key}", r.mediaHandler.ServeMediaHandler()).Methods("GET")
+
+	// Rutas de feeds
+	r.router.HandleFunc("/users/{username}/feed.rss", r.feedHandler.GetFeedHandler(handlers.FeedRSS)).Methods("GET", "HEAD")
+	r.router.HandleFunc("/users/{username}/feed.atom", r.feedHandler.GetFeedHandler(handlers.FeedAtom)).Methods("GET", "HEAD")
+	r.router.HandleFunc("/users/{username}/feed.json", r.feedHandler.GetFeedHandler(handlers.FeedJSON)).Methods("GET", "HEAD")
+
+	// Rutas de ActivityPub
+	r.router.HandleFunc("/.well-known/webfinger", r.apHandler.WebFingerHandler()).Methods("GET")
+	r.router.HandleFunc("/users/{username}", r.apHandler.ActorHandler()).Methods("GET")
+	r.router.HandleFunc("/users/{username}/inbox", r.apHandler.InboxHandler()).Methods("POST")
+	r.router.HandleFunc("/users/{username}/outbox", r.apHandler.OutboxHandler()).Methods("GET")
+	r.router.HandleFunc("/users/{username}/followers", r.apHandler.FollowersHandler()).Methods("GET")
+	r.router.HandleFunc("/users/{username}/notes/{post_id}", r.apHandler.NoteHandler()).Methods("GET")
+
+	// Rutas de Webmention
+	r.router.HandleFunc("/webmention", r.wmHandler.ReceiveWebmentionHandler()).Methods("POST")
+
+	// Rutas de Micropub
+	r.router.HandleFunc("/micropub", r.authMiddleware.AuthMiddleware(r.mpHandler.QueryHandler())).Methods("GET")
+	r.router.HandleFunc("/micropub", r.authMiddleware.FormAuthMiddleware(r.mpHandler.PostHandler())).Methods("POST")
+	r.router.HandleFunc("/micropub/media", r.authMiddleware.AuthMiddleware(r.mpHandler.MediaEndpointHandler())).Methods("POST")
 
 	fs := http.FileServer(http.Dir("./web"))
 	r.router.PathPrefix("/").Handler(fs)
@@ -0,0 +1,95 @@
+package infrastructure
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"postapi/internal/infrastructure/handlers"
+	"postapi/internal/infrastructure/openapi"
+	"postapi/internal/middleware"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// newTestRouter routes to handlers without dependencies; only the OpenAPI
+// document is served for real.
+func newTestRouter(doc *openapi.Document) *mux.Router {
+	return NewRouter(
+		&handlers.PostHandler{},
+		&handlers.FollowHandler{},
+		&handlers.UserHandler{},
+		&handlers.ProfileHandler{},
+		&handlers.TagHandler{},
+		&handlers.NotificationHandler{},
+		&handlers.StreamHandler{},
+		&handlers.WebSocketHandler{},
+		&handlers.ConversationHandler{},
+		&handlers.MediaHandler{},
+		&handlers.AvatarHandler{},
+		&handlers.FeedHandler{},
+		&handlers.ActivityPubHandler{},
+		&handlers.WebmentionHandler{},
+		&handlers.MicropubHandler{},
+		&handlers.OpenAPIHandler{Document: doc},
+		&middleware.AuthMiddleware{},
+	).SetupRoutes()
+}
+
+func TestSetupRoutes_MatchOpenAPIDocument(t *testing.T) {
+	doc := openapi.New("http://localhost:8080")
+
+	routed := map[string]bool{}
+	err := newTestRouter(doc).Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			// The static file server answers any method.
+			return nil
+		}
+		for _, method := range methods {
+			routed[method+" "+path] = true
+			if doc.Operation(method, path) == nil {
+				t.Errorf("%s %s is not described in the OpenAPI document", method, path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+
+	for path, item := range doc.Paths {
+		for method := range item {
+			if !routed[strings.ToUpper(method)+" "+path] {
+				t.Errorf("the OpenAPI document describes %s %s, which is not routed", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+func TestSetupRoutes_ServesOpenAPIDocument(t *testing.T) {
+	router := newTestRouter(openapi.New("https://blog.example"))
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/openapi.json", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("GET /api/openapi.json status = %d", rr.Code)
+	}
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Servers []struct {
+			URL string `json:"url"`
+		} `json:"servers"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if doc.OpenAPI != "3.1.0" || len(doc.Servers) != 1 || doc.Servers[0].URL != "https://blog.example" {
+		t.Errorf("document = %+v", doc)
+	}
+}
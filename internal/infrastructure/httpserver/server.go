@@ -4,25 +4,36 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"postapi/internal/infrastructure/realtime"
 	"time"
 )
 
 type Server struct {
 	httpServer *http.Server
 	router     *Router
+	// cancelRequests cancels the context of every request, and with it
+	// their queries.
+	cancelRequests context.CancelFunc
 }
 
-func NewServer(port string, router *Router) *Server {
-	return &Server{
+func NewServer(port string, router *Router, hub *realtime.Hub) *Server {
+	requests, cancelRequests := context.WithCancel(context.Background())
+	s := &Server{
 		router: router,
 		httpServer: &http.Server{
 			Addr:         fmt.Sprintf(":%s", port),
 			ReadTimeout:  15 * time.Second,
 			WriteTimeout: 15 * time.Second,
 			IdleTimeout:  60 * time.Second,
+			BaseContext:  func(net.Listener) context.Context { return requests },
 		},
+		cancelRequests: cancelRequests,
 	}
+	// Cerrar el hub termina los streams abiertos para que Shutdown no los espere
+	s.httpServer.RegisterOnShutdown(hub.Close)
+	return s
 }
 
 func (s *Server) Start() error {
@@ -34,5 +45,7 @@ func (s *Server) Start() error {
 
 func (s *Server) Shutdown(ctx context.Context) error {
 	log.Println("Shutting down server...")
+	// Si vence el plazo, las peticiones en curso se cancelan en vez de esperarlas
+	defer s.cancelRequests()
 	return s.httpServer.Shutdown(ctx)
 }
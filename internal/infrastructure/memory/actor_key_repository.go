@@ -0,0 +1,34 @@
+package memory
+
+import (
+	"context"
+	models "postapi/internal/domain"
+)
+
+type ActorKeyRepository struct {
+	store
+}
+
+func (a *ActorKeyRepository) Create(ctx context.Context, key *models.ActorKey) error {
+	a.lock()
+	defer a.unlock()
+
+	if _, ok := a.t.users[key.Username]; !ok {
+		return errMissingReference
+	}
+	if _, ok := a.t.actorKeys[key.Username]; !ok {
+		a.t.actorKeys[key.Username] = *key
+	}
+	return nil
+}
+
+func (a *ActorKeyRepository) FindByUsername(ctx context.Context, username string) (*models.ActorKey, error) {
+	a.lock()
+	defer a.unlock()
+
+	key, ok := a.t.actorKeys[username]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	return &key, nil
+}
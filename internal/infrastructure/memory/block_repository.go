@@ -0,0 +1,47 @@
+package memory
+
+import (
+	"context"
+	models "postapi/internal/domain"
+)
+
+type UserBlockRepository struct {
+	store
+}
+
+func (u *UserBlockRepository) Create(ctx context.Context, block *models.UserBlock) error {
+	u.lock()
+	defer u.unlock()
+
+	switch {
+	case block.BlockerUsername == block.BlockedUsername:
+		return errSelfBlock
+	case u.t.blocks[*block]:
+		return errAlreadyBlocked
+	}
+	if _, ok := u.t.users[block.BlockerUsername]; !ok {
+		return errMissingReference
+	}
+	if _, ok := u.t.users[block.BlockedUsername]; !ok {
+		return errUserNotFound
+	}
+	u.t.blocks[*block] = true
+	return nil
+}
+
+func (u *UserBlockRepository) Delete(ctx context.Context, block *models.UserBlock) error {
+	u.lock()
+	defer u.unlock()
+
+	delete(u.t.blocks, *block)
+	return nil
+}
+
+func (u *UserBlockRepository) IsBlocked(ctx context.Context, username string, other string) (bool, error) {
+	u.lock()
+	defer u.unlock()
+
+	blocked := u.t.blocks[models.UserBlock{BlockerUsername: username, BlockedUsername: other}] ||
+		u.t.blocks[models.UserBlock{BlockerUsername: other, BlockedUsername: username}]
+	return blocked, nil
+}
@@ -0,0 +1,181 @@
+package memory
+
+import (
+	"context"
+	models "postapi/internal/domain"
+	"sort"
+)
+
+type ConversationRepository struct {
+	store
+}
+
+func (c *ConversationRepository) Create(ctx context.Context, conversation *models.Conversation) error {
+	c.lock()
+	defer c.unlock()
+
+	if _, ok := c.t.users[conversation.CreatedBy]; !ok {
+		return errMissingReference
+	}
+	seen := map[string]bool{}
+	for _, member := range conversation.Members {
+		if _, ok := c.t.users[member]; !ok {
+			return errUserNotFound
+		}
+		if seen[member] {
+			return models.ErrConflict
+		}
+		seen[member] = true
+	}
+	conversation.ID = c.t.nextID("conversations")
+	c.t.conversations[conversation.ID] = models.Conversation{
+		ID:        conversation.ID,
+		CreatedBy: conversation.CreatedBy,
+		IsGroup:   conversation.IsGroup,
+		CreatedAt: conversation.CreatedAt,
+	}
+	for _, member := range conversation.Members {
+		c.t.members[memberKey{conversation.ID, member}] = 0
+	}
+	return nil
+}
+
+func (c *ConversationRepository) FindByID(ctx context.Context, id int64) (*models.Conversation, error) {
+	c.lock()
+	defer c.unlock()
+
+	conversation, ok := c.t.conversations[id]
+	if !ok {
+		return nil, errConversationNotFound
+	}
+	return c.withMembers(conversation), nil
+}
+
+func (c *ConversationRepository) FindDirect(ctx context.Context, username string, other string) (*models.Conversation, error) {
+	c.lock()
+	defer c.unlock()
+
+	var found *models.Conversation
+	for id, conversation := range c.t.conversations {
+		if conversation.IsGroup || (found != nil && found.ID < id) {
+			continue
+		}
+		_, a := c.t.members[memberKey{id, username}]
+		_, b := c.t.members[memberKey{id, other}]
+		if a && b {
+			found = c.withMembers(conversation)
+		}
+	}
+	if found == nil {
+		return nil, errConversationNotFound
+	}
+	return found, nil
+}
+
+func (c *ConversationRepository) FindByMember(ctx context.Context, username string, limit int, offset int) ([]*models.Conversation, error) {
+	c.lock()
+	defer c.unlock()
+
+	lastMessage := map[int64]int64{}
+	for id, message := range c.t.messages {
+		if id > lastMessage[message.ConversationID] {
+			lastMessage[message.ConversationID] = id
+		}
+	}
+	var conversations []*models.Conversation
+	for id, conversation := range c.t.conversations {
+		lastRead, ok := c.t.members[memberKey{id, username}]
+		if !ok {
+			continue
+		}
+		conversation.LastReadMessageID = lastRead
+		for messageID, message := range c.t.messages {
+			if message.ConversationID == id && messageID > lastRead && message.Sender != username {
+				conversation.UnreadCount++
+			}
+		}
+		conversations = append(conversations, c.withMembers(conversation))
+	}
+	sort.Slice(conversations, func(i, j int) bool {
+		a, b := conversations[i], conversations[j]
+		if lastMessage[a.ID] != lastMessage[b.ID] {
+			return lastMessage[a.ID] > lastMessage[b.ID]
+		}
+		return a.ID > b.ID
+	})
+	return page(conversations, limit, offset), nil
+}
+
+// withMembers returns a copy of conversation with its members, sorted by
+// username.
+func (c *ConversationRepository) withMembers(conversation models.Conversation) *models.Conversation {
+	conversation.Members = []string{}
+	for key := range c.t.members {
+		if key.conversationID == conversation.ID {
+			conversation.Members = append(conversation.Members, key.username)
+		}
+	}
+	sort.Strings(conversation.Members)
+	return &conversation
+}
+
+func (c *ConversationRepository) CreateMessage(ctx context.Context, message *models.Message) error {
+	c.lock()
+	defer c.unlock()
+
+	if _, ok := c.t.conversations[message.ConversationID]; !ok {
+		return errMissingReference
+	}
+	if _, ok := c.t.users[message.Sender]; !ok {
+		return errMissingReference
+	}
+	message.ID = c.t.nextID("messages")
+	c.t.messages[message.ID] = *message
+	return nil
+}
+
+func (c *ConversationRepository) FindMessages(ctx context.Context, conversationID int64, before int64, limit int) ([]*models.Message, error) {
+	c.lock()
+	defer c.unlock()
+
+	var messages []*models.Message
+	for id, message := range c.t.messages {
+		if message.ConversationID == conversationID && (before == 0 || id < before) {
+			copied := message
+			messages = append(messages, &copied)
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID > messages[j].ID })
+	return page(messages, limit, 0), nil
+}
+
+func (c *ConversationRepository) FindMessage(ctx context.Context, conversationID int64, id int64) (*models.Message, error) {
+	c.lock()
+	defer c.unlock()
+
+	message, ok := c.t.messages[id]
+	if !ok || message.ConversationID != conversationID {
+		return nil, errMessageNotFound
+	}
+	return &message, nil
+}
+
+func (c *ConversationRepository) MarkRead(ctx context.Context, conversationID int64, username string, messageID int64) error {
+	c.lock()
+	defer c.unlock()
+
+	key := memberKey{conversationID, username}
+	lastRead, ok := c.t.members[key]
+	if !ok {
+		return nil
+	}
+	if messageID == 0 {
+		for id, message := range c.t.messages {
+			if message.ConversationID == conversationID && id > messageID {
+				messageID = id
+			}
+		}
+	}
+	c.t.members[key] = max(lastRead, messageID)
+	return nil
+}
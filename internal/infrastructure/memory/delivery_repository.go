@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	models "postapi/internal/domain"
+	"sort"
+	"time"
+)
+
+type DeliveryRepository struct {
+	store
+}
+
+func (d *DeliveryRepository) Enqueue(ctx context.Context, delivery *models.Delivery) error {
+	d.lock()
+	defer d.unlock()
+
+	if _, ok := d.t.users[delivery.Sender]; !ok {
+		return errMissingReference
+	}
+	delivery.ID = d.t.nextID("deliveries")
+	d.t.deliveries[delivery.ID] = models.Delivery{
+		ID:            delivery.ID,
+		Sender:        delivery.Sender,
+		Inbox:         delivery.Inbox,
+		Payload:       delivery.Payload,
+		Attempts:      delivery.Attempts,
+		NextAttemptAt: delivery.NextAttemptAt,
+		CreatedAt:     delivery.CreatedAt,
+	}
+	return nil
+}
+
+func (d *DeliveryRepository) FindDue(ctx context.Context, now time.Time, limit int) ([]*models.Delivery, error) {
+	d.lock()
+	defer d.unlock()
+
+	var deliveries []*models.Delivery
+	for _, delivery := range d.t.deliveries {
+		if !delivery.NextAttemptAt.After(now) {
+			copied := delivery
+			deliveries = append(deliveries, &copied)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		if !deliveries[i].NextAttemptAt.Equal(deliveries[j].NextAttemptAt) {
+			return deliveries[i].NextAttemptAt.Before(deliveries[j].NextAttemptAt)
+		}
+		return deliveries[i].ID < deliveries[j].ID
+	})
+	return page(deliveries, limit, 0), nil
+}
+
+func (d *DeliveryRepository) Delete(ctx context.Context, id int64) error {
+	d.lock()
+	defer d.unlock()
+
+	delete(d.t.deliveries, id)
+	return nil
+}
+
+func (d *DeliveryRepository) Reschedule(ctx context.Context, id int64, attempts int, next time.Time, lastError string) error {
+	d.lock()
+	defer d.unlock()
+
+	if delivery, ok := d.t.deliveries[id]; ok {
+		delivery.Attempts = attempts
+		delivery.NextAttemptAt = next
+		delivery.LastError = lastError
+		d.t.deliveries[id] = delivery
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package memory
+
+import models "postapi/internal/domain"
+
+// The errors of the Postgres repositories, for the same violations.
+var (
+	errUserNotFound         = models.NotFound(models.CodeUserNotFound, "user not found")
+	errPostNotFound         = models.NotFound(models.CodePostNotFound, "post not found")
+	errProfileNotFound      = models.NotFound(models.CodeProfileNotFound, "profile not found")
+	errMediaNotFound        = models.NotFound(models.CodeMediaNotFound, "media not found")
+	errConversationNotFound = models.NotFound(models.CodeConversationNotFound, "conversation not found")
+	errMessageNotFound      = models.NotFound(models.CodeMessageNotFound, "message not found")
+	errFollowNotFound       = models.NotFound(models.CodeFollowNotFound, "not following this user")
+	errInvalidCredentials   = models.Unauthorized(models.CodeInvalidCredentials, "invalid credentials")
+	errUsernameTaken        = models.Conflict(models.CodeUsernameTaken, "username is already taken")
+	errEmailTaken           = models.Conflict(models.CodeEmailTaken, "email is already registered")
+	errProfileExists        = models.Conflict(models.CodeProfileExists, "profile already exists")
+	errAlreadyFollowing     = models.Conflict(models.CodeAlreadyFollowing, "already following this user")
+	errAlreadyBlocked       = models.Conflict(models.CodeAlreadyBlocked, "user is already blocked")
+	errSelfFollow           = models.Invalid(models.CodeSelfFollow, "cannot follow yourself")
+	errSelfBlock            = models.Invalid(models.CodeSelfBlock, "cannot block yourself")
+	// errMissingReference is a foreign key that names no row.
+	errMissingReference = models.NotFound(models.CodeNotFound, "referenced resource does not exist")
+)
@@ -0,0 +1,120 @@
+package memory
+
+import (
+	"context"
+	models "postapi/internal/domain"
+	"sort"
+)
+
+type UserFollowRepository struct {
+	store
+}
+
+func (u *UserFollowRepository) Create(ctx context.Context, follow *models.UserFollow) error {
+	u.lock()
+	defer u.unlock()
+
+	switch {
+	case follow.FollowerUsername == follow.FollowedUsername:
+		return errSelfFollow
+	case u.t.follows[*follow]:
+		return errAlreadyFollowing
+	}
+	if _, ok := u.t.users[follow.FollowerUsername]; !ok {
+		return errMissingReference
+	}
+	if _, ok := u.t.users[follow.FollowedUsername]; !ok {
+		return errUserNotFound
+	}
+	u.t.follows[*follow] = true
+	return nil
+}
+
+func (u *UserFollowRepository) Delete(ctx context.Context, follow *models.UserFollow) error {
+	u.lock()
+	defer u.unlock()
+
+	if _, ok := u.t.follows[*follow]; !ok {
+		return errFollowNotFound
+	}
+	delete(u.t.follows, *follow)
+	return nil
+}
+
+func (u *UserFollowRepository) GetFollowers(ctx context.Context, username string) ([]string, error) {
+	u.lock()
+	defer u.unlock()
+
+	return u.t.followers(username), nil
+}
+
+func (u *UserFollowRepository) GetFollowing(ctx context.Context, username string) ([]string, error) {
+	u.lock()
+	defer u.unlock()
+
+	return u.t.following(username), nil
+}
+
+func (u *UserFollowRepository) FindFollowers(ctx context.Context, username string) ([]*models.UserSummary, error) {
+	u.lock()
+	defer u.unlock()
+
+	return u.t.summaries(u.t.followers(username)), nil
+}
+
+func (u *UserFollowRepository) FindFollowing(ctx context.Context, username string) ([]*models.UserSummary, error) {
+	u.lock()
+	defer u.unlock()
+
+	return u.t.summaries(u.t.following(username)), nil
+}
+
+func (u *UserFollowRepository) Exists(ctx context.Context, follow *models.UserFollow) (bool, error) {
+	u.lock()
+	defer u.unlock()
+
+	return u.t.follows[*follow], nil
+}
+
+// followers returns the active users following username, sorted.
+func (t *tables) followers(username string) []string {
+	var followers []string
+	for f := range t.follows {
+		if f.FollowedUsername == username && t.isActive(f.FollowerUsername) {
+			followers = append(followers, f.FollowerUsername)
+		}
+	}
+	sort.Strings(followers)
+	return followers
+}
+
+// following returns the active users username follows, sorted.
+func (t *tables) following(username string) []string {
+	var following []string
+	for f := range t.follows {
+		if f.FollowerUsername == username && t.isActive(f.FollowedUsername) {
+			following = append(following, f.FollowedUsername)
+		}
+	}
+	sort.Strings(following)
+	return following
+}
+
+// summaries returns the summaries of the users named, in order.
+func (t *tables) summaries(usernames []string) []*models.UserSummary {
+	summaries := make([]*models.UserSummary, len(usernames))
+	for i, username := range usernames {
+		user := t.users[username]
+		profile := t.profiles[username]
+		summary := &models.UserSummary{
+			Username:       username,
+			Email:          user.Email,
+			Description:    profile.Description,
+			ProfilePicture: profile.ProfilePicture,
+			Private:        profile.Private,
+			UserCounts:     t.counts(username),
+		}
+		summaries[i] = summary
+	}
+	return summaries
+}
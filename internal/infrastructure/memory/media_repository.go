@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"context"
+	models "postapi/internal/domain"
+	"slices"
+	"sort"
+	"time"
+)
+
+type MediaRepository struct {
+	store
+}
+
+func (m *MediaRepository) Create(ctx context.Context, media *models.Media) error {
+	m.lock()
+	defer m.unlock()
+
+	if _, ok := m.t.users[media.Owner]; !ok {
+		return errMissingReference
+	}
+	for _, stored := range m.t.media {
+		if stored.Key == media.Key {
+			return models.ErrConflict
+		}
+	}
+	media.ID = m.t.nextID("media")
+	m.t.media[media.ID] = *media
+	return nil
+}
+
+func (m *MediaRepository) FindByIDs(ctx context.Context, ids []int64) ([]*models.Media, error) {
+	m.lock()
+	defer m.unlock()
+
+	var media []*models.Media
+	for _, id := range uniqueIDs(ids) {
+		if stored, ok := m.t.media[id]; ok {
+			media = append(media, &stored)
+		}
+	}
+	sortByID(media)
+	return media, nil
+}
+
+func (m *MediaRepository) FindByKeys(ctx context.Context, keys []string) ([]*models.Media, error) {
+	m.lock()
+	defer m.unlock()
+
+	var media []*models.Media
+	for _, stored := range m.t.media {
+		if slices.Contains(keys, stored.Key) {
+			copied := stored
+			media = append(media, &copied)
+		}
+	}
+	sortByID(media)
+	return media, nil
+}
+
+func (m *MediaRepository) SetPostAttachments(ctx context.Context, postID int64, mediaIDs []int64) error {
+	m.lock()
+	defer m.unlock()
+
+	if _, ok := m.t.posts[postID]; !ok {
+		return errMissingReference
+	}
+	for i, id := range mediaIDs {
+		if _, ok := m.t.media[id]; !ok {
+			return errMediaNotFound
+		}
+		if slices.Contains(mediaIDs[:i], id) {
+			return models.ErrConflict
+		}
+	}
+	m.t.postMedia[postID] = slices.Clone(mediaIDs)
+	return nil
+}
+
+func (m *MediaRepository) FindByPosts(ctx context.Context, postIDs []int64) ([]*models.PostAttachment, error) {
+	m.lock()
+	defer m.unlock()
+
+	var attachments []*models.PostAttachment
+	ids := uniqueIDs(postIDs)
+	slices.Sort(ids)
+	for _, postID := range ids {
+		for _, mediaID := range m.t.postMedia[postID] {
+			attachments = append(attachments, &models.PostAttachment{PostID: postID, Media: m.t.media[mediaID]})
+		}
+	}
+	return attachments, nil
+}
+
+func (m *MediaRepository) FindByDeletedOwners(ctx context.Context, deletedBefore time.Time) ([]*models.Media, error) {
+	m.lock()
+	defer m.unlock()
+
+	var media []*models.Media
+	for _, stored := range m.t.media {
+		owner := m.t.users[stored.Owner]
+		if owner.DeletedAt != nil && owner.DeletedAt.Before(deletedBefore) {
+			copied := stored
+			media = append(media, &copied)
+		}
+	}
+	sortByID(media)
+	return media, nil
+}
+
+// sortByID sorts media by id, as they were uploaded.
+func sortByID(media []*models.Media) {
+	sort.Slice(media, func(i, j int) bool { return media[i].ID < media[j].ID })
+}
@@ -0,0 +1,14 @@
+package memory
+
+import (
+	"postapi/internal/domain"
+	"postapi/internal/infrastructure/repotest"
+	"testing"
+)
+
+func TestRepositories(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) (domain.Repositories, domain.UnitOfWork) {
+		s := New()
+		return s.Repositories(), s.UnitOfWork()
+	})
+}
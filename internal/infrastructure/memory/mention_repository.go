@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	models "postapi/internal/domain"
+	"sort"
+)
+
+type MentionRepository struct {
+	store
+}
+
+func (m *MentionRepository) SetPostMentions(ctx context.Context, postID int64, mentions []*models.Mention) error {
+	m.lock()
+	defer m.unlock()
+
+	if _, ok := m.t.posts[postID]; !ok {
+		return errMissingReference
+	}
+	type position struct {
+		field string
+		start int
+	}
+	seen := map[position]bool{}
+	rows := make([]models.Mention, len(mentions))
+	for i, mention := range mentions {
+		if _, ok := m.t.users[mention.Username]; !ok {
+			return errMissingReference
+		}
+		at := position{mention.Field, mention.Start}
+		if seen[at] {
+			return models.ErrConflict
+		}
+		seen[at] = true
+		rows[i] = models.Mention{
+			PostID:   postID,
+			Username: mention.Username,
+			Field:    mention.Field,
+			Start:    mention.Start,
+			End:      mention.End,
+		}
+	}
+	m.t.mentions[postID] = rows
+	return nil
+}
+
+func (m *MentionRepository) FindByPosts(ctx context.Context, postIDs []int64) ([]*models.Mention, error) {
+	m.lock()
+	defer m.unlock()
+
+	var mentions []*models.Mention
+	for _, id := range uniqueIDs(postIDs) {
+		for _, mention := range m.t.mentions[id] {
+			copied := mention
+			mentions = append(mentions, &copied)
+		}
+	}
+	sort.Slice(mentions, func(i, j int) bool {
+		a, b := mentions[i], mentions[j]
+		if a.PostID != b.PostID {
+			return a.PostID < b.PostID
+		}
+		if a.Field != b.Field {
+			return a.Field < b.Field
+		}
+		return a.Start < b.Start
+	})
+	return mentions, nil
+}
+
+func (m *MentionRepository) FindPostsMentioning(ctx context.Context, username string, limit int, offset int) ([]*models.Post, error) {
+	m.lock()
+	defer m.unlock()
+
+	posts := m.t.findPosts(func(post models.Post) bool {
+		if !m.t.visiblePost(post) {
+			return false
+		}
+		for _, mention := range m.t.mentions[post.ID] {
+			if mention.Username == username {
+				return true
+			}
+		}
+		return false
+	})
+	newestFirst(posts)
+	return page(posts, limit, offset), nil
+}
+
+// uniqueIDs returns ids without repetitions, as an IN list matches them.
+func uniqueIDs(ids []int64) []int64 {
+	seen := map[int64]bool{}
+	var unique []int64
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	models "postapi/internal/domain"
+	"slices"
+	"sort"
+)
+
+type NotificationRepository struct {
+	store
+}
+
+func (n *NotificationRepository) Create(ctx context.Context, notification *models.Notification) error {
+	n.lock()
+	defer n.unlock()
+
+	_, recipient := n.t.users[notification.Recipient]
+	_, actor := n.t.users[notification.Actor]
+	if !recipient || !actor {
+		return errMissingReference
+	}
+	if notification.PostID != nil {
+		if _, ok := n.t.posts[*notification.PostID]; !ok {
+			return errMissingReference
+		}
+	}
+	notification.ID = n.t.nextID("notifications")
+	n.t.notifications[notification.ID] = models.Notification{
+		ID:        notification.ID,
+		Recipient: notification.Recipient,
+		Actor:     notification.Actor,
+		Type:      notification.Type,
+		PostID:    notification.PostID,
+		CreatedAt: notification.CreatedAt,
+	}
+	return nil
+}
+
+func (n *NotificationRepository) FindByRecipient(ctx context.Context, recipient string, unreadOnly bool, limit int, offset int) ([]*models.Notification, error) {
+	n.lock()
+	defer n.unlock()
+
+	var notifications []*models.Notification
+	for _, notification := range n.t.notifications {
+		if n.shown(notification, recipient) && (!unreadOnly || notification.ReadAt == nil) {
+			copied := notification
+			notifications = append(notifications, &copied)
+		}
+	}
+	sort.Slice(notifications, func(i, j int) bool { return notifications[i].ID > notifications[j].ID })
+	return page(notifications, limit, offset), nil
+}
+
+func (n *NotificationRepository) MarkRead(ctx context.Context, recipient string, ids []int64) (int64, error) {
+	n.lock()
+	defer n.unlock()
+
+	return n.markRead(recipient, func(id int64) bool { return slices.Contains(ids, id) }), nil
+}
+
+func (n *NotificationRepository) MarkAllRead(ctx context.Context, recipient string) (int64, error) {
+	n.lock()
+	defer n.unlock()
+
+	return n.markRead(recipient, func(int64) bool { return true }), nil
+}
+
+func (n *NotificationRepository) CountUnread(ctx context.Context, recipient string) (int64, error) {
+	n.lock()
+	defer n.unlock()
+
+	var count int64
+	for _, notification := range n.t.notifications {
+		if n.shown(notification, recipient) && notification.ReadAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// shown reports whether notification is listed to recipient: it is theirs
+// and its actor has not been deleted.
+func (n *NotificationRepository) shown(notification models.Notification, recipient string) bool {
+	return notification.Recipient == recipient && n.t.isActive(notification.Actor)
+}
+
+// markRead marks as read the unread notifications of recipient whose id
+// match accepts, and returns how many there were.
+func (n *NotificationRepository) markRead(recipient string, match func(id int64) bool) int64 {
+	readAt := now()
+	var marked int64
+	for id, notification := range n.t.notifications {
+		if notification.Recipient == recipient && notification.ReadAt == nil && match(id) {
+			notification.ReadAt = &readAt
+			n.t.notifications[id] = notification
+			marked++
+		}
+	}
+	return marked
+}
@@ -0,0 +1,171 @@
+package memory
+
+import (
+	"context"
+	models "postapi/internal/domain"
+	"sort"
+	"time"
+)
+
+type PostRepository struct {
+	store
+}
+
+func (p *PostRepository) Create(ctx context.Context, post *models.Post) error {
+	p.lock()
+	defer p.unlock()
+
+	if _, ok := p.t.users[post.Author]; !ok {
+		return errMissingReference
+	}
+	post.CreatedAt = now()
+	post.ID = p.t.nextID("posts")
+	p.t.posts[post.ID] = models.Post{
+		ID:        post.ID,
+		Title:     post.Title,
+		Content:   post.Content,
+		Format:    post.Format,
+		Author:    post.Author,
+		CreatedAt: post.CreatedAt,
+	}
+	return nil
+}
+
+func (p *PostRepository) Update(ctx context.Context, post *models.Post) error {
+	p.lock()
+	defer p.unlock()
+
+	stored, ok := p.t.posts[post.ID]
+	if !ok || stored.Author != post.Author || stored.DeletedAt != nil {
+		return errPostNotFound
+	}
+	stored.Title = post.Title
+	stored.Content = post.Content
+	stored.Format = post.Format
+	p.t.posts[post.ID] = stored
+	return nil
+}
+
+func (p *PostRepository) Delete(ctx context.Context, id int64, author string) error {
+	p.lock()
+	defer p.unlock()
+
+	stored, ok := p.t.posts[id]
+	if !ok || stored.Author != author || stored.DeletedAt != nil {
+		return errPostNotFound
+	}
+	deletedAt := now()
+	stored.DeletedAt = &deletedAt
+	p.t.posts[id] = stored
+	return nil
+}
+
+func (p *PostRepository) FindByID(ctx context.Context, id int64) (*models.Post, error) {
+	p.lock()
+	defer p.unlock()
+
+	post, ok := p.t.posts[id]
+	if !ok || !p.t.visiblePost(post) {
+		return nil, errPostNotFound
+	}
+	return &post, nil
+}
+
+func (p *PostRepository) FindByAuthor(ctx context.Context, author string) ([]*models.Post, error) {
+	p.lock()
+	defer p.unlock()
+
+	posts := p.t.findPosts(func(post models.Post) bool {
+		return post.Author == author && p.t.visiblePost(post)
+	})
+	newestFirst(posts)
+	return posts, nil
+}
+
+func (p *PostRepository) FindLatestByAuthor(ctx context.Context, author string, limit int, offset int) ([]*models.Post, error) {
+	p.lock()
+	defer p.unlock()
+
+	posts := p.t.findPosts(func(post models.Post) bool {
+		return post.Author == author && p.t.visiblePost(post)
+	})
+	newestFirst(posts)
+	return page(posts, limit, offset), nil
+}
+
+func (p *PostRepository) FindDeletedByAuthor(ctx context.Context, author string, deletedSince time.Time) ([]*models.Post, error) {
+	p.lock()
+	defer p.unlock()
+
+	posts := p.t.findPosts(func(post models.Post) bool {
+		return post.Author == author && post.DeletedAt != nil && !post.DeletedAt.Before(deletedSince)
+	})
+	sort.Slice(posts, func(i, j int) bool {
+		if !posts[i].DeletedAt.Equal(*posts[j].DeletedAt) {
+			return posts[i].DeletedAt.After(*posts[j].DeletedAt)
+		}
+		return posts[i].ID > posts[j].ID
+	})
+	return posts, nil
+}
+
+func (p *PostRepository) FindAfter(ctx context.Context, afterID int64, limit int) ([]*models.Post, error) {
+	p.lock()
+	defer p.unlock()
+
+	posts := p.t.findPosts(func(post models.Post) bool {
+		return post.ID > afterID
+	})
+	sort.Slice(posts, func(i, j int) bool { return posts[i].ID < posts[j].ID })
+	return page(posts, limit, 0), nil
+}
+
+func (p *PostRepository) Restore(ctx context.Context, id int64, author string, deletedSince time.Time) error {
+	p.lock()
+	defer p.unlock()
+
+	stored, ok := p.t.posts[id]
+	if !ok || stored.Author != author || stored.DeletedAt == nil || stored.DeletedAt.Before(deletedSince) {
+		return errPostNotFound
+	}
+	stored.DeletedAt = nil
+	p.t.posts[id] = stored
+	return nil
+}
+
+func (p *PostRepository) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	p.lock()
+	defer p.unlock()
+
+	var purged int64
+	for id, post := range p.t.posts {
+		if post.DeletedAt != nil && post.DeletedAt.Before(deletedBefore) {
+			p.t.deletePost(id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// findPosts returns copies of the posts match accepts, in no order.
+func (t *tables) findPosts(match func(post models.Post) bool) []*models.Post {
+	var posts []*models.Post
+	for _, post := range t.posts {
+		if match(post) {
+			copied := post
+			posts = append(posts, &copied)
+		}
+	}
+	return posts
+}
+
+// newestFirst sorts posts as the feeds list them: newest first, the later
+// id first on ties.
+func newestFirst(posts []*models.Post) {
+	sort.Slice(posts, func(i, j int) bool {
+		if !posts[i].CreatedAt.Equal(posts[j].CreatedAt) {
+			return posts[i].CreatedAt.After(posts[j].CreatedAt)
+		}
+		return posts[i].ID > posts[j].ID
+	})
+}
@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"context"
+	models "postapi/internal/domain"
+)
+
+type ProfileRepository struct {
+	store
+}
+
+func (pR *ProfileRepository) FindByUsername(ctx context.Context, username string) (*models.Profile, error) {
+	pR.lock()
+	defer pR.unlock()
+
+	profile, ok := pR.t.profiles[username]
+	if !ok || !pR.t.isActive(username) {
+		return nil, errProfileNotFound
+	}
+	profile = profileRow(&profile)
+	profile.UserCounts = pR.t.counts(username)
+	return &profile, nil
+}
+
+func (pR *ProfileRepository) Create(ctx context.Context, p *models.Profile) error {
+	pR.lock()
+	defer pR.unlock()
+
+	if _, ok := pR.t.profiles[p.Username]; ok {
+		return errProfileExists
+	}
+	if _, ok := pR.t.users[p.Username]; !ok {
+		return errMissingReference
+	}
+	if err := pR.checkAvatar(p); err != nil {
+		return err
+	}
+	pR.t.profiles[p.Username] = profileRow(p)
+	return nil
+}
+
+func (pR *ProfileRepository) Update(ctx context.Context, p *models.Profile) error {
+	pR.lock()
+	defer pR.unlock()
+
+	if err := pR.checkAvatar(p); err != nil {
+		return err
+	}
+	if _, ok := pR.t.profiles[p.Username]; !ok {
+		return errProfileNotFound
+	}
+	pR.t.profiles[p.Username] = profileRow(p)
+	return nil
+}
+
+func (pR *ProfileRepository) checkAvatar(p *models.Profile) error {
+	if p.AvatarMediaID == nil {
+		return nil
+	}
+	if _, ok := pR.t.media[*p.AvatarMediaID]; !ok {
+		return errMediaNotFound
+	}
+	return nil
+}
+
+// profileRow returns the columns of p that the profiles table keeps.
+func profileRow(p *models.Profile) models.Profile {
+	profile := models.Profile{
+		Username:       p.Username,
+		Description:    p.Description,
+		ProfilePicture: p.ProfilePicture,
+		Private:        p.Private,
+	}
+	if p.AvatarMediaID != nil {
+		id := *p.AvatarMediaID
+		profile.AvatarMediaID = &id
+	}
+	return profile
+}
@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"context"
+	models "postapi/internal/domain"
+	"sort"
+)
+
+type RemoteFollowerRepository struct {
+	store
+}
+
+func (r *RemoteFollowerRepository) Add(ctx context.Context, follower *models.RemoteFollower) error {
+	r.lock()
+	defer r.unlock()
+
+	if _, ok := r.t.users[follower.Username]; !ok {
+		return errMissingReference
+	}
+	key := remoteFollowerKey{follower.Username, follower.ActorID}
+	if stored, ok := r.t.remoteFollowers[key]; ok {
+		stored.Inbox = follower.Inbox
+		stored.FollowID = follower.FollowID
+		r.t.remoteFollowers[key] = stored
+		return nil
+	}
+	r.t.remoteFollowers[key] = *follower
+	return nil
+}
+
+func (r *RemoteFollowerRepository) Remove(ctx context.Context, username string, actorID string) error {
+	r.lock()
+	defer r.unlock()
+
+	delete(r.t.remoteFollowers, remoteFollowerKey{username, actorID})
+	return nil
+}
+
+func (r *RemoteFollowerRepository) Find(ctx context.Context, username string, actorID string) (*models.RemoteFollower, error) {
+	r.lock()
+	defer r.unlock()
+
+	follower, ok := r.t.remoteFollowers[remoteFollowerKey{username, actorID}]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	return &follower, nil
+}
+
+func (r *RemoteFollowerRepository) RemoveActor(ctx context.Context, actorID string) error {
+	r.lock()
+	defer r.unlock()
+
+	for key := range r.t.remoteFollowers {
+		if key.actorID == actorID {
+			delete(r.t.remoteFollowers, key)
+		}
+	}
+	return nil
+}
+
+func (r *RemoteFollowerRepository) FindByUsername(ctx context.Context, username string) ([]*models.RemoteFollower, error) {
+	r.lock()
+	defer r.unlock()
+
+	var followers []*models.RemoteFollower
+	for key, follower := range r.t.remoteFollowers {
+		if key.username == username {
+			copied := follower
+			followers = append(followers, &copied)
+		}
+	}
+	sort.Slice(followers, func(i, j int) bool {
+		if !followers[i].CreatedAt.Equal(followers[j].CreatedAt) {
+			return followers[i].CreatedAt.Before(followers[j].CreatedAt)
+		}
+		return followers[i].ActorID < followers[j].ActorID
+	})
+	return followers, nil
+}
+
+func (r *RemoteFollowerRepository) CountByUsername(ctx context.Context, username string) (int64, error) {
+	r.lock()
+	defer r.unlock()
+
+	var count int64
+	for key := range r.t.remoteFollowers {
+		if key.username == username {
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,335 @@
+// Package memory keeps every repository in memory. It behaves like the
+// Postgres repositories, constraints included, so tests can run the use
+// cases and handlers without a database.
+package memory
+
+import (
+	"context"
+	"maps"
+	"postapi/internal/domain"
+	"sync"
+	"time"
+)
+
+// Store holds the data of every repository.
+type Store struct {
+	mu sync.Mutex
+	t  *tables
+}
+
+// New returns an empty store.
+func New() *Store {
+	return &Store{t: newTables()}
+}
+
+// Repositories returns the repositories backed by s.
+func (s *Store) Repositories() domain.Repositories {
+	return newRepositories(store{mu: &s.mu, t: s.t})
+}
+
+// UnitOfWork returns a unit of work over the repositories of s.
+func (s *Store) UnitOfWork() domain.UnitOfWork {
+	return &UnitOfWork{s: s}
+}
+
+// newRepositories returns the repositories that work on st.
+func newRepositories(st store) domain.Repositories {
+	return domain.Repositories{
+		Users:           &UserRepository{st},
+		Posts:           &PostRepository{st},
+		Profiles:        &ProfileRepository{st},
+		Follows:         &UserFollowRepository{st},
+		Blocks:          &UserBlockRepository{st},
+		Tags:            &TagRepository{st},
+		Mentions:        &MentionRepository{st},
+		Notifications:   &NotificationRepository{st},
+		Conversations:   &ConversationRepository{st},
+		Media:           &MediaRepository{st},
+		ActorKeys:       &ActorKeyRepository{st},
+		RemoteFollowers: &RemoteFollowerRepository{st},
+		Deliveries:      &DeliveryRepository{st},
+		Webmentions:     &WebmentionRepository{st},
+	}
+}
+
+// store is embedded by every repository: the tables it works on and the
+// lock that guards them, which is nil inside a unit of work.
+type store struct {
+	mu *sync.Mutex
+	t  *tables
+}
+
+func (s store) lock() {
+	if s.mu != nil {
+		s.mu.Lock()
+	}
+}
+
+func (s store) unlock() {
+	if s.mu != nil {
+		s.mu.Unlock()
+	}
+}
+
+// UnitOfWork runs functions on a copy of the store that replaces it when
+// they succeed. Units of work run one at a time and block the other
+// repository calls while they run.
+type UnitOfWork struct {
+	s *Store
+}
+
+// Do keeps what fn writes only if fn succeeds.
+func (u *UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos domain.Repositories) error) error {
+	u.s.mu.Lock()
+	defer u.s.mu.Unlock()
+
+	tx := u.s.t.clone()
+	if err := fn(ctx, newRepositories(store{t: tx})); err != nil {
+		return err
+	}
+	*u.s.t = *tx
+	return nil
+}
+
+type followKey = domain.UserFollow
+
+type blockKey = domain.UserBlock
+
+type memberKey struct {
+	conversationID int64
+	username       string
+}
+
+type remoteFollowerKey struct {
+	username string
+	actorID  string
+}
+
+// tables are the rows of every repository, stored by value so that callers
+// cannot change them. Slices in the maps are replaced, never changed in
+// place, which lets clone share them.
+type tables struct {
+	lastID map[string]int64
+
+	users         map[string]domain.User
+	posts         map[int64]domain.Post
+	profiles      map[string]domain.Profile
+	follows       map[followKey]bool
+	blocks        map[blockKey]bool
+	postTags      map[int64][]string
+	mentions      map[int64][]domain.Mention
+	notifications map[int64]domain.Notification
+	conversations map[int64]domain.Conversation
+	// members maps each member of a conversation to the last message
+	// they read.
+	members         map[memberKey]int64
+	messages        map[int64]domain.Message
+	media           map[int64]domain.Media
+	postMedia       map[int64][]int64
+	actorKeys       map[string]domain.ActorKey
+	remoteFollowers map[remoteFollowerKey]domain.RemoteFollower
+	deliveries      map[int64]domain.Delivery
+	webmentions     map[int64]domain.Webmention
+}
+
+func newTables() *tables {
+	return &tables{
+		lastID:          map[string]int64{},
+		users:           map[string]domain.User{},
+		posts:           map[int64]domain.Post{},
+		profiles:        map[string]domain.Profile{},
+		follows:         map[followKey]bool{},
+		blocks:          map[blockKey]bool{},
+		postTags:        map[int64][]string{},
+		mentions:        map[int64][]domain.Mention{},
+		notifications:   map[int64]domain.Notification{},
+		conversations:   map[int64]domain.Conversation{},
+		members:         map[memberKey]int64{},
+		messages:        map[int64]domain.Message{},
+		media:           map[int64]domain.Media{},
+		postMedia:       map[int64][]int64{},
+		actorKeys:       map[string]domain.ActorKey{},
+		remoteFollowers: map[remoteFollowerKey]domain.RemoteFollower{},
+		deliveries:      map[int64]domain.Delivery{},
+		webmentions:     map[int64]domain.Webmention{},
+	}
+}
+
+func (t *tables) clone() *tables {
+	return &tables{
+		lastID:          maps.Clone(t.lastID),
+		users:           maps.Clone(t.users),
+		posts:           maps.Clone(t.posts),
+		profiles:        maps.Clone(t.profiles),
+		follows:         maps.Clone(t.follows),
+		blocks:          maps.Clone(t.blocks),
+		postTags:        maps.Clone(t.postTags),
+		mentions:        maps.Clone(t.mentions),
+		notifications:   maps.Clone(t.notifications),
+		conversations:   maps.Clone(t.conversations),
+		members:         maps.Clone(t.members),
+		messages:        maps.Clone(t.messages),
+		media:           maps.Clone(t.media),
+		postMedia:       maps.Clone(t.postMedia),
+		actorKeys:       maps.Clone(t.actorKeys),
+		remoteFollowers: maps.Clone(t.remoteFollowers),
+		deliveries:      maps.Clone(t.deliveries),
+		webmentions:     maps.Clone(t.webmentions),
+	}
+}
+
+// nextID returns the next serial id of table.
+func (t *tables) nextID(table string) int64 {
+	t.lastID[table]++
+	return t.lastID[table]
+}
+
+// isActive reports whether username is a user that has not been deleted.
+func (t *tables) isActive(username string) bool {
+	user, ok := t.users[username]
+	return ok && user.DeletedAt == nil
+}
+
+// visiblePost reports whether post is shown: neither it nor its author is
+// deleted.
+func (t *tables) visiblePost(post domain.Post) bool {
+	return post.DeletedAt == nil && t.isActive(post.Author)
+}
+
+// deleteUser removes a user and, as the foreign keys of the schema do,
+// everything that belongs to it.
+func (t *tables) deleteUser(username string) {
+	delete(t.users, username)
+	delete(t.profiles, username)
+	delete(t.actorKeys, username)
+	for id, post := range t.posts {
+		if post.Author == username {
+			t.deletePost(id)
+		}
+	}
+	for f := range t.follows {
+		if f.FollowerUsername == username || f.FollowedUsername == username {
+			delete(t.follows, f)
+		}
+	}
+	for b := range t.blocks {
+		if b.BlockerUsername == username || b.BlockedUsername == username {
+			delete(t.blocks, b)
+		}
+	}
+	for postID, mentions := range t.mentions {
+		kept := []domain.Mention{}
+		for _, m := range mentions {
+			if m.Username != username {
+				kept = append(kept, m)
+			}
+		}
+		t.mentions[postID] = kept
+	}
+	for id, n := range t.notifications {
+		if n.Recipient == username || n.Actor == username {
+			delete(t.notifications, id)
+		}
+	}
+	for id, c := range t.conversations {
+		if c.CreatedBy == username {
+			t.deleteConversation(id)
+		}
+	}
+	for key := range t.members {
+		if key.username == username {
+			delete(t.members, key)
+		}
+	}
+	for id, m := range t.messages {
+		if m.Sender == username {
+			delete(t.messages, id)
+		}
+	}
+	for id, m := range t.media {
+		if m.Owner == username {
+			t.deleteMedia(id)
+		}
+	}
+	for key := range t.remoteFollowers {
+		if key.username == username {
+			delete(t.remoteFollowers, key)
+		}
+	}
+	for id, d := range t.deliveries {
+		if d.Sender == username {
+			delete(t.deliveries, id)
+		}
+	}
+}
+
+// deletePost removes a post with its tags, mentions, attachments,
+// notifications and webmentions.
+func (t *tables) deletePost(id int64) {
+	delete(t.posts, id)
+	delete(t.postTags, id)
+	delete(t.mentions, id)
+	delete(t.postMedia, id)
+	for nid, n := range t.notifications {
+		if n.PostID != nil && *n.PostID == id {
+			delete(t.notifications, nid)
+		}
+	}
+	for wid, w := range t.webmentions {
+		if w.PostID == id {
+			delete(t.webmentions, wid)
+		}
+	}
+}
+
+func (t *tables) deleteConversation(id int64) {
+	delete(t.conversations, id)
+	for key := range t.members {
+		if key.conversationID == id {
+			delete(t.members, key)
+		}
+	}
+	for mid, m := range t.messages {
+		if m.ConversationID == id {
+			delete(t.messages, mid)
+		}
+	}
+}
+
+// deleteMedia removes a media item, detaching it from posts and clearing
+// the avatars that use it.
+func (t *tables) deleteMedia(id int64) {
+	delete(t.media, id)
+	for postID, ids := range t.postMedia {
+		kept := []int64{}
+		for _, mediaID := range ids {
+			if mediaID != id {
+				kept = append(kept, mediaID)
+			}
+		}
+		t.postMedia[postID] = kept
+	}
+	for username, p := range t.profiles {
+		if p.AvatarMediaID != nil && *p.AvatarMediaID == id {
+			p.AvatarMediaID = nil
+			t.profiles[username] = p
+		}
+	}
+}
+
+// page returns the items of s that LIMIT limit OFFSET offset would.
+func page[T any](s []T, limit, offset int) []T {
+	if offset >= len(s) {
+		return s[:0]
+	}
+	s = s[offset:]
+	if limit >= 0 && limit < len(s) {
+		s = s[:limit]
+	}
+	return s
+}
+
+// now is the time rows are stamped with, as the Postgres repositories do.
+func now() time.Time {
+	return time.Now().UTC()
+}
@@ -0,0 +1,69 @@
+package memory
+
+import (
+	"context"
+	models "postapi/internal/domain"
+	"slices"
+	"sort"
+	"time"
+)
+
+type TagRepository struct {
+	store
+}
+
+func (t *TagRepository) SetPostTags(ctx context.Context, postID int64, tags []string) error {
+	t.lock()
+	defer t.unlock()
+
+	if _, ok := t.t.posts[postID]; !ok {
+		return errMissingReference
+	}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		if seen[tag] {
+			return models.ErrConflict
+		}
+		seen[tag] = true
+	}
+	t.t.postTags[postID] = slices.Clone(tags)
+	return nil
+}
+
+func (t *TagRepository) FindPostsByTag(ctx context.Context, tag string, limit int, offset int) ([]*models.Post, error) {
+	t.lock()
+	defer t.unlock()
+
+	posts := t.t.findPosts(func(post models.Post) bool {
+		return t.t.visiblePost(post) && slices.Contains(t.t.postTags[post.ID], tag)
+	})
+	newestFirst(posts)
+	return page(posts, limit, offset), nil
+}
+
+func (t *TagRepository) Trending(ctx context.Context, since time.Time, limit int) ([]*models.TagCount, error) {
+	t.lock()
+	defer t.unlock()
+
+	counts := map[string]int64{}
+	for postID, tags := range t.t.postTags {
+		post, ok := t.t.posts[postID]
+		if !ok || post.CreatedAt.Before(since) || !t.t.visiblePost(post) {
+			continue
+		}
+		for _, tag := range tags {
+			counts[tag]++
+		}
+	}
+	var tags []*models.TagCount
+	for tag, count := range counts {
+		tags = append(tags, &models.TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+	return page(tags, limit, 0), nil
+}
@@ -0,0 +1,127 @@
+package memory
+
+import (
+	"context"
+	models "postapi/internal/domain"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type UserRepository struct {
+	store
+}
+
+func (u *UserRepository) Create(ctx context.Context, p *models.User) error {
+	u.lock()
+	defer u.unlock()
+
+	if _, ok := u.t.users[p.Username]; ok {
+		return errUsernameTaken
+	}
+	for _, user := range u.t.users {
+		if user.Email == p.Email {
+			return errEmailTaken
+		}
+	}
+	// El coste mínimo basta: nada de lo guardado aquí sale del proceso
+	hashed, err := bcrypt.GenerateFromPassword([]byte(p.Password), bcrypt.MinCost)
+	if err != nil {
+		return err
+	}
+	u.t.users[p.Username] = models.User{Username: p.Username, Email: p.Email, Password: string(hashed)}
+	return nil
+}
+
+func (u *UserRepository) LoginUser(ctx context.Context, p *models.User) (*models.User, error) {
+	u.lock()
+	defer u.unlock()
+
+	user, ok := u.t.users[p.Username]
+	if !ok || user.DeletedAt != nil {
+		return nil, errUserNotFound
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(p.Password)); err != nil {
+		return nil, errInvalidCredentials.Wrap(err)
+	}
+	user.Password = ""
+	user.UserCounts = u.t.counts(user.Username)
+	return &user, nil
+}
+
+func (u *UserRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	u.lock()
+	defer u.unlock()
+
+	user, ok := u.t.users[username]
+	if !ok || user.DeletedAt != nil {
+		return nil, errUserNotFound
+	}
+	user.UserCounts = u.t.counts(username)
+	return &user, nil
+}
+
+func (u *UserRepository) Delete(ctx context.Context, username string) error {
+	u.lock()
+	defer u.unlock()
+
+	user, ok := u.t.users[username]
+	if !ok || user.DeletedAt != nil {
+		return errUserNotFound
+	}
+	deletedAt := now()
+	user.DeletedAt = &deletedAt
+	u.t.users[username] = user
+	return nil
+}
+
+func (u *UserRepository) Restore(ctx context.Context, p *models.User, deletedSince time.Time) (*models.User, error) {
+	u.lock()
+	defer u.unlock()
+
+	user, ok := u.t.users[p.Username]
+	if !ok || user.DeletedAt == nil || user.DeletedAt.Before(deletedSince) {
+		return nil, errUserNotFound
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(p.Password)); err != nil {
+		return nil, errInvalidCredentials.Wrap(err)
+	}
+	user.DeletedAt = nil
+	u.t.users[p.Username] = user
+	user.Password = ""
+	user.UserCounts = u.t.counts(user.Username)
+	return &user, nil
+}
+
+func (u *UserRepository) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	u.lock()
+	defer u.unlock()
+
+	var purged int64
+	for username, user := range u.t.users {
+		if user.DeletedAt != nil && user.DeletedAt.Before(deletedBefore) {
+			u.t.deleteUser(username)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// Recount finds nothing to fix: the store counts when it reads the users.
+func (u *UserRepository) Recount(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+// counts returns the UserCounts of username.
+func (t *tables) counts(username string) models.UserCounts {
+	counts := models.UserCounts{
+		FollowerCount:  int64(len(t.followers(username))),
+		FollowingCount: int64(len(t.following(username))),
+	}
+	for _, post := range t.posts {
+		if post.Author == username && post.DeletedAt == nil {
+			counts.PostCount++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"context"
+	models "postapi/internal/domain"
+	"slices"
+	"sort"
+	"time"
+)
+
+type WebmentionRepository struct {
+	store
+}
+
+func (w *WebmentionRepository) Save(ctx context.Context, webmention *models.Webmention) error {
+	w.lock()
+	defer w.unlock()
+
+	if _, ok := w.t.posts[webmention.PostID]; !ok {
+		return errMissingReference
+	}
+	for id, stored := range w.t.webmentions {
+		if stored.Source == webmention.Source && stored.Target == webmention.Target {
+			stored.PostID = webmention.PostID
+			stored.Status = webmention.Status
+			stored.Attempts = 0
+			stored.NextAttemptAt = webmention.NextAttemptAt
+			stored.UpdatedAt = webmention.UpdatedAt
+			w.t.webmentions[id] = stored
+			webmention.ID = id
+			webmention.CreatedAt = stored.CreatedAt
+			return nil
+		}
+	}
+	webmention.ID = w.t.nextID("webmentions")
+	w.t.webmentions[webmention.ID] = models.Webmention{
+		ID:            webmention.ID,
+		PostID:        webmention.PostID,
+		Source:        webmention.Source,
+		Target:        webmention.Target,
+		Status:        webmention.Status,
+		Type:          webmention.Type,
+		NextAttemptAt: webmention.NextAttemptAt,
+		CreatedAt:     webmention.CreatedAt,
+		UpdatedAt:     webmention.UpdatedAt,
+	}
+	return nil
+}
+
+func (w *WebmentionRepository) FindPending(ctx context.Context, now time.Time, limit int) ([]*models.Webmention, error) {
+	w.lock()
+	defer w.unlock()
+
+	var webmentions []*models.Webmention
+	for _, stored := range w.t.webmentions {
+		if stored.Status == models.WebmentionPending && !stored.NextAttemptAt.After(now) {
+			copied := stored
+			webmentions = append(webmentions, &copied)
+		}
+	}
+	sort.Slice(webmentions, func(i, j int) bool {
+		a, b := webmentions[i], webmentions[j]
+		if !a.NextAttemptAt.Equal(b.NextAttemptAt) {
+			return a.NextAttemptAt.Before(b.NextAttemptAt)
+		}
+		return a.ID < b.ID
+	})
+	return page(webmentions, limit, 0), nil
+}
+
+func (w *WebmentionRepository) Update(ctx context.Context, webmention *models.Webmention) error {
+	w.lock()
+	defer w.unlock()
+
+	stored, ok := w.t.webmentions[webmention.ID]
+	if !ok {
+		return nil
+	}
+	stored.Status = webmention.Status
+	stored.Type = webmention.Type
+	stored.AuthorName = webmention.AuthorName
+	stored.AuthorURL = webmention.AuthorURL
+	stored.AuthorPhoto = webmention.AuthorPhoto
+	stored.Content = webmention.Content
+	stored.Published = webmention.Published
+	stored.Attempts = webmention.Attempts
+	stored.NextAttemptAt = webmention.NextAttemptAt
+	stored.UpdatedAt = webmention.UpdatedAt
+	stored.VerifiedAt = webmention.VerifiedAt
+	w.t.webmentions[webmention.ID] = stored
+	return nil
+}
+
+func (w *WebmentionRepository) FindVerifiedByPosts(ctx context.Context, postIDs []int64) ([]*models.Webmention, error) {
+	w.lock()
+	defer w.unlock()
+
+	webmentions := []*models.Webmention{}
+	for _, stored := range w.t.webmentions {
+		if stored.Status == models.WebmentionVerified && slices.Contains(postIDs, stored.PostID) {
+			copied := stored
+			webmentions = append(webmentions, &copied)
+		}
+	}
+	// Como en Postgres, las verificadas sin fecha van al final
+	sort.Slice(webmentions, func(i, j int) bool {
+		a, b := webmentions[i], webmentions[j]
+		switch {
+		case a.VerifiedAt == nil || b.VerifiedAt == nil:
+			if (a.VerifiedAt == nil) != (b.VerifiedAt == nil) {
+				return b.VerifiedAt == nil
+			}
+		case !a.VerifiedAt.Equal(*b.VerifiedAt):
+			return a.VerifiedAt.Before(*b.VerifiedAt)
+		}
+		return a.ID < b.ID
+	})
+	return webmentions, nil
+}
+
+func (w *WebmentionRepository) DeleteBySource(ctx context.Context, source string) error {
+	w.lock()
+	defer w.unlock()
+
+	for id, stored := range w.t.webmentions {
+		if stored.Source == source {
+			delete(w.t.webmentions, id)
+		}
+	}
+	return nil
+}
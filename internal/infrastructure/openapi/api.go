@@ -0,0 +1,379 @@
+package openapi
+
+import (
+	"net/http"
+	"postapi/internal/application"
+	"postapi/internal/domain"
+)
+
+// Bodies the handlers build from maps rather than domain types.
+type (
+	LoginResponse struct {
+		User  domain.JsonUser `json:"user"`
+		Token string          `json:"token"`
+	}
+
+	NotificationsUpdated struct {
+		Updated int64 `json:"updated"`
+	}
+
+	UnreadCount struct {
+		Unread int64 `json:"unread"`
+	}
+
+	WebmentionRequest struct {
+		Source string `json:"source"`
+		Target string `json:"target"`
+	}
+
+	WebmentionAccepted struct {
+		ID     int64  `json:"id"`
+		Status string `json:"status"`
+	}
+
+	// MicropubRequest is a JSON Micropub request: an h-entry to create, or an
+	// action on the post at url.
+	MicropubRequest struct {
+		Type       []string                       `json:"type,omitempty"`
+		Properties application.MicropubProperties `json:"properties,omitempty"`
+		Action     string                         `json:"action,omitempty"`
+		URL        string                         `json:"url,omitempty"`
+		Replace    application.MicropubProperties `json:"replace,omitempty"`
+		Add        application.MicropubProperties `json:"add,omitempty"`
+		// Delete is a list of properties or an object of values to remove.
+		Delete any `json:"delete,omitempty"`
+	}
+
+	// MicropubForm lists the form fields the endpoint understands; any other
+	// field is read as a property, with its "[]" suffix removed.
+	MicropubForm struct {
+		H           string   `json:"h,omitempty"`
+		Action      string   `json:"action,omitempty"`
+		URL         string   `json:"url,omitempty"`
+		Name        string   `json:"name,omitempty"`
+		Content     string   `json:"content,omitempty"`
+		Category    []string `json:"category[],omitempty"`
+		Photo       []string `json:"photo[],omitempty"`
+		AccessToken string   `json:"access_token,omitempty"`
+	}
+
+	MicropubConfig struct {
+		MediaEndpoint string   `json:"media-endpoint"`
+		SyndicateTo   []any    `json:"syndicate-to"`
+		Q             []string `json:"q"`
+	}
+
+	MicropubError struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+)
+
+const (
+	// Esquemas de seguridad
+	bearerAuth  = "bearerAuth"
+	accessToken = "accessToken"
+)
+
+// upload is the multipart body of the media endpoints.
+var upload = Schema{
+	"type":     "object",
+	"required": []string{"file"},
+	"properties": map[string]Schema{
+		"file": {"type": "string", "contentMediaType": "application/octet-stream"},
+	},
+}
+
+var binary = Schema{"type": "string", "contentMediaType": "application/octet-stream"}
+
+// New describes every route of the API served from baseURL.
+func New(baseURL string) *Document {
+	doc := &Document{
+		OpenAPI: Version,
+		Info: Info{
+			Title:       "postapi",
+			Version:     "1.0.0",
+			Description: "Microblogging API with real-time events, feeds, ActivityPub, Webmention and Micropub.",
+		},
+		Servers: []Server{{URL: baseURL}},
+	}
+	b := newBuilder(doc)
+	doc.Components.SecuritySchemes = map[string]SecurityScheme{
+		bearerAuth:  {Type: "http", Scheme: "bearer", BearerFormat: "JWT", Description: "Token returned by /api/login"},
+		accessToken: {Type: "apiKey", In: "query", Name: "access_token", Description: "The same token, for clients that cannot set headers"},
+	}
+
+	b.tag = "docs"
+	b.add("GET", "/api/openapi.json", "getOpenAPI", "This document").
+		respond(http.StatusOK, "OpenAPI document", "application/json", Schema{"type": "object"})
+
+	b.tag = "auth"
+	b.add("POST", "/api/register", "register", "Create an account").
+		json(domain.RegisterRequest{}).
+		returns(http.StatusOK, "The new user", domain.JsonUser{}).
+		fails(http.StatusBadRequest, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusInternalServerError)
+	b.add("POST", "/api/login", "login", "Get a token").
+		json(domain.UserResponse{}).
+		returns(http.StatusOK, "The user and its token", LoginResponse{}).
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)
+	b.add("DELETE", "/api/users/me", "deleteAccount", "Delete the account").
+		describe("The account can be restored during the trash retention period.").
+		auth(bearerAuth).
+		returns(http.StatusNoContent, "Deleted", nil).
+		fails(http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)
+	b.add("POST", "/api/users/restore", "restoreAccount", "Restore a deleted account").
+		json(domain.UserResponse{}).
+		returns(http.StatusOK, "The restored user", domain.JsonUser{}).
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)
+
+	b.tag = "posts"
+	b.add("POST", "/api/posts", "createPost", "Publish a post").
+		auth(bearerAuth).
+		json(domain.PostRequest{}).
+		returns(http.StatusOK, "The new post", domain.JsonPost{}).
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusUnprocessableEntity, http.StatusInternalServerError)
+	b.add("GET", "/api/posts/{post_id}", "getPost", "Get a post").
+		describe("Browsers and Webmention verifiers asking for text/html get the post as an h-entry page.").
+		returns(http.StatusOK, "The post", domain.JsonPost{}).
+		respond(http.StatusOK, "The post", "text/html", Schema{"type": "string"}).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+	b.add("PATCH", "/api/posts/{post_id}", "updatePost", "Edit a post").
+		auth(bearerAuth).
+		json(domain.PostRequest{}).
+		returns(http.StatusOK, "The updated post", domain.JsonPost{}).
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError)
+	b.add("DELETE", "/api/posts/{post_id}", "deletePost", "Move a post to the trash").
+		auth(bearerAuth).
+		returns(http.StatusNoContent, "Deleted", nil).
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)
+
+	b.tag = "tags"
+	b.add("GET", "/api/tags/trending", "getTrendingTags", "Most used tags").
+		query("hours", "integer", "Window in hours, 24 by default and at most 720").
+		returns(http.StatusOK, "Tags by number of posts", []domain.JsonTagCount{}).
+		fails(http.StatusBadRequest, http.StatusInternalServerError)
+	b.add("GET", "/api/tags/{tag}/posts", "getPostsByTag", "Posts with a tag").
+		paginated().
+		returns(http.StatusOK, "Posts, newest first", []domain.JsonPost{}).
+		fails(http.StatusBadRequest, http.StatusInternalServerError)
+
+	b.tag = "notifications"
+	b.add("GET", "/api/notifications", "getNotifications", "List notifications").
+		auth(bearerAuth).
+		paginated().
+		query("unread", "boolean", "Only unread notifications").
+		returns(http.StatusOK, "Notifications, newest first", []domain.JsonNotification{}).
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)
+	b.add("POST", "/api/notifications/read", "markNotificationsRead", "Mark notifications as read").
+		auth(bearerAuth).
+		json(domain.NotificationReadRequest{}).
+		returns(http.StatusOK, "Number of notifications updated", NotificationsUpdated{}).
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)
+	b.add("GET", "/api/notifications/unread-count", "getUnreadCount", "Count unread notifications").
+		auth(bearerAuth).
+		returns(http.StatusOK, "Unread notifications", UnreadCount{}).
+		fails(http.StatusUnauthorized, http.StatusInternalServerError)
+
+	b.tag = "conversations"
+	b.add("POST", "/api/conversations", "createConversation", "Start a conversation").
+		auth(bearerAuth).
+		json(domain.ConversationRequest{}).
+		returns(http.StatusOK, "The conversation", domain.JsonConversation{}).
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusUnprocessableEntity, http.StatusInternalServerError)
+	b.add("GET", "/api/conversations", "getConversations", "List conversations").
+		auth(bearerAuth).
+		paginated().
+		returns(http.StatusOK, "Conversations, most recent first", []domain.JsonConversation{}).
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)
+	b.add("GET", "/api/conversations/{conversation_id}/messages", "getMessages", "List messages").
+		auth(bearerAuth).
+		query("limit", "integer", "Page size, 20 by default and at most 100").
+		query("before", "integer", "Cursor: only messages with a lower id").
+		returns(http.StatusOK, "Messages, newest first", domain.JsonMessagePage{}).
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)
+	b.add("POST", "/api/conversations/{conversation_id}/messages", "sendMessage", "Send a message").
+		auth(bearerAuth).
+		json(domain.MessageRequest{}).
+		returns(http.StatusOK, "The message", domain.JsonMessage{}).
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError)
+	b.add("POST", "/api/conversations/{conversation_id}/read", "markConversationRead", "Mark messages as read").
+		auth(bearerAuth).
+		json(domain.MessageReadRequest{}).
+		returns(http.StatusNoContent, "Marked", nil).
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError)
+
+	b.tag = "realtime"
+	b.add("GET", "/api/stream", "stream", "Server-sent events").
+		auth(bearerAuth, accessToken).
+		header("Last-Event-ID", "Resume after this event").
+		query("last_event_id", "string", "Resume after this event, for clients that cannot set headers").
+		respond(http.StatusOK, "Event stream", "text/event-stream", Schema{"type": "string"}).
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusServiceUnavailable)
+	b.add("GET", "/api/ws", "websocket", "WebSocket events").
+		describe("Clients subscribe to streams by sending JSON messages after the upgrade.").
+		auth(bearerAuth, accessToken).
+		returns(http.StatusSwitchingProtocols, "Upgraded to a WebSocket", nil).
+		fails(http.StatusUnauthorized, http.StatusServiceUnavailable)
+
+	b.tag = "trash"
+	b.add("GET", "/api/trash/posts", "getTrash", "Deleted posts that can be restored").
+		auth(bearerAuth).
+		returns(http.StatusOK, "Deleted posts", []domain.JsonPost{}).
+		fails(http.StatusUnauthorized, http.StatusInternalServerError)
+	b.add("POST", "/api/trash/posts/{post_id}/restore", "restorePost", "Restore a deleted post").
+		auth(bearerAuth).
+		returns(http.StatusOK, "The restored post", domain.JsonPost{}).
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)
+
+	b.tag = "users"
+	b.add("GET", "/api/users/{username}", "getUser", "Get a user").
+		returns(http.StatusOK, "The user", domain.JsonUser{}).
+		fails(http.StatusNotFound, http.StatusInternalServerError)
+	b.add("GET", "/api/users/{username}/posts", "getPostsByUser", "Posts of a user").
+		returns(http.StatusOK, "Posts, newest first", []domain.JsonPost{}).
+		fails(http.StatusInternalServerError)
+	b.add("GET", "/api/users/{username}/mentions", "getMentions", "Posts mentioning a user").
+		paginated().
+		returns(http.StatusOK, "Posts, newest first", []domain.JsonPost{}).
+		fails(http.StatusBadRequest, http.StatusInternalServerError)
+	b.add("POST", "/api/follow/{username}", "follow", "Follow a user").
+		auth(bearerAuth).
+		returns(http.StatusOK, "The follow", domain.JsonUserFollow{}).
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusInternalServerError)
+	b.add("DELETE", "/api/unfollow/{username}", "unfollow", "Stop following a user").
+		auth(bearerAuth).
+		returns(http.StatusOK, "The removed follow", domain.JsonUserFollow{}).
+		fails(http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)
+	b.add("POST", "/api/block/{username}", "block", "Block a user").
+		auth(bearerAuth).
+		returns(http.StatusOK, "The block", domain.JsonUserBlock{}).
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusInternalServerError)
+	b.add("DELETE", "/api/unblock/{username}", "unblock", "Unblock a user").
+		auth(bearerAuth).
+		returns(http.StatusOK, "The removed block", domain.JsonUserBlock{}).
+		fails(http.StatusUnauthorized, http.StatusInternalServerError)
+	b.add("GET", "/api/users/{username}/followers", "getFollowers", "Followers of a user").
+		returns(http.StatusOK, "Users", []domain.JsonUserSummary{}).
+		fails(http.StatusInternalServerError)
+	b.add("GET", "/api/users/{username}/following", "getFollowing", "Users a user follows").
+		returns(http.StatusOK, "Users", []domain.JsonUserSummary{}).
+		fails(http.StatusInternalServerError)
+
+	b.tag = "profiles"
+	b.add("GET", "/api/profiles/{username}", "getProfile", "Get a profile").
+		returns(http.StatusOK, "The profile", domain.JsonProfile{}).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+	b.add("POST", "/api/profiles/me", "createProfile", "Create the profile").
+		auth(bearerAuth).
+		json(domain.ProfileRequest{}).
+		returns(http.StatusOK, "The profile", domain.JsonProfile{}).
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusInternalServerError)
+	b.add("PATCH", "/api/profiles/me", "updateProfile", "Edit the profile").
+		auth(bearerAuth).
+		json(domain.ProfileRequest{}).
+		returns(http.StatusOK, "The profile", domain.JsonProfile{}).
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError)
+	b.add("GET", "/api/avatars/{username}", "getAvatar", "Generated identicon").
+		query("format", "string", "svg (default) or png").
+		query("size", "integer", "Size in pixels of the png").
+		header("If-None-Match", "ETag of a cached avatar").
+		respond(http.StatusOK, "The avatar", "image/svg+xml", Schema{"type": "string"}).
+		respond(http.StatusOK, "The avatar", "image/png", binary).
+		returns(http.StatusNotModified, "Not modified", nil).
+		fails(http.StatusBadRequest, http.StatusInternalServerError)
+
+	b.tag = "media"
+	b.add("POST", "/api/media", "uploadMedia", "Upload an image").
+		auth(bearerAuth).
+		body("multipart/form-data", upload).
+		returns(http.StatusCreated, "The stored media", domain.JsonMedia{}).
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity, http.StatusInternalServerError)
+	b.add("GET", "/media/{key}", "getMedia", "Download a stored file").
+		respond(http.StatusOK, "The file", "*/*", binary).
+		fails(http.StatusNotFound)
+
+	b.tag = "feeds"
+	feeds := []struct{ ext, name, contentType string }{
+		{"rss", "RSS", "application/rss+xml"},
+		{"atom", "Atom", "application/atom+xml"},
+		{"json", "JSON", "application/feed+json"},
+	}
+	for _, f := range feeds {
+		path := "/users/{username}/feed." + f.ext
+		schema := Schema{"type": "string"}
+		for _, method := range []string{"GET", "HEAD"} {
+			op := "get"
+			if method == "HEAD" {
+				op = "head"
+			}
+			b.add(method, path, op+f.name+"Feed", f.name+" feed of a user's posts").
+				respond(http.StatusOK, "The feed", f.contentType, schema).
+				fails(http.StatusNotFound, http.StatusInternalServerError)
+		}
+	}
+
+	b.tag = "activitypub"
+	activity := domain.ActivityContentType
+	b.add("GET", "/.well-known/webfinger", "webfinger", "Resolve an acct: resource").
+		query("resource", "string", "acct:user@host or an actor URL").
+		respond(http.StatusOK, "JRD", "application/jrd+json", b.schema(domain.WebFinger{})).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+	b.add("GET", "/users/{username}", "getActor", "ActivityPub actor").
+		respond(http.StatusOK, "The actor", activity, b.schema(domain.APActor{})).
+		fails(http.StatusNotFound, http.StatusInternalServerError)
+	b.add("POST", "/users/{username}/inbox", "postInbox", "Deliver an activity").
+		describe("Requests must carry an HTTP Signature from the actor of the activity.").
+		body(activity, b.schema(domain.APIncoming{})).
+		returns(http.StatusAccepted, "Accepted", nil).
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)
+	b.add("GET", "/users/{username}/outbox", "getOutbox", "Public activities of a user").
+		query("page", "integer", "Page number; without it the collection is returned").
+		respond(http.StatusOK, "The outbox or one of its pages", activity, Schema{"oneOf": []Schema{
+			b.schema(domain.APOrderedCollection{}),
+			b.schema(domain.APOrderedCollectionPage{}),
+		}}).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+	b.add("GET", "/users/{username}/followers", "getRemoteFollowers", "Followers collection").
+		respond(http.StatusOK, "The collection", activity, b.schema(domain.APOrderedCollection{})).
+		fails(http.StatusNotFound, http.StatusInternalServerError)
+	b.add("GET", "/users/{username}/notes/{post_id}", "getNote", "A post as a Note").
+		respond(http.StatusOK, "The note", activity, b.schema(domain.APNote{})).
+		fails(http.StatusNotFound, http.StatusInternalServerError)
+
+	b.tag = "indieweb"
+	b.add("POST", "/webmention", "receiveWebmention", "Receive a Webmention").
+		describe("The source is fetched and verified in the background.").
+		body("application/x-www-form-urlencoded", b.schema(WebmentionRequest{})).
+		returns(http.StatusAccepted, "Queued for verification", WebmentionAccepted{}).
+		fails(http.StatusBadRequest, http.StatusInternalServerError)
+	b.add("GET", "/micropub", "queryMicropub", "Micropub queries").
+		auth(bearerAuth).
+		query("q", "string", "config, source or syndicate-to").
+		query("url", "string", "Post to return with q=source").
+		query("properties[]", "string", "Properties to return with q=source").
+		respond(http.StatusOK, "The configuration or the source of a post", "application/json", Schema{"oneOf": []Schema{
+			b.schema(MicropubConfig{}),
+			Schema{"type": "object", "properties": map[string]Schema{"type": {"type": "array"}, "properties": b.schema(application.MicropubProperties{})}},
+		}}).
+		respond(http.StatusBadRequest, "Bad Request", "application/json", b.schema(MicropubError{})).
+		fails(http.StatusUnauthorized)
+	b.add("POST", "/micropub", "postMicropub", "Create, update or delete posts").
+		describe("The token may also be sent in the access_token field of a form.").
+		auth(bearerAuth).
+		json(MicropubRequest{}).
+		body("application/x-www-form-urlencoded", b.schema(MicropubForm{})).
+		body("multipart/form-data", b.schema(MicropubForm{})).
+		returns(http.StatusCreated, "Created; the Location header holds the URL of the post", nil).
+		returns(http.StatusNoContent, "Updated or deleted", nil).
+		respond(http.StatusBadRequest, "Bad Request", "application/json", b.schema(MicropubError{})).
+		respond(http.StatusForbidden, "Forbidden", "application/json", b.schema(MicropubError{})).
+		respond(http.StatusInternalServerError, "Internal Server Error", "application/json", b.schema(MicropubError{})).
+		fails(http.StatusUnauthorized)
+	b.add("POST", "/micropub/media", "uploadMicropubMedia", "Micropub media endpoint").
+		auth(bearerAuth).
+		body("multipart/form-data", upload).
+		returns(http.StatusCreated, "Stored; the Location header holds the URL of the file", domain.JsonMedia{}).
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity, http.StatusInternalServerError)
+
+	return doc
+}
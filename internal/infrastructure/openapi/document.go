@@ -0,0 +1,222 @@
+// Package openapi describes the HTTP API as an OpenAPI 3.1 document. The
+// schemas are derived from the Json* types the handlers encode, so the
+// document follows the code instead of being kept in sync by hand.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"postapi/internal/middleware"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const Version = "3.1.0"
+
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Servers    []Server            `json:"servers,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Server struct {
+	URL string `json:"url"`
+}
+
+// PathItem maps lower-case HTTP methods to their operation.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary"`
+	Description string                `json:"description,omitempty"`
+	OperationID string                `json:"operationId"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string `json:"name"`
+	In          string `json:"in"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Schema      Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]Schema         `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// Schema is a JSON Schema (draft 2020-12, as used by OpenAPI 3.1).
+type Schema map[string]any
+
+// Operation returns the operation for method on the path template, or nil.
+func (d *Document) Operation(method, path string) *Operation {
+	return d.Paths[path][strings.ToLower(method)]
+}
+
+var pathParamRegex = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// builder assembles a Document, registering the schemas of the Go types it
+// is given as components.
+type builder struct {
+	doc   *Document
+	types map[string]reflect.Type
+	tag   string
+}
+
+func newBuilder(doc *Document) *builder {
+	doc.Paths = map[string]PathItem{}
+	doc.Components.Schemas = map[string]Schema{}
+	return &builder{doc: doc, types: map[string]reflect.Type{}}
+}
+
+// operation is the fluent handle returned by builder.add.
+type operation struct {
+	b  *builder
+	op *Operation
+}
+
+// add registers an operation under the current tag. Path parameters are read
+// from the template: those ending in _id are integers, the rest strings.
+func (b *builder) add(method, path, operationID, summary string) *operation {
+	key := strings.ToLower(method)
+	if b.doc.Paths[path] == nil {
+		b.doc.Paths[path] = PathItem{}
+	}
+	if _, ok := b.doc.Paths[path][key]; ok {
+		panic(fmt.Sprintf("openapi: %s %s is described twice", method, path))
+	}
+
+	op := &Operation{Summary: summary, OperationID: operationID, Responses: map[string]Response{}}
+	if b.tag != "" {
+		op.Tags = []string{b.tag}
+	}
+	for _, m := range pathParamRegex.FindAllStringSubmatch(path, -1) {
+		schema := Schema{"type": "string"}
+		if strings.HasSuffix(m[1], "_id") {
+			schema = Schema{"type": "integer", "format": "int64"}
+		}
+		op.Parameters = append(op.Parameters, Parameter{Name: m[1], In: "path", Required: true, Schema: schema})
+	}
+	b.doc.Paths[path][key] = op
+	return &operation{b: b, op: op}
+}
+
+func (o *operation) describe(description string) *operation {
+	o.op.Description = description
+	return o
+}
+
+// auth requires one of the given security schemes.
+func (o *operation) auth(schemes ...string) *operation {
+	for _, s := range schemes {
+		o.op.Security = append(o.op.Security, map[string][]string{s: {}})
+	}
+	return o
+}
+
+// query adds an optional query parameter of a JSON Schema type.
+func (o *operation) query(name, typ, description string) *operation {
+	o.op.Parameters = append(o.op.Parameters, Parameter{Name: name, In: "query", Description: description, Schema: Schema{"type": typ}})
+	return o
+}
+
+// header adds an optional request header.
+func (o *operation) header(name, description string) *operation {
+	o.op.Parameters = append(o.op.Parameters, Parameter{Name: name, In: "header", Description: description, Schema: Schema{"type": "string"}})
+	return o
+}
+
+// paginated adds the limit and offset parameters of paginated lists.
+func (o *operation) paginated() *operation {
+	return o.
+		query("limit", "integer", "Page size, 20 by default and at most 100").
+		query("offset", "integer", "Number of items to skip")
+}
+
+// body adds a request body of the given content type.
+func (o *operation) body(contentType string, schema Schema) *operation {
+	if o.op.RequestBody == nil {
+		o.op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{}}
+	}
+	o.op.RequestBody.Content[contentType] = MediaType{Schema: schema}
+	return o
+}
+
+// json adds a JSON request body shaped like v.
+func (o *operation) json(v any) *operation {
+	return o.body("application/json", o.b.schema(v))
+}
+
+// respond adds a response of the given content type; a nil schema means an
+// empty body.
+func (o *operation) respond(status int, description, contentType string, schema Schema) *operation {
+	code := strconv.Itoa(status)
+	resp, ok := o.op.Responses[code]
+	if !ok {
+		resp = Response{Description: description}
+	}
+	if schema != nil {
+		if resp.Content == nil {
+			resp.Content = map[string]MediaType{}
+		}
+		resp.Content[contentType] = MediaType{Schema: schema}
+	}
+	o.op.Responses[code] = resp
+	return o
+}
+
+// returns adds a JSON response shaped like v, or an empty one if v is nil.
+func (o *operation) returns(status int, description string, v any) *operation {
+	var schema Schema
+	if v != nil {
+		schema = o.b.schema(v)
+	}
+	return o.respond(status, description, "application/json", schema)
+}
+
+// fails adds the problem details responses for the given statuses.
+func (o *operation) fails(statuses ...int) *operation {
+	schema := o.b.schema(middleware.Problem{})
+	for _, status := range statuses {
+		o.respond(status, http.StatusText(status), middleware.ProblemContentType, schema)
+	}
+	return o
+}
@@ -0,0 +1,151 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testAuthor struct {
+	Name    string `json:"name" validate:"required,min=3,max=30,username"`
+	Email   string `json:"email" validate:"email"`
+	Website string `json:"website" validate:"url"`
+	Role    string `json:"role" validate:"oneof=owner guest"`
+}
+
+type testEntry struct {
+	ID        int64             `json:"id"`
+	Title     string            `json:"title,omitempty"`
+	Author    testAuthor        `json:"author"`
+	Editor    *testAuthor       `json:"editor"`
+	Reviewer  *testAuthor       `json:"reviewer,omitempty"`
+	Tags      []string          `json:"tags"`
+	Meta      map[string]int    `json:"meta"`
+	Extra     any               `json:"extra"`
+	CreatedAt time.Time         `json:"created_at"`
+	DeletedAt *time.Time        `json:"deleted_at"`
+	Raw       json.RawMessage   `json:"raw,omitempty"`
+	Replies   []*testEntry      `json:"replies"`
+	Labels    map[string]string `json:"-"`
+	internal  string
+}
+
+func TestSchema(t *testing.T) {
+	b := newBuilder(&Document{})
+	ref := b.schema(testEntry{})
+	if ref["$ref"] != "#/components/schemas/testEntry" {
+		t.Fatalf("schema() = %v", ref)
+	}
+
+	entry := b.doc.Components.Schemas["testEntry"]
+	props := entry["properties"].(map[string]Schema)
+	want := map[string]Schema{
+		"id":         {"type": "integer", "format": "int64"},
+		"title":      {"type": "string"},
+		"author":     {"$ref": "#/components/schemas/testAuthor"},
+		"editor":     {"anyOf": []Schema{{"$ref": "#/components/schemas/testAuthor"}, {"type": "null"}}},
+		"reviewer":   {"$ref": "#/components/schemas/testAuthor"},
+		"tags":       {"type": "array", "items": Schema{"type": "string"}},
+		"meta":       {"type": "object", "additionalProperties": Schema{"type": "integer", "format": "int64"}},
+		"extra":      {},
+		"created_at": {"type": "string", "format": "date-time"},
+		"deleted_at": {"type": []string{"string", "null"}, "format": "date-time"},
+		"raw":        {},
+		"replies":    {"type": "array", "items": Schema{"anyOf": []Schema{{"$ref": "#/components/schemas/testEntry"}, {"type": "null"}}}},
+	}
+	if !reflect.DeepEqual(props, want) {
+		t.Errorf("properties = %v, want %v", props, want)
+	}
+
+	required := entry["required"].([]string)
+	wantRequired := []string{"id", "author", "editor", "tags", "meta", "extra", "created_at", "deleted_at", "replies"}
+	if !reflect.DeepEqual(required, wantRequired) {
+		t.Errorf("required = %v, want %v", required, wantRequired)
+	}
+	author, ok := b.doc.Components.Schemas["testAuthor"]
+	if !ok {
+		t.Fatal("testAuthor was not registered")
+	}
+	wantAuthor := map[string]Schema{
+		"name":    {"type": "string", "minLength": 3, "maxLength": 30, "pattern": "^[A-Za-z0-9_]+$"},
+		"email":   {"type": "string", "format": "email"},
+		"website": {"type": "string", "format": "uri"},
+		"role":    {"type": "string", "enum": []string{"owner", "guest"}},
+	}
+	if got := author["properties"]; !reflect.DeepEqual(got, wantAuthor) {
+		t.Errorf("testAuthor properties = %v, want %v", got, wantAuthor)
+	}
+}
+
+// collectRefs returns every $ref in a decoded JSON document.
+func collectRefs(v any, refs []string) []string {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if s, ok := child.(string); ok && k == "$ref" {
+				refs = append(refs, s)
+				continue
+			}
+			refs = collectRefs(child, refs)
+		}
+	case []any:
+		for _, child := range v {
+			refs = collectRefs(child, refs)
+		}
+	}
+	return refs
+}
+
+func TestNew(t *testing.T) {
+	doc := New("https://blog.example")
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	refs := collectRefs(decoded, nil)
+	if len(refs) == 0 {
+		t.Fatal("the document references no schema")
+	}
+	for _, ref := range refs {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("%s does not resolve", ref)
+		}
+	}
+
+	ids := map[string]string{}
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			if other, ok := ids[op.OperationID]; ok {
+				t.Errorf("operationId %s is used by %s and %s %s", op.OperationID, other, method, path)
+			}
+			ids[op.OperationID] = method + " " + path
+			if len(op.Responses) == 0 {
+				t.Errorf("%s %s has no responses", method, path)
+			}
+			for _, security := range op.Security {
+				for scheme := range security {
+					if _, ok := doc.Components.SecuritySchemes[scheme]; !ok {
+						t.Errorf("%s %s uses unknown security scheme %s", method, path, scheme)
+					}
+				}
+			}
+		}
+	}
+
+	post := doc.Operation("GET", "/api/posts/{post_id}")
+	if post == nil || post.Parameters[0].Name != "post_id" || post.Parameters[0].Schema["type"] != "integer" {
+		t.Errorf("GET /api/posts/{post_id} = %+v", post)
+	}
+	if _, ok := doc.Components.Schemas["JsonPost"]["properties"].(map[string]Schema)["attachments"]; !ok {
+		t.Error("JsonPost has no attachments property")
+	}
+}
@@ -0,0 +1,163 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+)
+
+// schema returns the schema of the JSON encoding of v. Named structs are
+// registered as components and referenced.
+func (b *builder) schema(v any) Schema {
+	return b.schemaOf(reflect.TypeOf(v))
+}
+
+func (b *builder) schemaOf(t reflect.Type) Schema {
+	switch t {
+	case timeType:
+		return Schema{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(b.schemaOf(t.Elem()))
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		return b.component(t)
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return Schema{"type": "string", "contentEncoding": "base64"}
+		}
+		return Schema{"type": "array", "items": b.schemaOf(t.Elem())}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": b.schemaOf(t.Elem())}
+	case reflect.Interface:
+		return Schema{}
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return Schema{"type": "integer", "format": "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return Schema{"type": "integer", "format": "int32"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	case reflect.String:
+		return Schema{"type": "string"}
+	}
+	panic(fmt.Sprintf("openapi: no schema for %s", t))
+}
+
+// component registers the named struct t and returns a reference to it.
+func (b *builder) component(t reflect.Type) Schema {
+	name := t.Name()
+	ref := Schema{"$ref": "#/components/schemas/" + name}
+	if known, ok := b.types[name]; ok {
+		if known != t {
+			panic(fmt.Sprintf("openapi: %s and %s share the schema name %s", known, t, name))
+		}
+		return ref
+	}
+	// Registered before its fields so that recursive types terminate.
+	b.types[name] = t
+	b.doc.Components.Schemas[name] = b.object(t)
+	return ref
+}
+
+// object describes the exported fields of a struct as encoding/json sees
+// them. Fields without omitempty are required.
+func (b *builder) object(t reflect.Type) Schema {
+	properties := map[string]Schema{}
+	required := []string{}
+	b.fields(t, properties, &required)
+
+	s := Schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+func (b *builder) fields(t reflect.Type, properties map[string]Schema, required *[]string) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			b.fields(f.Type, properties, required)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		omitEmpty := strings.Contains(opts, "omitempty") || strings.Contains(opts, "omitzero")
+		if omitEmpty && f.Type.Kind() == reflect.Pointer {
+			// An omitted pointer is never encoded as null.
+			properties[name] = b.schemaOf(f.Type.Elem())
+		} else {
+			properties[name] = b.schemaOf(f.Type)
+		}
+		if rules := f.Tag.Get("validate"); rules != "" {
+			constrain(properties[name], rules)
+		}
+		if !omitEmpty {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// constrain adds the validate rules of a string field to its schema. Empty
+// strings skip every rule but required, which the schema cannot express.
+func constrain(s Schema, rules string) {
+	for _, rule := range strings.Split(rules, ",") {
+		rule, arg, _ := strings.Cut(rule, "=")
+		switch rule {
+		case "min":
+			s["minLength"], _ = strconv.Atoi(arg)
+		case "max":
+			s["maxLength"], _ = strconv.Atoi(arg)
+		case "username":
+			s["pattern"] = "^[A-Za-z0-9_]+$"
+		case "email":
+			s["format"] = "email"
+		case "url":
+			s["format"] = "uri"
+		case "oneof":
+			s["enum"] = strings.Fields(arg)
+		}
+	}
+}
+
+// nullable also accepts null.
+func nullable(s Schema) Schema {
+	if _, ok := s["$ref"]; ok {
+		return Schema{"anyOf": []Schema{s, {"type": "null"}}}
+	}
+	if typ, ok := s["type"].(string); ok {
+		n := Schema{}
+		for k, v := range s {
+			n[k] = v
+		}
+		n["type"] = []string{typ, "null"}
+		return n
+	}
+	return s
+}
@@ -0,0 +1,30 @@
+package persistence
+
+import (
+	"context"
+	models "postapi/internal/domain"
+)
+
+type ActorKeyRepositoryImpl struct {
+	queries
+}
+
+func (a *ActorKeyRepositoryImpl) Create(ctx context.Context, key *models.ActorKey) error {
+	ctx, cancel := a.withTimeout(ctx)
+	defer cancel()
+
+	_, err := a.db.ExecContext(ctx, insertActorKeySchema, key.Username, key.PrivateKeyPem, key.PublicKeyPem)
+	return err
+}
+
+func (a *ActorKeyRepositoryImpl) FindByUsername(ctx context.Context, username string) (*models.ActorKey, error) {
+	ctx, cancel := a.withTimeout(ctx)
+	defer cancel()
+
+	key := &models.ActorKey{}
+	err := a.db.GetContext(ctx, key, getActorKeySchema, username)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	return key, nil
+}
@@ -0,0 +1,36 @@
+package persistence
+
+import (
+	"context"
+	models "postapi/internal/domain"
+)
+
+type UserBlockRepositoryImpl struct {
+	queries
+}
+
+func (u *UserBlockRepositoryImpl) Create(ctx context.Context, block *models.UserBlock) error {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	_, err := u.db.ExecContext(ctx, insertBlockSchema, block.BlockerUsername, block.BlockedUsername)
+	return translateError(err, nil)
+}
+
+func (u *UserBlockRepositoryImpl) Delete(ctx context.Context, block *models.UserBlock) error {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	_, err := u.db.ExecContext(ctx, removeBlockSchema, block.BlockerUsername, block.BlockedUsername)
+	return err
+}
+
+func (u *UserBlockRepositoryImpl) IsBlocked(ctx context.Context, username string, other string) (bool, error) {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	var blocked bool
+	err := u.db.GetContext(ctx, &blocked, isBlockedSchema, username, other)
+
+	return blocked, err
+}
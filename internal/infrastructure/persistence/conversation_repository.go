@@ -0,0 +1,138 @@
+package persistence
+
+import (
+	"context"
+	models "postapi/internal/domain"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type ConversationRepositoryImpl struct {
+	queries
+}
+
+func (c *ConversationRepositoryImpl) Create(ctx context.Context, conversation *models.Conversation) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	return c.inTx(ctx, func(tx runner) error {
+		err := tx.QueryRowContext(ctx, insertConversationSchema, conversation.CreatedBy, conversation.IsGroup, conversation.CreatedAt).Scan(&conversation.ID)
+		if err != nil {
+			return err
+		}
+		for _, member := range conversation.Members {
+			if _, err := tx.ExecContext(ctx, insertConversationMemberSchema, conversation.ID, member); err != nil {
+				return translateError(err, nil)
+			}
+		}
+		return nil
+	})
+}
+
+func (c *ConversationRepositoryImpl) FindByID(ctx context.Context, id int64) (*models.Conversation, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	conversation := &models.Conversation{}
+	if err := c.db.GetContext(ctx, conversation, getConversationSchema, id); err != nil {
+		return nil, translateError(err, errConversationNotFound)
+	}
+	if err := c.loadMembers(ctx, conversation); err != nil {
+		return nil, err
+	}
+	return conversation, nil
+}
+
+func (c *ConversationRepositoryImpl) FindDirect(ctx context.Context, username string, other string) (*models.Conversation, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	conversation := &models.Conversation{}
+	if err := c.db.GetContext(ctx, conversation, findDirectConversationSchema, username, other); err != nil {
+		return nil, translateError(err, errConversationNotFound)
+	}
+	if err := c.loadMembers(ctx, conversation); err != nil {
+		return nil, err
+	}
+	return conversation, nil
+}
+
+func (c *ConversationRepositoryImpl) FindByMember(ctx context.Context, username string, limit int, offset int) ([]*models.Conversation, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	var conversations []*models.Conversation
+	if err := c.db.SelectContext(ctx, &conversations, findConversationsByMemberSchema, username, limit, offset); err != nil {
+		return nil, err
+	}
+	if err := c.loadMembers(ctx, conversations...); err != nil {
+		return nil, err
+	}
+	return conversations, nil
+}
+
+func (c *ConversationRepositoryImpl) loadMembers(ctx context.Context, conversations ...*models.Conversation) error {
+	if len(conversations) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(conversations))
+	byID := make(map[int64]*models.Conversation, len(conversations))
+	for i, conversation := range conversations {
+		ids[i] = conversation.ID
+		byID[conversation.ID] = conversation
+		conversation.Members = []string{}
+	}
+
+	query, args, err := sqlx.In(getConversationMembersSchema, ids)
+	if err != nil {
+		return err
+	}
+	var members []struct {
+		ConversationID int64  `db:"conversation_id"`
+		Username       string `db:"username"`
+	}
+	if err := c.db.SelectContext(ctx, &members, c.db.Rebind(query), args...); err != nil {
+		return err
+	}
+	for _, m := range members {
+		byID[m.ConversationID].Members = append(byID[m.ConversationID].Members, m.Username)
+	}
+	return nil
+}
+
+func (c *ConversationRepositoryImpl) CreateMessage(ctx context.Context, message *models.Message) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	err := c.db.QueryRowContext(ctx, insertMessageSchema, message.ConversationID, message.Sender, message.Body, message.CreatedAt).Scan(&message.ID)
+	return translateError(err, nil)
+}
+
+func (c *ConversationRepositoryImpl) FindMessages(ctx context.Context, conversationID int64, before int64, limit int) ([]*models.Message, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	var messages []*models.Message
+	err := c.db.SelectContext(ctx, &messages, findMessagesSchema, conversationID, before, limit)
+
+	return messages, err
+}
+
+func (c *ConversationRepositoryImpl) FindMessage(ctx context.Context, conversationID int64, id int64) (*models.Message, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	message := &models.Message{}
+	if err := c.db.GetContext(ctx, message, getMessageSchema, conversationID, id); err != nil {
+		return nil, translateError(err, errMessageNotFound)
+	}
+	return message, nil
+}
+
+func (c *ConversationRepositoryImpl) MarkRead(ctx context.Context, conversationID int64, username string, messageID int64) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	_, err := c.db.ExecContext(ctx, markConversationReadSchema, conversationID, username, messageID)
+	return err
+}
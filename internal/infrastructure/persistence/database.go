@@ -1,35 +1,103 @@
 package persistence
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"log"
 	"postapi/internal/domain"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 )
 
+// DefaultQueryTimeout bounds each repository call when DB.QueryTimeout is
+// not set.
+const DefaultQueryTimeout = 5 * time.Second
+
+// Bases de datos soportadas
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
 type DB struct {
-	db                   *sqlx.DB
-	UserRepository       domain.UserRepository
-	PostRepository       domain.PostRepository
-	ProfileRepository    domain.ProfileRepository
-	UserFollowRepository domain.UserFollowRepository
+	// QueryTimeout bounds each repository call, on top of the deadline of
+	// its context. Set it before Open; negative values disable it.
+	QueryTimeout time.Duration
+	// Driver is the database to open: DriverPostgres, the default, or
+	// DriverSQLite.
+	Driver string
+	// DSN is the Postgres connection string, the local server when empty,
+	// or the path of the SQLite database file.
+	DSN string
+
+	db                       *sqlx.DB
+	UserRepository           domain.UserRepository
+	PostRepository           domain.PostRepository
+	ProfileRepository        domain.ProfileRepository
+	UserFollowRepository     domain.UserFollowRepository
+	TagRepository            domain.TagRepository
+	MentionRepository        domain.MentionRepository
+	NotificationRepository   domain.NotificationRepository
+	UserBlockRepository      domain.UserBlockRepository
+	ConversationRepository   domain.ConversationRepository
+	MediaRepository          domain.MediaRepository
+	ActorKeyRepository       domain.ActorKeyRepository
+	RemoteFollowerRepository domain.RemoteFollowerRepository
+	DeliveryRepository       domain.DeliveryRepository
+	WebmentionRepository     domain.WebmentionRepository
+	UnitOfWork               domain.UnitOfWork
 }
 
 func (d *DB) Open() error {
-	pg, err := sqlx.Open("postgres", pgConnStr)
-	if err != nil {
-		return err
+	var db runner
+	switch d.Driver {
+	case "", DriverPostgres:
+		dsn := d.DSN
+		if dsn == "" {
+			dsn = pgConnStr
+		}
+		pg, err := sqlx.Open("postgres", dsn)
+		if err != nil {
+			return err
+		}
+		log.Println("Connected to Database!")
+		pg.MustExec(createSchema)
+		d.db = pg
+		db = pg
+	case DriverSQLite:
+		lite, err := openSQLite(d.DSN)
+		if err != nil {
+			return err
+		}
+		log.Println("Opened SQLite database", d.DSN)
+		d.db = lite
+		db = sqliteDB{lite}
+	default:
+		return fmt.Errorf("unknown database driver %q", d.Driver)
 	}
-	log.Println("Connected to Database!")
-	pg.MustExec(createSchema)
-	d.db = pg
 
-	d.UserRepository = &UserRepositoryImpl{db: d.db}
-	d.PostRepository = &PostRepositoryImpl{db: d.db}
-	d.ProfileRepository = &ProfileRepositoryImpl{db: d.db}
-	d.UserFollowRepository = &UserFollowRepositoryImpl{db: d.db}
+	if d.QueryTimeout == 0 {
+		d.QueryTimeout = DefaultQueryTimeout
+	}
+	repos := newRepositories(queries{db: db, timeout: d.QueryTimeout})
+	d.UserRepository = repos.Users
+	d.PostRepository = repos.Posts
+	d.ProfileRepository = repos.Profiles
+	d.UserFollowRepository = repos.Follows
+	d.TagRepository = repos.Tags
+	d.MentionRepository = repos.Mentions
+	d.NotificationRepository = repos.Notifications
+	d.UserBlockRepository = repos.Blocks
+	d.ConversationRepository = repos.Conversations
+	d.MediaRepository = repos.Media
+	d.ActorKeyRepository = repos.ActorKeys
+	d.RemoteFollowerRepository = repos.RemoteFollowers
+	d.DeliveryRepository = repos.Deliveries
+	d.WebmentionRepository = repos.Webmentions
+	d.UnitOfWork = &UnitOfWork{db: db, timeout: d.QueryTimeout}
 
 	return nil
 }
@@ -38,6 +106,82 @@ func (d *DB) Close() error {
 	return d.db.Close()
 }
 
+// newRepositories returns the repositories that run their queries with q.
+func newRepositories(q queries) domain.Repositories {
+	return domain.Repositories{
+		Users:           &UserRepositoryImpl{q},
+		Posts:           &PostRepositoryImpl{q},
+		Profiles:        &ProfileRepositoryImpl{q},
+		Follows:         &UserFollowRepositoryImpl{q},
+		Blocks:          &UserBlockRepositoryImpl{q},
+		Tags:            &TagRepositoryImpl{q},
+		Mentions:        &MentionRepositoryImpl{q},
+		Notifications:   &NotificationRepositoryImpl{q},
+		Conversations:   &ConversationRepositoryImpl{q},
+		Media:           &MediaRepositoryImpl{q},
+		ActorKeys:       &ActorKeyRepositoryImpl{q},
+		RemoteFollowers: &RemoteFollowerRepositoryImpl{q},
+		Deliveries:      &DeliveryRepositoryImpl{q},
+		Webmentions:     &WebmentionRepositoryImpl{q},
+	}
+}
+
+// runner is what queries run on: the database or a transaction.
+type runner interface {
+	sqlx.ExtContext
+	GetContext(ctx context.Context, dest any, query string, args ...any) error
+	SelectContext(ctx context.Context, dest any, query string, args ...any) error
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// queries is embedded by every repository: the database or transaction its
+// queries run on and how long each call may take.
+type queries struct {
+	db      runner
+	timeout time.Duration
+}
+
+// inTx runs fn in a transaction of its own, or in the one the repository is
+// bound to, so that writes of several rows are kept or dropped together.
+func (q queries) inTx(ctx context.Context, fn func(tx runner) error) error {
+	db, tx, err := begin(ctx, q.db, nil)
+	if err != nil {
+		return err
+	}
+	if tx == nil {
+		return fn(q.db)
+	}
+	defer tx.Rollback()
+
+	if err := fn(db); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// begin starts a transaction on db and returns what its queries run on and
+// the transaction to commit, or a nil transaction when db is one already.
+func begin(ctx context.Context, db runner, opts *sql.TxOptions) (runner, *sqlx.Tx, error) {
+	switch db := db.(type) {
+	case *sqlx.DB:
+		tx, err := db.BeginTxx(ctx, opts)
+		return tx, tx, err
+	case sqliteDB:
+		inner, tx, err := begin(ctx, db.runner, opts)
+		return sqliteDB{inner}, tx, err
+	}
+	return db, nil, nil
+}
+
+// withTimeout returns ctx bounded by the query timeout, so that a stuck
+// query is cancelled even when the caller has no deadline.
+func (q queries) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if q.timeout < 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, q.timeout)
+}
+
 var (
 	dbUsername = "postgres"
 	dbPassword = "postgres"
@@ -47,19 +191,23 @@ var (
 	pgConnStr  = fmt.Sprintf("host=%s port=%s user=%s dbname=%s password=%s sslmode=disable", dbHost, dbPort, dbUsername, dbTable, dbPassword)
 )
 
-const createSchema = `
+var createSchema = `
 	CREATE TABLE IF NOT EXISTS users
 	(
 		username TEXT PRIMARY KEY,
 		email TEXT UNIQUE,
-		password TEXT
+		password TEXT,
+		deleted_at TIMESTAMPTZ
 	);
 	CREATE TABLE IF NOT EXISTS posts
 	(
 		id SERIAL PRIMARY KEY,
 		title TEXT NOT NULL,
 		content TEXT NOT NULL,
-		author TEXT REFERENCES users(username) ON DELETE CASCADE
+		format TEXT NOT NULL DEFAULT 'plain',
+		author TEXT REFERENCES users(username) ON DELETE CASCADE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		deleted_at TIMESTAMPTZ
 	);
 	CREATE TABLE IF NOT EXISTS user_follows 
 	(
@@ -77,9 +225,160 @@ const createSchema = `
 		profile_picture TEXT,
 		FOREIGN KEY (username) REFERENCES users(username) ON DELETE CASCADE
 	);
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+	ALTER TABLE posts ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+	ALTER TABLE posts ADD COLUMN IF NOT EXISTS format TEXT NOT NULL DEFAULT 'plain';
+	-- Posts written before creation times were stored get the epoch, which
+	-- keeps them out of trending instead of making them all new
+	ALTER TABLE posts ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT 'epoch';
+	ALTER TABLE posts ALTER COLUMN created_at SET DEFAULT NOW();
+	CREATE TABLE IF NOT EXISTS post_tags
+	(
+		post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+		tag TEXT NOT NULL,
+		PRIMARY KEY (post_id, tag)
+	);
+	CREATE INDEX IF NOT EXISTS post_tags_tag_idx ON post_tags(tag);
+	CREATE TABLE IF NOT EXISTS post_mentions
+	(
+		post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+		username TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+		field TEXT NOT NULL,
+		start_offset INTEGER NOT NULL,
+		end_offset INTEGER NOT NULL,
+		PRIMARY KEY (post_id, field, start_offset)
+	);
+	CREATE INDEX IF NOT EXISTS post_mentions_username_idx ON post_mentions(username);
+	CREATE TABLE IF NOT EXISTS notifications
+	(
+		id SERIAL PRIMARY KEY,
+		recipient TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+		actor TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+		type TEXT NOT NULL,
+		post_id INTEGER REFERENCES posts(id) ON DELETE CASCADE,
+		created_at TIMESTAMPTZ NOT NULL,
+		read_at TIMESTAMPTZ
+	);
+	CREATE INDEX IF NOT EXISTS notifications_recipient_idx ON notifications(recipient, id);
+	ALTER TABLE profiles ADD COLUMN IF NOT EXISTS private BOOLEAN NOT NULL DEFAULT FALSE;
+	CREATE TABLE IF NOT EXISTS user_blocks
+	(
+		blocker_username TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+		blocked_username TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+		PRIMARY KEY (blocker_username, blocked_username),
+		CHECK (blocker_username <> blocked_username)
+	);
+	CREATE TABLE IF NOT EXISTS conversations
+	(
+		id SERIAL PRIMARY KEY,
+		created_by TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+		is_group BOOLEAN NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS conversation_members
+	(
+		conversation_id INTEGER NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+		username TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+		last_read_message_id INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (conversation_id, username)
+	);
+	CREATE INDEX IF NOT EXISTS conversation_members_username_idx ON conversation_members(username);
+	CREATE TABLE IF NOT EXISTS messages
+	(
+		id SERIAL PRIMARY KEY,
+		conversation_id INTEGER NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+		sender TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+		body TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS messages_conversation_idx ON messages(conversation_id, id);
+	CREATE TABLE IF NOT EXISTS media
+	(
+		id SERIAL PRIMARY KEY,
+		owner TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+		storage_key TEXT NOT NULL UNIQUE,
+		content_type TEXT NOT NULL,
+		size BIGINT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS post_media
+	(
+		post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+		media_id INTEGER NOT NULL REFERENCES media(id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		PRIMARY KEY (post_id, media_id)
+	);
+	ALTER TABLE profiles ADD COLUMN IF NOT EXISTS avatar_media_id INTEGER REFERENCES media(id) ON DELETE SET NULL;
+	ALTER TABLE media ADD COLUMN IF NOT EXISTS width INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE media ADD COLUMN IF NOT EXISTS height INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE media ADD COLUMN IF NOT EXISTS blurhash TEXT NOT NULL DEFAULT '';
+	UPDATE profiles SET profile_picture = '' WHERE profile_picture = 'https://i.redd.it/j6mkb6p73h791.jpg';
+	CREATE TABLE IF NOT EXISTS actor_keys
+	(
+		username TEXT PRIMARY KEY REFERENCES users(username) ON DELETE CASCADE,
+		private_key_pem TEXT NOT NULL,
+		public_key_pem TEXT NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS remote_followers
+	(
+		username TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+		actor_id TEXT NOT NULL,
+		inbox TEXT NOT NULL,
+		follow_id TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (username, actor_id)
+	);
+	CREATE TABLE IF NOT EXISTS deliveries
+	(
+		id SERIAL PRIMARY KEY,
+		sender TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+		inbox TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMPTZ NOT NULL,
+		last_error TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS deliveries_due_idx ON deliveries(next_attempt_at);
+	CREATE TABLE IF NOT EXISTS webmentions
+	(
+		id SERIAL PRIMARY KEY,
+		post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+		source TEXT NOT NULL,
+		target TEXT NOT NULL,
+		status TEXT NOT NULL,
+		type TEXT NOT NULL DEFAULT 'mention',
+		author_name TEXT NOT NULL DEFAULT '',
+		author_url TEXT NOT NULL DEFAULT '',
+		author_photo TEXT NOT NULL DEFAULT '',
+		content TEXT NOT NULL DEFAULT '',
+		published TIMESTAMPTZ,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMPTZ NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL,
+		verified_at TIMESTAMPTZ,
+		UNIQUE (source, target)
+	);
+	CREATE INDEX IF NOT EXISTS webmentions_post_idx ON webmentions(post_id);
+	CREATE INDEX IF NOT EXISTS webmentions_pending_idx ON webmentions(status, next_attempt_at);
+	CREATE INDEX IF NOT EXISTS user_follows_followed_idx ON user_follows(followed_username);
+	CREATE INDEX IF NOT EXISTS posts_author_idx ON posts(author);
+	DO $$
+	BEGIN
+		-- The counts are computed once when the columns are added, existing
+		-- rows included; the repositories keep them up to date afterwards
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = 'users' AND column_name = 'post_count') THEN
+			ALTER TABLE users ADD COLUMN IF NOT EXISTS follower_count INTEGER NOT NULL DEFAULT 0,
+				ADD COLUMN IF NOT EXISTS following_count INTEGER NOT NULL DEFAULT 0,
+				ADD COLUMN IF NOT EXISTS post_count INTEGER NOT NULL DEFAULT 0;
+			` + recountUsersSchema + `;
+		END IF;
+	END $$;
 	`
 
-var insertPostSchema = `INSERT INTO posts(title, content, author) VALUES($1, $2, $3) RETURNING id`
+var insertPostSchema = `INSERT INTO posts(title, content, format, author, created_at) VALUES($1, $2, $3, $4, $5) RETURNING id`
 
 var insertUserSchema = `INSERT INTO users(username, email, password) VALUES($1, $2, $3)`
 
@@ -87,12 +386,245 @@ var insertFollowSchema = `INSERT INTO user_follows (follower_username, followed_
 
 var removeFollowSchema = `DELETE FROM user_follows WHERE follower_username = $1 AND followed_username = $2`
 
-var getFollowersSchema = `SELECT follower_username FROM user_follows WHERE followed_username = $1`
+var getFollowersSchema = `SELECT f.follower_username FROM user_follows f
+	JOIN users u ON u.username = f.follower_username
+	WHERE f.followed_username = $1 AND u.deleted_at IS NULL`
+
+// userSummaryColumns select the UserSummary of the user u, whose profile is
+// p.
+var userSummaryColumns = `u.username, u.email,
+	COALESCE(p.description, '') AS description,
+	COALESCE(p.profile_picture, '') AS profile_picture,
+	COALESCE(p.private, FALSE) AS private,
+	u.follower_count, u.following_count, u.post_count`
+
+var findFollowersSchema = `SELECT ` + userSummaryColumns + ` FROM user_follows f
+	JOIN users u ON u.username = f.follower_username
+	LEFT JOIN profiles p ON p.username = u.username
+	WHERE f.followed_username = $1 AND u.deleted_at IS NULL
+	ORDER BY u.username`
+
+var findFollowingSchema = `SELECT ` + userSummaryColumns + ` FROM user_follows f
+	JOIN users u ON u.username = f.followed_username
+	LEFT JOIN profiles p ON p.username = u.username
+	WHERE f.follower_username = $1 AND u.deleted_at IS NULL
+	ORDER BY u.username`
+
+// addFollowerCountSchema adds $3 to the followers of $2 when its follower $1
+// is active, and addFollowingCountSchema adds it to the follows of $1 when
+// $2 is.
+var addFollowerCountSchema = `UPDATE users SET follower_count = follower_count + $3
+	WHERE username = $2 AND EXISTS(SELECT 1 FROM users WHERE username = $1 AND deleted_at IS NULL)`
+
+var addFollowingCountSchema = `UPDATE users SET following_count = following_count + $3
+	WHERE username = $1 AND EXISTS(SELECT 1 FROM users WHERE username = $2 AND deleted_at IS NULL)`
+
+// The counts of the users $1 follows and is followed by change by $2 when it
+// is deleted or restored.
+var addFollowedCountsSchema = `UPDATE users SET follower_count = follower_count + $2
+	WHERE username IN (SELECT followed_username FROM user_follows WHERE follower_username = $1)`
+
+var addFollowerCountsSchema = `UPDATE users SET following_count = following_count + $2
+	WHERE username IN (SELECT follower_username FROM user_follows WHERE followed_username = $1)`
+
+var addPostCountSchema = `UPDATE users SET post_count = post_count + $2 WHERE username = $1`
+
+var recountUsersSchema = `UPDATE users
+	SET follower_count = c.followers, following_count = c.following, post_count = c.posts
+	FROM (SELECT u.username,
+		(SELECT COUNT(*) FROM user_follows f JOIN users x ON x.username = f.follower_username
+			WHERE f.followed_username = u.username AND x.deleted_at IS NULL) AS followers,
+		(SELECT COUNT(*) FROM user_follows f JOIN users x ON x.username = f.followed_username
+			WHERE f.follower_username = u.username AND x.deleted_at IS NULL) AS following,
+		(SELECT COUNT(*) FROM posts p WHERE p.author = u.username AND p.deleted_at IS NULL) AS posts
+		FROM users u) c
+	WHERE users.username = c.username
+	AND (users.follower_count <> c.followers OR users.following_count <> c.following OR users.post_count <> c.posts)`
+
+var followExistsSchema = `SELECT EXISTS(SELECT 1 FROM user_follows WHERE follower_username = $1 AND followed_username = $2)`
+
+var getFollowingSchema = `SELECT f.followed_username FROM user_follows f
+	JOIN users u ON u.username = f.followed_username
+	WHERE f.follower_username = $1 AND u.deleted_at IS NULL`
+
+var insertProfileSchema = `INSERT INTO profiles(username, description, profile_picture, private, avatar_media_id) VALUES($1, $2, $3, $4, $5)`
+
+var getProfileSchema = `SELECT p.*, u.follower_count, u.following_count, u.post_count FROM profiles p
+	JOIN users u ON u.username = p.username
+	WHERE p.username = $1 AND u.deleted_at IS NULL`
+
+var updateProfileSchema = `UPDATE profiles SET description = $2, profile_picture = $3, private = $4, avatar_media_id = $5 WHERE username = $1`
+
+var getActiveUserSchema = `SELECT * FROM users WHERE username = $1 AND deleted_at IS NULL`
+
+var softDeleteUserSchema = `UPDATE users SET deleted_at = $2 WHERE username = $1 AND deleted_at IS NULL`
+
+var getDeletedUserSchema = `SELECT * FROM users WHERE username = $1 AND deleted_at >= $2`
+
+var restoreUserSchema = `UPDATE users SET deleted_at = NULL WHERE username = $1 AND deleted_at IS NOT NULL`
+
+var purgeUsersSchema = `DELETE FROM users WHERE deleted_at < $1`
+
+var purgePostsSchema = `DELETE FROM posts WHERE deleted_at < $1`
+
+var deletePostTagsSchema = `DELETE FROM post_tags WHERE post_id = $1`
+
+var insertPostTagSchema = `INSERT INTO post_tags(post_id, tag) VALUES($1, $2)`
+
+var findPostsByTagSchema = `SELECT p.* FROM posts p
+	JOIN post_tags t ON t.post_id = p.id
+	JOIN users u ON u.username = p.author
+	WHERE t.tag = $1 AND p.deleted_at IS NULL AND u.deleted_at IS NULL
+	ORDER BY p.created_at DESC, p.id DESC
+	LIMIT $2 OFFSET $3`
+
+var trendingTagsSchema = `SELECT t.tag, COUNT(*) AS count FROM post_tags t
+	JOIN posts p ON p.id = t.post_id
+	JOIN users u ON u.username = p.author
+	WHERE p.created_at >= $1 AND p.deleted_at IS NULL AND u.deleted_at IS NULL
+	GROUP BY t.tag
+	ORDER BY count DESC, t.tag
+	LIMIT $2`
+
+var deletePostMentionsSchema = `DELETE FROM post_mentions WHERE post_id = $1`
+
+var insertPostMentionSchema = `INSERT INTO post_mentions(post_id, username, field, start_offset, end_offset) VALUES($1, $2, $3, $4, $5)`
+
+var findMentionsByPostsSchema = `SELECT * FROM post_mentions WHERE post_id IN (?) ORDER BY post_id, field, start_offset`
+
+var findPostsMentioningSchema = `SELECT p.* FROM posts p
+	JOIN users u ON u.username = p.author
+	WHERE p.id IN (SELECT post_id FROM post_mentions WHERE username = $1)
+	AND p.deleted_at IS NULL AND u.deleted_at IS NULL
+	ORDER BY p.created_at DESC, p.id DESC
+	LIMIT $2 OFFSET $3`
+
+var insertNotificationSchema = `INSERT INTO notifications(recipient, actor, type, post_id, created_at) VALUES($1, $2, $3, $4, $5) RETURNING id`
+
+var findNotificationsSchema = `SELECT n.* FROM notifications n
+	JOIN users u ON u.username = n.actor
+	WHERE n.recipient = $1 AND ($2 = FALSE OR n.read_at IS NULL) AND u.deleted_at IS NULL
+	ORDER BY n.id DESC
+	LIMIT $3 OFFSET $4`
+
+var markNotificationsReadSchema = `UPDATE notifications SET read_at = ? WHERE recipient = ? AND read_at IS NULL AND id IN (?)`
+
+var markAllNotificationsReadSchema = `UPDATE notifications SET read_at = $2 WHERE recipient = $1 AND read_at IS NULL`
+
+var countUnreadNotificationsSchema = `SELECT COUNT(*) FROM notifications n
+	JOIN users u ON u.username = n.actor
+	WHERE n.recipient = $1 AND n.read_at IS NULL AND u.deleted_at IS NULL`
+
+var insertBlockSchema = `INSERT INTO user_blocks(blocker_username, blocked_username) VALUES($1, $2)`
+
+var removeBlockSchema = `DELETE FROM user_blocks WHERE blocker_username = $1 AND blocked_username = $2`
+
+var isBlockedSchema = `SELECT EXISTS(SELECT 1 FROM user_blocks
+	WHERE (blocker_username = $1 AND blocked_username = $2)
+	OR (blocker_username = $2 AND blocked_username = $1))`
+
+var insertConversationSchema = `INSERT INTO conversations(created_by, is_group, created_at) VALUES($1, $2, $3) RETURNING id`
+
+var insertConversationMemberSchema = `INSERT INTO conversation_members(conversation_id, username) VALUES($1, $2)`
+
+var getConversationSchema = `SELECT * FROM conversations WHERE id = $1`
+
+var getConversationMembersSchema = `SELECT conversation_id, username FROM conversation_members WHERE conversation_id IN (?) ORDER BY username`
+
+var findDirectConversationSchema = `SELECT c.* FROM conversations c
+	JOIN conversation_members a ON a.conversation_id = c.id AND a.username = $1
+	JOIN conversation_members b ON b.conversation_id = c.id AND b.username = $2
+	WHERE c.is_group = FALSE
+	LIMIT 1`
+
+var findConversationsByMemberSchema = `SELECT c.*, m.last_read_message_id,
+		(SELECT COUNT(*) FROM messages
+			WHERE conversation_id = c.id AND id > m.last_read_message_id AND sender <> $1) AS unread_count
+	FROM conversations c
+	JOIN conversation_members m ON m.conversation_id = c.id
+	WHERE m.username = $1
+	ORDER BY COALESCE((SELECT MAX(id) FROM messages WHERE conversation_id = c.id), 0) DESC, c.id DESC
+	LIMIT $2 OFFSET $3`
+
+var insertMessageSchema = `INSERT INTO messages(conversation_id, sender, body, created_at) VALUES($1, $2, $3, $4) RETURNING id`
+
+var findMessagesSchema = `SELECT * FROM messages
+	WHERE conversation_id = $1 AND ($2 = 0 OR id < $2)
+	ORDER BY id DESC
+	LIMIT $3`
+
+var getMessageSchema = `SELECT * FROM messages WHERE conversation_id = $1 AND id = $2`
+
+var markConversationReadSchema = `UPDATE conversation_members
+	SET last_read_message_id =
+		CASE WHEN $3 = 0 THEN (SELECT COALESCE(MAX(id), 0) FROM messages WHERE conversation_id = $1) ELSE $3 END
+	WHERE conversation_id = $1 AND username = $2 AND last_read_message_id <
+		CASE WHEN $3 = 0 THEN (SELECT COALESCE(MAX(id), 0) FROM messages WHERE conversation_id = $1) ELSE $3 END`
+
+var insertMediaSchema = `INSERT INTO media(owner, storage_key, content_type, size, width, height, blurhash, created_at)
+	VALUES($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+
+var findMediaByIDsSchema = `SELECT * FROM media WHERE id IN (?)`
+
+var findMediaByKeysSchema = `SELECT * FROM media WHERE storage_key IN (?)`
+
+var findMediaByDeletedOwnersSchema = `SELECT m.* FROM media m
+	JOIN users u ON u.username = m.owner
+	WHERE u.deleted_at < $1
+	ORDER BY m.id`
+
+var deletePostMediaSchema = `DELETE FROM post_media WHERE post_id = $1`
+
+var insertPostMediaSchema = `INSERT INTO post_media(post_id, media_id, position) VALUES($1, $2, $3)`
+
+var findMediaByPostsSchema = `SELECT pm.post_id, m.* FROM post_media pm
+	JOIN media m ON m.id = pm.media_id
+	WHERE pm.post_id IN (?)
+	ORDER BY pm.post_id, pm.position`
+
+var insertActorKeySchema = `INSERT INTO actor_keys(username, private_key_pem, public_key_pem) VALUES($1, $2, $3)
+	ON CONFLICT (username) DO NOTHING`
+
+var getActorKeySchema = `SELECT * FROM actor_keys WHERE username = $1`
+
+var insertRemoteFollowerSchema = `INSERT INTO remote_followers(username, actor_id, inbox, follow_id, created_at)
+	VALUES($1, $2, $3, $4, $5)
+	ON CONFLICT (username, actor_id) DO UPDATE SET inbox = EXCLUDED.inbox, follow_id = EXCLUDED.follow_id`
+
+var getRemoteFollowerSchema = `SELECT * FROM remote_followers WHERE username = $1 AND actor_id = $2`
+
+var removeRemoteFollowerSchema = `DELETE FROM remote_followers WHERE username = $1 AND actor_id = $2`
+
+var removeRemoteActorSchema = `DELETE FROM remote_followers WHERE actor_id = $1`
+
+var getRemoteFollowersSchema = `SELECT * FROM remote_followers WHERE username = $1 ORDER BY created_at`
+
+var countRemoteFollowersSchema = `SELECT COUNT(*) FROM remote_followers WHERE username = $1`
+
+var insertDeliverySchema = `INSERT INTO deliveries(sender, inbox, payload, attempts, next_attempt_at, created_at)
+	VALUES($1, $2, $3, $4, $5, $6) RETURNING id`
+
+var findDueDeliveriesSchema = `SELECT * FROM deliveries WHERE next_attempt_at <= $1 ORDER BY next_attempt_at, id LIMIT $2`
+
+var deleteDeliverySchema = `DELETE FROM deliveries WHERE id = $1`
+
+var rescheduleDeliverySchema = `UPDATE deliveries SET attempts = $2, next_attempt_at = $3, last_error = $4 WHERE id = $1`
+
+var saveWebmentionSchema = `INSERT INTO webmentions(post_id, source, target, status, type, next_attempt_at, created_at, updated_at)
+	VALUES($1, $2, $3, $4, $5, $6, $7, $8)
+	ON CONFLICT (source, target) DO UPDATE SET post_id = EXCLUDED.post_id, status = EXCLUDED.status,
+		attempts = 0, next_attempt_at = EXCLUDED.next_attempt_at, updated_at = EXCLUDED.updated_at
+	RETURNING id, created_at`
 
-var getFollowingSchema = `SELECT followed_username FROM user_follows WHERE follower_username = $1`
+var findPendingWebmentionsSchema = `SELECT * FROM webmentions WHERE status = 'pending' AND next_attempt_at <= $1
+	ORDER BY next_attempt_at, id LIMIT $2`
 
-var insertProfileSchema = `INSERT INTO profiles(username, description, profile_picture) VALUES($1, $2, $3)`
+var updateWebmentionSchema = `UPDATE webmentions SET status = $2, type = $3, author_name = $4, author_url = $5,
+	author_photo = $6, content = $7, published = $8, attempts = $9, next_attempt_at = $10, updated_at = $11,
+	verified_at = $12
+	WHERE id = $1`
 
-var getProfileSchema = `SELECT * FROM profiles WHERE username = $1`
+var deleteWebmentionsBySourceSchema = `DELETE FROM webmentions WHERE source = $1`
 
-var updateProfileSchema = `UPDATE profiles SET description = $2, profile_picture = $3 WHERE username = $1`
+var findVerifiedWebmentionsByPostsSchema = `SELECT * FROM webmentions
+	WHERE status = 'verified' AND post_id IN (?) ORDER BY verified_at, id`
@@ -0,0 +1,47 @@
+package persistence
+
+import (
+	"context"
+	models "postapi/internal/domain"
+	"time"
+)
+
+type DeliveryRepositoryImpl struct {
+	queries
+}
+
+func (d *DeliveryRepositoryImpl) Enqueue(ctx context.Context, delivery *models.Delivery) error {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	return d.db.QueryRowContext(ctx,
+		insertDeliverySchema,
+		delivery.Sender, delivery.Inbox, delivery.Payload, delivery.Attempts, delivery.NextAttemptAt, delivery.CreatedAt,
+	).Scan(&delivery.ID)
+}
+
+func (d *DeliveryRepositoryImpl) FindDue(ctx context.Context, now time.Time, limit int) ([]*models.Delivery, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	var deliveries []*models.Delivery
+	err := d.db.SelectContext(ctx, &deliveries, findDueDeliveriesSchema, now, limit)
+
+	return deliveries, err
+}
+
+func (d *DeliveryRepositoryImpl) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	_, err := d.db.ExecContext(ctx, deleteDeliverySchema, id)
+	return err
+}
+
+func (d *DeliveryRepositoryImpl) Reschedule(ctx context.Context, id int64, attempts int, next time.Time, lastError string) error {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	_, err := d.db.ExecContext(ctx, rescheduleDeliverySchema, id, attempts, next, lastError)
+	return err
+}
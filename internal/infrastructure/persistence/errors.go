@@ -0,0 +1,81 @@
+package persistence
+
+import (
+	"database/sql"
+	"errors"
+	models "postapi/internal/domain"
+
+	"github.com/lib/pq"
+)
+
+var (
+	errUserNotFound         = models.NotFound(models.CodeUserNotFound, "user not found")
+	errPostNotFound         = models.NotFound(models.CodePostNotFound, "post not found")
+	errProfileNotFound      = models.NotFound(models.CodeProfileNotFound, "profile not found")
+	errMediaNotFound        = models.NotFound(models.CodeMediaNotFound, "media not found")
+	errConversationNotFound = models.NotFound(models.CodeConversationNotFound, "conversation not found")
+	errMessageNotFound      = models.NotFound(models.CodeMessageNotFound, "message not found")
+	errFollowNotFound       = models.NotFound(models.CodeFollowNotFound, "not following this user")
+	errInvalidCredentials   = models.Unauthorized(models.CodeInvalidCredentials, "invalid credentials")
+)
+
+// constraintErrors names the violation of each constraint that clients can
+// run into; the names are the ones Postgres gives the constraints of the
+// schema, which sqliteViolation gives the SQLite ones too.
+var constraintErrors = map[string]*models.Error{
+	"users_pkey":                          models.Conflict(models.CodeUsernameTaken, "username is already taken"),
+	"users_email_key":                     models.Conflict(models.CodeEmailTaken, "email is already registered"),
+	"profiles_pkey":                       models.Conflict(models.CodeProfileExists, "profile already exists"),
+	"user_follows_pkey":                   models.Conflict(models.CodeAlreadyFollowing, "already following this user"),
+	"user_blocks_pkey":                    models.Conflict(models.CodeAlreadyBlocked, "user is already blocked"),
+	"user_follows_check":                  models.Invalid(models.CodeSelfFollow, "cannot follow yourself"),
+	"user_blocks_check":                   models.Invalid(models.CodeSelfBlock, "cannot block yourself"),
+	"user_follows_followed_username_fkey": errUserNotFound,
+	"user_blocks_blocked_username_fkey":   errUserNotFound,
+	"conversation_members_username_fkey":  errUserNotFound,
+	"profiles_avatar_media_id_fkey":       errMediaNotFound,
+	"post_media_media_id_fkey":            errMediaNotFound,
+}
+
+// translateError maps driver errors to domain errors: a missing row becomes
+// notFound (or ErrNotFound when nil) and constraint violations become
+// conflicts, validation or not found errors. The driver error is kept as the
+// cause; other errors are returned unchanged.
+func translateError(err error, notFound *models.Error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		if notFound == nil {
+			notFound = models.ErrNotFound
+		}
+		return notFound.Wrap(err)
+	}
+
+	constraint, class, ok := violation(err)
+	if !ok {
+		return err
+	}
+	if known, ok := constraintErrors[constraint]; ok {
+		return known.Wrap(err)
+	}
+	switch class {
+	case "unique_violation":
+		return models.ErrConflict.Wrap(err)
+	case "foreign_key_violation":
+		return models.NotFound(models.CodeNotFound, "referenced resource does not exist").Wrap(err)
+	case "check_violation", "not_null_violation", "string_data_right_truncation":
+		return models.ErrValidation.Wrap(err)
+	}
+	return err
+}
+
+// violation returns the constraint err violates and the Postgres name of the
+// kind of violation, whichever database reported it.
+func violation(err error) (constraint string, class string, ok bool) {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Constraint, pqErr.Code.Name(), true
+	}
+	return sqliteViolation(err)
+}
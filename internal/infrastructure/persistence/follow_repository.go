@@ -1,28 +1,62 @@
 package persistence
 
 import (
+	"context"
 	models "postapi/internal/domain"
-
-	"github.com/jmoiron/sqlx"
 )
 
 type UserFollowRepositoryImpl struct {
-	db *sqlx.DB
+	queries
 }
 
-func (u *UserFollowRepositoryImpl) Create(follow *models.UserFollow) error {
-	_, err := u.db.Exec(insertFollowSchema, follow.FollowerUsername, follow.FollowedUsername)
-	return err
+func (u *UserFollowRepositoryImpl) Create(ctx context.Context, follow *models.UserFollow) error {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	err := u.inTx(ctx, func(tx runner) error {
+		if _, err := tx.ExecContext(ctx, insertFollowSchema, follow.FollowerUsername, follow.FollowedUsername); err != nil {
+			return err
+		}
+		return addFollowCounts(ctx, tx, follow, 1)
+	})
+	return translateError(err, nil)
+}
+
+func (u *UserFollowRepositoryImpl) Delete(ctx context.Context, follow *models.UserFollow) error {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	return u.inTx(ctx, func(tx runner) error {
+		result, err := tx.ExecContext(ctx, removeFollowSchema, follow.FollowerUsername, follow.FollowedUsername)
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return errFollowNotFound
+		}
+		return addFollowCounts(ctx, tx, follow, -1)
+	})
 }
 
-func (u *UserFollowRepositoryImpl) Delete(follow *models.UserFollow) error {
-	_, err := u.db.Exec(removeFollowSchema, follow.FollowerUsername, follow.FollowedUsername)
+// addFollowCounts adds delta to the counts of the users of follow.
+func addFollowCounts(ctx context.Context, tx runner, follow *models.UserFollow, delta int) error {
+	if _, err := tx.ExecContext(ctx, addFollowerCountSchema, follow.FollowerUsername, follow.FollowedUsername, delta); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, addFollowingCountSchema, follow.FollowerUsername, follow.FollowedUsername, delta)
 	return err
 }
 
-func (u *UserFollowRepositoryImpl) GetFollowers(username string) ([]string, error) {
+func (u *UserFollowRepositoryImpl) GetFollowers(ctx context.Context, username string) ([]string, error) {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
 	var followers []string
-	err := u.db.Select(&followers, getFollowersSchema, username)
+	err := u.db.SelectContext(ctx, &followers, getFollowersSchema, username)
 
 	if err != nil {
 		return nil, err
@@ -31,9 +65,12 @@ func (u *UserFollowRepositoryImpl) GetFollowers(username string) ([]string, erro
 	return followers, nil
 }
 
-func (u *UserFollowRepositoryImpl) GetFollowing(username string) ([]string, error) {
+func (u *UserFollowRepositoryImpl) GetFollowing(ctx context.Context, username string) ([]string, error) {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
 	var following []string
-	err := u.db.Select(&following, getFollowingSchema, username)
+	err := u.db.SelectContext(ctx, &following, getFollowingSchema, username)
 
 	if err != nil {
 		return nil, err
@@ -41,3 +78,31 @@ func (u *UserFollowRepositoryImpl) GetFollowing(username string) ([]string, erro
 
 	return following, nil
 }
+
+func (u *UserFollowRepositoryImpl) FindFollowers(ctx context.Context, username string) ([]*models.UserSummary, error) {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	followers := []*models.UserSummary{}
+	err := u.db.SelectContext(ctx, &followers, findFollowersSchema, username)
+	return followers, err
+}
+
+func (u *UserFollowRepositoryImpl) FindFollowing(ctx context.Context, username string) ([]*models.UserSummary, error) {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	following := []*models.UserSummary{}
+	err := u.db.SelectContext(ctx, &following, findFollowingSchema, username)
+	return following, err
+}
+
+func (u *UserFollowRepositoryImpl) Exists(ctx context.Context, follow *models.UserFollow) (bool, error) {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	var exists bool
+	err := u.db.GetContext(ctx, &exists, followExistsSchema, follow.FollowerUsername, follow.FollowedUsername)
+
+	return exists, err
+}
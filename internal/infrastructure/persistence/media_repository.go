@@ -0,0 +1,101 @@
+package persistence
+
+import (
+	"context"
+	models "postapi/internal/domain"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type MediaRepositoryImpl struct {
+	queries
+}
+
+func (m *MediaRepositoryImpl) Create(ctx context.Context, media *models.Media) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	return m.db.QueryRowContext(ctx,
+		insertMediaSchema,
+		media.Owner, media.Key, media.ContentType, media.Size, media.Width, media.Height, media.Blurhash, media.CreatedAt,
+	).Scan(&media.ID)
+}
+
+func (m *MediaRepositoryImpl) FindByIDs(ctx context.Context, ids []int64) ([]*models.Media, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var media []*models.Media
+	if len(ids) == 0 {
+		return media, nil
+	}
+	query, args, err := sqlx.In(findMediaByIDsSchema, ids)
+	if err != nil {
+		return nil, err
+	}
+	err = m.db.SelectContext(ctx, &media, m.db.Rebind(query), args...)
+
+	return media, err
+}
+
+func (m *MediaRepositoryImpl) FindByKeys(ctx context.Context, keys []string) ([]*models.Media, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var media []*models.Media
+	if len(keys) == 0 {
+		return media, nil
+	}
+	query, args, err := sqlx.In(findMediaByKeysSchema, keys)
+	if err != nil {
+		return nil, err
+	}
+	err = m.db.SelectContext(ctx, &media, m.db.Rebind(query), args...)
+
+	return media, err
+}
+
+func (m *MediaRepositoryImpl) SetPostAttachments(ctx context.Context, postID int64, mediaIDs []int64) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	return m.inTx(ctx, func(tx runner) error {
+		if _, err := tx.ExecContext(ctx, deletePostMediaSchema, postID); err != nil {
+			return err
+		}
+		for i, id := range mediaIDs {
+			if _, err := tx.ExecContext(ctx, insertPostMediaSchema, postID, id, i); err != nil {
+				return translateError(err, nil)
+			}
+		}
+		return nil
+	})
+}
+
+func (m *MediaRepositoryImpl) FindByPosts(ctx context.Context, postIDs []int64) ([]*models.PostAttachment, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var attachments []*models.PostAttachment
+	if len(postIDs) == 0 {
+		return attachments, nil
+	}
+	query, args, err := sqlx.In(findMediaByPostsSchema, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	err = m.db.SelectContext(ctx, &attachments, m.db.Rebind(query), args...)
+
+	return attachments, err
+}
+
+func (m *MediaRepositoryImpl) FindByDeletedOwners(ctx context.Context, deletedBefore time.Time) ([]*models.Media, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var media []*models.Media
+	err := m.db.SelectContext(ctx, &media, findMediaByDeletedOwnersSchema, deletedBefore)
+
+	return media, err
+}
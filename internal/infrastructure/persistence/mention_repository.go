@@ -0,0 +1,57 @@
+package persistence
+
+import (
+	"context"
+	models "postapi/internal/domain"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type MentionRepositoryImpl struct {
+	queries
+}
+
+func (m *MentionRepositoryImpl) SetPostMentions(ctx context.Context, postID int64, mentions []*models.Mention) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	return m.inTx(ctx, func(tx runner) error {
+		if _, err := tx.ExecContext(ctx, deletePostMentionsSchema, postID); err != nil {
+			return err
+		}
+		for _, mention := range mentions {
+			_, err := tx.ExecContext(ctx, insertPostMentionSchema, postID, mention.Username, mention.Field, mention.Start, mention.End)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (m *MentionRepositoryImpl) FindByPosts(ctx context.Context, postIDs []int64) ([]*models.Mention, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var mentions []*models.Mention
+	if len(postIDs) == 0 {
+		return mentions, nil
+	}
+	query, args, err := sqlx.In(findMentionsByPostsSchema, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	err = m.db.SelectContext(ctx, &mentions, m.db.Rebind(query), args...)
+
+	return mentions, err
+}
+
+func (m *MentionRepositoryImpl) FindPostsMentioning(ctx context.Context, username string, limit int, offset int) ([]*models.Post, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var posts []*models.Post
+	err := m.db.SelectContext(ctx, &posts, findPostsMentioningSchema, username, limit, offset)
+
+	return posts, err
+}
@@ -0,0 +1,76 @@
+package persistence
+
+import (
+	"context"
+	models "postapi/internal/domain"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type NotificationRepositoryImpl struct {
+	queries
+}
+
+func (n *NotificationRepositoryImpl) Create(ctx context.Context, notification *models.Notification) error {
+	ctx, cancel := n.withTimeout(ctx)
+	defer cancel()
+
+	return n.db.QueryRowContext(ctx,
+		insertNotificationSchema,
+		notification.Recipient,
+		notification.Actor,
+		notification.Type,
+		notification.PostID,
+		notification.CreatedAt,
+	).Scan(&notification.ID)
+}
+
+func (n *NotificationRepositoryImpl) FindByRecipient(ctx context.Context, recipient string, unreadOnly bool, limit int, offset int) ([]*models.Notification, error) {
+	ctx, cancel := n.withTimeout(ctx)
+	defer cancel()
+
+	var notifications []*models.Notification
+	err := n.db.SelectContext(ctx, &notifications, findNotificationsSchema, recipient, unreadOnly, limit, offset)
+
+	return notifications, err
+}
+
+func (n *NotificationRepositoryImpl) MarkRead(ctx context.Context, recipient string, ids []int64) (int64, error) {
+	ctx, cancel := n.withTimeout(ctx)
+	defer cancel()
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	query, args, err := sqlx.In(markNotificationsReadSchema, time.Now().UTC(), recipient, ids)
+	if err != nil {
+		return 0, err
+	}
+	result, err := n.db.ExecContext(ctx, n.db.Rebind(query), args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (n *NotificationRepositoryImpl) MarkAllRead(ctx context.Context, recipient string) (int64, error) {
+	ctx, cancel := n.withTimeout(ctx)
+	defer cancel()
+
+	result, err := n.db.ExecContext(ctx, markAllNotificationsReadSchema, recipient, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (n *NotificationRepositoryImpl) CountUnread(ctx context.Context, recipient string) (int64, error) {
+	ctx, cancel := n.withTimeout(ctx)
+	defer cancel()
+
+	var count int64
+	err := n.db.GetContext(ctx, &count, countUnreadNotificationsSchema, recipient)
+
+	return count, err
+}
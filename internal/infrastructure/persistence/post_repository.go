@@ -1,34 +1,45 @@
 package persistence
 
 import (
+	"context"
 	"database/sql"
-	"errors"
 	models "postapi/internal/domain"
-
-	"github.com/jmoiron/sqlx"
+	"time"
 )
 
 type PostRepositoryImpl struct {
-	db *sqlx.DB
+	queries
 }
 
-func (p *PostRepositoryImpl) Create(post *models.Post) error {
-	if post.Title == "" || post.Content == "" {
-		return errors.New("Invalid Title / content")
-	}
-	err := p.db.QueryRow(insertPostSchema, post.Title, post.Content, post.Author).Scan(&post.ID)
-	return err
+func (p *PostRepositoryImpl) Create(ctx context.Context, post *models.Post) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	post.CreatedAt = time.Now().UTC()
+	err := p.inTx(ctx, func(tx runner) error {
+		err := tx.QueryRowContext(ctx, insertPostSchema, post.Title, post.Content, post.Format, post.Author, post.CreatedAt).Scan(&post.ID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, addPostCountSchema, post.Author, 1)
+		return err
+	})
+	return translateError(err, nil)
 }
 
-func (p *PostRepositoryImpl) Update(post *models.Post) error {
-	result, err := p.db.Exec(
+func (p *PostRepositoryImpl) Update(ctx context.Context, post *models.Post) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	result, err := p.db.ExecContext(ctx,
 		`UPDATE posts
-		 SET title = $1, content = $2
-		 WHERE id = $3 AND author = $4`,
+		 SET title = $1, content = $2, format = $5
+		 WHERE id = $3 AND author = $4 AND deleted_at IS NULL`,
 		post.Title,
 		post.Content,
 		post.ID,
 		post.Author,
+		post.Format,
 	)
 	if err != nil {
 		return err
@@ -39,39 +50,129 @@ func (p *PostRepositoryImpl) Update(post *models.Post) error {
 	}
 
 	if rows == 0 {
-		return sql.ErrNoRows
+		return errPostNotFound
 	}
 
 	return nil
 }
 
-func (p *PostRepositoryImpl) Delete(id int64, author string) error {
-	result, err := p.db.Exec("DELETE FROM posts WHERE id = $1 AND author = $2", id, author)
-	if err != nil {
-		return err
-	}
+func (p *PostRepositoryImpl) Delete(ctx context.Context, id int64, author string) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	return p.inTx(ctx, func(tx runner) error {
+		result, err := tx.ExecContext(ctx,
+			"UPDATE posts SET deleted_at = $3 WHERE id = $1 AND author = $2 AND deleted_at IS NULL",
+			id, author, time.Now().UTC(),
+		)
+		if err != nil {
+			return err
+		}
+		return addPostCount(ctx, tx, result, author, -1)
+	})
+}
+
+// addPostCount adds delta to the posts of author if result changed a post,
+// which it must have.
+func addPostCount(ctx context.Context, tx runner, result sql.Result, author string, delta int) error {
 	rows, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
 	if rows == 0 {
-		return sql.ErrNoRows
+		return errPostNotFound
 	}
-	return nil
+	_, err = tx.ExecContext(ctx, addPostCountSchema, author, delta)
+	return err
 }
 
-func (p *PostRepositoryImpl) FindByID(id int64) (*models.Post, error) {
+func (p *PostRepositoryImpl) FindByID(ctx context.Context, id int64) (*models.Post, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
 	post := &models.Post{}
-	err := p.db.Get(post, "SELECT * FROM posts WHERE id = $1", id)
+	err := p.db.GetContext(ctx, post, `SELECT p.* FROM posts p
+		JOIN users u ON u.username = p.author
+		WHERE p.id = $1 AND p.deleted_at IS NULL AND u.deleted_at IS NULL`, id)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err, errPostNotFound)
 	}
 	return post, nil
 }
 
-func (p *PostRepositoryImpl) FindByAuthor(author string) ([]*models.Post, error) {
+func (p *PostRepositoryImpl) FindByAuthor(ctx context.Context, author string) ([]*models.Post, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	var posts []*models.Post
+	err := p.db.SelectContext(ctx, &posts, `SELECT p.* FROM posts p
+		JOIN users u ON u.username = p.author
+		WHERE p.author = $1 AND p.deleted_at IS NULL AND u.deleted_at IS NULL
+		ORDER BY p.created_at DESC, p.id DESC`, author)
+
+	return posts, err
+}
+
+func (p *PostRepositoryImpl) FindLatestByAuthor(ctx context.Context, author string, limit int, offset int) ([]*models.Post, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
 	var posts []*models.Post
-	err := p.db.Select(&posts, "SELECT * FROM posts WHERE author = $1", author)
+	err := p.db.SelectContext(ctx, &posts, `SELECT p.* FROM posts p
+		JOIN users u ON u.username = p.author
+		WHERE p.author = $1 AND p.deleted_at IS NULL AND u.deleted_at IS NULL
+		ORDER BY p.created_at DESC, p.id DESC
+		LIMIT $2 OFFSET $3`, author, limit, offset)
 
 	return posts, err
 }
+
+func (p *PostRepositoryImpl) FindDeletedByAuthor(ctx context.Context, author string, deletedSince time.Time) ([]*models.Post, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	var posts []*models.Post
+	err := p.db.SelectContext(ctx, &posts,
+		"SELECT * FROM posts WHERE author = $1 AND deleted_at >= $2 ORDER BY deleted_at DESC",
+		author, deletedSince,
+	)
+
+	return posts, err
+}
+
+func (p *PostRepositoryImpl) FindAfter(ctx context.Context, afterID int64, limit int) ([]*models.Post, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	var posts []*models.Post
+	err := p.db.SelectContext(ctx, &posts, "SELECT * FROM posts WHERE id > $1 ORDER BY id LIMIT $2", afterID, limit)
+
+	return posts, err
+}
+
+func (p *PostRepositoryImpl) Restore(ctx context.Context, id int64, author string, deletedSince time.Time) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	return p.inTx(ctx, func(tx runner) error {
+		result, err := tx.ExecContext(ctx,
+			"UPDATE posts SET deleted_at = NULL WHERE id = $1 AND author = $2 AND deleted_at >= $3",
+			id, author, deletedSince,
+		)
+		if err != nil {
+			return err
+		}
+		return addPostCount(ctx, tx, result, author, 1)
+	})
+}
+
+func (p *PostRepositoryImpl) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	result, err := p.db.ExecContext(ctx, purgePostsSchema, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -1,44 +1,48 @@
 package persistence
 
 import (
-	"database/sql"
+	"context"
 	models "postapi/internal/domain"
-
-	"github.com/jmoiron/sqlx"
 )
 
 type ProfileRepositoryImpl struct {
-	db *sqlx.DB
+	queries
 }
 
-func (pR *ProfileRepositoryImpl) FindByUsername(username string) (*models.Profile, error) {
+func (pR *ProfileRepositoryImpl) FindByUsername(ctx context.Context, username string) (*models.Profile, error) {
+	ctx, cancel := pR.withTimeout(ctx)
+	defer cancel()
+
 	profile := &models.Profile{}
-	err := pR.db.Get(profile, getProfileSchema, username)
+	err := pR.db.GetContext(ctx, profile, getProfileSchema, username)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err, errProfileNotFound)
 	}
 	return profile, nil
 }
 
-func (pR *ProfileRepositoryImpl) Create(p *models.Profile) error {
-	var profilePicture string
-	if p.ProfilePicture == "" {
-		profilePicture = "https://i.redd.it/j6mkb6p73h791.jpg"
-	} else {
-		profilePicture = p.ProfilePicture
-	}
-	_, err := pR.db.Exec(insertProfileSchema, p.Username, p.Description, profilePicture)
-	return err
+func (pR *ProfileRepositoryImpl) Create(ctx context.Context, p *models.Profile) error {
+	ctx, cancel := pR.withTimeout(ctx)
+	defer cancel()
+
+	_, err := pR.db.ExecContext(ctx, insertProfileSchema, p.Username, p.Description, p.ProfilePicture, p.Private, p.AvatarMediaID)
+	return translateError(err, nil)
 }
-func (pR *ProfileRepositoryImpl) Update(p *models.Profile) error {
-	result, err := pR.db.Exec(updateProfileSchema, p.Username, p.Description, p.ProfilePicture)
+func (pR *ProfileRepositoryImpl) Update(ctx context.Context, p *models.Profile) error {
+	ctx, cancel := pR.withTimeout(ctx)
+	defer cancel()
+
+	result, err := pR.db.ExecContext(ctx, updateProfileSchema, p.Username, p.Description, p.ProfilePicture, p.Private, p.AvatarMediaID)
+	if err != nil {
+		return translateError(err, nil)
+	}
 	rows, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
 
 	if rows == 0 {
-		return sql.ErrNoRows
+		return errProfileNotFound
 	}
 
 	return nil
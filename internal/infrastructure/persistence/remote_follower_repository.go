@@ -0,0 +1,67 @@
+package persistence
+
+import (
+	"context"
+	models "postapi/internal/domain"
+)
+
+type RemoteFollowerRepositoryImpl struct {
+	queries
+}
+
+func (r *RemoteFollowerRepositoryImpl) Add(ctx context.Context, follower *models.RemoteFollower) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, insertRemoteFollowerSchema,
+		follower.Username, follower.ActorID, follower.Inbox, follower.FollowID, follower.CreatedAt)
+	return err
+}
+
+func (r *RemoteFollowerRepositoryImpl) Remove(ctx context.Context, username string, actorID string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, removeRemoteFollowerSchema, username, actorID)
+	return err
+}
+
+func (r *RemoteFollowerRepositoryImpl) Find(ctx context.Context, username string, actorID string) (*models.RemoteFollower, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	follower := &models.RemoteFollower{}
+	err := r.db.GetContext(ctx, follower, getRemoteFollowerSchema, username, actorID)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	return follower, nil
+}
+
+func (r *RemoteFollowerRepositoryImpl) RemoveActor(ctx context.Context, actorID string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, removeRemoteActorSchema, actorID)
+	return err
+}
+
+func (r *RemoteFollowerRepositoryImpl) FindByUsername(ctx context.Context, username string) ([]*models.RemoteFollower, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var followers []*models.RemoteFollower
+	err := r.db.SelectContext(ctx, &followers, getRemoteFollowersSchema, username)
+
+	return followers, err
+}
+
+func (r *RemoteFollowerRepositoryImpl) CountByUsername(ctx context.Context, username string) (int64, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var count int64
+	err := r.db.GetContext(ctx, &count, countRemoteFollowersSchema, username)
+
+	return count, err
+}
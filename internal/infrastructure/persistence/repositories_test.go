@@ -0,0 +1,174 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"postapi/internal/domain"
+	"postapi/internal/infrastructure/repotest"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// truncateSchema empties every table between tests.
+const truncateSchema = `TRUNCATE users, posts, user_follows, profiles, post_tags, post_mentions,
+	notifications, user_blocks, conversations, conversation_members, messages, media, post_media,
+	actor_keys, remote_followers, deliveries, webmentions RESTART IDENTITY CASCADE`
+
+// TestRepositories runs the contract tests against the database named by
+// POSTAPI_TEST_DATABASE_URL, whose tables it empties.
+func TestRepositories(t *testing.T) {
+	url := os.Getenv("POSTAPI_TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("POSTAPI_TEST_DATABASE_URL is not set")
+	}
+	db, err := sqlx.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("sqlx.Open() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(createSchema); err != nil {
+		t.Fatalf("creating the schema: %v", err)
+	}
+
+	repotest.Run(t, func(t *testing.T) (domain.Repositories, domain.UnitOfWork) {
+		if _, err := db.Exec(truncateSchema); err != nil {
+			t.Fatalf("emptying the tables: %v", err)
+		}
+		return newRepositories(queries{db: db, timeout: DefaultQueryTimeout}), &UnitOfWork{db: db, timeout: DefaultQueryTimeout}
+	})
+}
+
+// TestRepositories_SQLite runs the contract tests against a new SQLite
+// database file for each test.
+func TestRepositories_SQLite(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) (domain.Repositories, domain.UnitOfWork) {
+		d := openSQLiteDB(t, filepath.Join(t.TempDir(), "postapi.db"))
+		return newRepositories(queries{db: sqliteDB{d.db}, timeout: d.QueryTimeout}), d.UnitOfWork
+	})
+}
+
+func TestDB_Open_SQLiteMigrations(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "postapi.db")
+	first := openSQLiteDB(t, path)
+	if err := first.UserRepository.Create(ctx, &domain.User{Username: "alice", Email: "alice@example.com", Password: "secret"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	first.Close()
+
+	// Opening it again runs no migration twice and keeps the rows
+	again := openSQLiteDB(t, path)
+	var version int
+	if err := again.db.Get(&version, `PRAGMA user_version`); err != nil || version != len(sqliteMigrations) {
+		t.Errorf("user_version = %d, %v, want %d", version, err, len(sqliteMigrations))
+	}
+	if _, err := again.UserRepository.FindByUsername(ctx, "alice"); err != nil {
+		t.Errorf("FindByUsername() after reopening error = %v", err)
+	}
+}
+
+func TestDB_Open_UnknownDriver(t *testing.T) {
+	if err := (&DB{Driver: "mysql"}).Open(); err == nil {
+		t.Error("Open() error = nil, want an unknown driver error")
+	}
+}
+
+func openSQLiteDB(t *testing.T, path string) *DB {
+	t.Helper()
+	d := &DB{Driver: DriverSQLite, DSN: path}
+	if err := d.Open(); err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d
+}
+
+func TestUserRepositoryImpl_Recount(t *testing.T) {
+	ctx := context.Background()
+	d := openSQLiteDB(t, filepath.Join(t.TempDir(), "postapi.db"))
+	for _, username := range []string{"alice", "bob"} {
+		if err := d.UserRepository.Create(ctx, &domain.User{Username: username, Email: username + "@example.com", Password: "secret"}); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	if err := d.UserFollowRepository.Create(ctx, &domain.UserFollow{FollowerUsername: "alice", FollowedUsername: "bob"}); err != nil {
+		t.Fatalf("Follows.Create() error = %v", err)
+	}
+	if _, err := d.db.Exec(`UPDATE users SET follower_count = 7, post_count = 3 WHERE username = 'bob'`); err != nil {
+		t.Fatalf("breaking the counts: %v", err)
+	}
+
+	fixed, err := d.UserRepository.Recount(ctx)
+	if err != nil || fixed != 1 {
+		t.Errorf("Recount() = %d, %v, want 1 user fixed", fixed, err)
+	}
+	bob, err := d.UserRepository.FindByUsername(ctx, "bob")
+	if err != nil {
+		t.Fatalf("FindByUsername() error = %v", err)
+	}
+	if want := (domain.UserCounts{FollowerCount: 1}); bob.UserCounts != want {
+		t.Errorf("counts of bob after Recount() = %+v, want %+v", bob.UserCounts, want)
+	}
+}
+
+func TestDB_Open_QueryTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "postapi.db")
+	if d := openSQLiteDB(t, path); d.QueryTimeout != DefaultQueryTimeout {
+		t.Errorf("QueryTimeout = %v, want the default %v", d.QueryTimeout, DefaultQueryTimeout)
+	}
+	d := &DB{Driver: DriverSQLite, DSN: path, QueryTimeout: -1}
+	if err := d.Open(); err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { d.Close() })
+	if d.QueryTimeout != -1 {
+		t.Errorf("QueryTimeout = %v, want it left negative", d.QueryTimeout)
+	}
+
+	q := queries{timeout: -1}
+	if _, ok := func() (time.Time, bool) {
+		ctx, cancel := q.withTimeout(context.Background())
+		defer cancel()
+		return ctx.Deadline()
+	}(); ok {
+		t.Error("withTimeout() with a negative timeout set a deadline")
+	}
+}
+
+func TestQueries_Cancellation(t *testing.T) {
+	d := openSQLiteDB(t, filepath.Join(t.TempDir(), "postapi.db"))
+	alice := &domain.User{Username: "alice", Email: "alice@example.com", Password: "secret"}
+	if err := d.UserRepository.Create(context.Background(), alice); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := d.UserRepository.FindByUsername(cancelled, "alice"); !errors.Is(err, context.Canceled) {
+		t.Errorf("FindByUsername() with a cancelled context error = %v, want context.Canceled", err)
+	}
+	bob := &domain.User{Username: "bob", Email: "bob@example.com", Password: "secret"}
+	if err := d.UserRepository.Create(cancelled, bob); !errors.Is(err, context.Canceled) {
+		t.Errorf("Create() with a cancelled context error = %v, want context.Canceled", err)
+	}
+	if err := d.UserFollowRepository.Create(cancelled, &domain.UserFollow{FollowerUsername: "alice", FollowedUsername: "bob"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Follows.Create() in a transaction with a cancelled context error = %v, want context.Canceled", err)
+	}
+
+	expired := newRepositories(queries{db: sqliteDB{d.db}, timeout: time.Nanosecond})
+	if _, err := expired.Users.FindByUsername(context.Background(), "alice"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("FindByUsername() past the query timeout error = %v, want context.DeadlineExceeded", err)
+	}
+	if err := expired.Users.Create(context.Background(), bob); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Create() past the query timeout error = %v, want context.DeadlineExceeded", err)
+	}
+
+	// Nothing was written by the aborted calls
+	if _, err := d.UserRepository.FindByUsername(context.Background(), "bob"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("FindByUsername(bob) error = %v, want not found", err)
+	}
+}
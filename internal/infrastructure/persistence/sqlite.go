@@ -0,0 +1,382 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqlitePragmas are set on every connection: foreign keys are off by default
+// in SQLite, and writers wait for each other instead of failing at once.
+const sqlitePragmas = "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+
+// sqliteTimeFormat is how times are stored: UTC and of a fixed width, so that
+// comparing and sorting the text gives the order of the times.
+const sqliteTimeFormat = "2006-01-02 15:04:05.000000000"
+
+// sqliteMigrations build the SQLite schema; the database keeps in
+// user_version how many of them it has run. Append to the list, never edit
+// a migration that has shipped.
+var sqliteMigrations = []string{
+	`CREATE TABLE users
+	(
+		username TEXT NOT NULL PRIMARY KEY,
+		email TEXT UNIQUE,
+		password TEXT,
+		deleted_at TIMESTAMP,
+		follower_count INTEGER NOT NULL DEFAULT 0,
+		following_count INTEGER NOT NULL DEFAULT 0,
+		post_count INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE TABLE posts
+	(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title TEXT NOT NULL,
+		content TEXT NOT NULL,
+		format TEXT NOT NULL DEFAULT 'plain',
+		author TEXT REFERENCES users(username) ON DELETE CASCADE,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		deleted_at TIMESTAMP
+	);
+	CREATE INDEX posts_author_idx ON posts(author);
+	CREATE TABLE user_follows
+	(
+		follower_username TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+		followed_username TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+		PRIMARY KEY (follower_username, followed_username),
+		CONSTRAINT user_follows_check CHECK (follower_username <> followed_username)
+	);
+	CREATE INDEX user_follows_followed_idx ON user_follows(followed_username);
+	CREATE TABLE media
+	(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		owner TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+		storage_key TEXT NOT NULL UNIQUE,
+		content_type TEXT NOT NULL,
+		size INTEGER NOT NULL,
+		width INTEGER NOT NULL DEFAULT 0,
+		height INTEGER NOT NULL DEFAULT 0,
+		blurhash TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL
+	);
+	CREATE TABLE profiles
+	(
+		username TEXT NOT NULL PRIMARY KEY REFERENCES users(username) ON DELETE CASCADE,
+		description TEXT,
+		profile_picture TEXT,
+		private BOOLEAN NOT NULL DEFAULT FALSE,
+		avatar_media_id INTEGER REFERENCES media(id) ON DELETE SET NULL
+	);
+	CREATE TABLE post_tags
+	(
+		post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+		tag TEXT NOT NULL,
+		PRIMARY KEY (post_id, tag)
+	);
+	CREATE INDEX post_tags_tag_idx ON post_tags(tag);
+	CREATE TABLE post_mentions
+	(
+		post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+		username TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+		field TEXT NOT NULL,
+		start_offset INTEGER NOT NULL,
+		end_offset INTEGER NOT NULL,
+		PRIMARY KEY (post_id, field, start_offset)
+	);
+	CREATE INDEX post_mentions_username_idx ON post_mentions(username);
+	CREATE TABLE notifications
+	(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		recipient TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+		actor TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+		type TEXT NOT NULL,
+		post_id INTEGER REFERENCES posts(id) ON DELETE CASCADE,
+		created_at TIMESTAMP NOT NULL,
+		read_at TIMESTAMP
+	);
+	CREATE INDEX notifications_recipient_idx ON notifications(recipient, id);
+	CREATE TABLE user_blocks
+	(
+		blocker_username TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+		blocked_username TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+		PRIMARY KEY (blocker_username, blocked_username),
+		CONSTRAINT user_blocks_check CHECK (blocker_username <> blocked_username)
+	);
+	CREATE TABLE conversations
+	(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		created_by TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+		is_group BOOLEAN NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	);
+	CREATE TABLE conversation_members
+	(
+		conversation_id INTEGER NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+		username TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+		last_read_message_id INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (conversation_id, username)
+	);
+	CREATE INDEX conversation_members_username_idx ON conversation_members(username);
+	CREATE TABLE messages
+	(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		conversation_id INTEGER NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+		sender TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+		body TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	);
+	CREATE INDEX messages_conversation_idx ON messages(conversation_id, id);
+	CREATE TABLE post_media
+	(
+		post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+		media_id INTEGER NOT NULL REFERENCES media(id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		PRIMARY KEY (post_id, media_id)
+	);
+	CREATE TABLE actor_keys
+	(
+		username TEXT NOT NULL PRIMARY KEY REFERENCES users(username) ON DELETE CASCADE,
+		private_key_pem TEXT NOT NULL,
+		public_key_pem TEXT NOT NULL
+	);
+	CREATE TABLE remote_followers
+	(
+		username TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+		actor_id TEXT NOT NULL,
+		inbox TEXT NOT NULL,
+		follow_id TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (username, actor_id)
+	);
+	CREATE TABLE deliveries
+	(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		sender TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+		inbox TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMP NOT NULL,
+		last_error TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL
+	);
+	CREATE INDEX deliveries_due_idx ON deliveries(next_attempt_at);
+	CREATE TABLE webmentions
+	(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+		source TEXT NOT NULL,
+		target TEXT NOT NULL,
+		status TEXT NOT NULL,
+		type TEXT NOT NULL DEFAULT 'mention',
+		author_name TEXT NOT NULL DEFAULT '',
+		author_url TEXT NOT NULL DEFAULT '',
+		author_photo TEXT NOT NULL DEFAULT '',
+		content TEXT NOT NULL DEFAULT '',
+		published TIMESTAMP,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMP NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL,
+		verified_at TIMESTAMP,
+		UNIQUE (source, target)
+	);
+	CREATE INDEX webmentions_pending_idx ON webmentions(status, next_attempt_at);
+	CREATE INDEX webmentions_post_idx ON webmentions(post_id);`,
+}
+
+// openSQLite opens the database file at path, creating it if needed, and
+// brings its schema up to date.
+func openSQLite(path string) (*sqlx.DB, error) {
+	if path == "" {
+		return nil, errors.New("sqlite: no database file given")
+	}
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	db, err := sqlx.Open("sqlite", path+sep+sqlitePragmas)
+	if err != nil {
+		return nil, err
+	}
+	if err := migrateSQLite(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// migrateSQLite runs the migrations the database has not run yet, each in a
+// transaction of its own.
+func migrateSQLite(db *sqlx.DB) error {
+	var version int
+	if err := db.Get(&version, `PRAGMA user_version`); err != nil {
+		return err
+	}
+	for ; version < len(sqliteMigrations); version++ {
+		tx, err := db.Beginx()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(sqliteMigrations[version]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("sqlite migration %d: %w", version+1, err)
+		}
+		// PRAGMA does not take parameters
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, version+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sqliteDB runs the queries of the repositories, written for Postgres, on
+// SQLite: it stores times in sqliteTimeFormat and keeps the statement with
+// its errors, for sqliteViolation to tell which foreign key failed.
+type sqliteDB struct {
+	runner
+}
+
+func (s sqliteDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	res, err := s.runner.ExecContext(ctx, query, sqliteArgs(args)...)
+	return res, withStatement(err, query)
+}
+
+func (s sqliteDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	rows, err := s.runner.QueryContext(ctx, query, sqliteArgs(args)...)
+	return rows, withStatement(err, query)
+}
+
+func (s sqliteDB) QueryxContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error) {
+	rows, err := s.runner.QueryxContext(ctx, query, sqliteArgs(args)...)
+	return rows, withStatement(err, query)
+}
+
+func (s sqliteDB) QueryRowxContext(ctx context.Context, query string, args ...any) *sqlx.Row {
+	return s.runner.QueryRowxContext(ctx, query, sqliteArgs(args)...)
+}
+
+func (s sqliteDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return s.runner.QueryRowContext(ctx, query, sqliteArgs(args)...)
+}
+
+func (s sqliteDB) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	return withStatement(s.runner.GetContext(ctx, dest, query, sqliteArgs(args)...), query)
+}
+
+func (s sqliteDB) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	return withStatement(s.runner.SelectContext(ctx, dest, query, sqliteArgs(args)...), query)
+}
+
+// sqliteArgs returns args with the times formatted as SQLite stores them.
+func sqliteArgs(args []any) []any {
+	converted := make([]any, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case time.Time:
+			converted[i] = v.UTC().Format(sqliteTimeFormat)
+		case *time.Time:
+			if v != nil {
+				converted[i] = v.UTC().Format(sqliteTimeFormat)
+			}
+		default:
+			converted[i] = arg
+		}
+	}
+	return converted
+}
+
+// statementError is a SQLite error with the statement that caused it.
+type statementError struct {
+	query string
+	err   error
+}
+
+func (e *statementError) Error() string { return e.err.Error() }
+
+func (e *statementError) Unwrap() error { return e.err }
+
+func withStatement(err error, query string) error {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+	return &statementError{query: query, err: err}
+}
+
+// sqliteKeys names, after the constraint of the Postgres schema, the keys
+// SQLite reports by their columns.
+var sqliteKeys = map[string]string{
+	"users.username":    "users_pkey",
+	"users.email":       "users_email_key",
+	"profiles.username": "profiles_pkey",
+	"user_follows.follower_username, user_follows.followed_username": "user_follows_pkey",
+	"user_blocks.blocker_username, user_blocks.blocked_username":     "user_blocks_pkey",
+}
+
+// sqliteForeignKeys names the foreign key a write to each table violates.
+// SQLite does not say which one failed, so the table stands for the key
+// clients can get wrong: the others reference the signed-in user or rows
+// written in the same transaction.
+var sqliteForeignKeys = map[string]string{
+	"user_follows":         "user_follows_followed_username_fkey",
+	"user_blocks":          "user_blocks_blocked_username_fkey",
+	"conversation_members": "conversation_members_username_fkey",
+	"profiles":             "profiles_avatar_media_id_fkey",
+	"post_media":           "post_media_media_id_fkey",
+}
+
+var writtenTable = regexp.MustCompile(`^\s*(?:INSERT\s+INTO|UPDATE)\s+(\w+)`)
+
+// sqliteViolation returns the constraint err violates, named as in
+// Postgres, and the Postgres name of the kind of violation.
+func sqliteViolation(err error) (constraint string, class string, ok bool) {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return "", "", false
+	}
+	// The message ends in "constraint failed: <detail> (<code>)"
+	detail := sqliteErr.Error()
+	if i := strings.LastIndex(detail, "constraint failed: "); i >= 0 {
+		detail = detail[i+len("constraint failed: "):]
+	}
+	if i := strings.LastIndex(detail, " ("); i >= 0 {
+		detail = detail[:i]
+	}
+
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY, sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+		return sqliteKeys[detail], "unique_violation", true
+	case sqlite3.SQLITE_CONSTRAINT_CHECK:
+		return detail, "check_violation", true
+	case sqlite3.SQLITE_CONSTRAINT_NOTNULL:
+		return "", "not_null_violation", true
+	case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		var stmtErr *statementError
+		if errors.As(err, &stmtErr) {
+			if m := writtenTable.FindStringSubmatch(stmtErr.query); m != nil {
+				constraint = sqliteForeignKeys[m[1]]
+			}
+		}
+		return constraint, "foreign_key_violation", true
+	}
+	return "", "", false
+}
+
+// isSQLiteBusy reports whether err is SQLite giving up on a lock held by
+// another writer.
+func isSQLiteBusy(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code()&0xff == sqlite3.SQLITE_BUSY
+}
@@ -0,0 +1,48 @@
+package persistence
+
+import (
+	"context"
+	models "postapi/internal/domain"
+	"time"
+)
+
+type TagRepositoryImpl struct {
+	queries
+}
+
+func (t *TagRepositoryImpl) SetPostTags(ctx context.Context, postID int64, tags []string) error {
+	ctx, cancel := t.withTimeout(ctx)
+	defer cancel()
+
+	return t.inTx(ctx, func(tx runner) error {
+		if _, err := tx.ExecContext(ctx, deletePostTagsSchema, postID); err != nil {
+			return err
+		}
+		for _, tag := range tags {
+			if _, err := tx.ExecContext(ctx, insertPostTagSchema, postID, tag); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (t *TagRepositoryImpl) FindPostsByTag(ctx context.Context, tag string, limit int, offset int) ([]*models.Post, error) {
+	ctx, cancel := t.withTimeout(ctx)
+	defer cancel()
+
+	var posts []*models.Post
+	err := t.db.SelectContext(ctx, &posts, findPostsByTagSchema, tag, limit, offset)
+
+	return posts, err
+}
+
+func (t *TagRepositoryImpl) Trending(ctx context.Context, since time.Time, limit int) ([]*models.TagCount, error) {
+	ctx, cancel := t.withTimeout(ctx)
+	defer cancel()
+
+	var tags []*models.TagCount
+	err := t.db.SelectContext(ctx, &tags, trendingTagsSchema, since, limit)
+
+	return tags, err
+}
@@ -0,0 +1,69 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"postapi/internal/domain"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// maxTxAttempts is how many times UnitOfWork.Do runs a transaction that
+// keeps failing to serialize.
+const maxTxAttempts = 3
+
+// txRetryDelay is the wait before the second attempt; it grows with each
+// attempt.
+const txRetryDelay = 10 * time.Millisecond
+
+// UnitOfWork runs functions in serializable transactions and retries them
+// when Postgres aborts them for conflicting with concurrent ones, or SQLite
+// times out waiting for another writer.
+type UnitOfWork struct {
+	db      runner
+	timeout time.Duration
+}
+
+// Do commits what fn writes through repos, or nothing if fn fails.
+func (u *UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos domain.Repositories) error) error {
+	for attempt := 1; ; attempt++ {
+		err := u.run(ctx, fn)
+		if err == nil || attempt == maxTxAttempts || !isSerializationFailure(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * txRetryDelay):
+		}
+	}
+}
+
+func (u *UnitOfWork) run(ctx context.Context, fn func(ctx context.Context, repos domain.Repositories) error) error {
+	db, tx, err := begin(ctx, u.db, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(ctx, newRepositories(queries{db: db, timeout: u.timeout})); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// isSerializationFailure reports whether err aborted a transaction that can
+// succeed when run again.
+func isSerializationFailure(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return isSQLiteBusy(err)
+	}
+	switch pqErr.Code.Name() {
+	case "serialization_failure", "deadlock_detected":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"postapi/internal/domain"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestUnitOfWork_Retry(t *testing.T) {
+	serialization := &pq.Error{Code: "40001"}
+	ordinary := errors.New("not today")
+	tests := []struct {
+		name      string
+		failures  int
+		err       error
+		wantErr   error
+		wantCalls int
+	}{
+		{"Succeeds after a serialization failure", 1, serialization, nil, 2},
+		{"Succeeds after a deadlock", 1, &pq.Error{Code: "40P01"}, nil, 2},
+		{"Gives up after maxTxAttempts", maxTxAttempts, serialization, serialization, maxTxAttempts},
+		{"Does not retry other errors", 1, ordinary, ordinary, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := openSQLiteDB(t, filepath.Join(t.TempDir(), "postapi.db"))
+			calls := 0
+			err := d.UnitOfWork.Do(context.Background(), func(ctx context.Context, repos domain.Repositories) error {
+				calls++
+				// Every attempt writes the same row, which only fits once the
+				// previous attempts were rolled back
+				if err := repos.Users.Create(ctx, &domain.User{Username: "alice", Email: "alice@example.com", Password: "secret"}); err != nil {
+					return err
+				}
+				if calls <= tt.failures {
+					return tt.err
+				}
+				return nil
+			})
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Do() error = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("fn called %d times, want %d", calls, tt.wantCalls)
+			}
+			_, err = d.UserRepository.FindByUsername(context.Background(), "alice")
+			if committed := err == nil; committed != (tt.wantErr == nil) {
+				t.Errorf("alice committed = %v, FindByUsername() error = %v", committed, err)
+			}
+		})
+	}
+}
@@ -1,40 +1,27 @@
 package persistence
 
 import (
-	"errors"
-	"net/mail"
+	"context"
 	models "postapi/internal/domain"
+	"time"
 
-	"github.com/jmoiron/sqlx"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type UserRepositoryImpl struct {
-	db *sqlx.DB
+	queries
 }
 
-func (u *UserRepositoryImpl) Create(p *models.User) error {
-	if p.Username == "" {
-		return errors.New("username required")
-	}
-	if len(p.Password) < 8 {
-		return errors.New("password must be at least 8 characters")
-	}
-	if !checkValidEmail(p.Email) {
-		return errors.New("invalid email format")
-	}
+func (u *UserRepositoryImpl) Create(ctx context.Context, p *models.User) error {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
 
 	hashedPassword, err := hashPassword(p.Password)
 	if err != nil {
 		return err
 	}
-	_, err = u.db.Exec(insertUserSchema, p.Username, p.Email, hashedPassword)
-	return err
-}
-
-func checkValidEmail(email string) bool {
-	_, err := mail.ParseAddress(email)
-	return err == nil
+	_, err = u.db.ExecContext(ctx, insertUserSchema, p.Username, p.Email, hashedPassword)
+	return translateError(err, nil)
 }
 
 func hashPassword(password string) (string, error) {
@@ -45,26 +32,119 @@ func hashPassword(password string) (string, error) {
 	return string(hashed), nil
 }
 
-func (u *UserRepositoryImpl) LoginUser(p *models.User) (*models.User, error) {
+func (u *UserRepositoryImpl) LoginUser(ctx context.Context, p *models.User) (*models.User, error) {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
 	user := &models.User{}
-	err := u.db.Get(user, "SELECT * FROM users WHERE username = $1", p.Username)
+	err := u.db.GetContext(ctx, user, getActiveUserSchema, p.Username)
 
 	if err != nil {
-		return nil, err
+		return nil, translateError(err, errUserNotFound)
 	}
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(p.Password))
 	if err != nil {
-		return nil, err
+		return nil, errInvalidCredentials.Wrap(err)
 	}
 	user.Password = ""
 	return user, nil
 }
 
-func (u *UserRepositoryImpl) FindByUsername(username string) (*models.User, error) {
+func (u *UserRepositoryImpl) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
 	user := &models.User{}
-	err := u.db.Get(user, "SELECT * FROM users WHERE username = $1", username)
+	err := u.db.GetContext(ctx, user, getActiveUserSchema, username)
+	if err != nil {
+		return nil, translateError(err, errUserNotFound)
+	}
+	return user, nil
+}
+
+func (u *UserRepositoryImpl) Delete(ctx context.Context, username string) error {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	return u.inTx(ctx, func(tx runner) error {
+		result, err := tx.ExecContext(ctx, softDeleteUserSchema, username, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return errUserNotFound
+		}
+		return addNeighbourCounts(ctx, tx, username, -1)
+	})
+}
+
+// addNeighbourCounts adds delta to the counts of the users username follows
+// or is followed by, which count it only while it is active.
+func addNeighbourCounts(ctx context.Context, tx runner, username string, delta int) error {
+	if _, err := tx.ExecContext(ctx, addFollowedCountsSchema, username, delta); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, addFollowerCountsSchema, username, delta)
+	return err
+}
+
+func (u *UserRepositoryImpl) Restore(ctx context.Context, p *models.User, deletedSince time.Time) (*models.User, error) {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	user := &models.User{}
+	err := u.db.GetContext(ctx, user, getDeletedUserSchema, p.Username, deletedSince)
+	if err != nil {
+		return nil, translateError(err, errUserNotFound)
+	}
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(p.Password))
+	if err != nil {
+		return nil, errInvalidCredentials.Wrap(err)
+	}
+	err = u.inTx(ctx, func(tx runner) error {
+		result, err := tx.ExecContext(ctx, restoreUserSchema, user.Username)
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return errUserNotFound
+		}
+		return addNeighbourCounts(ctx, tx, user.Username, 1)
+	})
 	if err != nil {
 		return nil, err
 	}
+	user.Password = ""
+	user.DeletedAt = nil
 	return user, nil
 }
+
+func (u *UserRepositoryImpl) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	result, err := u.db.ExecContext(ctx, purgeUsersSchema, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (u *UserRepositoryImpl) Recount(ctx context.Context) (int64, error) {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	result, err := u.db.ExecContext(ctx, recountUsersSchema)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,72 @@
+package persistence
+
+import (
+	"context"
+	models "postapi/internal/domain"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type WebmentionRepositoryImpl struct {
+	queries
+}
+
+func (w *WebmentionRepositoryImpl) Save(ctx context.Context, webmention *models.Webmention) error {
+	ctx, cancel := w.withTimeout(ctx)
+	defer cancel()
+
+	return w.db.QueryRowContext(ctx,
+		saveWebmentionSchema,
+		webmention.PostID, webmention.Source, webmention.Target, webmention.Status, webmention.Type,
+		webmention.NextAttemptAt, webmention.CreatedAt, webmention.UpdatedAt,
+	).Scan(&webmention.ID, &webmention.CreatedAt)
+}
+
+func (w *WebmentionRepositoryImpl) FindPending(ctx context.Context, now time.Time, limit int) ([]*models.Webmention, error) {
+	ctx, cancel := w.withTimeout(ctx)
+	defer cancel()
+
+	var webmentions []*models.Webmention
+	err := w.db.SelectContext(ctx, &webmentions, findPendingWebmentionsSchema, now, limit)
+
+	return webmentions, err
+}
+
+func (w *WebmentionRepositoryImpl) Update(ctx context.Context, webmention *models.Webmention) error {
+	ctx, cancel := w.withTimeout(ctx)
+	defer cancel()
+
+	_, err := w.db.ExecContext(ctx,
+		updateWebmentionSchema,
+		webmention.ID, webmention.Status, webmention.Type, webmention.AuthorName, webmention.AuthorURL,
+		webmention.AuthorPhoto, webmention.Content, webmention.Published, webmention.Attempts,
+		webmention.NextAttemptAt, webmention.UpdatedAt, webmention.VerifiedAt,
+	)
+	return err
+}
+
+func (w *WebmentionRepositoryImpl) FindVerifiedByPosts(ctx context.Context, postIDs []int64) ([]*models.Webmention, error) {
+	ctx, cancel := w.withTimeout(ctx)
+	defer cancel()
+
+	webmentions := []*models.Webmention{}
+	if len(postIDs) == 0 {
+		return webmentions, nil
+	}
+	query, args, err := sqlx.In(findVerifiedWebmentionsByPostsSchema, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	err = w.db.SelectContext(ctx, &webmentions, w.db.Rebind(query), args...)
+
+	return webmentions, err
+}
+
+func (w *WebmentionRepositoryImpl) DeleteBySource(ctx context.Context, source string) error {
+	ctx, cancel := w.withTimeout(ctx)
+	defer cancel()
+
+	_, err := w.db.ExecContext(ctx, deleteWebmentionsBySourceSchema, source)
+	return err
+}
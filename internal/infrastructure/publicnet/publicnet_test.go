@@ -0,0 +1,29 @@
+package publicnet
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewHTTPClientRefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "secret")
+	}))
+	defer srv.Close()
+
+	if _, err := NewHTTPClient(time.Second).Get(srv.URL); !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("Get(%s) error = %v, want ErrPrivateAddress", srv.URL, err)
+	}
+	for _, addr := range []string{"10.0.0.1:80", "192.168.1.1:443", "[::1]:80", "169.254.169.254:80", "0.0.0.0:80"} {
+		if err := publicOnly("tcp", addr, nil); !errors.Is(err, ErrPrivateAddress) {
+			t.Errorf("publicOnly(%s) = %v, want ErrPrivateAddress", addr, err)
+		}
+	}
+	if err := publicOnly("tcp", "93.184.215.14:443", nil); err != nil {
+		t.Errorf("publicOnly(public address) = %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+package realtime
+
+import (
+	"errors"
+	"sync"
+)
+
+var ErrHubClosed = errors.New("hub closed")
+
+// Event is a message published to one or more topics. IDs are assigned by the
+// hub and grow monotonically, so clients can resume after the last one seen.
+type Event struct {
+	ID     uint64
+	Type   string
+	Topics []string
+	Data   any
+}
+
+// Hub is an in-process pub/sub broker. Each subscription has a bounded buffer;
+// a subscriber that falls behind is disconnected instead of slowing down
+// publishers, and can resume from the hub's recent history.
+type Hub struct {
+	mu         sync.Mutex
+	nextID     uint64
+	history    []Event
+	maxHistory int
+	bufferSize int
+	subs       map[*Subscription]struct{}
+	closed     bool
+}
+
+func NewHub(historySize int, bufferSize int) *Hub {
+	return &Hub{
+		maxHistory: historySize,
+		bufferSize: bufferSize,
+		subs:       make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe listens to topics. Events newer than lastEventID that are still
+// in the history are delivered first; pass 0 to only receive new events.
+func (h *Hub) Subscribe(topics []string, lastEventID uint64) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, ErrHubClosed
+	}
+
+	sub := &Subscription{hub: h, topics: make(map[string]bool)}
+	for _, topic := range topics {
+		sub.topics[topic] = true
+	}
+
+	var replay []Event
+	if lastEventID > 0 {
+		for _, ev := range h.history {
+			if ev.ID > lastEventID && sub.matches(ev) {
+				replay = append(replay, ev)
+			}
+		}
+	}
+	sub.events = make(chan Event, h.bufferSize+len(replay))
+	for _, ev := range replay {
+		sub.events <- ev
+	}
+	h.subs[sub] = struct{}{}
+	return sub, nil
+}
+
+// Publish delivers an event to every subscription listening to any of topics.
+func (h *Hub) Publish(eventType string, data any, topics ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed || len(topics) == 0 {
+		return
+	}
+
+	h.nextID++
+	ev := Event{ID: h.nextID, Type: eventType, Topics: topics, Data: data}
+	h.history = append(h.history, ev)
+	if len(h.history) > h.maxHistory {
+		h.history = h.history[len(h.history)-h.maxHistory:]
+	}
+
+	for sub := range h.subs {
+		if !sub.matches(ev) {
+			continue
+		}
+		select {
+		case sub.events <- ev:
+		default:
+			// Slow consumer: drop it, it can resume with its last event ID.
+			h.remove(sub)
+		}
+	}
+}
+
+// Close ends every subscription and rejects new ones.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	h.closed = true
+	for sub := range h.subs {
+		h.remove(sub)
+	}
+}
+
+// remove must be called with h.mu held.
+func (h *Hub) remove(sub *Subscription) {
+	if _, ok := h.subs[sub]; !ok {
+		return
+	}
+	delete(h.subs, sub)
+	close(sub.events)
+}
+
+// Subscription receives the events of its topics until it is closed, either
+// by the subscriber, because it fell behind, or because the hub shut down.
+type Subscription struct {
+	hub    *Hub
+	events chan Event
+	topics map[string]bool
+}
+
+// Events is closed when the subscription ends.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+func (s *Subscription) AddTopics(topics ...string) {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	for _, topic := range topics {
+		s.topics[topic] = true
+	}
+}
+
+func (s *Subscription) RemoveTopics(topics ...string) {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	for _, topic := range topics {
+		delete(s.topics, topic)
+	}
+}
+
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+// matches must be called with the hub lock held.
+func (s *Subscription) matches(ev Event) bool {
+	for _, topic := range ev.Topics {
+		if s.topics[topic] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package realtime
+
+import (
+	"testing"
+	"time"
+)
+
+func receive(t *testing.T, sub *Subscription) Event {
+	t.Helper()
+	select {
+	case ev, ok := <-sub.Events():
+		if !ok {
+			t.Fatal("subscription closed unexpectedly")
+		}
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+	return Event{}
+}
+
+func assertNoEvent(t *testing.T, sub *Subscription) {
+	t.Helper()
+	select {
+	case ev, ok := <-sub.Events():
+		if ok {
+			t.Fatalf("unexpected event %+v", ev)
+		}
+	default:
+	}
+}
+
+func TestHub_PublishToTopics(t *testing.T) {
+	hub := NewHub(10, 10)
+	alice, _ := hub.Subscribe([]string{"home:alice"}, 0)
+	bob, _ := hub.Subscribe([]string{"home:bob", "tag:go"}, 0)
+
+	hub.Publish("post.created", "p1", "home:alice", "tag:go")
+
+	if ev := receive(t, alice); ev.ID != 1 || ev.Type != "post.created" || ev.Data != "p1" {
+		t.Errorf("alice received %+v", ev)
+	}
+	if ev := receive(t, bob); ev.ID != 1 {
+		t.Errorf("bob received %+v", ev)
+	}
+
+	hub.Publish("post.created", "p2", "home:carol")
+	assertNoEvent(t, alice)
+	assertNoEvent(t, bob)
+}
+
+func TestHub_EventDeliveredOncePerSubscription(t *testing.T) {
+	hub := NewHub(10, 10)
+	sub, _ := hub.Subscribe([]string{"home:alice", "user:alice"}, 0)
+
+	hub.Publish("post.created", "p1", "home:alice", "user:alice")
+
+	receive(t, sub)
+	assertNoEvent(t, sub)
+}
+
+func TestHub_ResumeFromLastEventID(t *testing.T) {
+	hub := NewHub(10, 10)
+	hub.Publish("a", 1, "t")
+	hub.Publish("b", 2, "other")
+	hub.Publish("c", 3, "t")
+	hub.Publish("d", 4, "t")
+
+	sub, err := hub.Subscribe([]string{"t"}, 1)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	if ev := receive(t, sub); ev.ID != 3 {
+		t.Errorf("first replayed event ID = %d, want 3", ev.ID)
+	}
+	if ev := receive(t, sub); ev.ID != 4 {
+		t.Errorf("second replayed event ID = %d, want 4", ev.ID)
+	}
+	assertNoEvent(t, sub)
+}
+
+func TestHub_HistoryIsBounded(t *testing.T) {
+	hub := NewHub(2, 10)
+	for i := 0; i < 5; i++ {
+		hub.Publish("e", i, "t")
+	}
+
+	sub, _ := hub.Subscribe([]string{"t"}, 1)
+	if ev := receive(t, sub); ev.ID != 4 {
+		t.Errorf("oldest replayed event ID = %d, want 4", ev.ID)
+	}
+}
+
+func TestHub_SlowConsumerIsDropped(t *testing.T) {
+	hub := NewHub(10, 2)
+	slow, _ := hub.Subscribe([]string{"t"}, 0)
+	fast, _ := hub.Subscribe([]string{"t"}, 0)
+
+	for i := 0; i < 3; i++ {
+		hub.Publish("e", i, "t")
+		if i < 2 {
+			receive(t, fast)
+		}
+	}
+
+	// The slow subscriber gets its buffered events and then the channel closes.
+	receive(t, slow)
+	receive(t, slow)
+	if _, ok := <-slow.Events(); ok {
+		t.Error("slow subscription should have been closed")
+	}
+	if ev := receive(t, fast); ev.ID != 3 {
+		t.Errorf("fast subscriber received %+v, want event 3", ev)
+	}
+}
+
+func TestHub_AddRemoveTopics(t *testing.T) {
+	hub := NewHub(10, 10)
+	sub, _ := hub.Subscribe(nil, 0)
+
+	sub.AddTopics("tag:go")
+	hub.Publish("e", 1, "tag:go")
+	receive(t, sub)
+
+	sub.RemoveTopics("tag:go")
+	hub.Publish("e", 2, "tag:go")
+	assertNoEvent(t, sub)
+}
+
+func TestHub_Close(t *testing.T) {
+	hub := NewHub(10, 10)
+	sub, _ := hub.Subscribe([]string{"t"}, 0)
+
+	hub.Close()
+
+	if _, ok := <-sub.Events(); ok {
+		t.Error("subscription should be closed when the hub closes")
+	}
+	if _, err := hub.Subscribe([]string{"t"}, 0); err != ErrHubClosed {
+		t.Errorf("Subscribe() after Close error = %v, want ErrHubClosed", err)
+	}
+	hub.Publish("e", 1, "t")
+	sub.Close()
+}
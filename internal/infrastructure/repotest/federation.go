@@ -0,0 +1,235 @@
+package repotest
+
+import (
+	"errors"
+	"fmt"
+	"postapi/internal/domain"
+	"testing"
+	"time"
+)
+
+func testActorKeys(t *testing.T, newRepos Factory) {
+	t.Run("Keep the first key", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice")
+
+		_, err := repos.ActorKeys.FindByUsername(ctx, "alice")
+		if !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("FindByUsername() before Create() error = %v, want not found", err)
+		}
+		for _, pem := range []string{"first", "second"} {
+			key := &domain.ActorKey{Username: "alice", PrivateKeyPem: pem, PublicKeyPem: pem}
+			if err := repos.ActorKeys.Create(ctx, key); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+		}
+		key, err := repos.ActorKeys.FindByUsername(ctx, "alice")
+		if err != nil || key.PrivateKeyPem != "first" || key.PublicKeyPem != "first" {
+			t.Errorf("FindByUsername() = %+v, %v, want the first key", key, err)
+		}
+	})
+}
+
+func testRemoteFollowers(t *testing.T, newRepos Factory) {
+	t.Run("Add and remove", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice", "bob")
+		start := time.Now().UTC().Truncate(time.Second)
+		addRemoteFollower(t, repos, "alice", "https://b.example/bea", "https://b.example/inbox", start.Add(time.Minute))
+		addRemoteFollower(t, repos, "alice", "https://a.example/ann", "https://a.example/inbox", start)
+		addRemoteFollower(t, repos, "bob", "https://a.example/ann", "https://a.example/inbox", start)
+		// Following again only updates the inbox
+		addRemoteFollower(t, repos, "alice", "https://a.example/ann", "https://a.example/shared", start.Add(time.Hour))
+
+		followers, err := repos.RemoteFollowers.FindByUsername(ctx, "alice")
+		if err != nil {
+			t.Fatalf("FindByUsername() error = %v", err)
+		}
+		if len(followers) != 2 {
+			t.Fatalf("FindByUsername() returned %d followers, want 2", len(followers))
+		}
+		if f := followers[0]; f.ActorID != "https://a.example/ann" || f.Inbox != "https://a.example/shared" || !f.CreatedAt.Equal(start) {
+			t.Errorf("FindByUsername()[0] = %+v, want ann with the new inbox", f)
+		}
+		if followers[1].ActorID != "https://b.example/bea" {
+			t.Errorf("FindByUsername()[1] = %+v, want bea", followers[1])
+		}
+		follower, err := repos.RemoteFollowers.Find(ctx, "alice", "https://a.example/ann")
+		if err != nil || follower.FollowID != fmt.Sprintf("https://a.example/ann/follows/%d", start.Add(time.Hour).Unix()) {
+			t.Errorf("Find() = %+v, %v, want the last follow of ann", follower, err)
+		}
+		if _, err := repos.RemoteFollowers.Find(ctx, "bob", "https://b.example/bea"); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("Find() of no follow error = %v, want not found", err)
+		}
+		if count, err := repos.RemoteFollowers.CountByUsername(ctx, "alice"); err != nil || count != 2 {
+			t.Errorf("CountByUsername() = %d, %v, want 2", count, err)
+		}
+
+		if err := repos.RemoteFollowers.Remove(ctx, "alice", "https://b.example/bea"); err != nil {
+			t.Fatalf("Remove() error = %v", err)
+		}
+		if err := repos.RemoteFollowers.RemoveActor(ctx, "https://a.example/ann"); err != nil {
+			t.Fatalf("RemoveActor() error = %v", err)
+		}
+		for _, username := range []string{"alice", "bob"} {
+			if count, _ := repos.RemoteFollowers.CountByUsername(ctx, username); count != 0 {
+				t.Errorf("CountByUsername(%s) after removing = %d, want 0", username, count)
+			}
+		}
+	})
+}
+
+func addRemoteFollower(t *testing.T, repos domain.Repositories, username string, actorID string, inbox string, createdAt time.Time) {
+	t.Helper()
+	followID := fmt.Sprintf("%s/follows/%d", actorID, createdAt.Unix())
+	follower := &domain.RemoteFollower{Username: username, ActorID: actorID, Inbox: inbox, FollowID: followID, CreatedAt: createdAt}
+	if err := repos.RemoteFollowers.Add(ctx, follower); err != nil {
+		t.Fatalf("RemoteFollowers.Add() error = %v", err)
+	}
+}
+
+func testDeliveries(t *testing.T, newRepos Factory) {
+	t.Run("Due deliveries", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice")
+		now := time.Now().UTC().Truncate(time.Second)
+		later := enqueue(t, repos, now.Add(-time.Minute))
+		sooner := enqueue(t, repos, now.Add(-time.Hour))
+		future := enqueue(t, repos, now.Add(time.Hour))
+
+		due, err := repos.Deliveries.FindDue(ctx, now, 10)
+		if err != nil {
+			t.Fatalf("FindDue() error = %v", err)
+		}
+		wantIDs(t, "FindDue()", deliveryIDs(due), sooner.ID, later.ID)
+		if d := due[0]; d.Sender != "alice" || d.Inbox != "https://a.example/inbox" || d.Payload != "{}" || !d.NextAttemptAt.Equal(now.Add(-time.Hour)) {
+			t.Errorf("FindDue()[0] = %+v", d)
+		}
+		due, _ = repos.Deliveries.FindDue(ctx, now, 1)
+		wantIDs(t, "FindDue() limited", deliveryIDs(due), sooner.ID)
+
+		if err := repos.Deliveries.Reschedule(ctx, sooner.ID, 1, now.Add(2*time.Hour), "timeout"); err != nil {
+			t.Fatalf("Reschedule() error = %v", err)
+		}
+		if err := repos.Deliveries.Delete(ctx, later.ID); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		due, _ = repos.Deliveries.FindDue(ctx, now.Add(3*time.Hour), 10)
+		wantIDs(t, "FindDue() after the changes", deliveryIDs(due), future.ID, sooner.ID)
+		if d := due[1]; d.Attempts != 1 || d.LastError != "timeout" {
+			t.Errorf("FindDue() rescheduled = %+v, want 1 attempt and the error", d)
+		}
+	})
+}
+
+func enqueue(t *testing.T, repos domain.Repositories, next time.Time) *domain.Delivery {
+	t.Helper()
+	d := &domain.Delivery{Sender: "alice", Inbox: "https://a.example/inbox", Payload: "{}", NextAttemptAt: next, CreatedAt: time.Now().UTC()}
+	if err := repos.Deliveries.Enqueue(ctx, d); err != nil {
+		t.Fatalf("Deliveries.Enqueue() error = %v", err)
+	}
+	return d
+}
+
+func deliveryIDs(deliveries []*domain.Delivery) []int64 {
+	ids := make([]int64, len(deliveries))
+	for i, d := range deliveries {
+		ids[i] = d.ID
+	}
+	return ids
+}
+
+func testWebmentions(t *testing.T, newRepos Factory) {
+	t.Run("Save, verify and find", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice")
+		post := createPost(t, repos, "alice", "Hello")
+		start := time.Now().UTC().Truncate(time.Second)
+
+		first := saveWebmention(t, repos, post.ID, "https://a.example/1", start)
+		second := saveWebmention(t, repos, post.ID, "https://a.example/2", start.Add(-time.Minute))
+		pending, err := repos.Webmentions.FindPending(ctx, start, 10)
+		if err != nil {
+			t.Fatalf("FindPending() error = %v", err)
+		}
+		wantIDs(t, "FindPending()", webmentionIDs(pending), second.ID, first.ID)
+
+		// A failed attempt puts it off until its next attempt
+		first.Attempts = 2
+		first.NextAttemptAt = start.Add(2 * time.Hour)
+		first.UpdatedAt = start.Add(time.Minute)
+		if err := repos.Webmentions.Update(ctx, first); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+		pending, _ = repos.Webmentions.FindPending(ctx, start.Add(time.Hour), 10)
+		wantIDs(t, "FindPending() before the next attempt", webmentionIDs(pending), second.ID)
+
+		// Receiving it again resets it, keeping its id and creation time
+		again := saveWebmention(t, repos, post.ID, "https://a.example/1", start.Add(time.Hour))
+		if again.ID != first.ID || !again.CreatedAt.Equal(start) {
+			t.Errorf("Save() again = id %d created %v, want id %d created %v", again.ID, again.CreatedAt, first.ID, start)
+		}
+		pending, _ = repos.Webmentions.FindPending(ctx, start.Add(time.Hour), 10)
+		if len(pending) != 2 || pending[1].ID != first.ID || pending[1].Attempts != 0 {
+			t.Errorf("FindPending() after saving again = %+v, want it last with no attempts", pending)
+		}
+
+		verifiedAt := start.Add(2 * time.Hour)
+		second.Status = domain.WebmentionVerified
+		second.Type = domain.WebmentionReply
+		second.AuthorName = "Ann"
+		second.Content = "Nice"
+		second.VerifiedAt = &verifiedAt
+		if err := repos.Webmentions.Update(ctx, second); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+		verified, err := repos.Webmentions.FindVerifiedByPosts(ctx, []int64{post.ID})
+		if err != nil {
+			t.Fatalf("FindVerifiedByPosts() error = %v", err)
+		}
+		if len(verified) != 1 || verified[0].ID != second.ID || verified[0].Type != domain.WebmentionReply ||
+			verified[0].AuthorName != "Ann" || verified[0].Content != "Nice" {
+			t.Errorf("FindVerifiedByPosts() = %+v, want the verified reply", verified)
+		}
+		pending, _ = repos.Webmentions.FindPending(ctx, start.Add(time.Hour), 1)
+		wantIDs(t, "FindPending() limited", webmentionIDs(pending), first.ID)
+		if verified, err := repos.Webmentions.FindVerifiedByPosts(ctx, nil); err != nil || verified == nil || len(verified) != 0 {
+			t.Errorf("FindVerifiedByPosts(nil) = %v, %v, want an empty list", verified, err)
+		}
+
+		if err := repos.Webmentions.DeleteBySource(ctx, "https://a.example/2"); err != nil {
+			t.Fatalf("DeleteBySource() error = %v", err)
+		}
+		verified, _ = repos.Webmentions.FindVerifiedByPosts(ctx, []int64{post.ID})
+		pending, _ = repos.Webmentions.FindPending(ctx, start.Add(time.Hour), 10)
+		if len(verified) != 0 || len(pending) != 1 {
+			t.Errorf("after DeleteBySource() %d verified and %d pending, want only the other source pending", len(verified), len(pending))
+		}
+	})
+}
+
+func saveWebmention(t *testing.T, repos domain.Repositories, postID int64, source string, at time.Time) *domain.Webmention {
+	t.Helper()
+	w := &domain.Webmention{
+		PostID:        postID,
+		Source:        source,
+		Target:        "https://postapi.example/posts/1",
+		Status:        domain.WebmentionPending,
+		Type:          domain.WebmentionMention,
+		NextAttemptAt: at,
+		CreatedAt:     at,
+		UpdatedAt:     at,
+	}
+	if err := repos.Webmentions.Save(ctx, w); err != nil {
+		t.Fatalf("Webmentions.Save() error = %v", err)
+	}
+	return w
+}
+
+func webmentionIDs(webmentions []*domain.Webmention) []int64 {
+	ids := make([]int64, len(webmentions))
+	for i, w := range webmentions {
+		ids[i] = w.ID
+	}
+	return ids
+}
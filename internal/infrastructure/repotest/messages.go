@@ -0,0 +1,245 @@
+package repotest
+
+import (
+	"errors"
+	"postapi/internal/domain"
+	"testing"
+	"time"
+)
+
+func testNotifications(t *testing.T, newRepos Factory) {
+	t.Run("List, count and mark read", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice", "bob", "carol")
+		post := createPost(t, repos, "alice", "@bob")
+		first := notify(t, repos, "bob", "alice", domain.NotificationMention, &post.ID)
+		second := notify(t, repos, "bob", "carol", domain.NotificationFollow, nil)
+		third := notify(t, repos, "bob", "alice", domain.NotificationFollow, nil)
+		other := notify(t, repos, "alice", "bob", domain.NotificationFollow, nil)
+
+		notifications, err := repos.Notifications.FindByRecipient(ctx, "bob", false, 10, 0)
+		if err != nil {
+			t.Fatalf("FindByRecipient() error = %v", err)
+		}
+		wantIDs(t, "FindByRecipient()", notificationIDs(notifications), third.ID, second.ID, first.ID)
+		if n := notifications[2]; n.PostID == nil || *n.PostID != post.ID || n.Actor != "alice" || n.Type != domain.NotificationMention {
+			t.Errorf("FindByRecipient()[2] = %+v", n)
+		}
+		notifications, _ = repos.Notifications.FindByRecipient(ctx, "bob", false, 1, 1)
+		wantIDs(t, "FindByRecipient() second page", notificationIDs(notifications), second.ID)
+
+		marked, err := repos.Notifications.MarkRead(ctx, "bob", []int64{first.ID, other.ID})
+		if err != nil || marked != 1 {
+			t.Errorf("MarkRead() = %d, %v, want 1", marked, err)
+		}
+		if marked, _ := repos.Notifications.MarkRead(ctx, "bob", []int64{first.ID}); marked != 0 {
+			t.Errorf("MarkRead() of a read notification = %d, want 0", marked)
+		}
+		if marked, err := repos.Notifications.MarkRead(ctx, "bob", nil); err != nil || marked != 0 {
+			t.Errorf("MarkRead(nil) = %d, %v, want 0", marked, err)
+		}
+		unread, _ := repos.Notifications.FindByRecipient(ctx, "bob", true, 10, 0)
+		wantIDs(t, "FindByRecipient() unread", notificationIDs(unread), third.ID, second.ID)
+		if count, err := repos.Notifications.CountUnread(ctx, "bob"); err != nil || count != 2 {
+			t.Errorf("CountUnread() = %d, %v, want 2", count, err)
+		}
+
+		// Notifications by deleted users are not shown
+		if err := repos.Users.Delete(ctx, "carol"); err != nil {
+			t.Fatalf("Users.Delete() error = %v", err)
+		}
+		if count, _ := repos.Notifications.CountUnread(ctx, "bob"); count != 1 {
+			t.Errorf("CountUnread() without carol = %d, want 1", count)
+		}
+
+		marked, err = repos.Notifications.MarkAllRead(ctx, "bob")
+		if err != nil || marked != 2 {
+			t.Errorf("MarkAllRead() = %d, %v, want 2", marked, err)
+		}
+		if count, _ := repos.Notifications.CountUnread(ctx, "alice"); count != 1 {
+			t.Errorf("CountUnread() of alice = %d, want 1", count)
+		}
+	})
+}
+
+func notify(t *testing.T, repos domain.Repositories, recipient string, actor string, notificationType string, postID *int64) *domain.Notification {
+	t.Helper()
+	n := &domain.Notification{Recipient: recipient, Actor: actor, Type: notificationType, PostID: postID, CreatedAt: time.Now().UTC()}
+	if err := repos.Notifications.Create(ctx, n); err != nil {
+		t.Fatalf("Notifications.Create() error = %v", err)
+	}
+	if n.ID == 0 {
+		t.Fatal("Notifications.Create() did not set the id")
+	}
+	return n
+}
+
+func notificationIDs(notifications []*domain.Notification) []int64 {
+	ids := make([]int64, len(notifications))
+	for i, n := range notifications {
+		ids[i] = n.ID
+	}
+	return ids
+}
+
+func testConversations(t *testing.T, newRepos Factory) {
+	t.Run("Create and find", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice", "bob", "carol")
+		direct := createConversation(t, repos, "bob", false, "bob", "alice")
+		group := createConversation(t, repos, "alice", true, "alice", "bob", "carol")
+
+		found, err := repos.Conversations.FindByID(ctx, direct.ID)
+		if err != nil {
+			t.Fatalf("FindByID() error = %v", err)
+		}
+		if found.CreatedBy != "bob" || found.IsGroup || len(found.Members) != 2 || found.Members[0] != "alice" || found.Members[1] != "bob" {
+			t.Errorf("FindByID() = %+v, want the members sorted", found)
+		}
+		_, err = repos.Conversations.FindByID(ctx, group.ID+100)
+		wantCode(t, err, domain.CodeConversationNotFound)
+
+		found, err = repos.Conversations.FindDirect(ctx, "alice", "bob")
+		if err != nil || found.ID != direct.ID {
+			t.Errorf("FindDirect() = %+v, %v, want conversation %d", found, err, direct.ID)
+		}
+		_, err = repos.Conversations.FindDirect(ctx, "alice", "carol")
+		wantCode(t, err, domain.CodeConversationNotFound)
+	})
+
+	t.Run("Unknown members", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice")
+
+		err := repos.Conversations.Create(ctx, &domain.Conversation{CreatedBy: "alice", Members: []string{"alice", "ghost"}, CreatedAt: time.Now().UTC()})
+		wantCode(t, err, domain.CodeUserNotFound)
+		conversations, err := repos.Conversations.FindByMember(ctx, "alice", 10, 0)
+		if err != nil || len(conversations) != 0 {
+			t.Errorf("FindByMember() after the failed Create() = %d conversations, %v, want none", len(conversations), err)
+		}
+	})
+
+	t.Run("Messages", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice", "bob", "carol")
+		older := createConversation(t, repos, "alice", false, "alice", "bob")
+		newer := createConversation(t, repos, "alice", false, "alice", "carol")
+		quiet := createConversation(t, repos, "alice", true, "alice", "bob", "carol")
+
+		var messages []*domain.Message
+		for _, c := range []*domain.Conversation{newer, older, older} {
+			m := &domain.Message{ConversationID: c.ID, Sender: "alice", Body: "hi", CreatedAt: time.Now().UTC()}
+			if err := repos.Conversations.CreateMessage(ctx, m); err != nil {
+				t.Fatalf("CreateMessage() error = %v", err)
+			}
+			messages = append(messages, m)
+		}
+		err := repos.Conversations.CreateMessage(ctx, &domain.Message{ConversationID: quiet.ID + 100, Sender: "alice", Body: "hi", CreatedAt: time.Now().UTC()})
+		if !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("CreateMessage() to an unknown conversation error = %v, want not found", err)
+		}
+
+		// The conversation with the latest message comes first
+		conversations, err := repos.Conversations.FindByMember(ctx, "alice", 10, 0)
+		if err != nil {
+			t.Fatalf("FindByMember() error = %v", err)
+		}
+		wantIDs(t, "FindByMember()", conversationIDs(conversations), older.ID, newer.ID, quiet.ID)
+		if len(conversations[2].Members) != 3 {
+			t.Errorf("FindByMember() members = %v, want 3", conversations[2].Members)
+		}
+		conversations, _ = repos.Conversations.FindByMember(ctx, "bob", 1, 1)
+		wantIDs(t, "FindByMember() second page", conversationIDs(conversations), quiet.ID)
+
+		found, err := repos.Conversations.FindMessages(ctx, older.ID, 0, 10)
+		if err != nil {
+			t.Fatalf("FindMessages() error = %v", err)
+		}
+		wantIDs(t, "FindMessages()", messageIDs(found), messages[2].ID, messages[1].ID)
+		found, _ = repos.Conversations.FindMessages(ctx, older.ID, messages[2].ID, 10)
+		wantIDs(t, "FindMessages() before the latest", messageIDs(found), messages[1].ID)
+		found, _ = repos.Conversations.FindMessages(ctx, older.ID, 0, 1)
+		wantIDs(t, "FindMessages() limited", messageIDs(found), messages[2].ID)
+
+		if err := repos.Conversations.MarkRead(ctx, older.ID, "bob", 0); err != nil {
+			t.Errorf("MarkRead() error = %v", err)
+		}
+
+		if m, err := repos.Conversations.FindMessage(ctx, older.ID, messages[1].ID); err != nil || m.Body != "hi" {
+			t.Errorf("FindMessage() = %+v, %v", m, err)
+		}
+		_, err = repos.Conversations.FindMessage(ctx, newer.ID, messages[1].ID)
+		wantCode(t, err, domain.CodeMessageNotFound)
+	})
+
+	t.Run("Unread messages", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice", "bob")
+		c := createConversation(t, repos, "alice", false, "alice", "bob")
+		var messages []*domain.Message
+		for _, sender := range []string{"alice", "bob", "alice", "alice"} {
+			m := &domain.Message{ConversationID: c.ID, Sender: sender, Body: "hi", CreatedAt: time.Now().UTC()}
+			if err := repos.Conversations.CreateMessage(ctx, m); err != nil {
+				t.Fatalf("CreateMessage() error = %v", err)
+			}
+			messages = append(messages, m)
+		}
+
+		// Messages bob sent himself are never unread for him
+		wantRead(t, repos, "bob", 0, 3)
+		wantRead(t, repos, "alice", 0, 1)
+
+		if err := repos.Conversations.MarkRead(ctx, c.ID, "bob", messages[2].ID); err != nil {
+			t.Fatalf("MarkRead() error = %v", err)
+		}
+		wantRead(t, repos, "bob", messages[2].ID, 1)
+		// The read marker never goes back
+		if err := repos.Conversations.MarkRead(ctx, c.ID, "bob", messages[0].ID); err != nil {
+			t.Fatalf("MarkRead() error = %v", err)
+		}
+		wantRead(t, repos, "bob", messages[2].ID, 1)
+		if err := repos.Conversations.MarkRead(ctx, c.ID, "bob", 0); err != nil {
+			t.Fatalf("MarkRead() error = %v", err)
+		}
+		wantRead(t, repos, "bob", messages[3].ID, 0)
+		wantRead(t, repos, "alice", 0, 1)
+	})
+}
+
+// wantRead checks the read marker and unread count of the only
+// conversation of username.
+func wantRead(t *testing.T, repos domain.Repositories, username string, lastRead int64, unread int64) {
+	t.Helper()
+	conversations, err := repos.Conversations.FindByMember(ctx, username, 10, 0)
+	if err != nil || len(conversations) != 1 {
+		t.Fatalf("FindByMember(%s) = %d conversations, %v, want 1", username, len(conversations), err)
+	}
+	if c := conversations[0]; c.LastReadMessageID != lastRead || c.UnreadCount != unread {
+		t.Errorf("FindByMember(%s) last read = %d, unread = %d, want %d and %d", username, c.LastReadMessageID, c.UnreadCount, lastRead, unread)
+	}
+}
+
+func createConversation(t *testing.T, repos domain.Repositories, creator string, group bool, members ...string) *domain.Conversation {
+	t.Helper()
+	c := &domain.Conversation{CreatedBy: creator, IsGroup: group, Members: members, CreatedAt: time.Now().UTC()}
+	if err := repos.Conversations.Create(ctx, c); err != nil {
+		t.Fatalf("Conversations.Create() error = %v", err)
+	}
+	return c
+}
+
+func conversationIDs(conversations []*domain.Conversation) []int64 {
+	ids := make([]int64, len(conversations))
+	for i, c := range conversations {
+		ids[i] = c.ID
+	}
+	return ids
+}
+
+func messageIDs(messages []*domain.Message) []int64 {
+	ids := make([]int64, len(messages))
+	for i, m := range messages {
+		ids[i] = m.ID
+	}
+	return ids
+}
@@ -0,0 +1,406 @@
+package repotest
+
+import (
+	"errors"
+	"postapi/internal/domain"
+	"testing"
+	"time"
+)
+
+func testPosts(t *testing.T, newRepos Factory) {
+	t.Run("Create and find", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice")
+		post := createPost(t, repos, "alice", "Hello")
+		if post.ID == 0 || post.CreatedAt.IsZero() {
+			t.Fatalf("Create() = %+v, want the id and creation time set", post)
+		}
+
+		found, err := repos.Posts.FindByID(ctx, post.ID)
+		if err != nil {
+			t.Fatalf("FindByID() error = %v", err)
+		}
+		if found.Title != "Title" || found.Content != "Hello" || found.Format != domain.PostFormatPlain || found.Author != "alice" {
+			t.Errorf("FindByID() = %+v", found)
+		}
+		_, err = repos.Posts.FindByID(ctx, post.ID+1)
+		wantCode(t, err, domain.CodePostNotFound)
+
+		err = repos.Posts.Create(ctx, &domain.Post{Title: "T", Content: "C", Format: domain.PostFormatPlain, Author: "ghost"})
+		if !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("Create() by an unknown author error = %v, want not found", err)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice", "bob")
+		post := createPost(t, repos, "alice", "Hello")
+
+		post.Content = "Edited"
+		post.Format = domain.PostFormatMarkdown
+		if err := repos.Posts.Update(ctx, post); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+		found, err := repos.Posts.FindByID(ctx, post.ID)
+		if err != nil || found.Content != "Edited" || found.Format != domain.PostFormatMarkdown {
+			t.Errorf("FindByID() after Update() = %+v, %v", found, err)
+		}
+
+		stolen := *post
+		stolen.Author = "bob"
+		wantCode(t, repos.Posts.Update(ctx, &stolen), domain.CodePostNotFound)
+	})
+
+	t.Run("Delete and restore", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice", "bob")
+		first := createPost(t, repos, "alice", "First")
+		second := createPost(t, repos, "alice", "Second")
+		kept := createPost(t, repos, "alice", "Kept")
+		hourAgo := time.Now().Add(-time.Hour)
+
+		wantCode(t, repos.Posts.Delete(ctx, first.ID, "bob"), domain.CodePostNotFound)
+		for _, post := range []*domain.Post{first, second} {
+			if err := repos.Posts.Delete(ctx, post.ID, "alice"); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+		}
+		wantCode(t, repos.Posts.Delete(ctx, first.ID, "alice"), domain.CodePostNotFound)
+		wantCode(t, repos.Posts.Update(ctx, first), domain.CodePostNotFound)
+		_, err := repos.Posts.FindByID(ctx, first.ID)
+		wantCode(t, err, domain.CodePostNotFound)
+
+		posts, err := repos.Posts.FindByAuthor(ctx, "alice")
+		if err != nil {
+			t.Fatalf("FindByAuthor() error = %v", err)
+		}
+		wantIDs(t, "FindByAuthor()", postIDs(posts), kept.ID)
+
+		deleted, err := repos.Posts.FindDeletedByAuthor(ctx, "alice", hourAgo)
+		if err != nil {
+			t.Fatalf("FindDeletedByAuthor() error = %v", err)
+		}
+		wantIDs(t, "FindDeletedByAuthor()", postIDs(deleted), second.ID, first.ID)
+		if deleted[0].DeletedAt == nil {
+			t.Error("FindDeletedByAuthor() did not set the deletion time")
+		}
+		deleted, err = repos.Posts.FindDeletedByAuthor(ctx, "alice", time.Now().Add(time.Hour))
+		if err != nil || len(deleted) != 0 {
+			t.Errorf("FindDeletedByAuthor() after the deletions = %d posts, %v, want none", len(deleted), err)
+		}
+
+		wantCode(t, repos.Posts.Restore(ctx, first.ID, "alice", time.Now().Add(time.Hour)), domain.CodePostNotFound)
+		wantCode(t, repos.Posts.Restore(ctx, first.ID, "bob", hourAgo), domain.CodePostNotFound)
+		if err := repos.Posts.Restore(ctx, first.ID, "alice", hourAgo); err != nil {
+			t.Fatalf("Restore() error = %v", err)
+		}
+		if _, err := repos.Posts.FindByID(ctx, first.ID); err != nil {
+			t.Errorf("FindByID() after Restore() error = %v", err)
+		}
+		wantCode(t, repos.Posts.Restore(ctx, kept.ID, "alice", hourAgo), domain.CodePostNotFound)
+	})
+
+	t.Run("Posts of deleted users are hidden", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice")
+		post := createPost(t, repos, "alice", "Hello")
+		if err := repos.Users.Delete(ctx, "alice"); err != nil {
+			t.Fatalf("Users.Delete() error = %v", err)
+		}
+
+		_, err := repos.Posts.FindByID(ctx, post.ID)
+		wantCode(t, err, domain.CodePostNotFound)
+		posts, err := repos.Posts.FindByAuthor(ctx, "alice")
+		if err != nil || len(posts) != 0 {
+			t.Errorf("FindByAuthor() = %d posts, %v, want none", len(posts), err)
+		}
+	})
+
+	t.Run("By author, newest first", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice", "bob")
+		first := createPost(t, repos, "alice", "First")
+		second := createPost(t, repos, "alice", "Second")
+		createPost(t, repos, "bob", "Not hers")
+		third := createPost(t, repos, "alice", "Third")
+
+		posts, err := repos.Posts.FindByAuthor(ctx, "alice")
+		if err != nil {
+			t.Fatalf("FindByAuthor() error = %v", err)
+		}
+		wantIDs(t, "FindByAuthor()", postIDs(posts), third.ID, second.ID, first.ID)
+		posts, err = repos.Posts.FindLatestByAuthor(ctx, "alice", 10, 0)
+		if err != nil {
+			t.Fatalf("FindLatestByAuthor() error = %v", err)
+		}
+		wantIDs(t, "FindLatestByAuthor()", postIDs(posts), third.ID, second.ID, first.ID)
+		posts, _ = repos.Posts.FindLatestByAuthor(ctx, "alice", 1, 1)
+		wantIDs(t, "FindLatestByAuthor() second page", postIDs(posts), second.ID)
+
+		if err := repos.Posts.Delete(ctx, second.ID, "alice"); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		posts, _ = repos.Posts.FindLatestByAuthor(ctx, "alice", 10, 0)
+		wantIDs(t, "FindLatestByAuthor() after deleting", postIDs(posts), third.ID, first.ID)
+	})
+
+	t.Run("Find after", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice", "bob")
+		first := createPost(t, repos, "alice", "First")
+		second := createPost(t, repos, "bob", "Second")
+		third := createPost(t, repos, "alice", "Third")
+		if err := repos.Posts.Delete(ctx, second.ID, "bob"); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+
+		posts, err := repos.Posts.FindAfter(ctx, 0, 2)
+		if err != nil {
+			t.Fatalf("FindAfter() error = %v", err)
+		}
+		wantIDs(t, "FindAfter()", postIDs(posts), first.ID, second.ID)
+		posts, _ = repos.Posts.FindAfter(ctx, second.ID, 2)
+		wantIDs(t, "FindAfter() next page", postIDs(posts), third.ID)
+		posts, _ = repos.Posts.FindAfter(ctx, third.ID, 2)
+		wantIDs(t, "FindAfter() past the last post", postIDs(posts))
+	})
+
+	t.Run("Purge deleted", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice")
+		post := createPost(t, repos, "alice", "Hello #go")
+		createPost(t, repos, "alice", "Kept")
+		if err := repos.Tags.SetPostTags(ctx, post.ID, []string{"go"}); err != nil {
+			t.Fatalf("SetPostTags() error = %v", err)
+		}
+		if err := repos.Posts.Delete(ctx, post.ID, "alice"); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+
+		purged, err := repos.Posts.PurgeDeleted(ctx, time.Now().Add(time.Minute))
+		if err != nil || purged != 1 {
+			t.Fatalf("PurgeDeleted() = %d, %v, want 1", purged, err)
+		}
+		wantCode(t, repos.Posts.Restore(ctx, post.ID, "alice", time.Now().Add(-time.Hour)), domain.CodePostNotFound)
+		trending, err := repos.Tags.Trending(ctx, time.Now().Add(-time.Hour), 10)
+		if err != nil || len(trending) != 0 {
+			t.Errorf("Trending() after purging = %d tags, %v, want none", len(trending), err)
+		}
+	})
+}
+
+func testTags(t *testing.T, newRepos Factory) {
+	t.Run("Posts by tag", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice")
+		first := createPost(t, repos, "alice", "#go")
+		second := createPost(t, repos, "alice", "#go #rust")
+		third := createPost(t, repos, "alice", "#go")
+		setTags(t, repos, first.ID, "go")
+		setTags(t, repos, second.ID, "go", "rust")
+		setTags(t, repos, third.ID, "go")
+
+		posts, err := repos.Tags.FindPostsByTag(ctx, "go", 10, 0)
+		if err != nil {
+			t.Fatalf("FindPostsByTag() error = %v", err)
+		}
+		wantIDs(t, "FindPostsByTag()", postIDs(posts), third.ID, second.ID, first.ID)
+		posts, _ = repos.Tags.FindPostsByTag(ctx, "go", 1, 1)
+		wantIDs(t, "FindPostsByTag() second page", postIDs(posts), second.ID)
+
+		// Setting the tags replaces them, and deleted posts are not listed
+		setTags(t, repos, second.ID, "rust")
+		if err := repos.Posts.Delete(ctx, third.ID, "alice"); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		posts, _ = repos.Tags.FindPostsByTag(ctx, "go", 10, 0)
+		wantIDs(t, "FindPostsByTag() after the changes", postIDs(posts), first.ID)
+	})
+
+	t.Run("Trending", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice")
+		setTags(t, repos, createPost(t, repos, "alice", "1").ID, "go", "rust")
+		setTags(t, repos, createPost(t, repos, "alice", "2").ID, "go", "c")
+		setTags(t, repos, createPost(t, repos, "alice", "3").ID, "go", "rust")
+		setTags(t, repos, createPost(t, repos, "alice", "4").ID, "ada")
+
+		trending, err := repos.Tags.Trending(ctx, time.Now().Add(-time.Hour), 3)
+		if err != nil {
+			t.Fatalf("Trending() error = %v", err)
+		}
+		want := []domain.TagCount{{Tag: "go", Count: 3}, {Tag: "rust", Count: 2}, {Tag: "ada", Count: 1}}
+		if len(trending) != len(want) {
+			t.Fatalf("Trending() returned %d tags, want %d", len(trending), len(want))
+		}
+		for i, tag := range trending {
+			if *tag != want[i] {
+				t.Errorf("Trending()[%d] = %+v, want %+v", i, *tag, want[i])
+			}
+		}
+
+		trending, err = repos.Tags.Trending(ctx, time.Now().Add(time.Hour), 3)
+		if err != nil || len(trending) != 0 {
+			t.Errorf("Trending() of future posts = %d tags, %v, want none", len(trending), err)
+		}
+	})
+}
+
+func setTags(t *testing.T, repos domain.Repositories, postID int64, tags ...string) {
+	t.Helper()
+	if err := repos.Tags.SetPostTags(ctx, postID, tags); err != nil {
+		t.Fatalf("SetPostTags() error = %v", err)
+	}
+}
+
+func testMentions(t *testing.T, newRepos Factory) {
+	t.Run("Set and find", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice", "bob", "carol")
+		first := createPost(t, repos, "alice", "@bob @carol")
+		second := createPost(t, repos, "alice", "@bob")
+		setMentions(t, repos, first.ID,
+			&domain.Mention{Username: "carol", Field: domain.MentionFieldContent, Start: 5, End: 11},
+			&domain.Mention{Username: "bob", Field: domain.MentionFieldContent, Start: 0, End: 4},
+		)
+		setMentions(t, repos, second.ID, &domain.Mention{Username: "bob", Field: domain.MentionFieldContent, Start: 0, End: 4})
+
+		mentions, err := repos.Mentions.FindByPosts(ctx, []int64{second.ID, first.ID})
+		if err != nil {
+			t.Fatalf("FindByPosts() error = %v", err)
+		}
+		want := []domain.Mention{
+			{PostID: first.ID, Username: "bob", Field: domain.MentionFieldContent, Start: 0, End: 4},
+			{PostID: first.ID, Username: "carol", Field: domain.MentionFieldContent, Start: 5, End: 11},
+			{PostID: second.ID, Username: "bob", Field: domain.MentionFieldContent, Start: 0, End: 4},
+		}
+		if len(mentions) != len(want) {
+			t.Fatalf("FindByPosts() returned %d mentions, want %d", len(mentions), len(want))
+		}
+		for i, m := range mentions {
+			if *m != want[i] {
+				t.Errorf("FindByPosts()[%d] = %+v, want %+v", i, *m, want[i])
+			}
+		}
+		mentions, err = repos.Mentions.FindByPosts(ctx, nil)
+		if err != nil || len(mentions) != 0 {
+			t.Errorf("FindByPosts(nil) = %d mentions, %v, want none", len(mentions), err)
+		}
+
+		posts, err := repos.Mentions.FindPostsMentioning(ctx, "bob", 10, 0)
+		if err != nil {
+			t.Fatalf("FindPostsMentioning() error = %v", err)
+		}
+		wantIDs(t, "FindPostsMentioning()", postIDs(posts), second.ID, first.ID)
+
+		// Setting the mentions replaces them
+		setMentions(t, repos, first.ID)
+		posts, _ = repos.Mentions.FindPostsMentioning(ctx, "carol", 10, 0)
+		wantIDs(t, "FindPostsMentioning() after the change", postIDs(posts))
+	})
+}
+
+func setMentions(t *testing.T, repos domain.Repositories, postID int64, mentions ...*domain.Mention) {
+	t.Helper()
+	if err := repos.Mentions.SetPostMentions(ctx, postID, mentions); err != nil {
+		t.Fatalf("SetPostMentions() error = %v", err)
+	}
+}
+
+func testMedia(t *testing.T, newRepos Factory) {
+	t.Run("Create and find", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice")
+		first := createMedia(t, repos, "alice", "a.png")
+		second := createMedia(t, repos, "alice", "b.png")
+		if first.ID == 0 || first.ID == second.ID {
+			t.Fatalf("Create() ids = %d and %d, want distinct ids", first.ID, second.ID)
+		}
+
+		media, err := repos.Media.FindByIDs(ctx, []int64{second.ID, first.ID, second.ID + 100})
+		if err != nil {
+			t.Fatalf("FindByIDs() error = %v", err)
+		}
+		if len(media) != 2 {
+			t.Fatalf("FindByIDs() returned %d items, want 2", len(media))
+		}
+		byKey, err := repos.Media.FindByKeys(ctx, []string{"b.png", "missing.png"})
+		if err != nil || len(byKey) != 1 || byKey[0].ID != second.ID || byKey[0].Owner != "alice" {
+			t.Errorf("FindByKeys() = %v, %v, want b.png", byKey, err)
+		}
+		if media, err := repos.Media.FindByIDs(ctx, nil); err != nil || len(media) != 0 {
+			t.Errorf("FindByIDs(nil) = %d items, %v, want none", len(media), err)
+		}
+	})
+
+	t.Run("Attachments", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice")
+		first := createMedia(t, repos, "alice", "a.png")
+		second := createMedia(t, repos, "alice", "b.png")
+		post := createPost(t, repos, "alice", "Pictures")
+		other := createPost(t, repos, "alice", "More")
+
+		attach(t, repos, post.ID, second.ID, first.ID)
+		attach(t, repos, other.ID, first.ID)
+		attachments, err := repos.Media.FindByPosts(ctx, []int64{other.ID, post.ID})
+		if err != nil {
+			t.Fatalf("FindByPosts() error = %v", err)
+		}
+		got := make([][2]int64, len(attachments))
+		for i, a := range attachments {
+			got[i] = [2]int64{a.PostID, a.ID}
+		}
+		want := [][2]int64{{post.ID, second.ID}, {post.ID, first.ID}, {other.ID, first.ID}}
+		if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+			t.Errorf("FindByPosts() = %v, want %v as (post, media)", got, want)
+		}
+
+		// Setting the attachments replaces them
+		attach(t, repos, post.ID)
+		attachments, _ = repos.Media.FindByPosts(ctx, []int64{post.ID})
+		if len(attachments) != 0 {
+			t.Errorf("FindByPosts() after removing the attachments = %d items, want none", len(attachments))
+		}
+
+		err = repos.Media.SetPostAttachments(ctx, post.ID, []int64{second.ID + 100})
+		wantCode(t, err, domain.CodeMediaNotFound)
+	})
+
+	t.Run("Of deleted owners", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice", "bob")
+		first := createMedia(t, repos, "alice", "a.png")
+		second := createMedia(t, repos, "alice", "b.png")
+		createMedia(t, repos, "bob", "c.png")
+		if err := repos.Users.Delete(ctx, "alice"); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+
+		media, err := repos.Media.FindByDeletedOwners(ctx, time.Now().Add(-time.Minute))
+		if err != nil || len(media) != 0 {
+			t.Errorf("FindByDeletedOwners() before the deletion = %d items, %v, want none", len(media), err)
+		}
+		media, err = repos.Media.FindByDeletedOwners(ctx, time.Now().Add(time.Minute))
+		if err != nil || len(media) != 2 || media[0].ID != first.ID || media[1].ID != second.ID {
+			t.Errorf("FindByDeletedOwners() = %v, %v, want a.png and b.png", media, err)
+		}
+	})
+}
+
+func createMedia(t *testing.T, repos domain.Repositories, owner string, key string) *domain.Media {
+	t.Helper()
+	media := &domain.Media{Owner: owner, Key: key, ContentType: "image/png", Size: 10, Width: 2, Height: 1, CreatedAt: time.Now().UTC()}
+	if err := repos.Media.Create(ctx, media); err != nil {
+		t.Fatalf("Media.Create() error = %v", err)
+	}
+	return media
+}
+
+func attach(t *testing.T, repos domain.Repositories, postID int64, mediaIDs ...int64) {
+	t.Helper()
+	if err := repos.Media.SetPostAttachments(ctx, postID, mediaIDs); err != nil {
+		t.Fatalf("SetPostAttachments() error = %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+// Package repotest checks that an implementation of the domain repositories
+// behaves as the use cases expect. The tests of each implementation run it,
+// so that the in-memory repositories stay true to the Postgres ones.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"postapi/internal/domain"
+	"slices"
+	"testing"
+)
+
+// Factory returns empty repositories, and a unit of work over them, for one
+// test.
+type Factory func(t *testing.T) (domain.Repositories, domain.UnitOfWork)
+
+// Run runs every contract test against the repositories newRepos returns.
+func Run(t *testing.T, newRepos Factory) {
+	t.Run("Users", func(t *testing.T) { testUsers(t, newRepos) })
+	t.Run("UserCounts", func(t *testing.T) { testUserCounts(t, newRepos) })
+	t.Run("Posts", func(t *testing.T) { testPosts(t, newRepos) })
+	t.Run("Tags", func(t *testing.T) { testTags(t, newRepos) })
+	t.Run("Mentions", func(t *testing.T) { testMentions(t, newRepos) })
+	t.Run("Media", func(t *testing.T) { testMedia(t, newRepos) })
+	t.Run("Profiles", func(t *testing.T) { testProfiles(t, newRepos) })
+	t.Run("Follows", func(t *testing.T) { testFollows(t, newRepos) })
+	t.Run("Blocks", func(t *testing.T) { testBlocks(t, newRepos) })
+	t.Run("Notifications", func(t *testing.T) { testNotifications(t, newRepos) })
+	t.Run("Conversations", func(t *testing.T) { testConversations(t, newRepos) })
+	t.Run("ActorKeys", func(t *testing.T) { testActorKeys(t, newRepos) })
+	t.Run("RemoteFollowers", func(t *testing.T) { testRemoteFollowers(t, newRepos) })
+	t.Run("Deliveries", func(t *testing.T) { testDeliveries(t, newRepos) })
+	t.Run("Webmentions", func(t *testing.T) { testWebmentions(t, newRepos) })
+	t.Run("UnitOfWork", func(t *testing.T) { testUnitOfWork(t, newRepos) })
+}
+
+var ctx = context.Background()
+
+// createUsers registers each username, with a password equal to it.
+func createUsers(t *testing.T, repos domain.Repositories, usernames ...string) {
+	t.Helper()
+	for _, username := range usernames {
+		user := &domain.User{Username: username, Email: username + "@example.com", Password: username}
+		if err := repos.Users.Create(ctx, user); err != nil {
+			t.Fatalf("Users.Create(%s) error = %v", username, err)
+		}
+	}
+}
+
+func createPost(t *testing.T, repos domain.Repositories, author string, content string) *domain.Post {
+	t.Helper()
+	post := &domain.Post{Title: "Title", Content: content, Format: domain.PostFormatPlain, Author: author}
+	if err := repos.Posts.Create(ctx, post); err != nil {
+		t.Fatalf("Posts.Create() error = %v", err)
+	}
+	return post
+}
+
+// wantCode fails unless err is a domain error with code.
+func wantCode(t *testing.T, err error, code string) {
+	t.Helper()
+	var domainErr *domain.Error
+	if !errors.As(err, &domainErr) || domainErr.Code != code {
+		t.Errorf("error = %v, want code %s", err, code)
+	}
+}
+
+// postIDs returns the ids of posts, in order.
+func postIDs(posts []*domain.Post) []int64 {
+	ids := make([]int64, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	return ids
+}
+
+// wantIDs fails unless got lists the ids of want, in order.
+func wantIDs(t *testing.T, name string, got []int64, want ...int64) {
+	t.Helper()
+	if !slices.Equal(got, want) && !(len(got) == 0 && len(want) == 0) {
+		t.Errorf("%s = %v, want %v", name, got, want)
+	}
+}
+
+// wantUsernames fails unless got holds the usernames of want, in any order.
+func wantUsernames(t *testing.T, name string, got []string, want ...string) {
+	t.Helper()
+	got = slices.Sorted(slices.Values(got))
+	want = slices.Sorted(slices.Values(want))
+	if !slices.Equal(got, want) {
+		t.Errorf("%s = %v, want %v", name, got, want)
+	}
+}
@@ -0,0 +1,216 @@
+package repotest
+
+import (
+	"postapi/internal/domain"
+	"testing"
+)
+
+func testProfiles(t *testing.T, newRepos Factory) {
+	t.Run("Create, update and find", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice")
+		avatar := createMedia(t, repos, "alice", "avatar.png")
+
+		profile := &domain.Profile{Username: "alice", Description: "Hi", ProfilePicture: "pic"}
+		if err := repos.Profiles.Create(ctx, profile); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		wantCode(t, repos.Profiles.Create(ctx, profile), domain.CodeProfileExists)
+
+		profile.Description = "Changed"
+		profile.Private = true
+		profile.AvatarMediaID = &avatar.ID
+		if err := repos.Profiles.Update(ctx, profile); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+		found, err := repos.Profiles.FindByUsername(ctx, "alice")
+		if err != nil {
+			t.Fatalf("FindByUsername() error = %v", err)
+		}
+		if found.Description != "Changed" || !found.Private || found.ProfilePicture != "pic" ||
+			found.AvatarMediaID == nil || *found.AvatarMediaID != avatar.ID {
+			t.Errorf("FindByUsername() = %+v", found)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice", "bob")
+		missing := int64(1000)
+
+		_, err := repos.Profiles.FindByUsername(ctx, "alice")
+		wantCode(t, err, domain.CodeProfileNotFound)
+		wantCode(t, repos.Profiles.Update(ctx, &domain.Profile{Username: "alice"}), domain.CodeProfileNotFound)
+		wantCode(t, repos.Profiles.Create(ctx, &domain.Profile{Username: "alice", AvatarMediaID: &missing}), domain.CodeMediaNotFound)
+
+		if err := repos.Profiles.Create(ctx, &domain.Profile{Username: "bob"}); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		wantCode(t, repos.Profiles.Update(ctx, &domain.Profile{Username: "bob", AvatarMediaID: &missing}), domain.CodeMediaNotFound)
+		if err := repos.Users.Delete(ctx, "bob"); err != nil {
+			t.Fatalf("Users.Delete() error = %v", err)
+		}
+		_, err = repos.Profiles.FindByUsername(ctx, "bob")
+		wantCode(t, err, domain.CodeProfileNotFound)
+	})
+}
+
+func testFollows(t *testing.T, newRepos Factory) {
+	t.Run("Follow and unfollow", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice", "bob", "carol")
+		follow(t, repos, "alice", "carol")
+		follow(t, repos, "bob", "carol")
+		follow(t, repos, "carol", "alice")
+
+		followers, err := repos.Follows.GetFollowers(ctx, "carol")
+		if err != nil {
+			t.Fatalf("GetFollowers() error = %v", err)
+		}
+		wantUsernames(t, "GetFollowers()", followers, "alice", "bob")
+		following, err := repos.Follows.GetFollowing(ctx, "carol")
+		if err != nil {
+			t.Fatalf("GetFollowing() error = %v", err)
+		}
+		wantUsernames(t, "GetFollowing()", following, "alice")
+
+		f := &domain.UserFollow{FollowerUsername: "alice", FollowedUsername: "carol"}
+		if exists, err := repos.Follows.Exists(ctx, f); err != nil || !exists {
+			t.Errorf("Exists() = %v, %v, want true", exists, err)
+		}
+		if err := repos.Follows.Delete(ctx, f); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		if exists, err := repos.Follows.Exists(ctx, f); err != nil || exists {
+			t.Errorf("Exists() after Delete() = %v, %v, want false", exists, err)
+		}
+		wantCode(t, repos.Follows.Delete(ctx, f), domain.CodeFollowNotFound)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice", "bob")
+		follow(t, repos, "alice", "bob")
+
+		wantCode(t, repos.Follows.Create(ctx, &domain.UserFollow{FollowerUsername: "alice", FollowedUsername: "bob"}), domain.CodeAlreadyFollowing)
+		wantCode(t, repos.Follows.Create(ctx, &domain.UserFollow{FollowerUsername: "alice", FollowedUsername: "alice"}), domain.CodeSelfFollow)
+		wantCode(t, repos.Follows.Create(ctx, &domain.UserFollow{FollowerUsername: "alice", FollowedUsername: "ghost"}), domain.CodeUserNotFound)
+	})
+
+	t.Run("Deleted users are not listed", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice", "bob")
+		follow(t, repos, "alice", "bob")
+		follow(t, repos, "bob", "alice")
+		if err := repos.Users.Delete(ctx, "alice"); err != nil {
+			t.Fatalf("Users.Delete() error = %v", err)
+		}
+
+		followers, _ := repos.Follows.GetFollowers(ctx, "bob")
+		wantUsernames(t, "GetFollowers()", followers)
+		following, _ := repos.Follows.GetFollowing(ctx, "bob")
+		wantUsernames(t, "GetFollowing()", following)
+	})
+
+	t.Run("Summaries", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice", "bob", "carol", "dave")
+		if err := repos.Profiles.Create(ctx, &domain.Profile{Username: "bob", Description: "Hi", ProfilePicture: "pic", Private: true}); err != nil {
+			t.Fatalf("Profiles.Create() error = %v", err)
+		}
+		follow(t, repos, "alice", "carol")
+		follow(t, repos, "bob", "carol")
+		follow(t, repos, "bob", "alice")
+		follow(t, repos, "carol", "bob")
+		follow(t, repos, "dave", "carol")
+		createPost(t, repos, "bob", "Kept")
+		deleted := createPost(t, repos, "bob", "Deleted")
+		if err := repos.Posts.Delete(ctx, deleted.ID, "bob"); err != nil {
+			t.Fatalf("Posts.Delete() error = %v", err)
+		}
+		if err := repos.Users.Delete(ctx, "dave"); err != nil {
+			t.Fatalf("Users.Delete() error = %v", err)
+		}
+
+		followers, err := repos.Follows.FindFollowers(ctx, "carol")
+		if err != nil {
+			t.Fatalf("FindFollowers() error = %v", err)
+		}
+		wantUsernames(t, "FindFollowers()", summaryUsernames(followers), "alice", "bob")
+		if len(followers) != 2 {
+			t.FailNow()
+		}
+		want := domain.UserSummary{Username: "alice", Email: "alice@example.com",
+			UserCounts: domain.UserCounts{FollowerCount: 1, FollowingCount: 1}}
+		if *followers[0] != want {
+			t.Errorf("FindFollowers()[0] = %+v, want %+v", *followers[0], want)
+		}
+		want = domain.UserSummary{Username: "bob", Email: "bob@example.com", Description: "Hi", ProfilePicture: "pic",
+			Private: true, UserCounts: domain.UserCounts{FollowerCount: 1, FollowingCount: 2, PostCount: 1}}
+		if *followers[1] != want {
+			t.Errorf("FindFollowers()[1] = %+v, want %+v", *followers[1], want)
+		}
+
+		following, err := repos.Follows.FindFollowing(ctx, "bob")
+		if err != nil {
+			t.Fatalf("FindFollowing() error = %v", err)
+		}
+		wantUsernames(t, "FindFollowing()", summaryUsernames(following), "alice", "carol")
+		if len(following) != 2 {
+			t.FailNow()
+		}
+		if c := following[1]; c.FollowerCount != 2 || c.FollowingCount != 1 || c.PostCount != 0 {
+			t.Errorf("FindFollowing()[1] = %+v, want 2 followers, 1 follow and no posts", *c)
+		}
+		if none, err := repos.Follows.FindFollowers(ctx, "dave"); err != nil || len(none) != 0 {
+			t.Errorf("FindFollowers() of a user nobody follows = %v, %v, want none", none, err)
+		}
+	})
+}
+
+func summaryUsernames(summaries []*domain.UserSummary) []string {
+	usernames := make([]string, len(summaries))
+	for i, s := range summaries {
+		usernames[i] = s.Username
+	}
+	return usernames
+}
+
+func follow(t *testing.T, repos domain.Repositories, follower string, followed string) {
+	t.Helper()
+	if err := repos.Follows.Create(ctx, &domain.UserFollow{FollowerUsername: follower, FollowedUsername: followed}); err != nil {
+		t.Fatalf("Follows.Create(%s, %s) error = %v", follower, followed, err)
+	}
+}
+
+func testBlocks(t *testing.T, newRepos Factory) {
+	t.Run("Block and unblock", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice", "bob", "carol")
+		block := &domain.UserBlock{BlockerUsername: "alice", BlockedUsername: "bob"}
+		if err := repos.Blocks.Create(ctx, block); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+
+		for _, pair := range [][2]string{{"alice", "bob"}, {"bob", "alice"}} {
+			if blocked, err := repos.Blocks.IsBlocked(ctx, pair[0], pair[1]); err != nil || !blocked {
+				t.Errorf("IsBlocked(%s, %s) = %v, %v, want true", pair[0], pair[1], blocked, err)
+			}
+		}
+		if blocked, err := repos.Blocks.IsBlocked(ctx, "alice", "carol"); err != nil || blocked {
+			t.Errorf("IsBlocked(alice, carol) = %v, %v, want false", blocked, err)
+		}
+
+		wantCode(t, repos.Blocks.Create(ctx, block), domain.CodeAlreadyBlocked)
+		wantCode(t, repos.Blocks.Create(ctx, &domain.UserBlock{BlockerUsername: "alice", BlockedUsername: "alice"}), domain.CodeSelfBlock)
+		err := repos.Blocks.Create(ctx, &domain.UserBlock{BlockerUsername: "alice", BlockedUsername: "ghost"})
+		wantCode(t, err, domain.CodeUserNotFound)
+
+		if err := repos.Blocks.Delete(ctx, block); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		if blocked, err := repos.Blocks.IsBlocked(ctx, "bob", "alice"); err != nil || blocked {
+			t.Errorf("IsBlocked() after Delete() = %v, %v, want false", blocked, err)
+		}
+	})
+}
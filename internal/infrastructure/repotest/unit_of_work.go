@@ -0,0 +1,50 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"postapi/internal/domain"
+	"testing"
+)
+
+func testUnitOfWork(t *testing.T, newRepos Factory) {
+	t.Run("Commit", func(t *testing.T) {
+		repos, uow := newRepos(t)
+		createUsers(t, repos, "alice", "bob")
+
+		err := uow.Do(ctx, func(ctx context.Context, tx domain.Repositories) error {
+			if err := tx.Follows.Create(ctx, &domain.UserFollow{FollowerUsername: "alice", FollowedUsername: "bob"}); err != nil {
+				return err
+			}
+			return tx.Follows.Create(ctx, &domain.UserFollow{FollowerUsername: "bob", FollowedUsername: "alice"})
+		})
+		if err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+		following, _ := repos.Follows.GetFollowing(ctx, "alice")
+		wantUsernames(t, "GetFollowing() after the commit", following, "bob")
+		following, _ = repos.Follows.GetFollowing(ctx, "bob")
+		wantUsernames(t, "GetFollowing() after the commit", following, "alice")
+	})
+
+	t.Run("Roll back", func(t *testing.T) {
+		repos, uow := newRepos(t)
+		createUsers(t, repos, "alice", "bob")
+		failed := errors.New("failed")
+
+		err := uow.Do(ctx, func(ctx context.Context, tx domain.Repositories) error {
+			if err := tx.Follows.Create(ctx, &domain.UserFollow{FollowerUsername: "alice", FollowedUsername: "bob"}); err != nil {
+				return err
+			}
+			if following, _ := tx.Follows.GetFollowing(ctx, "alice"); len(following) != 1 {
+				t.Errorf("GetFollowing() inside the unit of work = %v, want bob", following)
+			}
+			return failed
+		})
+		if !errors.Is(err, failed) {
+			t.Errorf("Do() error = %v, want %v", err, failed)
+		}
+		following, _ := repos.Follows.GetFollowing(ctx, "alice")
+		wantUsernames(t, "GetFollowing() after the rollback", following)
+	})
+}
@@ -0,0 +1,188 @@
+package repotest
+
+import (
+	"postapi/internal/domain"
+	"testing"
+	"time"
+)
+
+func testUsers(t *testing.T, newRepos Factory) {
+	t.Run("Create and find", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice")
+
+		user, err := repos.Users.FindByUsername(ctx, "alice")
+		if err != nil {
+			t.Fatalf("FindByUsername() error = %v", err)
+		}
+		if user.Username != "alice" || user.Email != "alice@example.com" || user.DeletedAt != nil {
+			t.Errorf("FindByUsername() = %+v", user)
+		}
+		if user.Password == "alice" {
+			t.Error("FindByUsername() returned the password in clear")
+		}
+
+		_, err = repos.Users.FindByUsername(ctx, "bob")
+		wantCode(t, err, domain.CodeUserNotFound)
+
+		err = repos.Users.Create(ctx, &domain.User{Username: "alice", Email: "other@example.com", Password: "x"})
+		wantCode(t, err, domain.CodeUsernameTaken)
+		err = repos.Users.Create(ctx, &domain.User{Username: "bob", Email: "alice@example.com", Password: "x"})
+		wantCode(t, err, domain.CodeEmailTaken)
+	})
+
+	t.Run("Login", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice")
+
+		user, err := repos.Users.LoginUser(ctx, &domain.User{Username: "alice", Password: "alice"})
+		if err != nil {
+			t.Fatalf("LoginUser() error = %v", err)
+		}
+		if user.Username != "alice" || user.Password != "" {
+			t.Errorf("LoginUser() = %+v, want alice without the password", user)
+		}
+
+		_, err = repos.Users.LoginUser(ctx, &domain.User{Username: "alice", Password: "wrong"})
+		wantCode(t, err, domain.CodeInvalidCredentials)
+		_, err = repos.Users.LoginUser(ctx, &domain.User{Username: "bob", Password: "bob"})
+		wantCode(t, err, domain.CodeUserNotFound)
+	})
+
+	t.Run("Delete and restore", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice")
+		hourAgo := time.Now().Add(-time.Hour)
+
+		if err := repos.Users.Delete(ctx, "alice"); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		_, err := repos.Users.FindByUsername(ctx, "alice")
+		wantCode(t, err, domain.CodeUserNotFound)
+		_, err = repos.Users.LoginUser(ctx, &domain.User{Username: "alice", Password: "alice"})
+		wantCode(t, err, domain.CodeUserNotFound)
+		wantCode(t, repos.Users.Delete(ctx, "alice"), domain.CodeUserNotFound)
+
+		_, err = repos.Users.Restore(ctx, &domain.User{Username: "alice", Password: "wrong"}, hourAgo)
+		wantCode(t, err, domain.CodeInvalidCredentials)
+		_, err = repos.Users.Restore(ctx, &domain.User{Username: "alice", Password: "alice"}, time.Now().Add(time.Hour))
+		wantCode(t, err, domain.CodeUserNotFound)
+
+		user, err := repos.Users.Restore(ctx, &domain.User{Username: "alice", Password: "alice"}, hourAgo)
+		if err != nil {
+			t.Fatalf("Restore() error = %v", err)
+		}
+		if user.Username != "alice" || user.Password != "" || user.DeletedAt != nil {
+			t.Errorf("Restore() = %+v, want alice restored without the password", user)
+		}
+		if _, err := repos.Users.FindByUsername(ctx, "alice"); err != nil {
+			t.Errorf("FindByUsername() after Restore() error = %v", err)
+		}
+		_, err = repos.Users.Restore(ctx, &domain.User{Username: "alice", Password: "alice"}, hourAgo)
+		wantCode(t, err, domain.CodeUserNotFound)
+	})
+
+	t.Run("Purge deleted", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice", "bob")
+		createPost(t, repos, "alice", "Gone with her")
+		if err := repos.Users.Delete(ctx, "alice"); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+
+		purged, err := repos.Users.PurgeDeleted(ctx, time.Now().Add(-time.Minute))
+		if err != nil || purged != 0 {
+			t.Errorf("PurgeDeleted() before the deletion = %d, %v, want 0", purged, err)
+		}
+		purged, err = repos.Users.PurgeDeleted(ctx, time.Now().Add(time.Minute))
+		if err != nil || purged != 1 {
+			t.Fatalf("PurgeDeleted() = %d, %v, want 1", purged, err)
+		}
+
+		// The username is free again, and nothing of the old account is left
+		createUsers(t, repos, "alice")
+		posts, err := repos.Posts.FindByAuthor(ctx, "alice")
+		if err != nil || len(posts) != 0 {
+			t.Errorf("FindByAuthor() after purging = %d posts, %v, want none", len(posts), err)
+		}
+		if _, err := repos.Users.FindByUsername(ctx, "bob"); err != nil {
+			t.Errorf("PurgeDeleted() removed an active user: %v", err)
+		}
+	})
+}
+
+func testUserCounts(t *testing.T, newRepos Factory) {
+	t.Run("Follows, posts and deleted users", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice", "bob", "carol")
+		follow(t, repos, "alice", "bob")
+		follow(t, repos, "carol", "bob")
+		follow(t, repos, "bob", "alice")
+		createPost(t, repos, "alice", "Kept")
+		trashed := createPost(t, repos, "alice", "Trashed")
+		wantCounts(t, repos, "bob", domain.UserCounts{FollowerCount: 2, FollowingCount: 1})
+		wantCounts(t, repos, "alice", domain.UserCounts{FollowerCount: 1, FollowingCount: 1, PostCount: 2})
+
+		if err := repos.Posts.Delete(ctx, trashed.ID, "alice"); err != nil {
+			t.Fatalf("Posts.Delete() error = %v", err)
+		}
+		wantCounts(t, repos, "alice", domain.UserCounts{FollowerCount: 1, FollowingCount: 1, PostCount: 1})
+		if err := repos.Posts.Restore(ctx, trashed.ID, "alice", time.Now().Add(-time.Hour)); err != nil {
+			t.Fatalf("Posts.Restore() error = %v", err)
+		}
+		wantCounts(t, repos, "alice", domain.UserCounts{FollowerCount: 1, FollowingCount: 1, PostCount: 2})
+
+		// Deleted users are not counted until they are restored
+		if err := repos.Users.Delete(ctx, "carol"); err != nil {
+			t.Fatalf("Users.Delete() error = %v", err)
+		}
+		wantCounts(t, repos, "bob", domain.UserCounts{FollowerCount: 1, FollowingCount: 1})
+		if _, err := repos.Users.Restore(ctx, &domain.User{Username: "carol", Password: "carol"}, time.Now().Add(-time.Hour)); err != nil {
+			t.Fatalf("Users.Restore() error = %v", err)
+		}
+		wantCounts(t, repos, "bob", domain.UserCounts{FollowerCount: 2, FollowingCount: 1})
+
+		unfollow := &domain.UserFollow{FollowerUsername: "alice", FollowedUsername: "bob"}
+		if err := repos.Follows.Delete(ctx, unfollow); err != nil {
+			t.Fatalf("Follows.Delete() error = %v", err)
+		}
+		// Deleting it again changes no count
+		wantCode(t, repos.Follows.Delete(ctx, unfollow), domain.CodeFollowNotFound)
+		wantCounts(t, repos, "bob", domain.UserCounts{FollowerCount: 1, FollowingCount: 1})
+		wantCounts(t, repos, "alice", domain.UserCounts{FollowerCount: 1, PostCount: 2})
+
+		if fixed, err := repos.Users.Recount(ctx); err != nil || fixed != 0 {
+			t.Errorf("Recount() = %d, %v, want nothing to fix", fixed, err)
+		}
+	})
+
+	t.Run("Profiles", func(t *testing.T) {
+		repos, _ := newRepos(t)
+		createUsers(t, repos, "alice", "bob")
+		follow(t, repos, "alice", "bob")
+		createPost(t, repos, "bob", "Hello")
+		if err := repos.Profiles.Create(ctx, &domain.Profile{Username: "bob"}); err != nil {
+			t.Fatalf("Profiles.Create() error = %v", err)
+		}
+
+		profile, err := repos.Profiles.FindByUsername(ctx, "bob")
+		if err != nil {
+			t.Fatalf("Profiles.FindByUsername() error = %v", err)
+		}
+		if want := (domain.UserCounts{FollowerCount: 1, PostCount: 1}); profile.UserCounts != want {
+			t.Errorf("Profiles.FindByUsername() counts = %+v, want %+v", profile.UserCounts, want)
+		}
+	})
+}
+
+// wantCounts fails unless username has the counts want.
+func wantCounts(t *testing.T, repos domain.Repositories, username string, want domain.UserCounts) {
+	t.Helper()
+	user, err := repos.Users.FindByUsername(ctx, username)
+	if err != nil {
+		t.Fatalf("Users.FindByUsername(%s) error = %v", username, err)
+	}
+	if user.UserCounts != want {
+		t.Errorf("counts of %s = %+v, want %+v", username, user.UserCounts, want)
+	}
+}
@@ -0,0 +1,179 @@
+package webmention
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"postapi/internal/domain"
+	"postapi/internal/infrastructure/publicnet"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// maxPageSize bounds the pages the client reads.
+const maxPageSize = 1 << 20
+
+var ErrPrivateAddress = publicnet.ErrPrivateAddress
+
+// Client fetches the pages involved in webmentions. Anyone can make the
+// server fetch a URL by sending a webmention, so NewClient only connects to
+// public addresses.
+type Client struct {
+	HTTP      *http.Client
+	UserAgent string
+}
+
+func NewClient(userAgent string) *Client {
+	return &Client{
+		HTTP:      publicnet.NewHTTPClient(15 * time.Second),
+		UserAgent: userAgent,
+	}
+}
+
+// Fetch retrieves the page at u. Pages are returned whatever their status,
+// so that callers can tell a deleted page from an unreachable one.
+func (c *Client) Fetch(u string) (*domain.WebPage, error) {
+	page, _, err := c.get(u)
+	return page, err
+}
+
+func (c *Client) get(u string) (*domain.WebPage, http.Header, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", "text/html, application/xhtml+xml;q=0.9, */*;q=0.5")
+	req.Header.Set("User-Agent", c.UserAgent)
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize))
+	if err != nil {
+		return nil, nil, err
+	}
+	return &domain.WebPage{
+		URL:         resp.Request.URL.String(),
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        body,
+	}, resp.Header, nil
+}
+
+// DiscoverEndpoint looks for the webmention endpoint of target in its Link
+// headers and then in the first <link> or <a> with rel="webmention".
+func (c *Client) DiscoverEndpoint(target string) (string, error) {
+	page, header, err := c.get(target)
+	if err != nil {
+		return "", err
+	}
+	if page.StatusCode < 200 || page.StatusCode > 299 {
+		return "", fmt.Errorf("discover webmention endpoint of %s: status %d", target, page.StatusCode)
+	}
+	base, err := url.Parse(page.URL)
+	if err != nil {
+		return "", err
+	}
+	if href, ok := endpointFromHeader(header); ok {
+		return resolveEndpoint(base, href), nil
+	}
+	if href, ok := endpointFromHTML(page); ok {
+		return resolveEndpoint(base, href), nil
+	}
+	return "", nil
+}
+
+// endpointFromHeader reads headers such as
+// Link: <https://example.com/webmention>; rel="webmention".
+func endpointFromHeader(header http.Header) (string, bool) {
+	for _, value := range header.Values("Link") {
+		for _, link := range strings.Split(value, ",") {
+			target, params, ok := strings.Cut(link, ";")
+			target = strings.TrimSpace(target)
+			if !ok || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range strings.Split(params, ";") {
+				name, rel, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if strings.EqualFold(name, "rel") && slices.Contains(strings.Fields(strings.ToLower(strings.Trim(rel, `"`))), "webmention") {
+					return target[1 : len(target)-1], true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+func endpointFromHTML(page *domain.WebPage) (string, bool) {
+	if mediaType, _, _ := mime.ParseMediaType(page.ContentType); mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return "", false
+	}
+	doc, err := html.Parse(bytes.NewReader(page.Body))
+	if err != nil {
+		return "", false
+	}
+	for n := range doc.Descendants() {
+		if n.Type != html.ElementNode || (n.DataAtom != atom.Link && n.DataAtom != atom.A) {
+			continue
+		}
+		var rel, href string
+		hasHref := false
+		for _, a := range n.Attr {
+			switch a.Key {
+			case "rel":
+				rel = a.Val
+			case "href":
+				href, hasHref = a.Val, true
+			}
+		}
+		if hasHref && slices.Contains(strings.Fields(strings.ToLower(rel)), "webmention") {
+			return href, true
+		}
+	}
+	return "", false
+}
+
+// resolveEndpoint makes a relative endpoint absolute. An empty href means
+// the page is its own endpoint. Endpoints that are not http(s) are ignored.
+func resolveEndpoint(base *url.URL, href string) string {
+	ref, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return ""
+	}
+	endpoint := base.ResolveReference(ref)
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return ""
+	}
+	return endpoint.String()
+}
+
+// Send notifies endpoint that source links to target.
+func (c *Client) Send(endpoint, source, target string) error {
+	form := url.Values{"source": {source}, "target": {target}}
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", c.UserAgent)
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxPageSize))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("send webmention for %s to %s: %s", target, endpoint, resp.Status)
+	}
+	return nil
+}
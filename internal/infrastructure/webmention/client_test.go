@@ -0,0 +1,89 @@
+package webmention
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDiscoverEndpoint(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/header":
+			w.Header().Add("Link", `<https://other.example/>; rel="me"`)
+			w.Header().Add("Link", `</endpoints/header>; rel="webmention other"`)
+			fmt.Fprint(w, `<link rel="webmention" href="/endpoints/html">`)
+		case "/html":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<p><a rel="nofollow" href="/x">x</a><a rel="webmention" href="endpoint?v=1">wm</a>
+				<link rel="webmention" href="/later"></p>`)
+		case "/self":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<link rel="webmention" href="">`)
+		case "/redirect":
+			http.Redirect(w, r, "/dir/html", http.StatusFound)
+		case "/dir/html":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<link rel="webmention" href="endpoint">`)
+		case "/none":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<link rel="webmention" href="javascript:alert(1)">`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	client := &Client{HTTP: srv.Client()}
+
+	tests := map[string]string{
+		"/header":   srv.URL + "/endpoints/header",
+		"/html":     srv.URL + "/endpoint?v=1",
+		"/self":     srv.URL + "/self",
+		"/redirect": srv.URL + "/dir/endpoint",
+		"/none":     "",
+	}
+	for path, want := range tests {
+		got, err := client.DiscoverEndpoint(srv.URL + path)
+		if err != nil || got != want {
+			t.Errorf("DiscoverEndpoint(%s) = %q, %v, want %q", path, got, err, want)
+		}
+	}
+	if _, err := client.DiscoverEndpoint(srv.URL + "/missing"); err == nil {
+		t.Error("DiscoverEndpoint() of a missing page succeeded")
+	}
+}
+
+func TestSend(t *testing.T) {
+	var source, target string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+			http.Error(w, "bad content type", http.StatusBadRequest)
+			return
+		}
+		source, target = r.FormValue("source"), r.FormValue("target")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+	client := &Client{HTTP: srv.Client()}
+
+	if err := client.Send(srv.URL, "https://blog.example/api/posts/1", "https://a.example/?q=1&r=2"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if source != "https://blog.example/api/posts/1" || target != "https://a.example/?q=1&r=2" {
+		t.Errorf("endpoint received source = %q, target = %q", source, target)
+	}
+}
+
+func TestNewClientRefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "secret")
+	}))
+	defer srv.Close()
+
+	if _, err := NewClient("test").Fetch(srv.URL); !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("Fetch(%s) error = %v, want ErrPrivateAddress", srv.URL, err)
+	}
+}
@@ -2,21 +2,29 @@ package middleware
 
 import (
 	"context"
+	"errors"
+	"mime"
 	"net/http"
 	"postapi/internal/application"
+	"postapi/internal/domain"
 )
 
 type contextKey string
 
 const UsernameKey contextKey = "username"
 
+// AuthMiddleware accepts valid tokens of active users. Tokens outlive the
+// accounts they were issued for, so the user is looked up on every request
+// and a deleted account cannot keep acting until its token expires.
 type AuthMiddleware struct {
 	jwtService application.JWTService
+	userRepo   domain.UserRepository
 }
 
-func NewAuthMiddleware(jwtService application.JWTService) *AuthMiddleware {
+func NewAuthMiddleware(jwtService application.JWTService, userRepo domain.UserRepository) *AuthMiddleware {
 	return &AuthMiddleware{
 		jwtService: jwtService,
+		userRepo:   userRepo,
 	}
 }
 
@@ -24,20 +32,27 @@ func (a *AuthMiddleware) AuthMiddleware(next http.HandlerFunc) http.HandlerFunc
 	return func(w http.ResponseWriter, r *http.Request) {
 		tokenString := r.Header.Get("Authorization")
 		if tokenString == "" {
-			SendResponse(w, r, map[string]string{"error": "Missing authorization header"}, http.StatusUnauthorized)
+			SendProblem(w, r, http.StatusUnauthorized, domain.CodeUnauthorized, "Missing authorization header")
 			return
 		}
 
 		const bearerPrefix = "Bearer "
 		if len(tokenString) < len(bearerPrefix) || tokenString[:len(bearerPrefix)] != bearerPrefix {
-			SendResponse(w, r, map[string]string{"error": "Invalid authorization format"}, http.StatusUnauthorized)
+			SendProblem(w, r, http.StatusUnauthorized, domain.CodeUnauthorized, "Invalid authorization format")
 			return
 		}
 
 		tokenString = tokenString[len(bearerPrefix):]
 		username, err := a.jwtService.ValidateToken(tokenString)
 		if err != nil {
-			SendResponse(w, r, map[string]string{"error": "Invalid token"}, http.StatusUnauthorized)
+			SendProblem(w, r, http.StatusUnauthorized, domain.CodeInvalidToken, "Invalid token")
+			return
+		}
+		if _, err := a.userRepo.FindByUsername(r.Context(), username); errors.Is(err, domain.ErrNotFound) {
+			SendProblem(w, r, http.StatusUnauthorized, domain.CodeInvalidToken, "Invalid token")
+			return
+		} else if err != nil {
+			SendError(w, r, err, "Failed to authenticate")
 			return
 		}
 
@@ -45,3 +60,36 @@ func (a *AuthMiddleware) AuthMiddleware(next http.HandlerFunc) http.HandlerFunc
 		next.ServeHTTP(w, r.WithContext(ctx))
 	}
 }
+
+// StreamAuthMiddleware works like AuthMiddleware but also accepts the token in
+// the access_token query parameter, since browser EventSource and WebSocket
+// clients cannot set the Authorization header.
+func (a *AuthMiddleware) StreamAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	auth := a.AuthMiddleware(next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token := r.URL.Query().Get("access_token"); token != "" && r.Header.Get("Authorization") == "" {
+			r = r.Clone(r.Context())
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		auth(w, r)
+	}
+}
+
+// FormAuthMiddleware works like AuthMiddleware but also accepts the token in
+// the access_token field of a form-encoded body, where Micropub clients may
+// send it. Multipart bodies are not parsed here, so uploads need the header.
+func (a *AuthMiddleware) FormAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	auth := a.AuthMiddleware(next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" && r.Method == http.MethodPost {
+			mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if mediaType == "application/x-www-form-urlencoded" && r.ParseForm() == nil {
+				if token := r.PostForm.Get("access_token"); token != "" {
+					r = r.Clone(r.Context())
+					r.Header.Set("Authorization", "Bearer "+token)
+				}
+			}
+		}
+		auth(w, r)
+	}
+}
@@ -5,6 +5,10 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"postapi/internal/application"
+	"postapi/internal/domain"
+	"postapi/internal/infrastructure/memory"
+	"strings"
 	"testing"
 )
 
@@ -24,9 +28,22 @@ func (m *mockJWTService) ValidateToken(token string) (string, error) {
 	return "", errors.New("not implemented")
 }
 
+// mockUserRepository finds every user, or fails with err when it is set.
+type mockUserRepository struct {
+	domain.UserRepository
+	err error
+}
+
+func (m *mockUserRepository) FindByUsername(ctx context.Context, username string) (*domain.User, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &domain.User{Username: username}, nil
+}
+
 func TestAuthMiddleware_MissingHeader(t *testing.T) {
 	mockService := &mockJWTService{}
-	authMiddleware := NewAuthMiddleware(mockService)
+	authMiddleware := NewAuthMiddleware(mockService, &mockUserRepository{})
 
 	handler := authMiddleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
 		t.Error("Handler should not be called when auth fails")
@@ -44,7 +61,7 @@ func TestAuthMiddleware_MissingHeader(t *testing.T) {
 
 func TestAuthMiddleware_InvalidFormat(t *testing.T) {
 	mockService := &mockJWTService{}
-	authMiddleware := NewAuthMiddleware(mockService)
+	authMiddleware := NewAuthMiddleware(mockService, &mockUserRepository{})
 
 	tests := []struct {
 		name   string
@@ -81,7 +98,7 @@ func TestAuthMiddleware_InvalidToken(t *testing.T) {
 			return "", errors.New("invalid token")
 		},
 	}
-	authMiddleware := NewAuthMiddleware(mockService)
+	authMiddleware := NewAuthMiddleware(mockService, &mockUserRepository{})
 
 	handler := authMiddleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
 		t.Error("Handler should not be called")
@@ -96,6 +113,9 @@ func TestAuthMiddleware_InvalidToken(t *testing.T) {
 	if w.Code != http.StatusUnauthorized {
 		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
 	}
+	if p := decodeProblem(t, w); p.Code != domain.CodeInvalidToken {
+		t.Errorf("Expected code %s, got %s", domain.CodeInvalidToken, p.Code)
+	}
 }
 
 func TestAuthMiddleware_ValidToken(t *testing.T) {
@@ -108,7 +128,7 @@ func TestAuthMiddleware_ValidToken(t *testing.T) {
 			return "", errors.New("invalid token")
 		},
 	}
-	authMiddleware := NewAuthMiddleware(mockService)
+	authMiddleware := NewAuthMiddleware(mockService, &mockUserRepository{})
 
 	handlerCalled := false
 	handler := authMiddleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
@@ -142,6 +162,58 @@ func TestAuthMiddleware_ValidToken(t *testing.T) {
 	}
 }
 
+func TestAuthMiddleware_DeletedUser(t *testing.T) {
+	store := memory.New()
+	users := store.Repositories().Users
+	if err := users.Create(context.Background(), &domain.User{Username: "alice", Email: "alice@example.com", Password: "secret"}); err != nil {
+		t.Fatalf("Users.Create() error = %v", err)
+	}
+	jwtService := application.NewJWTService("test-secret")
+	token, err := jwtService.GenerateToken("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := users.Delete(context.Background(), "alice"); err != nil {
+		t.Fatalf("Users.Delete() error = %v", err)
+	}
+
+	handler := NewAuthMiddleware(jwtService, users).AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler should not be called for a deleted account")
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/posts", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+	if p := decodeProblem(t, w); p.Code != domain.CodeInvalidToken {
+		t.Errorf("Expected code %s, got %s", domain.CodeInvalidToken, p.Code)
+	}
+}
+
+func TestAuthMiddleware_UserLookupFails(t *testing.T) {
+	mockService := &mockJWTService{
+		validateFunc: func(token string) (string, error) {
+			return "testuser", nil
+		},
+	}
+	authMiddleware := NewAuthMiddleware(mockService, &mockUserRepository{err: errors.New("connection refused")})
+
+	handler := authMiddleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler should not be called")
+	})
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("Authorization", "Bearer valid-token")
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+}
+
 func TestAuthMiddleware_ContextKey(t *testing.T) {
 	// Test that the context key is of the correct type
 	if UsernameKey != contextKey("username") {
@@ -189,7 +261,7 @@ func TestAuthMiddleware_DifferentTokens(t *testing.T) {
 				},
 			}
 
-			authMiddleware := NewAuthMiddleware(mockService)
+			authMiddleware := NewAuthMiddleware(mockService, &mockUserRepository{})
 
 			handler := authMiddleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
 				username, _ := r.Context().Value(UsernameKey).(string)
@@ -214,7 +286,7 @@ func TestAuthMiddleware_DifferentTokens(t *testing.T) {
 
 func TestNewAuthMiddleware(t *testing.T) {
 	mockService := &mockJWTService{}
-	authMiddleware := NewAuthMiddleware(mockService)
+	authMiddleware := NewAuthMiddleware(mockService, &mockUserRepository{})
 
 	if authMiddleware == nil {
 		t.Error("NewAuthMiddleware should not return nil")
@@ -224,3 +296,103 @@ func TestNewAuthMiddleware(t *testing.T) {
 		t.Error("JWT service not properly assigned")
 	}
 }
+
+func TestStreamAuthMiddleware(t *testing.T) {
+	mockService := &mockJWTService{
+		validateFunc: func(token string) (string, error) {
+			if token == "valid-token" {
+				return "testuser", nil
+			}
+			return "", errors.New("invalid token")
+		},
+	}
+	authMiddleware := NewAuthMiddleware(mockService, &mockUserRepository{})
+
+	tests := []struct {
+		name           string
+		target         string
+		header         string
+		expectedStatus int
+	}{
+		{"Query token", "/stream?access_token=valid-token", "", http.StatusOK},
+		{"Header token", "/stream", "Bearer valid-token", http.StatusOK},
+		{"Invalid query token", "/stream?access_token=bad", "", http.StatusUnauthorized},
+		{"Missing token", "/stream", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := authMiddleware.StreamAuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+				if username, _ := r.Context().Value(UsernameKey).(string); username != "testuser" {
+					t.Errorf("Expected username testuser, got %s", username)
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			handler(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestFormAuthMiddleware(t *testing.T) {
+	mockService := &mockJWTService{
+		validateFunc: func(token string) (string, error) {
+			if token == "valid-token" {
+				return "testuser", nil
+			}
+			return "", errors.New("invalid token")
+		},
+	}
+	authMiddleware := NewAuthMiddleware(mockService, &mockUserRepository{})
+
+	tests := []struct {
+		name           string
+		contentType    string
+		body           string
+		header         string
+		expectedStatus int
+	}{
+		{"Form token", "application/x-www-form-urlencoded", "h=entry&access_token=valid-token", "", http.StatusOK},
+		{"Header token", "application/json", `{}`, "Bearer valid-token", http.StatusOK},
+		{"Invalid form token", "application/x-www-form-urlencoded", "access_token=bad", "", http.StatusUnauthorized},
+		{"Token in JSON body", "application/json", `{"access_token": "valid-token"}`, "", http.StatusUnauthorized},
+		{"Missing token", "application/x-www-form-urlencoded", "h=entry", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := authMiddleware.FormAuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+				if username, _ := r.Context().Value(UsernameKey).(string); username != "testuser" {
+					t.Errorf("Expected username testuser, got %s", username)
+				}
+				if tt.contentType == "application/x-www-form-urlencoded" && r.PostForm.Get("h") != "entry" {
+					t.Errorf("Expected the form to reach the handler, got %v", r.PostForm)
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/micropub", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			handler(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"postapi/internal/domain"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// problemTypePrefix identifies the problem types of the API; the code of the
+// error completes the URI.
+const problemTypePrefix = "urn:postapi:problem:"
+
+// Problem is an RFC 7807 problem details object. Code repeats the last
+// segment of Type so that clients can switch on it directly, and Errors lists
+// the invalid fields of a rejected request.
+type Problem struct {
+	Type     string              `json:"type"`
+	Title    string              `json:"title"`
+	Status   int                 `json:"status"`
+	Detail   string              `json:"detail,omitempty"`
+	Instance string              `json:"instance,omitempty"`
+	Code     string              `json:"code"`
+	Errors   []domain.FieldError `json:"errors,omitempty"`
+}
+
+func NewProblem(r *http.Request, status int, code string, detail string) Problem {
+	p := Problem{
+		Type:   problemTypePrefix + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+	if r != nil {
+		p.Instance = r.URL.Path
+	}
+	return p
+}
+
+// SendProblem answers with a problem+json body.
+func SendProblem(w http.ResponseWriter, r *http.Request, status int, code string, detail string) {
+	writeProblem(w, NewProblem(r, status, code, detail))
+}
+
+func writeProblem(w http.ResponseWriter, p Problem) {
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(p.Status)
+
+	err := json.NewEncoder(w).Encode(p)
+	if err != nil {
+		log.Printf("Cannot format json. err = %v\n", err)
+	}
+}
+
+// SendError answers with the status and code of a domain error. Any other
+// error is logged and answered with 500 and fallback, so that database
+// details never reach the client.
+func SendError(w http.ResponseWriter, r *http.Request, err error, fallback string) {
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) {
+		p := NewProblem(r, StatusOf(domainErr.Kind), domainErr.Code, domainErr.Message)
+		p.Errors = domainErr.Fields
+		writeProblem(w, p)
+		return
+	}
+	log.Printf("%s. err = %v\n", fallback, err)
+	SendProblem(w, r, http.StatusInternalServerError, domain.CodeInternal, fallback)
+}
+
+// StatusOf is the HTTP status of each kind of domain error.
+func StatusOf(kind domain.ErrorKind) int {
+	switch kind {
+	case domain.KindNotFound:
+		return http.StatusNotFound
+	case domain.KindConflict:
+		return http.StatusConflict
+	case domain.KindForbidden:
+		return http.StatusForbidden
+	case domain.KindValidation:
+		return http.StatusUnprocessableEntity
+	case domain.KindUnauthorized:
+		return http.StatusUnauthorized
+	case domain.KindTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case domain.KindUnsupportedMedia:
+		return http.StatusUnsupportedMediaType
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"postapi/internal/domain"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func decodeProblem(t *testing.T, w *httptest.ResponseRecorder) Problem {
+	t.Helper()
+	if ct := w.Header().Get("Content-Type"); ct != ProblemContentType {
+		t.Errorf("Content-Type = %q, want %q", ct, ProblemContentType)
+	}
+	var p Problem
+	if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+		t.Fatalf("Cannot decode problem: %v", err)
+	}
+	return p
+}
+
+func TestSendProblem(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/posts/7", nil)
+	w := httptest.NewRecorder()
+
+	SendProblem(w, req, http.StatusNotFound, domain.CodePostNotFound, "post not found")
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	want := Problem{
+		Type:     "urn:postapi:problem:post_not_found",
+		Title:    "Not Found",
+		Status:   http.StatusNotFound,
+		Detail:   "post not found",
+		Instance: "/api/posts/7",
+		Code:     domain.CodePostNotFound,
+	}
+	if got := decodeProblem(t, w); !reflect.DeepEqual(got, want) {
+		t.Errorf("Problem = %+v, want %+v", got, want)
+	}
+}
+
+func TestSendError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+		wantDetail string
+	}{
+		{
+			name:       "Not found",
+			err:        domain.NotFound(domain.CodeUserNotFound, "user not found"),
+			wantStatus: http.StatusNotFound,
+			wantCode:   domain.CodeUserNotFound,
+			wantDetail: "user not found",
+		},
+		{
+			name:       "Wrapped conflict",
+			err:        fmt.Errorf("create user: %w", domain.Conflict(domain.CodeUsernameTaken, "username is already taken").Wrap(errors.New("pq: duplicate key"))),
+			wantStatus: http.StatusConflict,
+			wantCode:   domain.CodeUsernameTaken,
+			wantDetail: "username is already taken",
+		},
+		{
+			name:       "Forbidden",
+			err:        domain.Forbidden(domain.CodeNotAllowed, "not allowed"),
+			wantStatus: http.StatusForbidden,
+			wantCode:   domain.CodeNotAllowed,
+			wantDetail: "not allowed",
+		},
+		{
+			name:       "Validation",
+			err:        domain.Invalid(domain.CodeInvalidMessage, "message is empty"),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   domain.CodeInvalidMessage,
+			wantDetail: "message is empty",
+		},
+		{
+			name:       "Unknown error",
+			err:        errors.New("pq: connection refused"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   domain.CodeInternal,
+			wantDetail: "Failed to get user",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/users/alice", nil)
+			w := httptest.NewRecorder()
+
+			SendError(w, req, tt.err, "Failed to get user")
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			p := decodeProblem(t, w)
+			if p.Code != tt.wantCode {
+				t.Errorf("Code = %q, want %q", p.Code, tt.wantCode)
+			}
+			if p.Detail != tt.wantDetail {
+				t.Errorf("Detail = %q, want %q", p.Detail, tt.wantDetail)
+			}
+			if strings.Contains(p.Detail, "pq:") {
+				t.Errorf("Detail leaks the driver error: %q", p.Detail)
+			}
+		})
+	}
+}
+
+func TestSendError_FieldErrors(t *testing.T) {
+	fields := []domain.FieldError{
+		{Field: "title", Code: domain.CodeRequired, Message: "title is required"},
+		{Field: "format", Code: domain.CodeInvalidChoice, Message: "format must be one of plain, markdown"},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/posts", nil)
+	w := httptest.NewRecorder()
+
+	SendError(w, req, domain.InvalidFields(fields), "Failed to create post")
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+	p := decodeProblem(t, w)
+	if p.Code != domain.CodeValidation {
+		t.Errorf("Code = %q, want %q", p.Code, domain.CodeValidation)
+	}
+	if !reflect.DeepEqual(p.Errors, fields) {
+		t.Errorf("Errors = %+v, want %+v", p.Errors, fields)
+	}
+	if p.Detail != "title is required; format must be one of plain, markdown" {
+		t.Errorf("Detail = %q", p.Detail)
+	}
+}
+
+func TestStatusOf(t *testing.T) {
+	tests := []struct {
+		kind domain.ErrorKind
+		want int
+	}{
+		{domain.KindNotFound, http.StatusNotFound},
+		{domain.KindConflict, http.StatusConflict},
+		{domain.KindForbidden, http.StatusForbidden},
+		{domain.KindValidation, http.StatusUnprocessableEntity},
+		{domain.KindUnauthorized, http.StatusUnauthorized},
+		{domain.KindTooLarge, http.StatusRequestEntityTooLarge},
+		{domain.KindUnsupportedMedia, http.StatusUnsupportedMediaType},
+		{0, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		if got := StatusOf(tt.kind); got != tt.want {
+			t.Errorf("StatusOf(%d) = %d, want %d", tt.kind, got, tt.want)
+		}
+	}
+}
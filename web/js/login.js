@@ -29,7 +29,7 @@ $loginForm.addEventListener('submit', async function (event) {
             
             window.location.href = '/';
         } else {
-            alert(data.error || 'Login failed');
+            alert(data.detail || 'Login failed');
         }
     } catch (error) {
         console.error('Login error:', error);
@@ -27,7 +27,7 @@ $registerForm.addEventListener('submit', async function (event) {
             alert('Registration successful! Please login.');
             window.location.href = '/login';
         } else {
-            alert(data.error || 'Registration failed');
+            alert(data.detail || 'Registration failed');
         }
     } catch (error) {
         console.error('Register error:', error);